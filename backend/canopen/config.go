@@ -0,0 +1,206 @@
+package canopen
+
+import (
+	"csv-parser/models"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ObjectDefinition 对象字典条目定义（definitions.json）
+type ObjectDefinition struct {
+	Name       string            `json:"name"`
+	DataType   string            `json:"dataType,omitempty"`
+	SubIndexes map[string]string `json:"subIndexes,omitempty"` // 子索引(十进制) -> 名称
+}
+
+// Definitions CANopen消息定义
+type Definitions struct {
+	Definitions    map[string]ObjectDefinition `json:"definitions"`    // 对象索引(小写十六进制4位) -> 定义
+	Nodes          map[string]string           `json:"nodes"`          // 节点ID(十进制) -> 设备名称
+	EmcyErrorCodes map[string]string           `json:"emcyErrorCodes"` // 错误码(小写十六进制4位) -> 描述
+}
+
+// FromToRule 服务类型到来源/目标的映射规则
+type FromToRule struct {
+	From        string `json:"from"`
+	To          string `json:"to"`
+	Description string `json:"description,omitempty"`
+}
+
+// FromToMapping From->To映射配置，键为服务名称（NMT、SDO_RX、TPDO1...）
+type FromToMapping struct {
+	Rules     map[string]FromToRule `json:"rules"`
+	Separator string                `json:"separator"`
+}
+
+// Config CANOPEN协议解析配置
+type Config struct {
+	Definitions   Definitions
+	FromToMapping FromToMapping
+	Dictionaries  map[uint8]*models.ObjectDictionary // 节点ID -> 导入的EDS/DCF对象字典
+}
+
+// DictionaryDir 对象字典文件在配置目录下的子目录名
+const DictionaryDir = "eds"
+
+// LoadConfig 从配置目录加载 definitions.json、from_to_mapping.json 以及 eds/ 下的对象字典
+// 文件不存在时使用空配置
+func LoadConfig(dir string) (*Config, error) {
+	config := &Config{}
+
+	if err := loadJSON(filepath.Join(dir, "definitions.json"), &config.Definitions); err != nil {
+		return nil, err
+	}
+	if err := loadJSON(filepath.Join(dir, "from_to_mapping.json"), &config.FromToMapping); err != nil {
+		return nil, err
+	}
+	if config.FromToMapping.Separator == "" {
+		config.FromToMapping.Separator = " => "
+	}
+
+	dictionaries, err := LoadDictionaries(filepath.Join(dir, DictionaryDir))
+	if err != nil {
+		return nil, err
+	}
+	config.Dictionaries = dictionaries
+
+	return config, nil
+}
+
+// LoadDictionaries 加载目录下所有EDS/DCF文件，文件名格式为 <节点ID>_<原始文件名>
+func LoadDictionaries(dir string) (map[uint8]*models.ObjectDictionary, error) {
+	dictionaries := make(map[uint8]*models.ObjectDictionary)
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return dictionaries, nil
+		}
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if entry.IsDir() || (ext != ".eds" && ext != ".dcf") {
+			continue
+		}
+		parts := strings.SplitN(entry.Name(), "_", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		nodeID, err := strconv.Atoi(parts[0])
+		if err != nil || nodeID < 1 || nodeID > 127 {
+			continue
+		}
+
+		file, err := os.Open(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		dict, err := ParseEDS(file, parts[1], uint8(nodeID))
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", entry.Name(), err)
+		}
+		dictionaries[uint8(nodeID)] = dict
+	}
+
+	return dictionaries, nil
+}
+
+// loadJSON 读取JSON配置文件，文件不存在时不报错
+func loadJSON(path string, v interface{}) error {
+	file, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if err := json.Unmarshal(file, v); err != nil {
+		return fmt.Errorf("解析%s失败: %v", filepath.Base(path), err)
+	}
+	return nil
+}
+
+// NodeName 返回节点名称，未配置时返回 "Node N"
+func (c *Config) NodeName(nodeID uint8) string {
+	if name, ok := c.Definitions.Nodes[strconv.Itoa(int(nodeID))]; ok && name != "" {
+		return name
+	}
+	return fmt.Sprintf("Node %d", nodeID)
+}
+
+// Object 查找对象索引定义
+func (c *Config) Object(index uint16) (ObjectDefinition, bool) {
+	def, ok := c.Definitions.Definitions[fmt.Sprintf("%04x", index)]
+	return def, ok
+}
+
+// Entry 查找节点对象字典中的条目，未导入该节点的字典时返回nil
+func (c *Config) Entry(nodeID uint8, index uint16, subIndex uint8) *models.ODEntry {
+	return c.Dictionaries[nodeID].Entry(index, subIndex)
+}
+
+// ObjectName 返回 "对象名.子索引名" 形式的名称
+// 优先使用节点的对象字典，其次使用 definitions.json
+func (c *Config) ObjectName(nodeID uint8, index uint16, subIndex uint8) string {
+	if entry := c.Entry(nodeID, index, subIndex); entry != nil && entry.Name != "" {
+		return entry.Name
+	}
+	def, ok := c.Object(index)
+	if !ok {
+		return ""
+	}
+	if sub, ok := def.SubIndexes[strconv.Itoa(int(subIndex))]; ok && sub != "" {
+		return def.Name + "." + sub
+	}
+	return def.Name
+}
+
+// DataType 返回对象的数据类型，优先使用节点的对象字典
+func (c *Config) DataType(nodeID uint8, index uint16, subIndex uint8) string {
+	if entry := c.Entry(nodeID, index, subIndex); entry != nil && entry.DataType != "" {
+		return entry.DataType
+	}
+	if def, ok := c.Object(index); ok {
+		return def.DataType
+	}
+	return ""
+}
+
+// EmcyDescription 查找EMCY错误码描述
+// 依次尝试完整错误码、高字节分组（xx00）、最高位分组（x000）
+func (c *Config) EmcyDescription(code uint16) string {
+	codes := c.Definitions.EmcyErrorCodes
+	for _, key := range []string{
+		fmt.Sprintf("%04x", code),
+		fmt.Sprintf("%04x", code&0xFF00),
+		fmt.Sprintf("%04x", code&0xF000),
+	} {
+		if desc, ok := codes[key]; ok {
+			return desc
+		}
+	}
+	return ""
+}
+
+// FromTo 根据服务名称生成From->To显示，规则中的 {node} 替换为节点名称
+// 节点ID为0（广播）时替换为 All
+func (c *Config) FromTo(service string, nodeID uint8) string {
+	rule, ok := c.FromToMapping.Rules[service]
+	if !ok {
+		return ""
+	}
+	node := "All"
+	if nodeID != 0 {
+		node = c.NodeName(nodeID)
+	}
+	from := strings.ReplaceAll(rule.From, "{node}", node)
+	to := strings.ReplaceAll(rule.To, "{node}", node)
+	return from + c.FromToMapping.Separator + to
+}
@@ -0,0 +1,243 @@
+package canopen
+
+import (
+	"csv-parser/models"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 服务名称，同时作为 from_to_mapping.json 中规则的键
+const (
+	ServiceNMT       = "NMT"
+	ServiceSYNC      = "SYNC"
+	ServiceEMCY      = "EMCY"
+	ServiceTIME      = "TIME"
+	ServiceSDOTx     = "SDO_TX" // 服务器 -> 客户端（0x580+节点）
+	ServiceSDORx     = "SDO_RX" // 客户端 -> 服务器（0x600+节点）
+	ServiceHeartbeat = "HEARTBEAT"
+	ServiceLSS       = "LSS"
+	ServiceUnknown   = "UNKNOWN"
+)
+
+// Message CANopen报文的解析结果
+type Message struct {
+	COBID        uint32
+	FunctionCode uint8
+	NodeID       uint8
+	Service      string
+	HasObject    bool // 是否包含对象索引/子索引（SDO）
+	Index        uint16
+	SubIndex     uint8
+	Summary      string
+	Fields       []models.DecodedField
+}
+
+// Decoder CANopen解析器
+// SDO分段传输需要跨帧跟踪状态，因此同一个Decoder必须按时间顺序处理报文
+type Decoder struct {
+	config    *Config
+	transfers map[uint8]*sdoTransfer // 节点ID -> 进行中的SDO传输
+}
+
+// NewDecoder 创建CANopen解析器
+func NewDecoder(config *Config) *Decoder {
+	if config == nil {
+		config = &Config{}
+	}
+	return &Decoder{
+		config:    config,
+		transfers: make(map[uint8]*sdoTransfer),
+	}
+}
+
+// Classify 按预定义连接集（CiA 301）拆分COB-ID，返回功能码、节点ID和服务名称
+func Classify(frame *models.CANFrame) (uint8, uint8, string) {
+	if frame.Extended || frame.ErrorFrame {
+		return 0, 0, ServiceUnknown
+	}
+
+	cobID := frame.ID & 0x7FF
+	functionCode := uint8(cobID >> 7)
+	nodeID := uint8(cobID & 0x7F)
+
+	switch {
+	case cobID == 0x000:
+		return functionCode, 0, ServiceNMT
+	case cobID == 0x080:
+		return functionCode, 0, ServiceSYNC
+	case cobID == 0x100:
+		return functionCode, 0, ServiceTIME
+	case cobID == 0x7E4 || cobID == 0x7E5:
+		return functionCode, 0, ServiceLSS
+	case nodeID == 0:
+		return functionCode, 0, ServiceUnknown
+	}
+
+	switch functionCode {
+	case 0x1:
+		return functionCode, nodeID, ServiceEMCY
+	case 0x3, 0x5, 0x7, 0x9:
+		return functionCode, nodeID, fmt.Sprintf("TPDO%d", (functionCode-1)/2)
+	case 0x4, 0x6, 0x8, 0xA:
+		return functionCode, nodeID, fmt.Sprintf("RPDO%d", (functionCode-2)/2)
+	case 0xB:
+		return functionCode, nodeID, ServiceSDOTx
+	case 0xC:
+		return functionCode, nodeID, ServiceSDORx
+	case 0xE:
+		return functionCode, nodeID, ServiceHeartbeat
+	}
+	return functionCode, nodeID, ServiceUnknown
+}
+
+// Decode 解析一帧CANopen报文
+func (d *Decoder) Decode(frame *models.CANFrame) *Message {
+	functionCode, nodeID, service := Classify(frame)
+	msg := &Message{
+		COBID:        frame.ID,
+		FunctionCode: functionCode,
+		NodeID:       nodeID,
+		Service:      service,
+	}
+
+	data := frame.Data
+	switch service {
+	case ServiceNMT:
+		d.decodeNMT(msg, data)
+	case ServiceSYNC:
+		msg.Summary = "SYNC"
+		if len(data) >= 1 {
+			msg.Summary = fmt.Sprintf("SYNC counter=%d", data[0])
+			msg.Fields = append(msg.Fields, numberField("Counter", float64(data[0]), fmt.Sprintf("%d", data[0])))
+		}
+	case ServiceEMCY:
+		d.decodeEMCY(msg, data)
+	case ServiceTIME:
+		decodeTime(msg, data)
+	case ServiceSDORx, ServiceSDOTx:
+		d.decodeSDO(msg, data)
+	case ServiceHeartbeat:
+		decodeHeartbeat(msg, data)
+	case ServiceLSS:
+		msg.Summary = "LSS " + hexString(data)
+	case ServiceUnknown:
+		msg.Summary = ""
+	default:
+		// PDO：内容由导入的对象字典中的映射参数决定
+		d.decodePDO(msg, data)
+	}
+
+	return msg
+}
+
+// nmtCommands NMT命令字
+var nmtCommands = map[byte]string{
+	0x01: "Start Remote Node",
+	0x02: "Stop Remote Node",
+	0x80: "Enter Pre-Operational",
+	0x81: "Reset Node",
+	0x82: "Reset Communication",
+}
+
+func (d *Decoder) decodeNMT(msg *Message, data []byte) {
+	if len(data) < 2 {
+		msg.Summary = "NMT (invalid length)"
+		return
+	}
+	command, ok := nmtCommands[data[0]]
+	if !ok {
+		command = fmt.Sprintf("Unknown command 0x%02X", data[0])
+	}
+	target := "all nodes"
+	if data[1] != 0 {
+		target = d.config.NodeName(data[1])
+	}
+	msg.NodeID = data[1]
+	msg.Summary = fmt.Sprintf("NMT %s -> %s", command, target)
+	msg.Fields = append(msg.Fields,
+		models.DecodedField{Name: "Command", Raw: fmt.Sprintf("%02x", data[0]), Display: command},
+		numberField("Node", float64(data[1]), target),
+	)
+}
+
+func (d *Decoder) decodeEMCY(msg *Message, data []byte) {
+	if len(data) < 3 {
+		msg.Summary = "EMCY (invalid length)"
+		return
+	}
+	code := binary.LittleEndian.Uint16(data[0:2])
+	register := data[2]
+	desc := d.config.EmcyDescription(code)
+	if desc == "" {
+		desc = "Unknown error"
+	}
+
+	msg.Summary = fmt.Sprintf("EMCY 0x%04X %s, ErrReg=0x%02X", code, desc, register)
+	if code == 0 {
+		msg.Summary = fmt.Sprintf("EMCY Error Reset, ErrReg=0x%02X", register)
+	}
+	msg.Fields = append(msg.Fields,
+		numberField("ErrorCode", float64(code), fmt.Sprintf("0x%04X %s", code, desc)),
+		numberField("ErrorRegister", float64(register), fmt.Sprintf("0x%02X", register)),
+	)
+	if len(data) > 3 {
+		msg.Fields = append(msg.Fields, models.DecodedField{Name: "Manufacturer", Raw: hexString(data[3:]), Display: hexString(data[3:])})
+	}
+}
+
+// canopenEpoch TIME对象的起始日期
+var canopenEpoch = time.Date(1984, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func decodeTime(msg *Message, data []byte) {
+	if len(data) < 6 {
+		msg.Summary = "TIME (invalid length)"
+		return
+	}
+	ms := binary.LittleEndian.Uint32(data[0:4]) & 0x0FFFFFFF
+	days := binary.LittleEndian.Uint16(data[4:6])
+	t := canopenEpoch.AddDate(0, 0, int(days)).Add(time.Duration(ms) * time.Millisecond)
+	msg.Summary = "TIME " + t.Format("2006-01-02 15:04:05.000")
+	msg.Fields = append(msg.Fields, models.DecodedField{Name: "Time", Display: t.Format(time.RFC3339Nano)})
+}
+
+// heartbeatStates NMT状态（心跳/节点保护）
+var heartbeatStates = map[byte]string{
+	0x00: "Boot-up",
+	0x04: "Stopped",
+	0x05: "Operational",
+	0x7F: "Pre-Operational",
+}
+
+func decodeHeartbeat(msg *Message, data []byte) {
+	if len(data) < 1 {
+		// 远程帧：节点保护请求
+		msg.Summary = "Node Guarding request"
+		return
+	}
+	state := data[0] & 0x7F
+	name, ok := heartbeatStates[state]
+	if !ok {
+		name = fmt.Sprintf("Unknown state 0x%02X", state)
+	}
+	msg.Summary = "Heartbeat " + name
+	msg.Fields = append(msg.Fields, numberField("State", float64(state), name))
+}
+
+// numberField 构造带数值的解码字段
+func numberField(name string, value float64, display string) models.DecodedField {
+	return models.DecodedField{Name: name, Value: &value, Display: display}
+}
+
+// hexString 将字节格式化为大写十六进制，空格分隔
+func hexString(data []byte) string {
+	var sb strings.Builder
+	for i, b := range data {
+		if i > 0 {
+			sb.WriteByte(' ')
+		}
+		fmt.Fprintf(&sb, "%02X", b)
+	}
+	return sb.String()
+}
@@ -0,0 +1,81 @@
+package canopen
+
+import (
+	"csv-parser/models"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name     string
+		frame    models.CANFrame
+		function uint8
+		nodeID   uint8
+		service  string
+	}{
+		{"NMT", models.CANFrame{ID: 0x000}, 0x0, 0, ServiceNMT},
+		{"SYNC", models.CANFrame{ID: 0x080}, 0x1, 0, ServiceSYNC},
+		{"EMCY", models.CANFrame{ID: 0x085}, 0x1, 5, ServiceEMCY},
+		{"TIME", models.CANFrame{ID: 0x100}, 0x2, 0, ServiceTIME},
+		{"TPDO1", models.CANFrame{ID: 0x185}, 0x3, 5, "TPDO1"},
+		{"RPDO1", models.CANFrame{ID: 0x205}, 0x4, 5, "RPDO1"},
+		{"TPDO4", models.CANFrame{ID: 0x481}, 0x9, 1, "TPDO4"},
+		{"RPDO4", models.CANFrame{ID: 0x57F}, 0xA, 127, "RPDO4"},
+		{"SDO tx", models.CANFrame{ID: 0x585}, 0xB, 5, ServiceSDOTx},
+		{"SDO rx", models.CANFrame{ID: 0x605}, 0xC, 5, ServiceSDORx},
+		{"heartbeat", models.CANFrame{ID: 0x705}, 0xE, 5, ServiceHeartbeat},
+		{"LSS master", models.CANFrame{ID: 0x7E5}, 0xF, 0, ServiceLSS},
+		{"LSS slave", models.CANFrame{ID: 0x7E4}, 0xF, 0, ServiceLSS},
+		{"node 0", models.CANFrame{ID: 0x180}, 0x3, 0, ServiceUnknown},
+		{"unused function code", models.CANFrame{ID: 0x685}, 0xD, 5, ServiceUnknown},
+		{"extended", models.CANFrame{ID: 0x185, Extended: true}, 0, 0, ServiceUnknown},
+		{"error frame", models.CANFrame{ID: 0x185, ErrorFrame: true}, 0, 0, ServiceUnknown},
+	}
+	for _, tt := range tests {
+		function, nodeID, service := Classify(&tt.frame)
+		if function != tt.function || nodeID != tt.nodeID || service != tt.service {
+			t.Errorf("%s: Classify(0x%03X) = (0x%X, %d, %s), want (0x%X, %d, %s)",
+				tt.name, tt.frame.ID, function, nodeID, service, tt.function, tt.nodeID, tt.service)
+		}
+	}
+}
+
+func TestDecodeEMCY(t *testing.T) {
+	config := &Config{Definitions: Definitions{EmcyErrorCodes: map[string]string{
+		"2310": "Continuous over current",
+		"3000": "Voltage",
+	}}}
+
+	tests := []struct {
+		name    string
+		data    []byte
+		summary string
+		fields  int
+	}{
+		{"exact code", []byte{0x10, 0x23, 0x02}, "EMCY 0x2310 Continuous over current, ErrReg=0x02", 2},
+		{"group fallback", []byte{0x20, 0x31, 0x04, 0xAA, 0xBB}, "EMCY 0x3120 Voltage, ErrReg=0x04", 3},
+		{"unknown code", []byte{0x00, 0x90, 0x01}, "EMCY 0x9000 Unknown error, ErrReg=0x01", 2},
+		{"error reset", []byte{0x00, 0x00, 0x00, 0, 0, 0, 0, 0}, "EMCY Error Reset, ErrReg=0x00", 3},
+		{"too short", []byte{0x10, 0x23}, "EMCY (invalid length)", 0},
+	}
+	for _, tt := range tests {
+		msg := NewDecoder(config).Decode(&models.CANFrame{ID: 0x085, DLC: len(tt.data), Data: tt.data})
+		if msg.Service != ServiceEMCY || msg.NodeID != 5 {
+			t.Errorf("%s: service/node = %s/%d, want EMCY/5", tt.name, msg.Service, msg.NodeID)
+		}
+		if msg.Summary != tt.summary {
+			t.Errorf("%s: summary = %q, want %q", tt.name, msg.Summary, tt.summary)
+		}
+		if len(msg.Fields) != tt.fields {
+			t.Errorf("%s: fields = %d, want %d", tt.name, len(msg.Fields), tt.fields)
+		}
+	}
+
+	msg := NewDecoder(config).Decode(&models.CANFrame{ID: 0x085, DLC: 5, Data: []byte{0x20, 0x31, 0x04, 0xAA, 0xBB}})
+	if f := msg.Fields[0]; f.Value == nil || *f.Value != 0x3120 {
+		t.Errorf("ErrorCode value = %v, want 0x3120", f.Value)
+	}
+	if f := msg.Fields[2]; f.Name != "Manufacturer" || f.Display != "AA BB" {
+		t.Errorf("manufacturer field = %+v", f)
+	}
+}
@@ -0,0 +1,273 @@
+package canopen
+
+import (
+	"bufio"
+	"csv-parser/models"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// edsDataTypes EDS中DataType编码到数据类型名称的映射（CiA 301）
+var edsDataTypes = map[uint64]string{
+	0x0001: "BOOLEAN",
+	0x0002: "INTEGER8",
+	0x0003: "INTEGER16",
+	0x0004: "INTEGER32",
+	0x0005: "UNSIGNED8",
+	0x0006: "UNSIGNED16",
+	0x0007: "UNSIGNED32",
+	0x0008: "REAL32",
+	0x0009: "VISIBLE_STRING",
+	0x000A: "OCTET_STRING",
+	0x000B: "UNICODE_STRING",
+	0x000F: "DOMAIN",
+	0x0010: "INTEGER24",
+	0x0011: "REAL64",
+	0x0012: "INTEGER40",
+	0x0013: "INTEGER48",
+	0x0014: "INTEGER56",
+	0x0015: "INTEGER64",
+	0x0016: "UNSIGNED24",
+	0x0018: "UNSIGNED40",
+	0x0019: "UNSIGNED48",
+	0x001A: "UNSIGNED56",
+	0x001B: "UNSIGNED64",
+}
+
+// edsObjectTypes EDS中ObjectType编码
+var edsObjectTypes = map[uint64]string{
+	0x2: "DOMAIN",
+	0x5: "DEFTYPE",
+	0x6: "DEFSTRUCT",
+	0x7: "VAR",
+	0x8: "ARRAY",
+	0x9: "RECORD",
+}
+
+var (
+	// 对象段名: [1018]
+	edsObjectSection = regexp.MustCompile(`^([0-9a-fA-F]{4})$`)
+	// 子索引段名: [1018sub1]
+	edsSubSection = regexp.MustCompile(`^([0-9a-fA-F]{4})sub([0-9a-fA-F]{1,2})$`)
+)
+
+// edsSection INI段（键统一转为小写）
+type edsSection map[string]string
+
+// parseINI 解析EDS/DCF的INI结构，段名保持原样，键转为小写
+func parseINI(r io.Reader) (map[string]edsSection, error) {
+	sections := make(map[string]edsSection)
+	var current edsSection
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, ";") || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			name := strings.TrimSpace(line[1 : len(line)-1])
+			current = make(edsSection)
+			sections[strings.ToLower(name)] = current
+			continue
+		}
+		if current == nil {
+			continue
+		}
+		if idx := strings.Index(line, "="); idx > 0 {
+			key := strings.ToLower(strings.TrimSpace(line[:idx]))
+			current[key] = strings.TrimSpace(line[idx+1:])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return sections, nil
+}
+
+// ParseEDS 解析EDS/DCF文件并构建对象字典
+// nodeID为0时使用DCF中[DeviceComissioning]段的NodeID
+func ParseEDS(r io.Reader, fileName string, nodeID uint8) (*models.ObjectDictionary, error) {
+	sections, err := parseINI(r)
+	if err != nil {
+		return nil, fmt.Errorf("读取EDS文件失败: %v", err)
+	}
+
+	fileType := "EDS"
+	if strings.EqualFold(filepath.Ext(fileName), ".dcf") {
+		fileType = "DCF"
+	}
+	if commissioning, ok := sections["devicecomissioning"]; ok {
+		fileType = "DCF"
+		if nodeID == 0 {
+			if v, err := parseEDSNumber(commissioning["nodeid"], 0); err == nil && v > 0 && v <= 127 {
+				nodeID = uint8(v)
+			}
+		}
+	}
+	if nodeID == 0 || nodeID > 127 {
+		return nil, fmt.Errorf("无效的节点ID: 需要1-127，DCF文件可在[DeviceComissioning]中提供NodeID")
+	}
+
+	dict := &models.ObjectDictionary{
+		NodeID:   nodeID,
+		FileName: fileName,
+		FileType: fileType,
+	}
+	if info, ok := sections["deviceinfo"]; ok {
+		dict.VendorName = info["vendorname"]
+		dict.ProductName = info["productname"]
+	}
+
+	// 先收集有子索引段的对象
+	hasSubs := make(map[string]bool)
+	for name := range sections {
+		if m := edsSubSection.FindStringSubmatch(name); m != nil {
+			hasSubs[m[1]] = true
+		}
+	}
+
+	for name, sec := range sections {
+		if m := edsObjectSection.FindStringSubmatch(name); m != nil {
+			index, _ := strconv.ParseUint(m[1], 16, 16)
+			objectType := edsObjectType(sec["objecttype"])
+
+			// VAR类型对象本身就是子索引0
+			if !hasSubs[m[1]] {
+				dict.Entries = append(dict.Entries, edsEntry(uint16(index), 0, sec, objectType, nodeID))
+				compactSubs(dict, uint16(index), sec, sections[m[1]+"name"], nodeID)
+			}
+			continue
+		}
+		if m := edsSubSection.FindStringSubmatch(name); m != nil {
+			index, _ := strconv.ParseUint(m[1], 16, 16)
+			sub, _ := strconv.ParseUint(m[2], 16, 8)
+			parent := sections[m[1]]
+			entry := edsEntry(uint16(index), uint8(sub), sec, edsObjectType(parent["objecttype"]), nodeID)
+			if parentName := parent["parametername"]; parentName != "" && entry.Name != "" {
+				entry.Name = parentName + "." + entry.Name
+			}
+			dict.Entries = append(dict.Entries, entry)
+		}
+	}
+
+	if len(dict.Entries) == 0 {
+		return nil, fmt.Errorf("文件中没有对象字典条目")
+	}
+
+	sort.Slice(dict.Entries, func(i, j int) bool {
+		if dict.Entries[i].Index != dict.Entries[j].Index {
+			return dict.Entries[i].Index < dict.Entries[j].Index
+		}
+		return dict.Entries[i].SubIndex < dict.Entries[j].SubIndex
+	})
+	dict.BuildIndex()
+
+	return dict, nil
+}
+
+// edsEntry 根据对象段构建条目
+func edsEntry(index uint16, subIndex uint8, sec edsSection, objectType string, nodeID uint8) *models.ODEntry {
+	entry := &models.ODEntry{
+		Index:      index,
+		SubIndex:   subIndex,
+		Name:       sec["parametername"],
+		ObjectType: objectType,
+		Access:     strings.ToLower(sec["accesstype"]),
+		Default:    sec["defaultvalue"],
+		Value:      sec["parametervalue"],
+		PDOMapping: sec["pdomapping"] == "1",
+	}
+	if dt, err := parseEDSNumber(sec["datatype"], nodeID); err == nil {
+		if name, ok := edsDataTypes[dt]; ok {
+			entry.DataType = name
+		}
+	}
+	return entry
+}
+
+// compactSubs 处理CompactSubObj形式的数组（子索引不单独成段）
+// 子索引名称取自 [XXXXName] 段（如 1=Error 1），没有时为 "数组名[子索引]"
+func compactSubs(dict *models.ObjectDictionary, index uint16, sec, names edsSection, nodeID uint8) {
+	count, err := parseEDSNumber(sec["compactsubobj"], nodeID)
+	if err != nil || count == 0 || count > 254 {
+		return
+	}
+	name := sec["parametername"]
+	dataType := ""
+	if dt, err := parseEDSNumber(sec["datatype"], nodeID); err == nil {
+		dataType = edsDataTypes[dt]
+	}
+
+	// 子索引0为条目数
+	dict.Entries[len(dict.Entries)-1].Name = name + ".NrOfObjects"
+	dict.Entries[len(dict.Entries)-1].DataType = "UNSIGNED8"
+	for i := uint64(1); i <= count; i++ {
+		subName := fmt.Sprintf("%s[%d]", name, i)
+		if own := names[strconv.FormatUint(i, 10)]; own != "" {
+			subName = name + "." + own
+		}
+		dict.Entries = append(dict.Entries, &models.ODEntry{
+			Index:      index,
+			SubIndex:   uint8(i),
+			Name:       subName,
+			ObjectType: "ARRAY",
+			DataType:   dataType,
+			Access:     strings.ToLower(sec["accesstype"]),
+			Default:    sec["defaultvalue"],
+			PDOMapping: sec["pdomapping"] == "1",
+		})
+	}
+}
+
+// edsObjectType 将ObjectType编码转换为名称，缺省为VAR
+func edsObjectType(raw string) string {
+	if v, err := parseEDSNumber(raw, 0); err == nil {
+		if name, ok := edsObjectTypes[v]; ok {
+			return name
+		}
+	}
+	return "VAR"
+}
+
+// parseEDSNumber 解析EDS中的数值，支持十六进制、八进制和 $NODEID+0x180 形式
+func parseEDSNumber(raw string, nodeID uint8) (uint64, error) {
+	s := strings.ToUpper(strings.TrimSpace(raw))
+	if s == "" {
+		return 0, fmt.Errorf("空值")
+	}
+
+	var total uint64
+	for _, term := range strings.Split(s, "+") {
+		term = strings.TrimSpace(term)
+		if term == "$NODEID" {
+			total += uint64(nodeID)
+			continue
+		}
+		v, err := strconv.ParseUint(term, 0, 64)
+		if err != nil {
+			return 0, err
+		}
+		total += v
+	}
+	return total, nil
+}
+
+// EntryValue 返回条目的有效值（DCF的ParameterValue优先，其次DefaultValue）
+func EntryValue(entry *models.ODEntry, nodeID uint8) (uint64, bool) {
+	if entry == nil {
+		return 0, false
+	}
+	raw := entry.Value
+	if raw == "" {
+		raw = entry.Default
+	}
+	v, err := parseEDSNumber(raw, nodeID)
+	return v, err == nil
+}
@@ -0,0 +1,79 @@
+package canopen
+
+import (
+	"strings"
+	"testing"
+)
+
+const testEDS = `[DeviceInfo]
+VendorName=Test
+ProductName=Drive
+
+[1003]
+ParameterName=Pre-defined error field
+ObjectType=0x8
+DataType=0x0007
+AccessType=ro
+CompactSubObj=3
+
+[1003Name]
+NrOfEntries=2
+1=Standard error field 1
+2=Standard error field 2
+
+[1016]
+ParameterName=Consumer heartbeat time
+ObjectType=0x8
+DataType=0x0007
+AccessType=rw
+CompactSubObj=2
+
+[1018]
+ParameterName=Identity
+ObjectType=0x9
+SubNumber=2
+
+[1018sub0]
+ParameterName=Number of entries
+DataType=0x0005
+AccessType=ro
+DefaultValue=1
+
+[1018sub1]
+ParameterName=Vendor-ID
+DataType=0x0007
+AccessType=ro
+DefaultValue=0x1234
+`
+
+func TestParseEDSNames(t *testing.T) {
+	dict, err := ParseEDS(strings.NewReader(testEDS), "drive.eds", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make(map[[2]int]string)
+	for _, e := range dict.Entries {
+		names[[2]int{int(e.Index), int(e.SubIndex)}] = e.Name
+	}
+	tests := []struct {
+		index, sub int
+		want       string
+	}{
+		{0x1003, 0, "Pre-defined error field.NrOfObjects"},
+		{0x1003, 1, "Pre-defined error field.Standard error field 1"},
+		{0x1003, 2, "Pre-defined error field.Standard error field 2"},
+		// [1003Name]中没有的子索引按数组名加下标命名
+		{0x1003, 3, "Pre-defined error field[3]"},
+		{0x1016, 1, "Consumer heartbeat time[1]"},
+		{0x1016, 2, "Consumer heartbeat time[2]"},
+		{0x1018, 1, "Identity.Vendor-ID"},
+	}
+	for _, tt := range tests {
+		if got := names[[2]int{tt.index, tt.sub}]; got != tt.want {
+			t.Errorf("%04X sub%d name = %q, want %q", tt.index, tt.sub, got, tt.want)
+		}
+	}
+	if len(dict.Entries) != 9 {
+		t.Errorf("entries = %d, want 9", len(dict.Entries))
+	}
+}
@@ -0,0 +1,93 @@
+package canopen
+
+import (
+	"fmt"
+	"strings"
+)
+
+// pdoMapping PDO映射中的一个对象
+type pdoMapping struct {
+	index    uint16
+	subIndex uint8
+	bits     int
+}
+
+// pdoMappingIndex 按预定义连接集返回PDO映射参数的对象索引
+// TPDOn -> 0x1A00+n-1，RPDOn -> 0x1600+n-1
+func pdoMappingIndex(service string) (uint16, bool) {
+	var n int
+	if _, err := fmt.Sscanf(service, "TPDO%d", &n); err == nil && n >= 1 {
+		return 0x1A00 + uint16(n-1), true
+	}
+	if _, err := fmt.Sscanf(service, "RPDO%d", &n); err == nil && n >= 1 {
+		return 0x1600 + uint16(n-1), true
+	}
+	return 0, false
+}
+
+// pdoMappings 从节点对象字典读取PDO映射
+func (d *Decoder) pdoMappings(nodeID uint8, service string) []pdoMapping {
+	mappingIndex, ok := pdoMappingIndex(service)
+	if !ok {
+		return nil
+	}
+	count, ok := EntryValue(d.config.Entry(nodeID, mappingIndex, 0), nodeID)
+	if !ok || count == 0 || count > 64 {
+		return nil
+	}
+
+	var mappings []pdoMapping
+	for i := uint64(1); i <= count; i++ {
+		v, ok := EntryValue(d.config.Entry(nodeID, mappingIndex, uint8(i)), nodeID)
+		if !ok || v == 0 {
+			continue
+		}
+		mappings = append(mappings, pdoMapping{
+			index:    uint16(v >> 16),
+			subIndex: uint8(v >> 8),
+			bits:     int(v & 0xFF),
+		})
+	}
+	return mappings
+}
+
+// decodePDO 按对象字典中的映射参数解析PDO，没有映射时只显示原始数据
+func (d *Decoder) decodePDO(msg *Message, data []byte) {
+	mappings := d.pdoMappings(msg.NodeID, msg.Service)
+	if len(mappings) == 0 {
+		msg.Summary = fmt.Sprintf("%s [%s]", msg.Service, hexString(data))
+		return
+	}
+
+	var parts []string
+	bitOffset := 0
+	for _, m := range mappings {
+		if bitOffset+m.bits > len(data)*8 {
+			parts = append(parts, fmt.Sprintf("0x%04X:%02X (missing data)", m.index, m.subIndex))
+			break
+		}
+		value := extractBits(data, bitOffset, m.bits)
+		bitOffset += m.bits
+
+		// 0x0001-0x0007为占位用的数据类型对象（dummy mapping）
+		if m.index < 0x1000 {
+			continue
+		}
+		field := d.valueField(msg.NodeID, m.index, m.subIndex, value)
+		msg.Fields = append(msg.Fields, field)
+		parts = append(parts, field.Name+"="+field.Display)
+	}
+	msg.Summary = fmt.Sprintf("%s %s", msg.Service, strings.Join(parts, ", "))
+}
+
+// extractBits 从小端字节流中取出指定位段，按小端字节返回
+func extractBits(data []byte, offset, bits int) []byte {
+	out := make([]byte, (bits+7)/8)
+	for i := 0; i < bits; i++ {
+		src := offset + i
+		if data[src/8]&(1<<(src%8)) != 0 {
+			out[i/8] |= 1 << (i % 8)
+		}
+	}
+	return out
+}
@@ -0,0 +1,146 @@
+package canopen
+
+import (
+	"csv-parser/models"
+	"strings"
+	"testing"
+)
+
+const testPDOEDS = `[1A00]
+ParameterName=TPDO1 mapping parameter
+ObjectType=0x9
+SubNumber=4
+
+[1A00sub0]
+ParameterName=Number of mapped objects
+DataType=0x0005
+DefaultValue=3
+
+[1A00sub1]
+ParameterName=Mapped object 1
+DataType=0x0007
+DefaultValue=0x60410010
+
+[1A00sub2]
+ParameterName=Mapped object 2
+DataType=0x0007
+DefaultValue=0x00050008
+
+[1A00sub3]
+ParameterName=Mapped object 3
+DataType=0x0007
+DefaultValue=0x60640020
+
+[1600]
+ParameterName=RPDO1 mapping parameter
+ObjectType=0x9
+SubNumber=2
+
+[1600sub0]
+ParameterName=Number of mapped objects
+DataType=0x0005
+DefaultValue=1
+
+[1600sub1]
+ParameterName=Mapped object 1
+DataType=0x0007
+ParameterValue=0x60400010
+
+[6040]
+ParameterName=Controlword
+DataType=0x0006
+
+[6041]
+ParameterName=Statusword
+DataType=0x0006
+
+[6064]
+ParameterName=Position actual value
+DataType=0x0004
+`
+
+func TestDecodePDO(t *testing.T) {
+	dict, err := ParseEDS(strings.NewReader(testPDOEDS), "drive.eds", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &Config{Dictionaries: map[uint8]*models.ObjectDictionary{5: dict}}
+
+	tests := []struct {
+		name    string
+		id      uint32
+		data    []byte
+		summary string
+		values  []float64
+	}{
+		{
+			name: "TPDO1 with dummy mapping",
+			id:   0x185,
+			data: []byte{0x37, 0x02, 0xFF, 0x18, 0xFC, 0xFF, 0xFF},
+			// 0x0005 为UNSIGNED8占位，跳过但占用8位
+			summary: "TPDO1 Statusword=567 (0x237), Position actual value=-1000",
+			values:  []float64{0x237, -1000},
+		},
+		{
+			name:    "RPDO1 from ParameterValue",
+			id:      0x205,
+			data:    []byte{0x0F, 0x00},
+			summary: "RPDO1 Controlword=15 (0xF)",
+			values:  []float64{15},
+		},
+		{
+			name:    "missing data",
+			id:      0x185,
+			data:    []byte{0x37, 0x02, 0xFF},
+			summary: "TPDO1 Statusword=567 (0x237), 0x6064:00 (missing data)",
+			values:  []float64{0x237},
+		},
+		{
+			name:    "no mapping for PDO",
+			id:      0x285,
+			data:    []byte{0x01, 0x02},
+			summary: "TPDO2 [01 02]",
+		},
+		{
+			name:    "no dictionary for node",
+			id:      0x186,
+			data:    []byte{0x01, 0x02},
+			summary: "TPDO1 [01 02]",
+		},
+	}
+	for _, tt := range tests {
+		msg := NewDecoder(config).Decode(&models.CANFrame{ID: tt.id, DLC: len(tt.data), Data: tt.data})
+		if msg.Summary != tt.summary {
+			t.Errorf("%s: summary = %q, want %q", tt.name, msg.Summary, tt.summary)
+		}
+		if len(msg.Fields) != len(tt.values) {
+			t.Errorf("%s: fields = %d, want %d", tt.name, len(msg.Fields), len(tt.values))
+			continue
+		}
+		for i, want := range tt.values {
+			if v := msg.Fields[i].Value; v == nil || *v != want {
+				t.Errorf("%s: field %d value = %v, want %v", tt.name, i, v, want)
+			}
+		}
+	}
+}
+
+func TestExtractBits(t *testing.T) {
+	data := []byte{0xB4, 0x5A}
+	tests := []struct {
+		offset, bits int
+		want         []byte
+	}{
+		{0, 16, []byte{0xB4, 0x5A}},
+		{0, 4, []byte{0x04}},
+		{4, 8, []byte{0xAB}},
+		{2, 1, []byte{0x01}},
+		{3, 1, []byte{0x00}},
+	}
+	for _, tt := range tests {
+		got := extractBits(data, tt.offset, tt.bits)
+		if string(got) != string(tt.want) {
+			t.Errorf("extractBits(%d, %d) = % X, want % X", tt.offset, tt.bits, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,319 @@
+package canopen
+
+import (
+	"csv-parser/models"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// sdoTransfer 进行中的SDO分段传输
+type sdoTransfer struct {
+	upload   bool
+	index    uint16
+	subIndex uint8
+	size     int // 声明的数据大小，-1表示未声明
+	data     []byte
+	started  bool // 分段阶段是否已开始（上传需等待服务器的启动响应）
+}
+
+// sdoAbortCodes SDO中止代码（CiA 301）
+var sdoAbortCodes = map[uint32]string{
+	0x05030000: "Toggle bit not alternated",
+	0x05040000: "SDO protocol timed out",
+	0x05040001: "Client/server command specifier not valid or unknown",
+	0x05040002: "Invalid block size",
+	0x05040003: "Invalid sequence number",
+	0x05040004: "CRC error",
+	0x05040005: "Out of memory",
+	0x06010000: "Unsupported access to an object",
+	0x06010001: "Attempt to read a write only object",
+	0x06010002: "Attempt to write a read only object",
+	0x06020000: "Object does not exist in the object dictionary",
+	0x06040041: "Object cannot be mapped to the PDO",
+	0x06040042: "The number and length of the objects to be mapped would exceed PDO length",
+	0x06040043: "General parameter incompatibility reason",
+	0x06040047: "General internal incompatibility in the device",
+	0x06060000: "Access failed due to a hardware error",
+	0x06070010: "Data type does not match, length of service parameter does not match",
+	0x06070012: "Data type does not match, length of service parameter too high",
+	0x06070013: "Data type does not match, length of service parameter too low",
+	0x06090011: "Sub-index does not exist",
+	0x06090030: "Invalid value for parameter",
+	0x06090031: "Value of parameter written too high",
+	0x06090032: "Value of parameter written too low",
+	0x06090036: "Maximum value is less than minimum value",
+	0x060A0023: "Resource not available: SDO connection",
+	0x08000000: "General error",
+	0x08000020: "Data cannot be transferred or stored to the application",
+	0x08000021: "Data cannot be transferred or stored to the application because of local control",
+	0x08000022: "Data cannot be transferred or stored to the application because of the present device state",
+	0x08000023: "Object dictionary dynamic generation fails or no object dictionary is present",
+	0x08000024: "No data available",
+}
+
+// decodeSDO 解析SDO报文（加速/分段上传与下载、中止）
+func (d *Decoder) decodeSDO(msg *Message, data []byte) {
+	if len(data) < 1 {
+		msg.Summary = "SDO (invalid length)"
+		return
+	}
+	client := msg.Service == ServiceSDORx
+	cs := data[0] >> 5
+
+	// 块传输暂不解析内容
+	if cs == 5 || cs == 6 {
+		delete(d.transfers, msg.NodeID)
+		msg.Summary = "SDO block transfer " + hexString(data)
+		return
+	}
+
+	// 中止报文两个方向的命令字相同
+	if cs == 4 {
+		d.decodeSDOAbort(msg, data)
+		return
+	}
+
+	if client {
+		switch cs {
+		case 1:
+			d.sdoInitiate(msg, data, false, "download request")
+		case 2:
+			if !d.setObject(msg, data) {
+				return
+			}
+			d.transfers[msg.NodeID] = &sdoTransfer{upload: true, index: msg.Index, subIndex: msg.SubIndex, size: -1}
+			msg.Summary = "SDO upload request " + d.objectText(msg.NodeID, msg.Index, msg.SubIndex)
+		case 0:
+			d.sdoSegment(msg, data, false)
+		case 3:
+			d.sdoSegmentRequest(msg, data)
+		default:
+			msg.Summary = "SDO unknown client command " + hexString(data)
+		}
+		return
+	}
+
+	switch cs {
+	case 2:
+		d.sdoInitiate(msg, data, true, "upload response")
+	case 3:
+		if !d.setObject(msg, data) {
+			return
+		}
+		msg.Summary = "SDO download response " + d.objectText(msg.NodeID, msg.Index, msg.SubIndex)
+	case 0:
+		d.sdoSegment(msg, data, true)
+	case 1:
+		d.sdoSegmentRequest(msg, data)
+	default:
+		msg.Summary = "SDO unknown server command " + hexString(data)
+	}
+}
+
+// setObject 从SDO报文中读取对象索引和子索引
+func (d *Decoder) setObject(msg *Message, data []byte) bool {
+	if len(data) < 4 {
+		msg.Summary = "SDO (invalid length)"
+		return false
+	}
+	msg.HasObject = true
+	msg.Index = binary.LittleEndian.Uint16(data[1:3])
+	msg.SubIndex = data[3]
+	return true
+}
+
+// sdoInitiate 解析启动下载请求（客户端）或启动上传响应（服务器）
+func (d *Decoder) sdoInitiate(msg *Message, data []byte, upload bool, action string) {
+	if !d.setObject(msg, data) {
+		return
+	}
+	expedited := data[0]&0x02 != 0
+	sizeIndicated := data[0]&0x01 != 0
+	object := d.objectText(msg.NodeID, msg.Index, msg.SubIndex)
+
+	if expedited {
+		delete(d.transfers, msg.NodeID)
+		n := 4
+		if sizeIndicated {
+			n = 4 - int((data[0]>>2)&0x03)
+		}
+		if 4+n > len(data) {
+			n = len(data) - 4
+		}
+		value := data[4 : 4+n]
+		field := d.valueField(msg.NodeID, msg.Index, msg.SubIndex, value)
+		msg.Fields = append(msg.Fields, field)
+		msg.Summary = fmt.Sprintf("SDO %s (expedited) %s = %s", action, object, field.Display)
+		return
+	}
+
+	size := -1
+	if sizeIndicated && len(data) >= 8 {
+		size = int(binary.LittleEndian.Uint32(data[4:8]))
+	}
+	d.transfers[msg.NodeID] = &sdoTransfer{upload: upload, index: msg.Index, subIndex: msg.SubIndex, size: size, started: true}
+	if size >= 0 {
+		msg.Summary = fmt.Sprintf("SDO %s (segmented) %s, size=%d", action, object, size)
+	} else {
+		msg.Summary = fmt.Sprintf("SDO %s (segmented) %s", action, object)
+	}
+}
+
+// sdoSegment 解析携带数据的分段报文（下载分段请求 / 上传分段响应）
+func (d *Decoder) sdoSegment(msg *Message, data []byte, upload bool) {
+	toggle := (data[0] >> 4) & 0x01
+	last := data[0]&0x01 != 0
+	n := 7 - int((data[0]>>1)&0x07)
+	if 1+n > len(data) {
+		n = len(data) - 1
+	}
+	segment := data[1 : 1+n]
+
+	action := "download segment"
+	if upload {
+		action = "upload segment"
+	}
+
+	transfer := d.transfers[msg.NodeID]
+	if transfer == nil || transfer.upload != upload || !transfer.started {
+		msg.Summary = fmt.Sprintf("SDO %s t=%d [%s] (no initiate)", action, toggle, hexString(segment))
+		return
+	}
+
+	msg.HasObject = true
+	msg.Index = transfer.index
+	msg.SubIndex = transfer.subIndex
+	transfer.data = append(transfer.data, segment...)
+	object := d.objectText(msg.NodeID, transfer.index, transfer.subIndex)
+
+	if !last {
+		msg.Summary = fmt.Sprintf("SDO %s t=%d %s [%s]", action, toggle, object, hexString(segment))
+		return
+	}
+
+	delete(d.transfers, msg.NodeID)
+	field := d.valueField(msg.NodeID, transfer.index, transfer.subIndex, transfer.data)
+	msg.Fields = append(msg.Fields, field)
+	msg.Summary = fmt.Sprintf("SDO %s t=%d (last) %s = %s", action, toggle, object, field.Display)
+	if transfer.size >= 0 && transfer.size != len(transfer.data) {
+		msg.Summary += fmt.Sprintf(" (size mismatch: declared %d, received %d)", transfer.size, len(transfer.data))
+	}
+}
+
+// sdoSegmentRequest 解析不携带数据的分段报文（上传分段请求 / 下载分段响应）
+func (d *Decoder) sdoSegmentRequest(msg *Message, data []byte) {
+	toggle := (data[0] >> 4) & 0x01
+	action := "upload segment request"
+	if msg.Service == ServiceSDOTx {
+		action = "download segment response"
+	}
+	if transfer := d.transfers[msg.NodeID]; transfer != nil {
+		msg.HasObject = true
+		msg.Index = transfer.index
+		msg.SubIndex = transfer.subIndex
+		msg.Summary = fmt.Sprintf("SDO %s t=%d %s", action, toggle, d.objectText(msg.NodeID, transfer.index, transfer.subIndex))
+		return
+	}
+	msg.Summary = fmt.Sprintf("SDO %s t=%d", action, toggle)
+}
+
+// decodeSDOAbort 解析SDO中止报文
+func (d *Decoder) decodeSDOAbort(msg *Message, data []byte) {
+	delete(d.transfers, msg.NodeID)
+	if !d.setObject(msg, data) {
+		return
+	}
+	var code uint32
+	if len(data) >= 8 {
+		code = binary.LittleEndian.Uint32(data[4:8])
+	}
+	reason, ok := sdoAbortCodes[code]
+	if !ok {
+		reason = "Unknown abort code"
+	}
+	msg.Summary = fmt.Sprintf("SDO abort %s: 0x%08X %s", d.objectText(msg.NodeID, msg.Index, msg.SubIndex), code, reason)
+	msg.Fields = append(msg.Fields, numberField("AbortCode", float64(code), fmt.Sprintf("0x%08X %s", code, reason)))
+}
+
+// objectText 返回 "0x1018:01 (Identity object.Vendor-ID)" 形式的对象描述
+func (d *Decoder) objectText(nodeID uint8, index uint16, subIndex uint8) string {
+	text := fmt.Sprintf("0x%04X:%02X", index, subIndex)
+	if name := d.config.ObjectName(nodeID, index, subIndex); name != "" {
+		text += " (" + name + ")"
+	}
+	return text
+}
+
+// valueField 根据对象数据类型格式化SDO/PDO数据
+func (d *Decoder) valueField(nodeID uint8, index uint16, subIndex uint8, value []byte) models.DecodedField {
+	dataType := d.config.DataType(nodeID, index, subIndex)
+	name := d.config.ObjectName(nodeID, index, subIndex)
+	if name == "" {
+		name = fmt.Sprintf("0x%04X:%02X", index, subIndex)
+	}
+
+	field := models.DecodedField{Name: name, Type: dataType, Raw: strings.ToLower(hexString(value))}
+	num, display := formatValue(dataType, value)
+	field.Value = num
+	field.Display = display
+	return field
+}
+
+// formatValue 按CANopen数据类型格式化数值
+func formatValue(dataType string, value []byte) (*float64, string) {
+	setNum := func(v float64) *float64 {
+		// NaN和Inf无法编码为JSON，只保留显示文本
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil
+		}
+		return &v
+	}
+	le := func(b []byte) uint64 {
+		var v uint64
+		for i := len(b) - 1; i >= 0; i-- {
+			v = v<<8 | uint64(b[i])
+		}
+		return v
+	}
+
+	switch strings.ToUpper(dataType) {
+	case "BOOLEAN":
+		if len(value) >= 1 {
+			return setNum(float64(value[0])), fmt.Sprintf("%t", value[0] != 0)
+		}
+	case "UNSIGNED8", "UNSIGNED16", "UNSIGNED24", "UNSIGNED32", "UNSIGNED40", "UNSIGNED48", "UNSIGNED56", "UNSIGNED64":
+		if len(value) >= 1 && len(value) <= 8 {
+			v := le(value)
+			return setNum(float64(v)), fmt.Sprintf("%d (0x%X)", v, v)
+		}
+	case "INTEGER8", "INTEGER16", "INTEGER24", "INTEGER32", "INTEGER40", "INTEGER48", "INTEGER56", "INTEGER64":
+		if len(value) >= 1 && len(value) <= 8 {
+			shift := uint(64 - 8*len(value))
+			v := int64(le(value)<<shift) >> shift
+			return setNum(float64(v)), fmt.Sprintf("%d", v)
+		}
+	case "REAL32":
+		if len(value) == 4 {
+			v := float64(math.Float32frombits(binary.LittleEndian.Uint32(value)))
+			return setNum(v), fmt.Sprintf("%g", v)
+		}
+	case "REAL64":
+		if len(value) == 8 {
+			v := math.Float64frombits(binary.LittleEndian.Uint64(value))
+			return setNum(v), fmt.Sprintf("%g", v)
+		}
+	case "VISIBLE_STRING":
+		return nil, fmt.Sprintf("%q", strings.TrimRight(string(value), "\x00"))
+	case "OCTET_STRING", "DOMAIN":
+		return nil, hexString(value)
+	}
+
+	// 未知类型：4字节以内按无符号整数显示，否则显示原始字节
+	if len(value) >= 1 && len(value) <= 4 {
+		v := le(value)
+		return setNum(float64(v)), fmt.Sprintf("0x%0*X (%d)", len(value)*2, v, v)
+	}
+	return nil, hexString(value)
+}
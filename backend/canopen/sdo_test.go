@@ -0,0 +1,176 @@
+package canopen
+
+import (
+	"csv-parser/models"
+	"encoding/json"
+	"testing"
+)
+
+func TestFormatValueNonFinite(t *testing.T) {
+	tests := []struct {
+		dataType string
+		value    []byte
+		display  string
+	}{
+		{"REAL32", []byte{0x00, 0x00, 0xC0, 0x7F}, "NaN"},
+		{"REAL32", []byte{0x00, 0x00, 0x80, 0x7F}, "+Inf"},
+		{"REAL32", []byte{0x00, 0x00, 0x80, 0xFF}, "-Inf"},
+		{"REAL64", []byte{0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0xF8, 0x7F}, "NaN"},
+		{"REAL64", []byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xF0, 0x7F}, "+Inf"},
+	}
+	for _, tt := range tests {
+		value, display := formatValue(tt.dataType, tt.value)
+		if value != nil {
+			t.Errorf("%s % X: value = %v, want nil", tt.dataType, tt.value, *value)
+		}
+		if display != tt.display {
+			t.Errorf("%s % X: display = %q, want %q", tt.dataType, tt.value, display, tt.display)
+		}
+	}
+}
+
+func TestDecodeSDONaNMarshals(t *testing.T) {
+	config := &Config{Definitions: Definitions{Definitions: map[string]ObjectDefinition{
+		"2000": {Name: "Setpoint", DataType: "REAL32"},
+	}}}
+	d := NewDecoder(config)
+
+	// 加速上传响应 0x2000:00 = 0x7FC00000 (NaN)
+	frame := &models.CANFrame{ID: 0x585, DLC: 8, Data: []byte{0x43, 0x00, 0x20, 0x00, 0x00, 0x00, 0xC0, 0x7F}}
+	msg := d.Decode(frame)
+	if len(msg.Fields) != 1 {
+		t.Fatalf("fields = %d, want 1", len(msg.Fields))
+	}
+	if msg.Fields[0].Value != nil || msg.Fields[0].Display != "NaN" {
+		t.Errorf("field = %+v, want nil value with display NaN", msg.Fields[0])
+	}
+	if _, err := json.Marshal(msg.Fields); err != nil {
+		t.Errorf("json.Marshal: %v", err)
+	}
+}
+
+// sdoStep 一帧SDO报文及期望的解析结果
+type sdoStep struct {
+	id      uint32
+	data    []byte
+	summary string
+	value   *float64
+}
+
+func runSDO(t *testing.T, name string, d *Decoder, steps []sdoStep) {
+	t.Helper()
+	for i, step := range steps {
+		msg := d.Decode(&models.CANFrame{ID: step.id, DLC: len(step.data), Data: step.data})
+		if msg.Summary != step.summary {
+			t.Errorf("%s step %d: summary = %q, want %q", name, i, msg.Summary, step.summary)
+		}
+		if step.value == nil {
+			continue
+		}
+		if len(msg.Fields) != 1 || msg.Fields[0].Value == nil || *msg.Fields[0].Value != *step.value {
+			t.Errorf("%s step %d: fields = %+v, want value %v", name, i, msg.Fields, *step.value)
+		}
+	}
+}
+
+func num(v float64) *float64 { return &v }
+
+func sdoTestConfig() *Config {
+	return &Config{Definitions: Definitions{Definitions: map[string]ObjectDefinition{
+		"1008": {Name: "Device name", DataType: "VISIBLE_STRING"},
+		"1017": {Name: "Producer heartbeat time", DataType: "UNSIGNED16"},
+		"1018": {Name: "Identity", DataType: "UNSIGNED32", SubIndexes: map[string]string{"1": "Vendor-ID"}},
+		"2001": {Name: "Offset", DataType: "INTEGER16"},
+	}}}
+}
+
+func TestDecodeSDOExpedited(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps []sdoStep
+	}{
+		{"upload", []sdoStep{
+			{0x605, []byte{0x40, 0x18, 0x10, 0x01, 0, 0, 0, 0}, "SDO upload request 0x1018:01 (Identity.Vendor-ID)", nil},
+			{0x585, []byte{0x43, 0x18, 0x10, 0x01, 0x34, 0x12, 0x00, 0x00},
+				"SDO upload response (expedited) 0x1018:01 (Identity.Vendor-ID) = 4660 (0x1234)", num(0x1234)},
+		}},
+		{"download with size", []sdoStep{
+			{0x605, []byte{0x2B, 0x17, 0x10, 0x00, 0xE8, 0x03, 0x00, 0x00},
+				"SDO download request (expedited) 0x1017:00 (Producer heartbeat time) = 1000 (0x3E8)", num(1000)},
+			{0x585, []byte{0x60, 0x17, 0x10, 0x00, 0, 0, 0, 0}, "SDO download response 0x1017:00 (Producer heartbeat time)", nil},
+		}},
+		{"signed value", []sdoStep{
+			{0x605, []byte{0x2B, 0x01, 0x20, 0x00, 0xFE, 0xFF, 0x00, 0x00},
+				"SDO download request (expedited) 0x2001:00 (Offset) = -2", num(-2)},
+		}},
+		{"unknown object", []sdoStep{
+			{0x585, []byte{0x4F, 0x00, 0x30, 0x02, 0x7F, 0, 0, 0},
+				"SDO upload response (expedited) 0x3000:02 = 0x7F (127)", num(127)},
+		}},
+		{"short frame", []sdoStep{
+			{0x605, []byte{0x40, 0x18}, "SDO (invalid length)", nil},
+		}},
+	}
+	for _, tt := range tests {
+		runSDO(t, tt.name, NewDecoder(sdoTestConfig()), tt.steps)
+	}
+}
+
+func TestDecodeSDOSegmented(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps []sdoStep
+	}{
+		{"upload", []sdoStep{
+			{0x605, []byte{0x40, 0x08, 0x10, 0x00, 0, 0, 0, 0}, "SDO upload request 0x1008:00 (Device name)", nil},
+			{0x585, []byte{0x41, 0x08, 0x10, 0x00, 0x0A, 0, 0, 0}, "SDO upload response (segmented) 0x1008:00 (Device name), size=10", nil},
+			{0x605, []byte{0x60, 0, 0, 0, 0, 0, 0, 0}, "SDO upload segment request t=0 0x1008:00 (Device name)", nil},
+			{0x585, []byte{0x00, 'M', 'o', 't', 'o', 'r', ' ', 'C'}, "SDO upload segment t=0 0x1008:00 (Device name) [4D 6F 74 6F 72 20 43]", nil},
+			{0x605, []byte{0x70, 0, 0, 0, 0, 0, 0, 0}, "SDO upload segment request t=1 0x1008:00 (Device name)", nil},
+			// n=4：7字节中只有3字节有效，c=1为最后一段
+			{0x585, []byte{0x19, 't', 'r', 'l', 0, 0, 0, 0}, `SDO upload segment t=1 (last) 0x1008:00 (Device name) = "Motor Ctrl"`, nil},
+		}},
+		{"download size mismatch", []sdoStep{
+			{0x605, []byte{0x21, 0x08, 0x10, 0x00, 0x05, 0, 0, 0}, "SDO download request (segmented) 0x1008:00 (Device name), size=5", nil},
+			{0x585, []byte{0x60, 0x08, 0x10, 0x00, 0, 0, 0, 0}, "SDO download response 0x1008:00 (Device name)", nil},
+			{0x605, []byte{0x07, 'a', 'b', 'c', 'd', 0, 0, 0}, `SDO download segment t=0 (last) 0x1008:00 (Device name) = "abcd" (size mismatch: declared 5, received 4)`, nil},
+			{0x585, []byte{0x20, 0, 0, 0, 0, 0, 0, 0}, "SDO download segment response t=0", nil},
+		}},
+		{"segment without initiate", []sdoStep{
+			{0x585, []byte{0x00, 1, 2, 3, 4, 5, 6, 7}, "SDO upload segment t=0 [01 02 03 04 05 06 07] (no initiate)", nil},
+		}},
+		{"transfers are tracked per node", []sdoStep{
+			{0x605, []byte{0x21, 0x08, 0x10, 0x00, 0x02, 0, 0, 0}, "SDO download request (segmented) 0x1008:00 (Device name), size=2", nil},
+			{0x606, []byte{0x0B, 'x', 'y', 0, 0, 0, 0, 0}, "SDO download segment t=0 [78 79] (no initiate)", nil},
+			{0x605, []byte{0x0B, 'x', 'y', 0, 0, 0, 0, 0}, `SDO download segment t=0 (last) 0x1008:00 (Device name) = "xy"`, nil},
+		}},
+	}
+	for _, tt := range tests {
+		runSDO(t, tt.name, NewDecoder(sdoTestConfig()), tt.steps)
+	}
+}
+
+func TestDecodeSDOAbort(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps []sdoStep
+	}{
+		{"known code", []sdoStep{
+			{0x585, []byte{0x80, 0x18, 0x10, 0x01, 0x00, 0x00, 0x02, 0x06},
+				"SDO abort 0x1018:01 (Identity.Vendor-ID): 0x06020000 Object does not exist in the object dictionary", num(0x06020000)},
+		}},
+		{"unknown code", []sdoStep{
+			{0x605, []byte{0x80, 0x00, 0x30, 0x00, 0x78, 0x56, 0x34, 0x12},
+				"SDO abort 0x3000:00: 0x12345678 Unknown abort code", num(0x12345678)},
+		}},
+		{"abort ends segmented transfer", []sdoStep{
+			{0x585, []byte{0x41, 0x08, 0x10, 0x00, 0x0A, 0, 0, 0}, "SDO upload response (segmented) 0x1008:00 (Device name), size=10", nil},
+			{0x605, []byte{0x80, 0x08, 0x10, 0x00, 0x00, 0x00, 0x04, 0x05},
+				"SDO abort 0x1008:00 (Device name): 0x05040000 SDO protocol timed out", num(0x05040000)},
+			{0x585, []byte{0x00, 'M', 'o', 't', 'o', 'r', ' ', 'C'}, "SDO upload segment t=0 [4D 6F 74 6F 72 20 43] (no initiate)", nil},
+		}},
+	}
+	for _, tt := range tests {
+		runSDO(t, tt.name, NewDecoder(sdoTestConfig()), tt.steps)
+	}
+}
@@ -0,0 +1,359 @@
+package dbc
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// extendedFlag DBC中扩展帧ID的标志位（BO_ 的ID第31位）
+const extendedFlag = 0x80000000
+
+// Signal DBC信号定义（SG_）
+type Signal struct {
+	Name         string
+	StartBit     int
+	Length       int
+	LittleEndian bool // @1 为Intel（小端），@0 为Motorola（大端）
+	Signed       bool
+	Factor       float64
+	Offset       float64
+	Min          float64
+	Max          float64
+	Unit         string
+	Receivers    []string
+	Comment      string
+	ValueType    string // 空字符串为整数，float32 / float64 来自 SIG_VALTYPE_
+	Multiplexer  bool   // M：多路复用选择信号
+	MultiplexID  *int   // mN：仅当选择信号值为N时有效
+	Values       []ValueDescription
+}
+
+// ValueDescription 信号值描述（VAL_）
+type ValueDescription struct {
+	Value       int64
+	Description string
+}
+
+// Message DBC报文定义（BO_）
+type Message struct {
+	ID          uint32
+	Extended    bool
+	Name        string
+	DLC         int
+	Transmitter string
+	Comment     string
+	Signals     []*Signal
+}
+
+// Database DBC文件内容
+type Database struct {
+	Version  string
+	Nodes    []string
+	Messages []*Message
+}
+
+var (
+	boPattern        = regexp.MustCompile(`^BO_\s+(\d+)\s+(\w+)\s*:\s*(\d+)\s+(\S+)`)
+	sgPattern        = regexp.MustCompile(`^SG_\s+(\w+)\s*(M|m\d+M?)?\s*:\s*(\d+)\|(\d+)@([01])([+-])\s*\(\s*([^,]+?)\s*,\s*([^)]+?)\s*\)\s*\[\s*([^|]*?)\s*\|\s*([^\]]*?)\s*\]\s*"([^"]*)"\s*(.*)$`)
+	cmMessagePattern = regexp.MustCompile(`(?s)^CM_\s+BO_\s+(\d+)\s+"(.*)"\s*;$`)
+	cmSignalPattern  = regexp.MustCompile(`(?s)^CM_\s+SG_\s+(\d+)\s+(\w+)\s+"(.*)"\s*;$`)
+	valPattern       = regexp.MustCompile(`(?s)^VAL_\s+(\d+)\s+(\w+)\s+(.*);$`)
+	valEntryPattern  = regexp.MustCompile(`(-?\d+)\s+"([^"]*)"`)
+	valTypePattern   = regexp.MustCompile(`^SIG_VALTYPE_\s+(\d+)\s+(\w+)\s*:?\s*(\d)\s*;$`)
+	versionPattern   = regexp.MustCompile(`^VERSION\s+"([^"]*)"`)
+)
+
+// Parse 解析DBC文件
+func Parse(r io.Reader) (*Database, error) {
+	db := &Database{}
+	messages := make(map[uint32]*Message)
+	var current *Message
+
+	statements, err := splitStatements(r)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, st := range statements {
+		switch {
+		case strings.HasPrefix(st.text, "VERSION"):
+			if m := versionPattern.FindStringSubmatch(st.text); m != nil {
+				db.Version = m[1]
+			}
+
+		case strings.HasPrefix(st.text, "BU_"):
+			fields := strings.Fields(strings.TrimPrefix(strings.TrimPrefix(st.text, "BU_"), ":"))
+			db.Nodes = append(db.Nodes, fields...)
+
+		case strings.HasPrefix(st.text, "BO_TX_BU_"):
+			// 额外发送节点，不影响解码
+
+		case strings.HasPrefix(st.text, "BO_ "):
+			m := boPattern.FindStringSubmatch(st.text)
+			if m == nil {
+				return nil, fmt.Errorf("第 %d 行: 无效的BO_定义", st.line)
+			}
+			rawID, _ := strconv.ParseUint(m[1], 10, 32)
+			dlc, _ := strconv.Atoi(m[3])
+			current = &Message{
+				ID:          uint32(rawID) &^ extendedFlag,
+				Extended:    rawID&extendedFlag != 0,
+				Name:        m[2],
+				DLC:         dlc,
+				Transmitter: m[4],
+			}
+			messages[uint32(rawID)] = current
+			db.Messages = append(db.Messages, current)
+
+		case strings.HasPrefix(st.text, "SG_ "):
+			if current == nil {
+				return nil, fmt.Errorf("第 %d 行: SG_ 不属于任何BO_", st.line)
+			}
+			signal, err := parseSignal(st.text)
+			if err != nil {
+				return nil, fmt.Errorf("第 %d 行: %v", st.line, err)
+			}
+			current.Signals = append(current.Signals, signal)
+
+		case strings.HasPrefix(st.text, "CM_ "):
+			if m := cmMessagePattern.FindStringSubmatch(st.text); m != nil {
+				if msg := lookupMessage(messages, m[1]); msg != nil {
+					msg.Comment = unescape(m[2])
+				}
+			} else if m := cmSignalPattern.FindStringSubmatch(st.text); m != nil {
+				if sig := lookupSignal(messages, m[1], m[2]); sig != nil {
+					sig.Comment = unescape(m[3])
+				}
+			}
+
+		case strings.HasPrefix(st.text, "VAL_ "):
+			m := valPattern.FindStringSubmatch(st.text)
+			if m == nil {
+				continue
+			}
+			sig := lookupSignal(messages, m[1], m[2])
+			if sig == nil {
+				continue
+			}
+			for _, entry := range valEntryPattern.FindAllStringSubmatch(m[3], -1) {
+				v, err := strconv.ParseInt(entry[1], 10, 64)
+				if err != nil {
+					continue
+				}
+				sig.Values = append(sig.Values, ValueDescription{Value: v, Description: entry[2]})
+			}
+			sort.SliceStable(sig.Values, func(i, j int) bool {
+				return sig.Values[i].Value < sig.Values[j].Value
+			})
+
+		case strings.HasPrefix(st.text, "SIG_VALTYPE_ "):
+			if m := valTypePattern.FindStringSubmatch(st.text); m != nil {
+				if sig := lookupSignal(messages, m[1], m[2]); sig != nil {
+					switch m[3] {
+					case "1":
+						sig.ValueType = "float32"
+					case "2":
+						sig.ValueType = "float64"
+					}
+				}
+			}
+		}
+	}
+
+	if len(db.Messages) == 0 {
+		return nil, fmt.Errorf("DBC文件中没有报文定义（BO_）")
+	}
+	return db, nil
+}
+
+// parseSignal 解析SG_行
+func parseSignal(text string) (*Signal, error) {
+	m := sgPattern.FindStringSubmatch(text)
+	if m == nil {
+		return nil, fmt.Errorf("无效的SG_定义")
+	}
+
+	sig := &Signal{
+		Name:         m[1],
+		LittleEndian: m[5] == "1",
+		Signed:       m[6] == "-",
+		Unit:         m[11],
+	}
+	sig.StartBit, _ = strconv.Atoi(m[3])
+	sig.Length, _ = strconv.Atoi(m[4])
+	if sig.Length < 1 || sig.Length > 64 {
+		return nil, fmt.Errorf("信号 %s 长度无效: %d", sig.Name, sig.Length)
+	}
+
+	var err error
+	if sig.Factor, err = strconv.ParseFloat(m[7], 64); err != nil {
+		return nil, fmt.Errorf("信号 %s factor无效: %s", sig.Name, m[7])
+	}
+	if sig.Offset, err = strconv.ParseFloat(m[8], 64); err != nil {
+		return nil, fmt.Errorf("信号 %s offset无效: %s", sig.Name, m[8])
+	}
+	sig.Min, _ = strconv.ParseFloat(m[9], 64)
+	sig.Max, _ = strconv.ParseFloat(m[10], 64)
+
+	switch mux := m[2]; {
+	case mux == "M":
+		sig.Multiplexer = true
+	case strings.HasPrefix(mux, "m"):
+		id, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(mux, "m"), "M"))
+		if err == nil {
+			sig.MultiplexID = &id
+		}
+		sig.Multiplexer = strings.HasSuffix(mux, "M")
+	}
+
+	for _, r := range strings.Split(m[12], ",") {
+		if r = strings.TrimSpace(r); r != "" {
+			sig.Receivers = append(sig.Receivers, r)
+		}
+	}
+
+	return sig, nil
+}
+
+// statement 一条DBC语句及其起始行号
+type statement struct {
+	text string
+	line int
+}
+
+// multiLineKeywords 以分号结束、可能跨多行的语句
+var multiLineKeywords = []string{"CM_ ", "VAL_ ", "VAL_TABLE_ ", "BA_ ", "BA_DEF_ ", "BA_DEF_DEF_ ", "SIG_VALTYPE_ ", "SIG_GROUP_ ", "EV_ ", "ENVVAR_DATA_ "}
+
+// splitStatements 按行切分DBC内容，跨行的语句（如带换行的注释）合并为一条
+func splitStatements(r io.Reader) ([]statement, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+
+	var statements []statement
+	var pending *statement
+	lineNumber := 0
+	inNS := false
+
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		if pending != nil {
+			pending.text += "\n" + line
+			if statementComplete(pending.text) {
+				pending.text = strings.TrimSpace(pending.text)
+				statements = append(statements, *pending)
+				pending = nil
+			}
+			continue
+		}
+
+		// NS_ 段中缩进的符号列表不是语句
+		if inNS && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			continue
+		}
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+		inNS = strings.HasPrefix(trimmed, "NS_ ") || trimmed == "NS_:"
+		multiLine := false
+		for _, kw := range multiLineKeywords {
+			if strings.HasPrefix(trimmed, kw) {
+				multiLine = true
+				break
+			}
+		}
+		if multiLine && !statementComplete(trimmed) {
+			pending = &statement{text: trimmed, line: lineNumber}
+			continue
+		}
+		statements = append(statements, statement{text: trimmed, line: lineNumber})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取DBC文件失败: %v", err)
+	}
+	if pending != nil {
+		statements = append(statements, *pending)
+	}
+	return statements, nil
+}
+
+// statementComplete 判断语句是否以引号之外的分号结束
+func statementComplete(text string) bool {
+	inQuote := false
+	escaped := false
+	for _, c := range text {
+		switch {
+		case escaped:
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case c == '"':
+			inQuote = !inQuote
+		case c == ';' && !inQuote:
+			return true
+		}
+	}
+	return false
+}
+
+func lookupMessage(messages map[uint32]*Message, rawID string) *Message {
+	id, err := strconv.ParseUint(rawID, 10, 32)
+	if err != nil {
+		return nil
+	}
+	return messages[uint32(id)]
+}
+
+func lookupSignal(messages map[uint32]*Message, rawID, name string) *Signal {
+	msg := lookupMessage(messages, rawID)
+	if msg == nil {
+		return nil
+	}
+	for _, sig := range msg.Signals {
+		if sig.Name == name {
+			return sig
+		}
+	}
+	return nil
+}
+
+// unescape 处理DBC字符串中的转义引号
+func unescape(s string) string {
+	return strings.ReplaceAll(s, `\"`, `"`)
+}
+
+// RawID 返回写入DBC时使用的ID（扩展帧带第31位标志）
+func (m *Message) RawID() uint32 {
+	if m.Extended {
+		return m.ID | extendedFlag
+	}
+	return m.ID
+}
+
+// ByteSpan 返回信号覆盖的首尾字节（含）
+func (s *Signal) ByteSpan() (int, int) {
+	if s.LittleEndian {
+		return s.StartBit / 8, (s.StartBit + s.Length - 1) / 8
+	}
+	// Motorola：起始位为MSB，按锯齿编号向后（更高字节）延伸
+	first, last := s.StartBit/8, s.StartBit/8
+	pos := s.StartBit
+	for i := 0; i < s.Length; i++ {
+		if b := pos / 8; b > last {
+			last = b
+		}
+		if pos%8 == 0 {
+			pos += 15
+		} else {
+			pos--
+		}
+	}
+	return first, last
+}
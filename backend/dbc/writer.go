@@ -0,0 +1,145 @@
+package dbc
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// PlaceholderNode DBC中未指定发送/接收节点时使用的占位名称
+const PlaceholderNode = "Vector__XXX"
+
+// nsSymbols 新建DBC文件时NS_段列出的标准符号
+var nsSymbols = []string{
+	"NS_DESC_", "CM_", "BA_DEF_", "BA_", "VAL_", "CAT_DEF_", "CAT_", "FILTER",
+	"BA_DEF_DEF_", "EV_DATA_", "ENVVAR_DATA_", "SGTYPE_", "SGTYPE_VAL_",
+	"BA_DEF_SGTYPE_", "BA_SGTYPE_", "SIG_TYPE_REF_", "VAL_TABLE_", "SIG_GROUP_",
+	"SIG_VALTYPE_", "SIGTYPE_VALTYPE_", "BO_TX_BU_", "BA_DEF_REL_", "BA_REL_",
+	"BA_DEF_DEF_REL_", "BU_SG_REL_", "BU_EV_REL_", "BU_BO_REL_", "SG_MUL_VAL_",
+}
+
+var invalidIdentifierChars = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+
+// Identifier 将任意名称转换为合法的DBC标识符（字母、数字、下划线，不以数字开头）
+func Identifier(name string) string {
+	id := strings.Trim(invalidIdentifierChars.ReplaceAllString(strings.TrimSpace(name), "_"), "_")
+	if id == "" {
+		return "Unnamed"
+	}
+	if id[0] >= '0' && id[0] <= '9' {
+		id = "_" + id
+	}
+	return id
+}
+
+// Write 将Database输出为DBC文本
+func Write(w io.Writer, db *Database) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintf(bw, "VERSION %s\n\n\n", quote(db.Version))
+	bw.WriteString("NS_ :\n")
+	for _, sym := range nsSymbols {
+		bw.WriteString("\t" + sym + "\n")
+	}
+	bw.WriteString("\nBS_:\n\n")
+	bw.WriteString("BU_:")
+	for _, node := range db.Nodes {
+		bw.WriteString(" " + node)
+	}
+	bw.WriteString("\n\n\n")
+
+	for _, msg := range db.Messages {
+		transmitter := msg.Transmitter
+		if transmitter == "" {
+			transmitter = PlaceholderNode
+		}
+		fmt.Fprintf(bw, "BO_ %d %s: %d %s\n", msg.RawID(), msg.Name, msg.DLC, transmitter)
+		for _, sig := range msg.Signals {
+			bw.WriteString(" " + signalLine(sig) + "\n")
+		}
+		bw.WriteString("\n")
+	}
+	bw.WriteString("\n")
+
+	for _, msg := range db.Messages {
+		if msg.Comment != "" {
+			fmt.Fprintf(bw, "CM_ BO_ %d %s;\n", msg.RawID(), quote(msg.Comment))
+		}
+		for _, sig := range msg.Signals {
+			if sig.Comment != "" {
+				fmt.Fprintf(bw, "CM_ SG_ %d %s %s;\n", msg.RawID(), sig.Name, quote(sig.Comment))
+			}
+		}
+	}
+
+	for _, msg := range db.Messages {
+		for _, sig := range msg.Signals {
+			if len(sig.Values) == 0 {
+				continue
+			}
+			fmt.Fprintf(bw, "VAL_ %d %s", msg.RawID(), sig.Name)
+			for _, v := range sig.Values {
+				fmt.Fprintf(bw, " %d %s", v.Value, quote(v.Description))
+			}
+			bw.WriteString(" ;\n")
+		}
+	}
+
+	for _, msg := range db.Messages {
+		for _, sig := range msg.Signals {
+			switch sig.ValueType {
+			case "float32":
+				fmt.Fprintf(bw, "SIG_VALTYPE_ %d %s : 1;\n", msg.RawID(), sig.Name)
+			case "float64":
+				fmt.Fprintf(bw, "SIG_VALTYPE_ %d %s : 2;\n", msg.RawID(), sig.Name)
+			}
+		}
+	}
+
+	return bw.Flush()
+}
+
+// signalLine 生成SG_行（不含前导空格）
+func signalLine(sig *Signal) string {
+	mux := ""
+	switch {
+	case sig.MultiplexID != nil && sig.Multiplexer:
+		mux = fmt.Sprintf(" m%dM", *sig.MultiplexID)
+	case sig.MultiplexID != nil:
+		mux = fmt.Sprintf(" m%d", *sig.MultiplexID)
+	case sig.Multiplexer:
+		mux = " M"
+	}
+
+	order := "0"
+	if sig.LittleEndian {
+		order = "1"
+	}
+	sign := "+"
+	if sig.Signed {
+		sign = "-"
+	}
+	receivers := PlaceholderNode
+	if len(sig.Receivers) > 0 {
+		receivers = strings.Join(sig.Receivers, ",")
+	}
+
+	return fmt.Sprintf("SG_ %s%s : %d|%d@%s%s (%s,%s) [%s|%s] %s %s",
+		sig.Name, mux, sig.StartBit, sig.Length, order, sign,
+		formatFloat(sig.Factor), formatFloat(sig.Offset),
+		formatFloat(sig.Min), formatFloat(sig.Max),
+		quote(sig.Unit), receivers)
+}
+
+// formatFloat 以最短的十进制形式输出数值
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// quote 输出带引号的DBC字符串
+func quote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
@@ -0,0 +1,70 @@
+package dbc
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// Write 的输出经 Parse 读回后与原始Database一致
+func TestWriteParseRoundTrip(t *testing.T) {
+	mux := 2
+	db := &Database{
+		Version: "1.0",
+		Nodes:   []string{"ECU", "HOST"},
+		Messages: []*Message{
+			{
+				ID: 0x123, Name: "Muxed", DLC: 8, Transmitter: "ECU", Comment: `says "hi"`,
+				Signals: []*Signal{
+					{Name: "Selector", StartBit: 0, Length: 8, LittleEndian: true, Factor: 1, Max: 255,
+						Receivers: []string{"HOST"}, Multiplexer: true},
+					{Name: "Pressure", StartBit: 23, Length: 16, Signed: true, Factor: 0.01, Offset: -5, Min: -332.68, Max: 322.67,
+						Unit: "bar", Receivers: []string{"HOST"}, MultiplexID: &mux, Comment: "second page",
+						Values: []ValueDescription{{Value: -1, Description: "Invalid"}, {Value: 0, Description: "Zero"}}},
+				},
+			},
+			{
+				ID: 0x1ABCDEF0, Extended: true, Name: "Wide", DLC: 64, Transmitter: PlaceholderNode,
+				Signals: []*Signal{
+					{Name: "Ratio", StartBit: 0, Length: 64, LittleEndian: true, Factor: 1,
+						Receivers: []string{PlaceholderNode}, ValueType: "float64"},
+				},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, db); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Parse(&buf)
+	if err != nil {
+		t.Fatalf("Parse: %v\n%s", err, buf.String())
+	}
+	if !reflect.DeepEqual(got, db) {
+		for i := range db.Messages {
+			if i < len(got.Messages) && !reflect.DeepEqual(got.Messages[i], db.Messages[i]) {
+				t.Errorf("message %d:\n got %+v\nwant %+v", i, got.Messages[i], db.Messages[i])
+				for j, s := range got.Messages[i].Signals {
+					t.Logf("signal %d: %+v", j, *s)
+				}
+			}
+		}
+		t.Errorf("round trip mismatch:\n%s", buf.String())
+	}
+}
+
+func TestIdentifier(t *testing.T) {
+	tests := map[string]string{
+		"Engine Speed": "Engine_Speed",
+		"  (rpm)  ":    "rpm",
+		"1st gear":     "_1st_gear",
+		"温度":           "Unnamed",
+		"A-B/C":        "A_B_C",
+	}
+	for in, want := range tests {
+		if got := Identifier(in); got != want {
+			t.Errorf("Identifier(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package decoder
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// BitFieldConfig 位字段配置（bitfield类型使用）
+type BitFieldConfig struct {
+	Name   string            `json:"name"`
+	Start  int               `json:"start"`
+	Bits   int               `json:"bits"`
+	Values map[string]string `json:"values,omitempty"`
+}
+
+// FieldConfig 单个字节范围的解析规则
+type FieldConfig struct {
+	Name        string            `json:"name,omitempty"`
+	Type        string            `json:"type"`
+	Order       *int              `json:"order,omitempty"`
+	Values      map[string]string `json:"values,omitempty"`
+	Fields      []BitFieldConfig  `json:"fields,omitempty"`
+	Scale       float64           `json:"scale,omitempty"`
+	Precision   *int              `json:"precision,omitempty"`
+	Unit        string            `json:"unit,omitempty"`
+	HideIfZero  bool              `json:"hideIfZero,omitempty"`
+	ZeroText    string            `json:"zeroText,omitempty"`
+	NonZeroText string            `json:"nonZeroText,omitempty"`
+	SignalConfig
+}
+
+// SignalConfig 按位定义的信号（signal类型使用，对应DBC中的SG_）
+// values 的键为原始值的十进制字符串
+type SignalConfig struct {
+	StartBit       *int     `json:"startBit,omitempty"`
+	Length         int      `json:"length,omitempty"`
+	ByteOrder      string   `json:"byteOrder,omitempty"` // intel（缺省，小端）或 motorola（大端）
+	Signed         bool     `json:"signed,omitempty"`
+	ValueType      string   `json:"valueType,omitempty"` // 空为整数，float32 / float64
+	Factor         *float64 `json:"factor,omitempty"`
+	Offset         float64  `json:"offset,omitempty"`
+	Min            *float64 `json:"min,omitempty"`
+	Max            *float64 `json:"max,omitempty"`
+	Multiplexer    bool     `json:"multiplexer,omitempty"`    // 多路复用选择信号
+	MultiplexValue *int     `json:"multiplexValue,omitempty"` // 仅当选择信号等于该值时解析
+	Comment        string   `json:"comment,omitempty"`
+}
+
+// ByteRule 字节范围及其解析规则，例如 "4-5" 或 "0"
+// 同一字节范围有多条规则时可加 "#后缀" 区分，例如 "0#Mode"
+type ByteRule struct {
+	Range     string
+	StartByte int
+	EndByte   int
+	FieldConfig
+}
+
+// ByteRules 有序的字节规则列表
+// JSON对象的键顺序在map中会丢失，这里保留文件中的原始顺序
+type ByteRules []ByteRule
+
+// MessageConfig 单个消息（按CAN ID或Name匹配）的解析配置
+type MessageConfig struct {
+	Name        string    `json:"name"`
+	MatchBy     string    `json:"matchBy,omitempty"`
+	DisplayText string    `json:"displayText,omitempty"`
+	Bytes       ByteRules `json:"bytes,omitempty"`
+}
+
+// Config data_parser.json 的完整配置，键为小写十六进制CAN ID或Name
+type Config map[string]*MessageConfig
+
+// LoadConfig 加载 data_parser.json
+// start/bits无效的位字段（负数、长度为0或超出单字节）在解析时会导致移位panic，
+// 加载时跳过这些位字段，并以 "ID.字节范围.字段名 (start=N, bits=N)" 的形式返回
+func LoadConfig(path string) (Config, []string, error) {
+	file, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var config Config
+	if err := json.Unmarshal(file, &config); err != nil {
+		return nil, nil, fmt.Errorf("解析data_parser.json失败: %v", err)
+	}
+
+	return config, config.dropInvalidBitfields(), nil
+}
+
+// dropInvalidBitfields 删除start/bits不满足 0<=start、1<=bits、start+bits<=8 的位字段
+func (c Config) dropInvalidBitfields() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var skipped []string
+	for _, key := range keys {
+		msg := c[key]
+		if msg == nil {
+			continue
+		}
+		for i := range msg.Bytes {
+			rule := &msg.Bytes[i]
+			if rule.Type != "bitfield" {
+				continue
+			}
+			fields := rule.Fields[:0]
+			for _, bf := range rule.Fields {
+				if bf.Start < 0 || bf.Bits < 1 || bf.Start+bf.Bits > 8 {
+					skipped = append(skipped, fmt.Sprintf("%s.%s.%s (start=%d, bits=%d)", key, rule.Range, bf.Name, bf.Start, bf.Bits))
+					continue
+				}
+				fields = append(fields, bf)
+			}
+			rule.Fields = fields
+		}
+	}
+	return skipped
+}
+
+// parseByteRange 解析 "4-5" / "0" 形式的字节范围，忽略 "#" 之后的后缀
+func parseByteRange(r string) (int, int, error) {
+	if idx := strings.Index(r, "#"); idx >= 0 {
+		r = r[:idx]
+	}
+	if idx := strings.Index(r, "-"); idx >= 0 {
+		start, err := strconv.Atoi(strings.TrimSpace(r[:idx]))
+		if err != nil {
+			return 0, 0, fmt.Errorf("无效的字节范围 %q", r)
+		}
+		end, err := strconv.Atoi(strings.TrimSpace(r[idx+1:]))
+		if err != nil {
+			return 0, 0, fmt.Errorf("无效的字节范围 %q", r)
+		}
+		return start, end, nil
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(r))
+	if err != nil {
+		return 0, 0, fmt.Errorf("无效的字节范围 %q", r)
+	}
+	return n, n, nil
+}
+
+// UnmarshalJSON 按文件中的键顺序读取字节规则，并按与前端相同的规则排序：
+// 整数键按数值升序排在前面，其余键保持原顺序，最后按order字段（缺省999）稳定排序
+func (r *ByteRules) UnmarshalJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '{' {
+		return fmt.Errorf("bytes必须是JSON对象")
+	}
+
+	var rules ByteRules
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key := tok.(string)
+
+		var field FieldConfig
+		if err := dec.Decode(&field); err != nil {
+			return fmt.Errorf("字节规则 %q 解析失败: %v", key, err)
+		}
+		start, end, err := parseByteRange(key)
+		if err != nil {
+			return err
+		}
+		rules = append(rules, ByteRule{Range: key, StartByte: start, EndByte: end, FieldConfig: field})
+	}
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+
+	isIndex := func(key string) bool {
+		_, err := strconv.ParseUint(key, 10, 32)
+		return err == nil
+	}
+	sort.SliceStable(rules, func(i, j int) bool {
+		ii, ji := isIndex(rules[i].Range), isIndex(rules[j].Range)
+		if ii && ji {
+			return rules[i].StartByte < rules[j].StartByte
+		}
+		return ii && !ji
+	})
+	sort.SliceStable(rules, func(i, j int) bool {
+		return rules[i].order() < rules[j].order()
+	})
+
+	*r = rules
+	return nil
+}
+
+// MarshalJSON 按当前顺序输出字节规则对象
+func (r ByteRules) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, rule := range r {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(rule.Range)
+		value, err := json.Marshal(rule.FieldConfig)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// order 返回规则的排序值，未配置时为999（与前端一致）
+func (r ByteRule) order() int {
+	if r.Order != nil {
+		return *r.Order
+	}
+	return 999
+}
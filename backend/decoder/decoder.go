@@ -0,0 +1,359 @@
+package decoder
+
+import (
+	"csv-parser/models"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Lookup 根据CAN ID（十六进制字符串，不区分大小写）查找按字节解析的配置
+func (c Config) Lookup(canID string) *MessageConfig {
+	msg, exists := c[strings.ToLower(strings.TrimSpace(canID))]
+	if !exists || msg == nil || len(msg.Bytes) == 0 {
+		return nil
+	}
+	return msg
+}
+
+// DecodeByName 按Name匹配的配置（如RTB信号）返回displayText对应的字段
+func (c Config) DecodeByName(name string) []models.DecodedField {
+	msg, exists := c[name]
+	if !exists || msg == nil || msg.MatchBy != "name" || msg.DisplayText == "" {
+		return nil
+	}
+	return []models.DecodedField{{Name: msg.Name, Type: "name", Display: msg.DisplayText}}
+}
+
+// Decode 按配置解析CAN数据字节（Z0-Z7 LSB-MSB顺序）
+// 行为与前端 parseCanData 保持一致（前端传入两位小写的原始字节）：hideIfZero 的字段、无法解析的字段不会出现在结果中
+func (c Config) Decode(canID string, data []byte) []models.DecodedField {
+	msg := c.Lookup(canID)
+	if msg == nil || len(data) == 0 {
+		return nil
+	}
+
+	// 多路复用：先取出选择信号的值，mN信号只在值匹配时解析
+	var muxValue *int
+	for _, rule := range msg.Bytes {
+		if rule.Type == "signal" && rule.Multiplexer {
+			if raw, ok := rule.signalRaw(data); ok {
+				v := int(raw)
+				muxValue = &v
+			}
+			break
+		}
+	}
+
+	var fields []models.DecodedField
+	for _, rule := range msg.Bytes {
+		if rule.MultiplexValue != nil && (muxValue == nil || *muxValue != *rule.MultiplexValue) {
+			continue
+		}
+		if field, ok := decodeRule(rule, data); ok {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// FormatFields 将解码字段拼接为前端显示的描述文本
+func FormatFields(fields []models.DecodedField) string {
+	parts := make([]string, 0, len(fields))
+	for _, f := range fields {
+		parts = append(parts, f.Display)
+	}
+	return strings.Join(parts, " - ")
+}
+
+// decodeRule 解析单条字节规则，返回false表示该字段不显示
+func decodeRule(rule ByteRule, data []byte) (models.DecodedField, bool) {
+	if rule.StartByte < 0 || rule.StartByte >= len(data) {
+		return models.DecodedField{}, false
+	}
+	end := rule.EndByte + 1
+	if end > len(data) {
+		end = len(data)
+	}
+	if end <= rule.StartByte {
+		end = rule.StartByte + 1
+	}
+	relevant := data[rule.StartByte:end]
+
+	fieldName := rule.Name
+	if fieldName == "" {
+		fieldName = rule.Range
+	}
+	// 字段名与字节范围不同时才作为前缀显示
+	withName := func(s string) string {
+		if fieldName != rule.Range {
+			return fieldName + " " + s
+		}
+		return s
+	}
+	withUnit := func(s string) string {
+		return s + rule.Unit
+	}
+
+	field := models.DecodedField{
+		Name:  fieldName,
+		Bytes: rule.Range,
+		Type:  rule.Type,
+		Raw:   hexBytes(relevant, " "),
+		Unit:  rule.Unit,
+	}
+
+	var value *float64
+	display := ""
+	hasDisplay := false
+	// 前端对缩放后的整数使用toFixed得到的字符串，hideIfZero对其不生效
+	scaledText := false
+	setValue := func(v float64) {
+		// NaN和Inf无法编码为JSON，只保留显示文本
+		if !math.IsNaN(v) && !math.IsInf(v, 0) {
+			value = &v
+		}
+	}
+
+	switch rule.Type {
+	case "enum":
+		// 单字节枚举值
+		key := fmt.Sprintf("%02x", relevant[0])
+		if text, ok := rule.Values[key]; ok && text != "" {
+			display = text
+		} else {
+			display = field.Raw
+		}
+		hasDisplay = true
+
+	case "uint8":
+		v := float64(relevant[0])
+		if rule.Scale != 0 {
+			v *= rule.Scale
+		}
+		setValue(v)
+		display = withName(withUnit(formatNumber(v)))
+		hasDisplay = true
+
+	case "uint16_le", "uint24_le":
+		size := 2
+		if rule.Type == "uint24_le" {
+			size = 3
+		}
+		if len(relevant) >= size {
+			v := float64(littleEndian(relevant[:size]))
+			text := formatNumber(v)
+			if rule.Scale != 0 {
+				v *= rule.Scale
+				text = toFixed(v, rule.precisionOr(1))
+				scaledText = true
+			}
+			setValue(v)
+			display = withName(withUnit(text))
+			hasDisplay = true
+		}
+
+	case "uint32_le":
+		if len(relevant) >= 4 {
+			v := float64(littleEndian(relevant[:4]))
+			text := formatNumber(v)
+			if rule.Scale != 0 {
+				v *= rule.Scale
+				text = toFixed(v, 2)
+				scaledText = true
+			}
+			setValue(v)
+			display = withName(withUnit(text))
+			hasDisplay = true
+		}
+
+	case "float32_le":
+		if len(relevant) >= 4 {
+			v := float64(math.Float32frombits(binary.LittleEndian.Uint32(relevant[:4])))
+			setValue(v)
+			// 与前端一致：precision为0或未配置时使用2位小数
+			precision := 2
+			if rule.Precision != nil && *rule.Precision != 0 {
+				precision = *rule.Precision
+			}
+			display = withName(withUnit(toFixed(v, precision)))
+			hasDisplay = true
+		}
+
+	case "hex8":
+		v := float64(relevant[0])
+		setValue(v)
+		switch {
+		case v == 0 && rule.ZeroText != "":
+			display = rule.ZeroText
+		case v != 0 && rule.NonZeroText != "":
+			display = rule.NonZeroText
+		default:
+			display = fmt.Sprintf("0x%02X", relevant[0])
+		}
+		hasDisplay = true
+
+	case "hex16_le":
+		if len(relevant) >= 2 {
+			setValue(float64(littleEndian(relevant[:2])))
+			display = fmt.Sprintf("0x%02X%02X", relevant[1], relevant[0])
+			hasDisplay = true
+		}
+
+	case "hex32_le":
+		if len(relevant) >= 4 {
+			setValue(float64(littleEndian(relevant[:4])))
+			display = fmt.Sprintf("0x%02X%02X%02X%02X", relevant[3], relevant[2], relevant[1], relevant[0])
+			if fieldName != rule.Range {
+				display = fieldName + ": " + display
+			}
+			hasDisplay = true
+		}
+
+	case "bitfield":
+		// 位字段解析：解析单字节中的多个位字段
+		if len(rule.Fields) > 0 {
+			byteValue := int(relevant[0])
+			parts := make([]string, 0, len(rule.Fields))
+			for _, bf := range rule.Fields {
+				mask := ((1 << bf.Bits) - 1) << bf.Start
+				fv := (byteValue & mask) >> bf.Start
+				text := strconv.Itoa(fv)
+				if named, ok := bf.Values[text]; ok && named != "" {
+					text = named
+				}
+				sub := models.DecodedField{Name: bf.Name, Display: text}
+				subValue := float64(fv)
+				sub.Value = &subValue
+				field.Fields = append(field.Fields, sub)
+
+				if bf.Name != "" {
+					parts = append(parts, bf.Name+":"+text)
+				} else {
+					parts = append(parts, text)
+				}
+			}
+			setValue(float64(byteValue))
+			display = strings.Join(parts, " - ")
+			hasDisplay = true
+		}
+
+	case "signal":
+		// 按位定义的信号（DBC导入）：物理值 = 原始值 * factor + offset
+		if raw, ok := rule.signalRaw(data); ok {
+			v := rule.physical(raw)
+			setValue(v)
+			if text, ok := rule.Values[rule.rawKey(raw)]; ok && text != "" {
+				display = withName(text)
+			} else {
+				display = withName(withUnit(toFixed(v, rule.precisionOr(rule.signalPrecision()))))
+			}
+			hasDisplay = true
+		}
+
+	case "ascii":
+		// 将字节转换为ASCII字符串，只保留可打印字符
+		var sb strings.Builder
+		for _, b := range relevant {
+			if b >= 32 && b <= 126 {
+				sb.WriteByte(b)
+			}
+		}
+		if sb.Len() > 0 {
+			if fieldName != rule.Range {
+				display = fmt.Sprintf("%s: \"%s\"", fieldName, sb.String())
+			} else {
+				display = fmt.Sprintf("\"%s\"", sb.String())
+			}
+			hasDisplay = true
+		}
+
+	default:
+		display = field.Raw
+		hasDisplay = true
+	}
+
+	// 如果配置了 hideIfZero 且值为0（或没有数值），则不显示该字段
+	if rule.HideIfZero && !scaledText && (value == nil || *value == 0) {
+		return models.DecodedField{}, false
+	}
+	if !hasDisplay {
+		return models.DecodedField{}, false
+	}
+
+	field.Value = value
+	field.Display = display
+	return field, true
+}
+
+// precisionOr 返回配置的精度，未配置时返回默认值
+func (r ByteRule) precisionOr(def int) int {
+	if r.Precision != nil {
+		return *r.Precision
+	}
+	return def
+}
+
+// littleEndian 按小端序组合最多8个字节
+func littleEndian(b []byte) uint64 {
+	var v uint64
+	for i := len(b) - 1; i >= 0; i-- {
+		v = v<<8 | uint64(b[i])
+	}
+	return v
+}
+
+// formatNumber 以最短形式格式化数值，与JavaScript的数字转字符串一致：
+// 绝对值不小于1e21或小于1e-6时使用指数形式（如 1e+21、5e-7）
+func formatNumber(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "Infinity"
+	case math.IsInf(v, -1):
+		return "-Infinity"
+	case v == 0:
+		return "0"
+	}
+	if abs := math.Abs(v); abs >= 1e21 || abs < 1e-6 {
+		// Go的指数至少两位（5e-07），JavaScript不补0
+		mantissa, exp, _ := strings.Cut(strconv.FormatFloat(v, 'e', -1, 64), "e")
+		return mantissa + "e" + exp[:1] + strings.TrimLeft(exp[1:], "0")
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// toFixed 与JavaScript的Number.prototype.toFixed一致：
+// 恰好位于两个结果中间时取绝对值较大的一个（strconv为四舍六入五成双），绝对值不小于1e21时同formatNumber
+func toFixed(v float64, precision int) string {
+	if math.IsNaN(v) || math.IsInf(v, 0) || math.Abs(v) >= 1e21 {
+		return formatNumber(v)
+	}
+	abs := math.Abs(v)
+	if s := strconv.FormatFloat(abs, 'f', precision+1, 64); strings.HasSuffix(s, "5") {
+		// 只有末位为5时才可能恰好居中，用精确的有理数确认
+		r := new(big.Rat).SetFloat64(abs)
+		r.Mul(r, new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(precision+1)), nil)))
+		if r.IsInt() {
+			abs = math.Nextafter(abs, math.Inf(1))
+		}
+	}
+	text := strconv.FormatFloat(abs, 'f', precision, 64)
+	if v < 0 {
+		text = "-" + text
+	}
+	return text
+}
+
+// hexBytes 将字节格式化为小写两位十六进制
+func hexBytes(b []byte, sep string) string {
+	parts := make([]string, len(b))
+	for i, v := range b {
+		parts[i] = fmt.Sprintf("%02x", v)
+	}
+	return strings.Join(parts, sep)
+}
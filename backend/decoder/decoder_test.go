@@ -0,0 +1,150 @@
+package decoder
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// 期望值与前端 parseCanData 对同样的配置和两位小写数据字节的输出一致
+const testConfig = `{
+	"1a0": {"bytes": {
+		"0": {"type": "enum", "values": {"01": "ON"}},
+		"1": {"type": "hex8", "name": "H"},
+		"2-3": {"type": "uint16_le", "scale": 0.5, "hideIfZero": true, "name": "S16", "unit": "V"},
+		"4-6": {"type": "uint24_le", "scale": 0.1, "precision": 2, "hideIfZero": true},
+		"7": {"type": "unknown"}
+	}},
+	"1a1": {"bytes": {
+		"0-3": {"type": "uint32_le", "scale": 0.01, "hideIfZero": true, "name": "S32"},
+		"4-7": {"type": "uint32_le", "name": "U32", "hideIfZero": true},
+		"0-3#h": {"type": "hex32_le", "name": "X"},
+		"5#u": {"type": "uint8", "scale": 0.1, "hideIfZero": true, "name": "U8"}
+	}},
+	"1a2": {"bytes": {
+		"0-1": {"type": "uint16_le", "scale": 0.5, "precision": 0, "name": "T0"},
+		"2-3": {"type": "uint16_le", "scale": 0.125, "precision": 2, "name": "T2"},
+		"4": {"type": "uint8", "scale": 1e-7, "name": "Tiny"},
+		"0-3#f": {"type": "float32_le", "precision": 1, "name": "F"}
+	}},
+	"1a3": {"bytes": {
+		"0": {"type": "hex8", "zeroText": "OFF", "nonZeroText": "ON"},
+		"1": {"type": "enum", "values": {"0a": "TEN"}, "hideIfZero": true},
+		"2#s": {"type": "signal", "startBit": 16, "length": 8, "factor": 1e-7, "name": "Sig"}
+	}}
+}`
+
+func TestDecodeMatchesFrontend(t *testing.T) {
+	var config Config
+	if err := json.Unmarshal([]byte(testConfig), &config); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		id   string
+		data []byte
+		want string
+	}{
+		// 枚举未命中和未知类型显示原始字节（小写），hex8显示两位大写
+		{"1a0", []byte{0x01, 0x0a, 0, 0, 0, 0, 0, 0xab}, "ON - 0x0A - ab - S16 0.0V - 0.00"},
+		{"1a0", []byte{0xfe, 0xff}, "fe - 0xFF"},
+		// 缩放后的值在前端是toFixed得到的字符串，hideIfZero不隐藏
+		{"1a0", []byte{0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}, "02 - 0x00 - S16 0.0V - 0.00"},
+		{"1a0", []byte{0x02, 0x00, 0x03, 0x00, 0x0a, 0x00, 0x00}, "02 - 0x00 - S16 1.5V - 1.00"},
+		// uint32最高位为1时仍为无符号数；未缩放的0被隐藏
+		{"1a1", []byte{0x00, 0x00, 0x00, 0x80, 0x00, 0x00, 0x00, 0x00}, "S32 21474836.48 - X: 0x80000000"},
+		{"1a1", []byte{0x00, 0x00, 0x00, 0x00, 0xff, 0xff, 0xff, 0xff}, "S32 0.00 - U32 4294967295 - X: 0x00000000 - U8 25.5"},
+		// toFixed恰好居中时取绝对值较大的一个；极小的数使用指数形式
+		{"1a2", []byte{0x05, 0x00, 0x01, 0x00, 0x04}, "Tiny 4e-7 - T0 3 - T2 0.13 - F 0.0"},
+		{"1a2", []byte{0x03, 0x00, 0x03, 0x00, 0x32}, "Tiny 0.0000049999999999999996 - T0 2 - T2 0.38 - F 0.0"},
+		// float32的无穷大和极大值
+		{"1a2", []byte{0x00, 0x00, 0x80, 0xff, 0x00}, "Tiny 0 - T0 0 - T2 8176.00 - F -Infinity"},
+		{"1a2", []byte{0x00, 0x00, 0x00, 0xff, 0x00}, "Tiny 0 - T0 0 - T2 8160.00 - F -1.7014118346046923e+38"},
+		// 枚举没有数值，配置hideIfZero时总是隐藏；factor为1e-7时按前端取0位小数
+		{"1a3", []byte{0x00, 0x0a, 0x05}, "OFF - Sig 0"},
+		{"1a3", []byte{0x07}, "ON"},
+	}
+	for _, tt := range tests {
+		if got := FormatFields(config.Decode(tt.id, tt.data)); got != tt.want {
+			t.Errorf("Decode(%s, % x) = %q, want %q", tt.id, tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestToFixed(t *testing.T) {
+	tests := []struct {
+		v         float64
+		precision int
+		want      string
+	}{
+		{2.5, 0, "3"},
+		{-2.5, 0, "-3"},
+		{0.125, 2, "0.13"},
+		{1.005, 2, "1.00"}, // 1.005的二进制值略小于1.005
+		{1.45, 1, "1.4"},   // 同上
+		{-0.001, 2, "-0.00"},
+		{math.Copysign(0, -1), 2, "0.00"},
+		{1e21, 2, "1e+21"},
+		{math.Inf(-1), 2, "-Infinity"},
+		{math.NaN(), 2, "NaN"},
+	}
+	for _, tt := range tests {
+		if got := toFixed(tt.v, tt.precision); got != tt.want {
+			t.Errorf("toFixed(%v, %d) = %q, want %q", tt.v, tt.precision, got, tt.want)
+		}
+	}
+}
+
+func TestFormatNumber(t *testing.T) {
+	tests := []struct {
+		v    float64
+		want string
+	}{
+		{0, "0"},
+		{25.5, "25.5"},
+		{0.30000000000000004, "0.30000000000000004"},
+		{0.000001, "0.000001"},
+		{5e-7, "5e-7"},
+		{-1.5e-10, "-1.5e-10"},
+		{1e20, "100000000000000000000"},
+		{1e21, "1e+21"},
+		{1.7014118346046923e+38, "1.7014118346046923e+38"},
+	}
+	for _, tt := range tests {
+		if got := formatNumber(tt.v); got != tt.want {
+			t.Errorf("formatNumber(%v) = %q, want %q", tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestLoadConfigSkipsInvalidBitfield(t *testing.T) {
+	tests := []struct {
+		name  string
+		field string
+		ok    bool
+	}{
+		{"valid", `{"name": "A", "start": 4, "bits": 4}`, true},
+		{"negative start", `{"name": "A", "start": -1, "bits": 2}`, false},
+		{"negative bits", `{"name": "A", "start": 0, "bits": -3}`, false},
+		{"zero bits", `{"name": "A", "start": 0, "bits": 0}`, false},
+		{"past byte end", `{"name": "A", "start": 6, "bits": 3}`, false},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "data_parser.json")
+		content := `{"1a0": {"bytes": {"0": {"type": "bitfield", "fields": [` + tt.field + `]}}}}`
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		config, skipped, err := LoadConfig(path)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if kept := len(config["1a0"].Bytes[0].Fields); (kept == 1) != tt.ok || (len(skipped) == 0) != tt.ok {
+			t.Errorf("%s: kept %d fields, skipped %v, want ok=%v", tt.name, kept, skipped, tt.ok)
+		}
+		// 跳过的位字段不参与解析，不会panic
+		config.Decode("1a0", []byte{0xFF})
+	}
+}
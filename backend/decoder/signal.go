@@ -0,0 +1,102 @@
+package decoder
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// extractBits 按DBC位编号从数据中取出原始值
+// intel：startBit为最低位，按位号递增；motorola：startBit为最高位，按锯齿顺序递减
+func extractBits(data []byte, startBit, length int, motorola bool) (uint64, bool) {
+	if length < 1 || length > 64 || startBit < 0 {
+		return 0, false
+	}
+
+	var v uint64
+	if !motorola {
+		for i := length - 1; i >= 0; i-- {
+			pos := startBit + i
+			if pos/8 >= len(data) {
+				return 0, false
+			}
+			v = v<<1 | uint64(data[pos/8]>>(pos%8)&1)
+		}
+		return v, true
+	}
+
+	pos := startBit
+	for i := 0; i < length; i++ {
+		if pos/8 >= len(data) {
+			return 0, false
+		}
+		v = v<<1 | uint64(data[pos/8]>>(pos%8)&1)
+		if pos%8 == 0 {
+			pos += 15
+		} else {
+			pos--
+		}
+	}
+	return v, true
+}
+
+// signalRaw 取出信号的原始值
+func (s SignalConfig) signalRaw(data []byte) (uint64, bool) {
+	if s.StartBit == nil {
+		return 0, false
+	}
+	return extractBits(data, *s.StartBit, s.Length, strings.EqualFold(s.ByteOrder, "motorola"))
+}
+
+// physical 将原始值转换为物理值：raw * factor + offset
+func (s SignalConfig) physical(raw uint64) float64 {
+	var v float64
+	switch {
+	case s.ValueType == "float32" && s.Length == 32:
+		v = float64(math.Float32frombits(uint32(raw)))
+	case s.ValueType == "float64" && s.Length == 64:
+		v = math.Float64frombits(raw)
+	case s.Signed && s.Length < 64:
+		shift := uint(64 - s.Length)
+		v = float64(int64(raw<<shift) >> shift)
+	case s.Signed:
+		v = float64(int64(raw))
+	default:
+		v = float64(raw)
+	}
+	if s.Factor != nil {
+		v *= *s.Factor
+	}
+	return v + s.Offset
+}
+
+// rawKey 返回values表使用的键（有符号信号按补码转换后的十进制）
+func (s SignalConfig) rawKey(raw uint64) string {
+	if s.Signed && s.Length < 64 {
+		shift := uint(64 - s.Length)
+		return strconv.FormatInt(int64(raw<<shift)>>shift, 10)
+	}
+	return strconv.FormatUint(raw, 10)
+}
+
+// signalPrecision 未配置precision时，按factor和offset的小数位数确定显示精度
+func (s SignalConfig) signalPrecision() int {
+	if s.ValueType != "" {
+		return 2
+	}
+	precision := 0
+	values := []float64{s.Offset}
+	if s.Factor != nil {
+		values = append(values, *s.Factor)
+	}
+	for _, v := range values {
+		text := formatNumber(v)
+		if idx := strings.Index(text, "."); idx >= 0 && len(text)-idx-1 > precision {
+			precision = len(text) - idx - 1
+		}
+	}
+	if precision > 9 {
+		precision = 9
+	}
+	return precision
+}
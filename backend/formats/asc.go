@@ -0,0 +1,347 @@
+package formats
+
+import (
+	"bufio"
+	"csv-parser/models"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ascDateLayouts ASC文件 "date" 行可能出现的时间格式（am/pm统一转为大写后匹配）
+var ascDateLayouts = []string{
+	"Mon Jan 2 03:04:05.000 PM 2006",
+	"Mon Jan 2 03:04:05 PM 2006",
+	"Mon Jan 2 15:04:05.000 2006",
+	"Mon Jan 2 15:04:05 2006",
+}
+
+var ascTimestampPattern = regexp.MustCompile(`^\d+(\.\d+)?$`)
+
+// ASCReader 读取Vector ASC文本日志
+// 支持头部的 date、base hex/dec、timestamps absolute/relative，经典CAN帧、远程帧、错误帧和CANFD帧
+type ASCReader struct {
+	scanner    *bufio.Scanner
+	lineNumber int
+	start      time.Time
+	hasDate    bool
+	base       int  // 数据和ID的进制
+	relative   bool // true表示时间戳为相对上一事件的增量
+	elapsed    time.Duration
+}
+
+// NewASCReader 创建ASC日志读取器
+func NewASCReader(r io.Reader) *ASCReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	return &ASCReader{scanner: scanner, base: 16}
+}
+
+// Next 读取下一帧，头部、注释和非CAN事件行会被跳过
+func (a *ASCReader) Next() (*models.LogFrame, error) {
+	for a.scanner.Scan() {
+		a.lineNumber++
+		line := strings.TrimSpace(a.scanner.Text())
+		if line == "" || strings.HasPrefix(line, "//") {
+			continue
+		}
+		fields := strings.Fields(line)
+
+		switch strings.ToLower(fields[0]) {
+		case "date":
+			if t, ok := parseASCDate(strings.TrimSpace(line[len(fields[0]):])); ok {
+				a.start = t
+				a.hasDate = true
+			}
+			continue
+		case "base":
+			// base hex  timestamps absolute
+			for i := 0; i+1 < len(fields); i++ {
+				switch strings.ToLower(fields[i]) {
+				case "base":
+					if strings.EqualFold(fields[i+1], "dec") {
+						a.base = 10
+					} else {
+						a.base = 16
+					}
+				case "timestamps":
+					a.relative = strings.EqualFold(fields[i+1], "relative")
+				}
+			}
+			continue
+		}
+
+		if !ascTimestampPattern.MatchString(fields[0]) || len(fields) < 3 {
+			continue
+		}
+		ts, err := parseSeconds(fields[0])
+		if err != nil {
+			continue
+		}
+
+		frame, ok := a.parseEvent(fields[1:])
+		if !ok {
+			// 非CAN事件（Start of measurement、统计信息等）仍需累计相对时间
+			if a.relative {
+				a.elapsed += ts
+			}
+			continue
+		}
+
+		if a.relative {
+			a.elapsed += ts
+		} else {
+			a.elapsed = ts
+		}
+		frame.Relative = a.elapsed
+		base := noDateBase
+		if a.hasDate {
+			base = a.start
+		}
+		frame.Time = base.Add(a.elapsed)
+		return frame, nil
+	}
+	if err := a.scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取ASC文件失败（第 %d 行）: %v", a.lineNumber, err)
+	}
+	return nil, io.EOF
+}
+
+// parseEvent 解析时间戳之后的事件内容
+func (a *ASCReader) parseEvent(fields []string) (*models.LogFrame, bool) {
+	// CANFD <ch> <dir> <id>[x] [symbol] <brs> <esi> <dlc> <len> <data...>
+	if strings.EqualFold(fields[0], "CANFD") {
+		return a.parseFDEvent(fields[1:])
+	}
+
+	channel := fields[0]
+	if _, err := strconv.Atoi(channel); err != nil {
+		return nil, false
+	}
+
+	// <ch> ErrorFrame
+	if strings.EqualFold(fields[1], "ErrorFrame") {
+		return &models.LogFrame{
+			CANFrame: models.CANFrame{ErrorFrame: true},
+			Channel:  channel,
+		}, true
+	}
+
+	// <ch> <id>[x] <dir> d <dlc> <data...> | <ch> <id>[x] <dir> r [dlc]
+	if len(fields) < 4 {
+		return nil, false
+	}
+	id, extended, ok := a.parseID(fields[1])
+	if !ok {
+		return nil, false
+	}
+	direction, ok := ascDirection(fields[2])
+	if !ok {
+		return nil, false
+	}
+
+	frame := &models.LogFrame{
+		CANFrame:  models.CANFrame{ID: id, Extended: extended},
+		Channel:   channel,
+		Direction: direction,
+	}
+
+	switch strings.ToLower(fields[3]) {
+	case "r":
+		frame.Remote = true
+		if len(fields) > 4 {
+			if dlc, err := strconv.ParseUint(fields[4], 16, 8); err == nil {
+				frame.DLC = dlcToLength(int(dlc), false)
+			}
+		}
+		return frame, true
+	case "d":
+		if len(fields) < 5 {
+			return nil, false
+		}
+		dlc, err := strconv.ParseUint(fields[4], 16, 8)
+		if err != nil {
+			return nil, false
+		}
+		frame.DLC = dlcToLength(int(dlc), false)
+		n := frame.DLC
+		if 5+n > len(fields) {
+			n = len(fields) - 5
+		}
+		data, err := parseHexBytes(fields[5:5+n], a.base)
+		if err != nil {
+			return nil, false
+		}
+		frame.Data = data
+		return frame, true
+	}
+	return nil, false
+}
+
+// parseFDEvent 解析CANFD事件行
+func (a *ASCReader) parseFDEvent(fields []string) (*models.LogFrame, bool) {
+	if len(fields) < 3 {
+		return nil, false
+	}
+	channel := fields[0]
+	direction, ok := ascDirection(fields[1])
+	if !ok {
+		return nil, false
+	}
+	id, extended, ok := a.parseID(fields[2])
+	if !ok {
+		return nil, false
+	}
+
+	rest := fields[3:]
+	// 可选的符号名称：BRS/ESI位之前不是单个0/1的字段
+	if len(rest) > 0 && rest[0] != "0" && rest[0] != "1" {
+		rest = rest[1:]
+	}
+	if len(rest) < 4 {
+		return nil, false
+	}
+	dlc, err := strconv.ParseUint(rest[2], 16, 8)
+	if err != nil {
+		return nil, false
+	}
+	length, err := strconv.Atoi(rest[3])
+	if err != nil || length < 0 || length > fdLengths[len(fdLengths)-1] {
+		return nil, false
+	}
+
+	frame := &models.LogFrame{
+		CANFrame:  models.CANFrame{ID: id, Extended: extended, FD: true, DLC: dlcToLength(int(dlc), true)},
+		Channel:   channel,
+		Direction: direction,
+	}
+	if length != frame.DLC {
+		frame.DLC = length
+	}
+	n := length
+	if 4+n > len(rest) {
+		n = len(rest) - 4
+	}
+	data, err := parseHexBytes(rest[4:4+n], a.base)
+	if err != nil {
+		return nil, false
+	}
+	frame.Data = data
+	return frame, true
+}
+
+// parseID 解析ASC中的CAN ID，以 x 结尾表示扩展帧
+func (a *ASCReader) parseID(s string) (uint32, bool, bool) {
+	extended := false
+	if strings.HasSuffix(s, "x") || strings.HasSuffix(s, "X") {
+		extended = true
+		s = s[:len(s)-1]
+	}
+	id, err := strconv.ParseUint(s, a.base, 32)
+	if err != nil || id > 0x1FFFFFFF {
+		return 0, false, false
+	}
+	return uint32(id), extended || id > 0x7FF, true
+}
+
+// ascDirection 规范化方向字段
+func ascDirection(s string) (string, bool) {
+	switch strings.ToLower(s) {
+	case "rx":
+		return "Rx", true
+	case "tx", "txrq":
+		return "Tx", true
+	}
+	return "", false
+}
+
+// parseASCDate 解析 "Mon Nov 14 05:03:36.739 pm 2025" 形式的日期
+func parseASCDate(s string) (time.Time, bool) {
+	fields := strings.Fields(s)
+	for i, f := range fields {
+		if strings.EqualFold(f, "am") || strings.EqualFold(f, "pm") {
+			fields[i] = strings.ToUpper(f)
+		}
+	}
+	normalized := strings.Join(fields, " ")
+	for _, layout := range ascDateLayouts {
+		if t, err := time.ParseInLocation(layout, normalized, time.Local); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// ASCWriter 写出Vector ASC文本日志（十六进制、绝对时间戳）
+type ASCWriter struct {
+	w *bufio.Writer
+}
+
+// NewASCWriter 创建ASC日志写出器并写出文件头，start为测量开始时间
+func NewASCWriter(w io.Writer, start time.Time) (*ASCWriter, error) {
+	a := &ASCWriter{w: bufio.NewWriter(w)}
+	date := start.Format("Mon Jan 2 03:04:05.000 pm 2006")
+	fmt.Fprintf(a.w, "date %s\n", date)
+	fmt.Fprintf(a.w, "base hex  timestamps absolute\n")
+	fmt.Fprintf(a.w, "internal events logged\n")
+	fmt.Fprintf(a.w, "// version 9.0.0\n")
+	fmt.Fprintf(a.w, "Begin Triggerblock %s\n", date)
+	_, err := fmt.Fprintf(a.w, "   0.000000 Start of measurement\n")
+	return a, err
+}
+
+// WriteFrame 写出一帧，时间戳为相对测量开始的秒数
+func (a *ASCWriter) WriteFrame(frame *models.LogFrame) error {
+	ts := frame.Relative.Seconds()
+	channel := channelNumber(frame.Channel)
+	if frame.ErrorFrame {
+		_, err := fmt.Fprintf(a.w, "%11.6f %d  ErrorFrame\n", ts, channel)
+		return err
+	}
+
+	id := fmt.Sprintf("%X", frame.ID)
+	if frame.Extended {
+		id += "x"
+	}
+	direction := frame.Direction
+	if direction == "" {
+		direction = "Rx"
+	}
+	data := payload(frame)
+
+	if writeAsFD(frame) {
+		dlc := lengthToDLC(len(data))
+		// 数据长度不是合法的FD长度时按DLC补零
+		padded := make([]byte, fdLengths[dlc])
+		copy(padded, data)
+		_, err := fmt.Fprintf(a.w, "%11.6f CANFD %3d %s %8s 0 0 %x %2d%s\n",
+			ts, channel, direction, id, dlc, len(padded), ascHexBytes(padded))
+		return err
+	}
+	if frame.Remote {
+		_, err := fmt.Fprintf(a.w, "%11.6f %d  %-15s %s   r %d\n", ts, channel, id, direction, frame.DLC)
+		return err
+	}
+	_, err := fmt.Fprintf(a.w, "%11.6f %d  %-15s %s   d %d%s\n", ts, channel, id, direction, len(data), ascHexBytes(data))
+	return err
+}
+
+// Close 写出文件尾并刷新缓冲区
+func (a *ASCWriter) Close() error {
+	if _, err := fmt.Fprintf(a.w, "End TriggerBlock\n"); err != nil {
+		return err
+	}
+	return a.w.Flush()
+}
+
+// ascHexBytes 将数据格式化为 " 01 02 03"
+func ascHexBytes(data []byte) string {
+	var sb strings.Builder
+	for _, b := range data {
+		fmt.Fprintf(&sb, " %02X", b)
+	}
+	return sb.String()
+}
@@ -0,0 +1,138 @@
+package formats
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func readAllASC(t *testing.T, content string) []frameSummary {
+	t.Helper()
+	r := NewASCReader(strings.NewReader(content))
+	var frames []frameSummary
+	for {
+		frame, err := r.Next()
+		if err == io.EOF {
+			return frames
+		}
+		if err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+		frames = append(frames, summarize(frame))
+	}
+}
+
+func TestASCReader(t *testing.T) {
+	content := `date Fri Nov 14 05:03:36.739 pm 2025
+base hex  timestamps absolute
+internal events logged
+Begin Triggerblock Fri Nov 14 05:03:36.739 pm 2025
+   0.000000 Start of measurement
+   0.001000 1  1CC             Rx   d 8 03 00 00 00 00 00 00 00
+   0.002000 1  18FF0102x       Tx   d 2 AA BB
+   0.003000 2  123             Rx   r 4
+   0.004000 1  ErrorFrame
+   0.005000 CANFD 1 Rx 208 0 0 9 12 01 02 03 04 05 06 07 08 09 0A 0B 0C
+   0.006000 CANFD 1 Rx 209 Sym 1 0 2 2 AA BB
+End TriggerBlock
+`
+	want := []frameSummary{
+		{id: 0x1CC, dlc: 8, data: "0300000000000000", channel: "1", direction: "Rx", relative: time.Millisecond},
+		{id: 0x18FF0102, extended: true, dlc: 2, data: "aabb", channel: "1", direction: "Tx", relative: 2 * time.Millisecond},
+		{id: 0x123, remote: true, dlc: 4, data: "", channel: "2", direction: "Rx", relative: 3 * time.Millisecond},
+		{errorFrame: true, data: "", channel: "1", relative: 4 * time.Millisecond},
+		{id: 0x208, fd: true, dlc: 12, data: "0102030405060708090a0b0c", channel: "1", direction: "Rx", relative: 5 * time.Millisecond},
+		{id: 0x209, fd: true, dlc: 2, data: "aabb", channel: "1", direction: "Rx", relative: 6 * time.Millisecond},
+	}
+	got := readAllASC(t, content)
+	compareFrames(t, got, want)
+
+	start := time.Date(2025, 11, 14, 17, 3, 36, 739000000, time.Local)
+	r := NewASCReader(strings.NewReader(content))
+	frame, err := r.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wantTime := start.Add(time.Millisecond); !frame.Time.Equal(wantTime) {
+		t.Errorf("time = %v, want %v", frame.Time, wantTime)
+	}
+}
+
+func TestASCReaderRelativeDecimal(t *testing.T) {
+	content := `base dec  timestamps relative
+   0.500000 Start of measurement
+   0.010000 1  460             Rx   d 2 10 255
+   0.010000 1  460             Rx   d 1 16
+`
+	want := []frameSummary{
+		{id: 460, dlc: 2, data: "0aff", channel: "1", direction: "Rx", relative: 510 * time.Millisecond},
+		{id: 460, dlc: 1, data: "10", channel: "1", direction: "Rx", relative: 520 * time.Millisecond},
+	}
+	compareFrames(t, readAllASC(t, content), want)
+}
+
+// 格式错误的事件行应被跳过，不能panic
+func TestASCReaderMalformedLines(t *testing.T) {
+	lines := []string{
+		"0.1 CANFD 1 Rx 123 0 0 8 -1 11 22",
+		"0.1 CANFD 1 Rx 123 0 0 8 65 11 22",
+		"0.1 CANFD 1 Rx 123 0 0 8 x 11",
+		"0.1 CANFD 1 Rx 123 0 0",
+		"0.1 CANFD 1 Xx 123 0 0 8 8",
+		"0.1 CANFD 1",
+		"0.1 1 123 Rx d",
+		"0.1 1 123 Rx d zz",
+		"0.1 1 123 Rx d 8 GG",
+		"0.1 1 123",
+		"0.1 1 20000000x Rx d 0",
+		"0.1 1 123 Up d 1 01",
+		"0.1 x 123 Rx d 1 01",
+		"abc 1 123 Rx d 1 01",
+	}
+	for _, line := range lines {
+		t.Run(line, func(t *testing.T) {
+			if got := readAllASC(t, line+"\n"); len(got) != 0 {
+				t.Errorf("got %d frames, want 0: %+v", len(got), got)
+			}
+		})
+	}
+
+	// DLC大于实际数据字节时保留声明的长度，只读取已有的数据
+	got := readAllASC(t, "0.1 1 123 Rx d 8 01 02\n0.2 CANFD 1 Rx 123 0 0 f 64 01\n")
+	want := []frameSummary{
+		{id: 0x123, dlc: 8, data: "0102", channel: "1", direction: "Rx", relative: 100 * time.Millisecond},
+		{id: 0x123, fd: true, dlc: 64, data: "01", channel: "1", direction: "Rx", relative: 200 * time.Millisecond},
+	}
+	compareFrames(t, got, want)
+}
+
+func TestASCWriterRoundTrip(t *testing.T) {
+	const input = "0.001 1 1CC Rx d 2 03 07\n0.002 CANFD 1 Tx 18FF0102x 0 0 9 12 01 02 03 04 05 06 07 08 09 0A 0B 0C\n"
+	start := time.Date(2025, 11, 14, 17, 3, 36, 0, time.Local)
+	frames := readAllASC(t, input)
+
+	var buf bytes.Buffer
+	w, err := NewASCWriter(&buf, start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewASCReader(strings.NewReader(input))
+	for {
+		frame, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := w.WriteFrame(frame); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	compareFrames(t, readAllASC(t, buf.String()), frames)
+}
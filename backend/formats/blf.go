@@ -0,0 +1,339 @@
+package formats
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"csv-parser/models"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// BLF对象类型（Vector binlog_objects.h）
+const (
+	blfObjCANMessage     = 1
+	blfObjCANError       = 2
+	blfObjLogContainer   = 10
+	blfObjCANErrorExt    = 73
+	blfObjCANMessage2    = 86
+	blfObjCANFDMessage   = 100
+	blfObjCANFDMessage64 = 101 // CAN_FD_MESSAGE_64，其对象大小不做4字节对齐
+)
+
+// BLF对象头与帧标志
+const (
+	blfObjHeaderBaseSize = 16
+	blfMaxHeaderSize     = 4096     // 文件头的最大长度（通常为144）
+	blfMaxObjectSize     = 64 << 20 // 对象（含解压后的日志容器）的最大长度，超过视为文件损坏
+	blfTimeTenMics       = 0x1      // 时间戳单位为10微秒
+	blfCANDirTx          = 0x1      // CAN_MESSAGE flags: 发送方向
+	blfCANRemote         = 0x80     // CAN_MESSAGE flags: 远程帧
+	blfCANFDEDL          = 0x1      // CAN_FD_MESSAGE canFdFlags: FD帧
+	blfCANFD64Remote     = 0x0010   // CAN_FD_MESSAGE_64 flags: 远程帧
+	blfCANFD64EDL        = 0x1000   // CAN_FD_MESSAGE_64 flags: FD帧
+	blfCANExtended       = 0x80000000
+	blfCompressionNone   = 0
+	blfCompressionZlib   = 2
+)
+
+var (
+	blfFileSignature   = []byte("LOGG")
+	blfObjectSignature = []byte("LOBJ")
+)
+
+// BLFReader 读取Vector BLF二进制日志
+// 依次读取LOG_CONTAINER对象并解压，从解压后的数据中解析CAN_MESSAGE、CAN_MESSAGE2、CAN_FD_MESSAGE、CAN_FD_MESSAGE_64等对象，
+// 同一时间只在内存中保留一个容器的数据
+type BLFReader struct {
+	r          *bufio.Reader
+	headerRead bool
+	start      time.Time
+	pending    []byte // 已解压但尚未解析的数据，对象可能跨越容器边界
+	eof        bool
+}
+
+// NewBLFReader 创建BLF日志读取器
+func NewBLFReader(r io.Reader) *BLFReader {
+	return &BLFReader{r: bufio.NewReaderSize(r, 256*1024)}
+}
+
+// Next 读取下一帧，非CAN对象（环境变量、统计信息等）会被跳过
+func (b *BLFReader) Next() (*models.LogFrame, error) {
+	if !b.headerRead {
+		if err := b.readFileHeader(); err != nil {
+			return nil, err
+		}
+		b.headerRead = true
+	}
+
+	for {
+		obj, ok, err := b.nextPendingObject()
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			if frame := b.parseObject(obj); frame != nil {
+				return frame, nil
+			}
+			continue
+		}
+		if b.eof {
+			return nil, io.EOF
+		}
+		if err := b.readContainer(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// readFileHeader 读取 "LOGG" 文件头，其中包含测量开始时间（SYSTEMTIME）
+func (b *BLFReader) readFileHeader() error {
+	head := make([]byte, 8)
+	if _, err := io.ReadFull(b.r, head); err != nil || !bytes.Equal(head[:4], blfFileSignature) {
+		return fmt.Errorf("不是BLF文件（缺少LOGG文件头）")
+	}
+	headerSize := binary.LittleEndian.Uint32(head[4:])
+	if headerSize < 72 || headerSize > blfMaxHeaderSize {
+		return fmt.Errorf("BLF文件头长度无效: %d", headerSize)
+	}
+	rest := make([]byte, headerSize-8)
+	if _, err := io.ReadFull(b.r, rest); err != nil {
+		return fmt.Errorf("BLF文件头不完整: %v", err)
+	}
+
+	// 偏移40（相对文件开头）为开始时间: year, month, dayOfWeek, day, hour, minute, second, milliseconds
+	st := rest[32:48]
+	u16 := func(i int) int { return int(binary.LittleEndian.Uint16(st[i*2:])) }
+	if year := u16(0); year > 0 {
+		b.start = time.Date(year, time.Month(u16(1)), u16(3), u16(4), u16(5), u16(6), u16(7)*int(time.Millisecond), time.Local)
+	} else {
+		b.start = noDateBase
+	}
+	return nil
+}
+
+// readContainer 读取下一个顶层对象，LOG_CONTAINER解压后追加到待解析数据中
+func (b *BLFReader) readContainer() error {
+	header := make([]byte, blfObjHeaderBaseSize)
+	if _, err := io.ReadFull(b.r, header); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			b.eof = true
+			return nil
+		}
+		return fmt.Errorf("读取BLF对象失败: %v", err)
+	}
+	if !bytes.Equal(header[:4], blfObjectSignature) {
+		return fmt.Errorf("BLF对象签名无效")
+	}
+	objSize := binary.LittleEndian.Uint32(header[8:])
+	objType := binary.LittleEndian.Uint32(header[12:])
+	if objSize < blfObjHeaderBaseSize || objSize > blfMaxObjectSize {
+		return fmt.Errorf("BLF对象长度无效: %d", objSize)
+	}
+
+	body := make([]byte, objSize-blfObjHeaderBaseSize)
+	if _, err := io.ReadFull(b.r, body); err != nil {
+		// 记录被截断时，保留已读出的帧
+		b.eof = true
+		return nil
+	}
+	// 顶层对象按4字节对齐
+	if pad := int(objSize % 4); pad > 0 {
+		b.r.Discard(pad)
+	}
+
+	if objType != blfObjLogContainer {
+		// 未放在容器中的对象直接交给对象解析
+		b.pending = append(b.pending, header...)
+		b.pending = append(b.pending, body...)
+		return nil
+	}
+
+	// LOG_CONTAINER: compressionMethod(2) reserved(6) uncompressedSize(4) reserved(4) data
+	if len(body) < 16 {
+		return fmt.Errorf("BLF日志容器长度无效")
+	}
+	method := binary.LittleEndian.Uint16(body)
+	uncompressedSize := binary.LittleEndian.Uint32(body[8:])
+	if uncompressedSize > blfMaxObjectSize {
+		return fmt.Errorf("BLF日志容器解压后长度无效: %d", uncompressedSize)
+	}
+	data := body[16:]
+
+	switch method {
+	case blfCompressionNone:
+		b.pending = append(b.pending, data...)
+	case blfCompressionZlib:
+		zr, err := zlib.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return fmt.Errorf("BLF日志容器解压失败: %v", err)
+		}
+		buf := bytes.NewBuffer(make([]byte, 0, uncompressedSize))
+		// 解压后的数据不能超过容器头中声明的长度
+		_, err = io.Copy(buf, io.LimitReader(zr, int64(uncompressedSize)+1))
+		zr.Close()
+		if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return fmt.Errorf("BLF日志容器解压失败: %v", err)
+		}
+		if buf.Len() > int(uncompressedSize) {
+			return fmt.Errorf("BLF日志容器解压后超过声明的长度 %d", uncompressedSize)
+		}
+		b.pending = append(b.pending, buf.Bytes()...)
+	default:
+		return fmt.Errorf("不支持的BLF压缩方式: %d", method)
+	}
+	return nil
+}
+
+// nextPendingObject 从待解析数据中取出一个完整对象，数据不足时返回false
+// 对象长度超过blfMaxObjectSize时返回错误，避免为损坏的对象头无限累积待解析数据
+func (b *BLFReader) nextPendingObject() ([]byte, bool, error) {
+	for {
+		if len(b.pending) < blfObjHeaderBaseSize {
+			return nil, false, nil
+		}
+		if !bytes.Equal(b.pending[:4], blfObjectSignature) {
+			// 数据损坏时跳到下一个对象签名
+			idx := bytes.Index(b.pending[1:], blfObjectSignature)
+			if idx < 0 {
+				b.pending = b.pending[len(b.pending)-3:]
+				return nil, false, nil
+			}
+			b.pending = b.pending[idx+1:]
+			continue
+		}
+
+		objSize := int(binary.LittleEndian.Uint32(b.pending[8:]))
+		objType := binary.LittleEndian.Uint32(b.pending[12:])
+		if objSize < blfObjHeaderBaseSize {
+			b.pending = b.pending[4:]
+			continue
+		}
+		if objSize > blfMaxObjectSize {
+			return nil, false, fmt.Errorf("BLF对象长度无效: %d", objSize)
+		}
+		next := objSize
+		if objType != blfObjCANFDMessage64 {
+			next += objSize % 4
+		}
+		if len(b.pending) < objSize {
+			return nil, false, nil
+		}
+		obj := b.pending[:objSize]
+		if next > len(b.pending) {
+			next = len(b.pending)
+		}
+		b.pending = b.pending[next:]
+		if len(b.pending) == 0 {
+			b.pending = nil
+		}
+		return obj, true, nil
+	}
+}
+
+// parseObject 解析单个BLF对象，非CAN对象返回nil
+func (b *BLFReader) parseObject(obj []byte) *models.LogFrame {
+	headerSize := int(binary.LittleEndian.Uint16(obj[4:]))
+	objType := binary.LittleEndian.Uint32(obj[12:])
+	// 对象头V1/V2的flags都在偏移16，时间戳都在偏移24
+	if headerSize < 32 || len(obj) < headerSize {
+		return nil
+	}
+	flags := binary.LittleEndian.Uint32(obj[16:])
+	ts := binary.LittleEndian.Uint64(obj[24:])
+	relative := time.Duration(ts)
+	if flags&blfTimeTenMics != 0 {
+		relative = time.Duration(ts) * 10 * time.Microsecond
+	}
+	body := obj[headerSize:]
+
+	frame := &models.LogFrame{Relative: relative, Time: b.start.Add(relative)}
+
+	switch objType {
+	case blfObjCANMessage, blfObjCANMessage2:
+		// channel(2) flags(1) dlc(1) id(4) data(8)
+		if len(body) < 16 {
+			return nil
+		}
+		b.applyCANHeader(frame, body)
+		msgFlags := body[2]
+		frame.Remote = msgFlags&blfCANRemote != 0
+		frame.DLC = dlcToLength(int(body[3]), false)
+		if !frame.Remote {
+			frame.Data = append([]byte(nil), body[8:8+frame.DLC]...)
+		}
+	case blfObjCANFDMessage:
+		// channel(2) flags(1) dlc(1) id(4) frameLength(4) bitCount(1) fdFlags(1) validDataBytes(1) reserved(5) data(64)
+		if len(body) < 20 {
+			return nil
+		}
+		b.applyCANHeader(frame, body)
+		msgFlags := body[2]
+		frame.FD = body[13]&blfCANFDEDL != 0
+		frame.Remote = !frame.FD && msgFlags&blfCANRemote != 0
+		frame.DLC = dlcToLength(int(body[3]), frame.FD)
+		if valid := int(body[14]); valid > 0 && valid < frame.DLC {
+			frame.DLC = valid
+		}
+		if !frame.Remote {
+			end := 20 + frame.DLC
+			if end > len(body) {
+				end = len(body)
+			}
+			frame.Data = append([]byte(nil), body[20:end]...)
+			frame.DLC = len(frame.Data)
+		}
+	case blfObjCANFDMessage64:
+		// channel(1) dlc(1) validDataBytes(1) txCount(1) id(4) frameLength(4) flags(4) btrCfgArb(4) btrCfgData(4)
+		// timeOffsetBrsNs(4) timeOffsetCrcDelNs(4) bitCount(2) dir(1) extDataOffset(1) crc(4) data(validDataBytes)
+		if len(body) < 40 {
+			return nil
+		}
+		frame.Channel = fmt.Sprintf("%d", body[0])
+		frame.Direction = "Rx"
+		if body[34] != 0 {
+			frame.Direction = "Tx"
+		}
+		id := binary.LittleEndian.Uint32(body[4:])
+		frame.Extended = id&blfCANExtended != 0
+		frame.ID = id & canEFFMask
+		msgFlags := binary.LittleEndian.Uint32(body[12:])
+		frame.FD = msgFlags&blfCANFD64EDL != 0
+		frame.Remote = !frame.FD && msgFlags&blfCANFD64Remote != 0
+		frame.DLC = dlcToLength(int(body[1]), frame.FD)
+		if !frame.Remote {
+			// 数据只有validDataBytes个字节，对象大小也按此计算
+			end := 40 + min(frame.DLC, int(body[2]))
+			if end > len(body) {
+				end = len(body)
+			}
+			frame.Data = append([]byte(nil), body[40:end]...)
+			frame.DLC = len(frame.Data)
+		}
+	case blfObjCANError, blfObjCANErrorExt:
+		if len(body) < 2 {
+			return nil
+		}
+		frame.Channel = fmt.Sprintf("%d", binary.LittleEndian.Uint16(body))
+		frame.Direction = "Rx"
+		frame.ErrorFrame = true
+	default:
+		return nil
+	}
+	return frame
+}
+
+// applyCANHeader 解析CAN对象公共的通道、方向和ID字段
+func (b *BLFReader) applyCANHeader(frame *models.LogFrame, body []byte) {
+	frame.Channel = fmt.Sprintf("%d", binary.LittleEndian.Uint16(body))
+	frame.Direction = "Rx"
+	if body[2]&blfCANDirTx != 0 {
+		frame.Direction = "Tx"
+	}
+	id := binary.LittleEndian.Uint32(body[4:])
+	frame.Extended = id&blfCANExtended != 0
+	frame.ID = id & canEFFMask
+}
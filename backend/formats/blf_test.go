@@ -0,0 +1,226 @@
+package formats
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// blfFileHeader 构造144字节的LOGG文件头，开始时间为2025-11-14 17:03:36.739
+func blfFileHeader() []byte {
+	h := make([]byte, 144)
+	copy(h, "LOGG")
+	binary.LittleEndian.PutUint32(h[4:], 144)
+	for i, v := range []uint16{2025, 11, 5, 14, 17, 3, 36, 739} {
+		binary.LittleEndian.PutUint16(h[40+i*2:], v)
+	}
+	return h
+}
+
+// blfObject 构造带V1对象头（32字节）的对象，时间戳单位为纳秒
+func blfObject(objType uint32, ts uint64, body []byte) []byte {
+	obj := make([]byte, 32, 32+len(body))
+	copy(obj, "LOBJ")
+	binary.LittleEndian.PutUint16(obj[4:], 32)
+	binary.LittleEndian.PutUint16(obj[6:], 1)
+	binary.LittleEndian.PutUint32(obj[8:], uint32(32+len(body)))
+	binary.LittleEndian.PutUint32(obj[12:], objType)
+	binary.LittleEndian.PutUint32(obj[16:], 2) // 纳秒时间戳
+	binary.LittleEndian.PutUint64(obj[24:], ts)
+	// BLF对象之后的填充字节数为 objSize%4
+	return append(append(obj, body...), make([]byte, (32+len(body))%4)...)
+}
+
+func blfCANMessage(ts uint64, channel uint16, flags byte, id uint32, data []byte) []byte {
+	body := make([]byte, 16)
+	binary.LittleEndian.PutUint16(body, channel)
+	body[2] = flags
+	body[3] = byte(len(data))
+	binary.LittleEndian.PutUint32(body[4:], id)
+	copy(body[8:], data)
+	return blfObject(blfObjCANMessage, ts, body)
+}
+
+func blfCANFDMessage(ts uint64, id uint32, dlc byte, data []byte) []byte {
+	body := make([]byte, 20+64)
+	binary.LittleEndian.PutUint16(body, 1)
+	body[3] = dlc
+	binary.LittleEndian.PutUint32(body[4:], id)
+	body[13] = blfCANFDEDL
+	body[14] = byte(len(data))
+	copy(body[20:], data)
+	return blfObject(blfObjCANFDMessage, ts, body)
+}
+
+// blfCANFDMessage64 构造CAN_FD_MESSAGE_64对象，与其他对象不同，之后没有填充字节
+func blfCANFDMessage64(ts uint64, channel byte, dir byte, bitCount uint16, flags uint32, id uint32, dlc byte, data []byte) []byte {
+	body := make([]byte, 40+len(data))
+	body[0] = channel
+	body[1] = dlc
+	body[2] = byte(len(data))
+	binary.LittleEndian.PutUint32(body[4:], id)
+	binary.LittleEndian.PutUint32(body[12:], flags)
+	binary.LittleEndian.PutUint16(body[32:], bitCount)
+	body[34] = dir
+	copy(body[40:], data)
+	obj := blfObject(blfObjCANFDMessage64, ts, body)
+	return obj[:32+len(body)]
+}
+
+// blfContainer 将对象打包为LOG_CONTAINER，uncompressedSize为0时使用实际长度
+func blfContainer(objects []byte, compress bool, uncompressedSize uint32) []byte {
+	if uncompressedSize == 0 {
+		uncompressedSize = uint32(len(objects))
+	}
+	data := objects
+	method := uint16(blfCompressionNone)
+	if compress {
+		var buf bytes.Buffer
+		zw := zlib.NewWriter(&buf)
+		zw.Write(objects)
+		zw.Close()
+		data = buf.Bytes()
+		method = blfCompressionZlib
+	}
+	c := make([]byte, 32, 32+len(data))
+	copy(c, "LOBJ")
+	binary.LittleEndian.PutUint16(c[4:], 16)
+	binary.LittleEndian.PutUint16(c[6:], 1)
+	binary.LittleEndian.PutUint32(c[8:], uint32(32+len(data)))
+	binary.LittleEndian.PutUint32(c[12:], blfObjLogContainer)
+	binary.LittleEndian.PutUint16(c[16:], method)
+	binary.LittleEndian.PutUint32(c[24:], uncompressedSize)
+	return append(append(c, data...), make([]byte, (32+len(data))%4)...)
+}
+
+func readAllBLF(data []byte) ([]frameSummary, error) {
+	r := NewBLFReader(bytes.NewReader(data))
+	var frames []frameSummary
+	for {
+		frame, err := r.Next()
+		if err == io.EOF {
+			return frames, nil
+		}
+		if err != nil {
+			return frames, err
+		}
+		frames = append(frames, summarize(frame))
+	}
+}
+
+func TestBLFReader(t *testing.T) {
+	first := append(blfCANMessage(1000000, 1, 0, 0x1CC, []byte{0x03, 0, 0, 0, 0, 0, 0, 0}),
+		blfCANMessage(2000000, 2, blfCANDirTx, 0x18FF0102|blfCANExtended, []byte{0xAA, 0xBB})...)
+	fd := blfCANFDMessage(3000000, 0x208, 9, []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12})
+	// 第三个对象跨越两个容器
+	second, third := fd[:40], fd[40:]
+
+	file := blfFileHeader()
+	file = append(file, blfContainer(first, true, 0)...)
+	file = append(file, blfContainer(second, false, 0)...)
+	file = append(file, blfContainer(third, true, 0)...)
+
+	want := []frameSummary{
+		{id: 0x1CC, dlc: 8, data: "0300000000000000", channel: "1", direction: "Rx", relative: time.Millisecond},
+		{id: 0x18FF0102, extended: true, dlc: 2, data: "aabb", channel: "2", direction: "Tx", relative: 2 * time.Millisecond},
+		{id: 0x208, fd: true, dlc: 12, data: "0102030405060708090a0b0c", channel: "1", direction: "Rx", relative: 3 * time.Millisecond},
+	}
+	got, err := readAllBLF(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compareFrames(t, got, want)
+
+	frame, err := NewBLFReader(bytes.NewReader(file)).Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wantTime := time.Date(2025, 11, 14, 17, 3, 36, 740000000, time.Local); !frame.Time.Equal(wantTime) {
+		t.Errorf("time = %v, want %v", frame.Time, wantTime)
+	}
+}
+
+func TestBLFReaderCANFDMessage64(t *testing.T) {
+	var objects []byte
+	// 32+40+5=77字节，之后紧跟下一个对象
+	objects = append(objects, blfCANFDMessage64(1000000, 1, 0, 0, blfCANFD64EDL, 0x208, 5, []byte{1, 2, 3, 4, 5})...)
+	objects = append(objects, blfCANFDMessage64(2000000, 2, 1, 0, blfCANFD64EDL, 0x18FF0102|blfCANExtended, 9,
+		[]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12})...)
+	objects = append(objects, blfCANFDMessage64(3000000, 1, 0, 0, 0, 0x1CC, 2, []byte{0xAA, 0xBB})...)
+	objects = append(objects, blfCANFDMessage64(4000000, 1, 0, 0, blfCANFD64Remote, 0x123, 4, nil)...)
+	// bitCount大于255时高字节非0，不能被当作方向
+	objects = append(objects, blfCANFDMessage64(4500000, 1, 0, 0x0180, blfCANFD64EDL, 0x301, 8, []byte{1, 2, 3, 4, 5, 6, 7, 8})...)
+	objects = append(objects, blfCANFDMessage64(4600000, 1, 1, 0x0180, blfCANFD64EDL, 0x302, 1, []byte{0x55})...)
+	objects = append(objects, blfCANMessage(5000000, 1, 0, 0x1CC, []byte{0x03})...)
+
+	file := append(blfFileHeader(), blfContainer(objects, true, 0)...)
+	want := []frameSummary{
+		{id: 0x208, fd: true, dlc: 5, data: "0102030405", channel: "1", direction: "Rx", relative: time.Millisecond},
+		{id: 0x18FF0102, extended: true, fd: true, dlc: 12, data: "0102030405060708090a0b0c", channel: "2", direction: "Tx", relative: 2 * time.Millisecond},
+		{id: 0x1CC, dlc: 2, data: "aabb", channel: "1", direction: "Rx", relative: 3 * time.Millisecond},
+		{id: 0x123, remote: true, dlc: 4, channel: "1", direction: "Rx", relative: 4 * time.Millisecond},
+		{id: 0x301, fd: true, dlc: 8, data: "0102030405060708", channel: "1", direction: "Rx", relative: 4500 * time.Microsecond},
+		{id: 0x302, fd: true, dlc: 1, data: "55", channel: "1", direction: "Tx", relative: 4600 * time.Microsecond},
+		{id: 0x1CC, dlc: 1, data: "03", channel: "1", direction: "Rx", relative: 5 * time.Millisecond},
+	}
+	got, err := readAllBLF(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compareFrames(t, got, want)
+}
+
+// 损坏的长度字段应返回错误，而不是按声明的长度分配内存
+func TestBLFReaderCorruptSizes(t *testing.T) {
+	msg := blfCANMessage(1000000, 1, 0, 0x1CC, []byte{0x03})
+
+	hugeObject := blfContainer(msg, false, 0)
+	binary.LittleEndian.PutUint32(hugeObject[8:], 0xFFFFFFF0)
+
+	hugeInner := append([]byte(nil), msg...)
+	binary.LittleEndian.PutUint32(hugeInner[8:], 0xFFFFFFF0)
+
+	// 解压后的数据比容器头声明的长
+	bomb := blfContainer(bytes.Repeat(msg, 100), true, uint32(len(msg)))
+
+	hugeHeader := blfFileHeader()
+	binary.LittleEndian.PutUint32(hugeHeader[4:], 0xFFFFFFF0)
+
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"object size", append(blfFileHeader(), hugeObject...), "BLF对象长度无效"},
+		{"uncompressed size", append(blfFileHeader(), blfContainer(msg, true, 0xFFFFFFF0)...), "解压后长度无效"},
+		{"inner object size", append(blfFileHeader(), blfContainer(hugeInner, true, 0)...), "BLF对象长度无效"},
+		{"decompressed overflow", append(blfFileHeader(), bomb...), "超过声明的长度"},
+		{"file header size", hugeHeader, "文件头长度无效"},
+		{"not blf", []byte("not a blf file at all"), "不是BLF文件"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := readAllBLF(tt.data)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+// 截断的文件保留已读出的帧
+func TestBLFReaderTruncated(t *testing.T) {
+	file := append(blfFileHeader(), blfContainer(blfCANMessage(1000000, 1, 0, 0x1CC, []byte{0x03}), true, 0)...)
+	second := blfContainer(blfCANMessage(2000000, 1, 0, 0x208, []byte{0x04}), true, 0)
+	file = append(file, second[:len(second)-5]...)
+
+	got, err := readAllBLF(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compareFrames(t, got, []frameSummary{{id: 0x1CC, dlc: 1, data: "03", channel: "1", direction: "Rx", relative: time.Millisecond}})
+}
@@ -0,0 +1,207 @@
+package formats
+
+import (
+	"bufio"
+	"csv-parser/models"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SocketCAN ID标志位（linux/can.h）
+const (
+	canEFFFlag = 0x80000000
+	canRTRFlag = 0x40000000
+	canERRFlag = 0x20000000
+	canEFFMask = 0x1FFFFFFF
+	canSFFMask = 0x000007FF
+)
+
+// candumpLinePattern candump -l 日志行: (1700000000.123456) can0 2CF#1040FF3748C10A00 [T|R]
+var candumpLinePattern = regexp.MustCompile(`^\((\d+)\.(\d+)\)\s+(\S+)\s+([0-9A-Fa-f]{1,8})(##?)(\S*)(?:\s+([TR]))?\s*$`)
+
+// CandumpReader 读取 candump -l 日志
+type CandumpReader struct {
+	scanner    *bufio.Scanner
+	lineNumber int
+	start      time.Time
+	started    bool
+}
+
+// NewCandumpReader 创建candump日志读取器
+func NewCandumpReader(r io.Reader) *CandumpReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	return &CandumpReader{scanner: scanner}
+}
+
+// Next 读取下一帧，跳过空行和无法识别的行
+func (c *CandumpReader) Next() (*models.LogFrame, error) {
+	for c.scanner.Scan() {
+		c.lineNumber++
+		line := strings.TrimSpace(c.scanner.Text())
+		if line == "" {
+			continue
+		}
+		frame, err := ParseCandumpLine(line)
+		if err != nil {
+			continue
+		}
+		if !c.started {
+			c.start = frame.Time
+			c.started = true
+		}
+		frame.Relative = frame.Time.Sub(c.start)
+		return frame, nil
+	}
+	if err := c.scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取candump日志失败（第 %d 行）: %v", c.lineNumber, err)
+	}
+	return nil, io.EOF
+}
+
+// ParseCandumpLine 解析一行candump日志
+// 支持经典帧 ID#data、远程帧 ID#R[len]、CAN FD帧 ID##<flags><data>，8位ID为扩展帧
+func ParseCandumpLine(line string) (*models.LogFrame, error) {
+	m := candumpLinePattern.FindStringSubmatch(line)
+	if m == nil {
+		return nil, fmt.Errorf("不是candump日志格式: %.60s", line)
+	}
+
+	offset, err := parseSeconds(m[1] + "." + m[2])
+	if err != nil {
+		return nil, err
+	}
+
+	rawID, err := strconv.ParseUint(m[4], 16, 32)
+	if err != nil {
+		return nil, fmt.Errorf("无效的CAN ID: %s", m[4])
+	}
+
+	frame := &models.LogFrame{
+		Time:    time.Unix(0, 0).Add(offset),
+		Channel: m[3],
+	}
+	switch m[7] {
+	case "T":
+		frame.Direction = "Tx"
+	case "R":
+		frame.Direction = "Rx"
+	}
+
+	// 8位十六进制ID表示扩展帧，其中可能带有错误帧标志
+	if len(m[4]) == 8 {
+		frame.Extended = true
+		frame.ErrorFrame = rawID&canERRFlag != 0
+		frame.ID = uint32(rawID) & canEFFMask
+		if frame.ErrorFrame {
+			frame.Extended = false
+		}
+	} else {
+		frame.ID = uint32(rawID) & canSFFMask
+	}
+
+	payload := m[6]
+	if m[5] == "##" {
+		// CAN FD：第一个字符为FD标志（BRS/ESI），其后为数据
+		if payload == "" {
+			return nil, fmt.Errorf("CAN FD帧缺少标志位: %s", line)
+		}
+		frame.FD = true
+		payload = payload[1:]
+	} else if strings.HasPrefix(payload, "R") || strings.HasPrefix(payload, "r") {
+		frame.Remote = true
+		if n, err := strconv.Atoi(payload[1:]); err == nil && n >= 0 && n <= 8 {
+			frame.DLC = n
+		}
+		return frame, nil
+	}
+
+	// 数据字节之间允许有 '.' 分隔
+	payload = strings.ReplaceAll(payload, ".", "")
+	// 经典帧的 _<dlc> 后缀（DLC大于8时）
+	if idx := strings.Index(payload, "_"); idx >= 0 && !frame.FD {
+		payload = payload[:idx]
+	}
+	if len(payload)%2 != 0 {
+		return nil, fmt.Errorf("数据字节数不完整: %s", line)
+	}
+	tokens := make([]string, len(payload)/2)
+	for i := range tokens {
+		tokens[i] = payload[2*i : 2*i+2]
+	}
+	data, err := parseHexBytes(tokens, 16)
+	if err != nil {
+		return nil, err
+	}
+	frame.Data = data
+	frame.DLC = len(data)
+
+	return frame, nil
+}
+
+// CandumpWriter 写出 candump -l 日志（可直接用 canplayer 回放）
+type CandumpWriter struct {
+	w *bufio.Writer
+}
+
+// NewCandumpWriter 创建candump日志写出器
+func NewCandumpWriter(w io.Writer) *CandumpWriter {
+	return &CandumpWriter{w: bufio.NewWriter(w)}
+}
+
+// WriteFrame 写出一帧，时间戳为帧的绝对时间
+func (c *CandumpWriter) WriteFrame(frame *models.LogFrame) error {
+	_, err := fmt.Fprintf(c.w, "(%d.%06d) %s %s\n", frame.Time.Unix(), frame.Time.Nanosecond()/1000,
+		candumpInterface(frame.Channel), FormatCandumpFrame(frame))
+	return err
+}
+
+// Close 刷新缓冲区
+func (c *CandumpWriter) Close() error {
+	return c.w.Flush()
+}
+
+// FormatCandumpFrame 按 cansend/candump 的帧格式输出：ID#data、ID#R[len]、ID##<flags><data>
+func FormatCandumpFrame(frame *models.LogFrame) string {
+	var id string
+	switch {
+	case frame.ErrorFrame:
+		id = fmt.Sprintf("%08X", canERRFlag|frame.ID&canEFFMask)
+	case frame.Extended:
+		id = fmt.Sprintf("%08X", frame.ID&canEFFMask)
+	default:
+		id = fmt.Sprintf("%03X", frame.ID&canSFFMask)
+	}
+
+	if frame.Remote {
+		if frame.DLC > 0 {
+			return fmt.Sprintf("%s#R%d", id, frame.DLC)
+		}
+		return id + "#R"
+	}
+	data := fmt.Sprintf("%X", payload(frame))
+	if writeAsFD(frame) {
+		// 标志位：BRS=1，ESI=2；嗅探器和日志中均无此信息，固定为0
+		return id + "##0" + data
+	}
+	return id + "#" + data
+}
+
+// candumpInterface 将通道名称转换为SocketCAN接口名：数字通道（从1开始）映射为 can0、can1...
+func candumpInterface(channel string) string {
+	if channel == "" {
+		return "can0"
+	}
+	if n, err := strconv.Atoi(channel); err == nil {
+		if n > 0 {
+			n--
+		}
+		return fmt.Sprintf("can%d", n)
+	}
+	// 接口名中不能有空白
+	return strings.Join(strings.Fields(channel), "_")
+}
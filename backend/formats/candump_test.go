@@ -0,0 +1,84 @@
+package formats
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCandumpLine(t *testing.T) {
+	tests := []struct {
+		line string
+		want frameSummary
+	}{
+		{"(1763111016.739000) can0 1CC#0300000000000000", frameSummary{id: 0x1CC, dlc: 8, data: "0300000000000000", channel: "can0"}},
+		{"(1763111016.739000) can1 18FF0102#AABB T", frameSummary{id: 0x18FF0102, extended: true, dlc: 2, data: "aabb", channel: "can1", direction: "Tx"}},
+		{"(1763111016.739000) can0 00000123#01 R", frameSummary{id: 0x123, extended: true, dlc: 1, data: "01", channel: "can0", direction: "Rx"}},
+		{"(1763111016.739000) can0 123#R", frameSummary{id: 0x123, remote: true, channel: "can0"}},
+		{"(1763111016.739000) can0 123#R4", frameSummary{id: 0x123, remote: true, dlc: 4, channel: "can0"}},
+		{"(1763111016.739000) can0 123#", frameSummary{id: 0x123, channel: "can0"}},
+		{"(1763111016.739000) can0 123#11.22.33", frameSummary{id: 0x123, dlc: 3, data: "112233", channel: "can0"}},
+		{"(1763111016.739000) can0 123#1122334455667788_C", frameSummary{id: 0x123, dlc: 8, data: "1122334455667788", channel: "can0"}},
+		{"(1763111016.739000) can0 208##1010203040506070809", frameSummary{id: 0x208, fd: true, dlc: 9, data: "010203040506070809", channel: "can0"}},
+		{"(1763111016.739000) can0 20000004#0000800000000000", frameSummary{id: 0x4, errorFrame: true, dlc: 8, data: "0000800000000000", channel: "can0"}},
+	}
+	for _, tt := range tests {
+		frame, err := ParseCandumpLine(tt.line)
+		if err != nil {
+			t.Errorf("ParseCandumpLine(%q): %v", tt.line, err)
+			continue
+		}
+		if got := summarize(frame); got != tt.want {
+			t.Errorf("ParseCandumpLine(%q):\ngot:  %+v\nwant: %+v", tt.line, got, tt.want)
+		}
+		if want := time.Unix(1763111016, 739000000); !frame.Time.Equal(want) {
+			t.Errorf("ParseCandumpLine(%q) time = %v, want %v", tt.line, frame.Time, want)
+		}
+	}
+}
+
+func TestParseCandumpLineInvalid(t *testing.T) {
+	for _, line := range []string{
+		"",
+		"can0 123#01",
+		"(1763111016.739000) can0 123",
+		"(1763111016.739000) can0 123#012",
+		"(1763111016.739000) can0 123#GG",
+		"(1763111016.739000) can0 123##",
+		"(1763111016) can0 123#01",
+	} {
+		if _, err := ParseCandumpLine(line); err == nil {
+			t.Errorf("ParseCandumpLine(%q) succeeded, want error", line)
+		}
+	}
+}
+
+func TestCandumpWriterRoundTrip(t *testing.T) {
+	const input = `(1763111016.739000) can0 1CC#0300000000000000
+(1763111016.741500) can1 18FF0102#AABB
+(1763111016.742000) can0 123#R4
+(1763111016.743000) can0 208##0010203040506070809000000
+(1763111016.744000) can0 20000004#0000800000000000
+`
+	frames, err := ReadAllFrames(NewCandumpReader(strings.NewReader(input)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	w := NewCandumpWriter(&buf)
+	for _, frame := range frames {
+		if err := w.WriteFrame(frame); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != input {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), input)
+	}
+	if want := 5 * time.Millisecond; frames[len(frames)-1].Relative != want {
+		t.Errorf("relative = %v, want %v", frames[len(frames)-1].Relative, want)
+	}
+}
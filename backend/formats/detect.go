@@ -0,0 +1,127 @@
+package formats
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// sniffSize 内容识别时读取的文件开头长度
+const sniffSize = 8 * 1024
+
+// sniffLines 文本格式识别时最多检查的非空行数
+const sniffLines = 50
+
+// ErrUnknownFormat 文件内容不属于任何支持的格式
+var ErrUnknownFormat = errors.New("unrecognized file content")
+
+// FormatNames 各格式的显示名称
+var FormatNames = map[string]string{
+	FormatSnifferCSV: "sniffer CSV",
+	FormatCandump:    "candump log",
+	FormatASC:        "Vector ASC",
+	FormatBLF:        "Vector BLF",
+	FormatTRC:        "PCAN-View TRC",
+	FormatPcap:       "pcap/pcapng",
+}
+
+var (
+	// trcRecordPattern TRC 1.x 消息行: "     1)      1059.9  Rx ..."
+	trcRecordPattern = regexp.MustCompile(`^\d+\)\s+\d+(\.\d+)?\s`)
+	// ascRecordPattern ASC 消息行: "   0.001234 1  2CF  Rx   d 8 ..."
+	ascRecordPattern = regexp.MustCompile(`^\d+\.\d+\s+(\d+|CANFD)\s+\S+\s+\S+`)
+	// snifferTypes 嗅探器CSV第一列的消息类型
+	snifferTypes = map[string]bool{"publish": true, "receive": true, "receive_request": true}
+)
+
+// DetectFormat 根据文件开头的内容识别格式，二进制格式按magic识别，文本格式按头部行和记录行识别
+// 无法识别时返回空字符串
+func DetectFormat(head []byte) string {
+	if len(head) >= 4 {
+		if bytes.Equal(head[:4], blfFileSignature) {
+			return FormatBLF
+		}
+		magicBE := binary.BigEndian.Uint32(head)
+		magicLE := binary.LittleEndian.Uint32(head)
+		switch {
+		case magicBE == pcapngBlockSHB,
+			magicBE == pcapMagicMicroseconds, magicLE == pcapMagicMicroseconds,
+			magicBE == pcapMagicNanoseconds, magicLE == pcapMagicNanoseconds:
+			return FormatPcap
+		}
+	}
+
+	text := string(bytes.TrimPrefix(head, []byte("\xEF\xBB\xBF")))
+	if strings.ContainsRune(text, 0) {
+		return ""
+	}
+
+	lines := strings.Split(text, "\n")
+	// 最后一行可能被截断
+	if len(head) >= sniffSize && len(lines) > 1 {
+		lines = lines[:len(lines)-1]
+	}
+	checked := 0
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if checked++; checked > sniffLines {
+			break
+		}
+		if format := detectLine(line); format != "" {
+			return format
+		}
+	}
+	return ""
+}
+
+// detectLine 判断单行文本属于哪种格式
+func detectLine(line string) string {
+	lower := strings.ToLower(line)
+	switch {
+	case strings.HasPrefix(line, ";$FILEVERSION"), strings.HasPrefix(line, ";$STARTTIME"),
+		strings.HasPrefix(line, ";") && strings.Contains(lower, "pcan"),
+		trcRecordPattern.MatchString(line):
+		return FormatTRC
+	case strings.HasPrefix(lower, "date "), strings.HasPrefix(lower, "base hex"), strings.HasPrefix(lower, "base dec"),
+		strings.HasPrefix(lower, "begin triggerblock"), ascRecordPattern.MatchString(line):
+		return FormatASC
+	case candumpLinePattern.MatchString(line):
+		return FormatCandump
+	}
+
+	fields := strings.Split(line, ",")
+	if len(fields) >= 6 {
+		first := strings.ToLower(strings.TrimSpace(fields[0]))
+		if snifferTypes[first] || (first == "type" && strings.EqualFold(strings.TrimSpace(fields[5]), "Buffer")) {
+			return FormatSnifferCSV
+		}
+	}
+	return ""
+}
+
+// DetectReader 读取r开头的内容识别格式，返回的Reader仍从文件开头读取
+func DetectReader(r io.Reader) (string, io.Reader, error) {
+	head := make([]byte, sniffSize)
+	n, err := io.ReadFull(r, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", nil, err
+	}
+	head = head[:n]
+	return DetectFormat(head), io.MultiReader(bytes.NewReader(head), r), nil
+}
+
+// SupportedFormatNames 返回支持的格式名称列表（用于错误提示）
+func SupportedFormatNames() string {
+	order := []string{FormatSnifferCSV, FormatCandump, FormatASC, FormatTRC, FormatBLF, FormatPcap}
+	names := make([]string, len(order))
+	for i, format := range order {
+		names[i] = FormatNames[format]
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,87 @@
+package formats
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestDetectFormat(t *testing.T) {
+	pcapLE := binary.LittleEndian.AppendUint32(nil, pcapMagicMicroseconds)
+	pcapBE := binary.BigEndian.AppendUint32(nil, pcapMagicNanoseconds)
+	pcapng := binary.BigEndian.AppendUint32(nil, pcapngBlockSHB)
+
+	tests := []struct {
+		name string
+		head string
+		want string
+	}{
+		{"blf", "LOGG\x90\x00\x00\x00", FormatBLF},
+		{"pcap little-endian", string(pcapLE) + "\x02\x00\x04\x00", FormatPcap},
+		{"pcap big-endian nanoseconds", string(pcapBE), FormatPcap},
+		{"pcapng", string(pcapng) + "\x1c\x00\x00\x00", FormatPcap},
+		{"asc header", "date Fri Nov 14 05:03:36.739 pm 2025\nbase hex  timestamps absolute\n", FormatASC},
+		{"asc records only", "   0.001000 1  1CC             Rx   d 8 03 00 00 00 00 00 00 00\n", FormatASC},
+		{"asc canfd record", "   0.005000 CANFD 1 Rx 208 0 0 9 12 01 02\n", FormatASC},
+		{"trc 2.x header", ";$FILEVERSION=2.1\n;$STARTTIME=45975.7\n", FormatTRC},
+		{"trc 1.x pcan comment", ";##########\n;   PCAN-View v3\n", FormatTRC},
+		{"trc 1.x record", "     1)      1059.9  Rx         0300  8  00 00 00 00 04 00 00 00\n", FormatTRC},
+		{"candump", "(1763111016.739000) can0 1CC#0300000000000000\n", FormatCandump},
+		{"candump fd with direction", "(1763111016.739000) vcan0 208##1AABB T\n", FormatCandump},
+		{"sniffer header", "Type,Source,Target,Name,Time,Buffer\npublish,XRTechMgr,N/A,FROM_JEDI_MSG,2025-11-24 17:03:48.853.257,string=2cf:8:[11 80 f2 aa 47 c8 16 00]\n", FormatSnifferCSV},
+		{"sniffer without header", "receive,XRTechMgr,N/A,X,2025-11-24 17:03:48.853.257,string=ushort=1\n", FormatSnifferCSV},
+		{"sniffer with bom", "\xEF\xBB\xBFType,Source,Target,Name,Time,Buffer\n", FormatSnifferCSV},
+		{"leading blank lines", "\n\n  \n(1763111016.739000) can0 1CC#03\n", FormatCandump},
+		{"other csv", "a,b,c,d,e,f\n1,2,3,4,5,6\n", ""},
+		{"binary", "\x00\x01\x02\x03 data", ""},
+		{"plain text", "hello world\n", ""},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectFormat([]byte(tt.head)); got != tt.want {
+				t.Errorf("DetectFormat(%q) = %q, want %q", tt.head, got, tt.want)
+			}
+		})
+	}
+}
+
+// 只检查前sniffLines个非空行
+func TestDetectFormatLineLimit(t *testing.T) {
+	noise := strings.Repeat("noise\n", sniffLines)
+	if got := DetectFormat([]byte(noise + "(1763111016.739000) can0 1CC#03\n")); got != "" {
+		t.Errorf("got %q after %d unrecognized lines, want \"\"", got, sniffLines)
+	}
+	if got := DetectFormat([]byte(noise[len("noise\n"):] + "(1763111016.739000) can0 1CC#03\n")); got != FormatCandump {
+		t.Errorf("got %q, want %q", got, FormatCandump)
+	}
+}
+
+// 读满sniffSize时最后一行可能被截断，不参与识别
+func TestDetectFormatTruncatedLastLine(t *testing.T) {
+	head := strings.Repeat(" ", sniffSize-len("(1763111016.739000) can0 1CC#03")-1) + "\n(1763111016.739000) can0 1CC#03"
+	if got := DetectFormat([]byte(head)); got != "" {
+		t.Errorf("got %q, want \"\"", got)
+	}
+}
+
+func TestDetectReader(t *testing.T) {
+	content := "(1763111016.739000) can0 1CC#03\n" + strings.Repeat("(1763111016.741000) can0 208#0102\n", 500)
+	format, r, err := DetectReader(strings.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format != FormatCandump {
+		t.Errorf("format = %q, want %q", format, FormatCandump)
+	}
+	// 返回的Reader仍从文件开头读取全部内容
+	all, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(all, []byte(content)) {
+		t.Errorf("reader returned %d bytes, want %d", len(all), len(content))
+	}
+}
@@ -0,0 +1,49 @@
+package formats
+
+import (
+	"csv-parser/models"
+	"encoding/hex"
+	"testing"
+	"time"
+)
+
+// frameSummary 测试中比较的帧字段，data为小写十六进制
+type frameSummary struct {
+	id         uint32
+	extended   bool
+	remote     bool
+	errorFrame bool
+	fd         bool
+	dlc        int
+	data       string
+	channel    string
+	direction  string
+	relative   time.Duration
+}
+
+func summarize(frame *models.LogFrame) frameSummary {
+	return frameSummary{
+		id:         frame.ID,
+		extended:   frame.Extended,
+		remote:     frame.Remote,
+		errorFrame: frame.ErrorFrame,
+		fd:         frame.FD,
+		dlc:        frame.DLC,
+		data:       hex.EncodeToString(frame.Data),
+		channel:    frame.Channel,
+		direction:  frame.Direction,
+		relative:   frame.Relative,
+	}
+}
+
+func compareFrames(t *testing.T, got, want []frameSummary) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d frames, want %d\ngot:  %+v\nwant: %+v", len(got), len(want), got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("frame %d:\ngot:  %+v\nwant: %+v", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,318 @@
+package formats
+
+import (
+	"csv-parser/models"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 上传文件格式
+const (
+	FormatSnifferCSV = "sniffer_csv" // 嗅探器导出的CSV
+	FormatCandump    = "candump"     // candump -l 日志
+	FormatASC        = "asc"         // Vector ASC文本日志
+	FormatTRC        = "trc"         // PEAK PCAN-View TRC日志
+	FormatBLF        = "blf"         // Vector BLF二进制日志
+	FormatPcap       = "pcap"        // pcap/pcapng抓包（SocketCAN链路类型）
+)
+
+// FrameReader 逐帧读取日志文件，读完时返回 io.EOF
+type FrameReader interface {
+	Next() (*models.LogFrame, error)
+}
+
+// NewFrameReader 按格式创建帧读取器，嗅探器CSV不经过此接口
+func NewFrameReader(format string, r io.Reader) (FrameReader, error) {
+	switch format {
+	case FormatCandump:
+		return NewCandumpReader(r), nil
+	case FormatASC:
+		return NewASCReader(r), nil
+	case FormatTRC:
+		return NewTRCReader(r), nil
+	case FormatBLF:
+		return NewBLFReader(r), nil
+	case FormatPcap:
+		return NewPcapReader(r), nil
+	}
+	return nil, fmt.Errorf("不支持的日志格式: %s", format)
+}
+
+// FrameWriter 逐帧写出日志文件，写完后必须调用Close
+type FrameWriter interface {
+	WriteFrame(frame *models.LogFrame) error
+	Close() error
+}
+
+// WritableFormats 支持导出的日志格式及文件扩展名
+var WritableFormats = map[string]string{
+	FormatCandump: ".log",
+	FormatASC:     ".asc",
+	FormatTRC:     ".trc",
+}
+
+// NewFrameWriter 按格式创建帧写出器，start为测量开始时间（写入文件头）
+func NewFrameWriter(format string, w io.Writer, start time.Time) (FrameWriter, error) {
+	switch format {
+	case FormatCandump:
+		return NewCandumpWriter(w), nil
+	case FormatASC:
+		return NewASCWriter(w, start)
+	case FormatTRC:
+		return NewTRCWriter(w, start)
+	}
+	return nil, fmt.Errorf("不支持导出的日志格式: %s", format)
+}
+
+// FormatByExtension 根据扩展名判断文件格式，无法识别时返回空字符串
+func FormatByExtension(filename string) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return FormatSnifferCSV
+	case ".log":
+		return FormatCandump
+	case ".asc":
+		return FormatASC
+	case ".trc":
+		return FormatTRC
+	case ".blf":
+		return FormatBLF
+	case ".pcap", ".pcapng":
+		return FormatPcap
+	}
+	return ""
+}
+
+// ReadAllFrames 读取全部帧
+func ReadAllFrames(fr FrameReader) ([]*models.LogFrame, error) {
+	var frames []*models.LogFrame
+	for {
+		frame, err := fr.Next()
+		if err == io.EOF {
+			return frames, nil
+		}
+		if err != nil {
+			return frames, err
+		}
+		frames = append(frames, frame)
+	}
+}
+
+// LogHeaders 日志帧转换为嗅探器CSV行时的表头
+// 前6列与嗅探器CSV一致，因此可以复用同一套行过滤、ID定义和解码流程
+var LogHeaders = []string{"Type", "Source", "Target", "Name", "Time", "Buffer", "Channel", "Direction", "Flags", "RelTime"}
+
+// LogFrameRow 将日志帧转换为嗅探器CSV行
+// Type按方向映射（Tx为publish，其余为receive），Name为通道名称
+func LogFrameRow(frame *models.LogFrame) []string {
+	msgType := "receive"
+	if strings.EqualFold(frame.Direction, "Tx") {
+		msgType = "publish"
+	}
+
+	return []string{
+		msgType,
+		"N/A",
+		"N/A",
+		frame.Channel,
+		FormatTime(frame.Time),
+		FormatSnifferBuffer(&frame.CANFrame),
+		frame.Channel,
+		frame.Direction,
+		FrameFlags(&frame.CANFrame),
+		fmt.Sprintf("%.6f", frame.Relative.Seconds()),
+	}
+}
+
+// FrameFlags 返回帧标志，如 "EFF RTR"
+func FrameFlags(frame *models.CANFrame) string {
+	var flags []string
+	if frame.Extended {
+		flags = append(flags, "EFF")
+	}
+	if frame.Remote {
+		flags = append(flags, "RTR")
+	}
+	if frame.ErrorFrame {
+		flags = append(flags, "ERR")
+	}
+	if frame.FD {
+		flags = append(flags, "FD")
+	}
+	return strings.Join(flags, " ")
+}
+
+// FormatTime 按嗅探器CSV的时间格式输出：2025-11-14 17:03:36.739.127（毫秒.微秒）
+func FormatTime(t time.Time) string {
+	us := t.Nanosecond() / 1000
+	return fmt.Sprintf("%s.%03d.%03d", t.Format("2006-01-02 15:04:05"), us/1000, us%1000)
+}
+
+// ParseTime 解析嗅探器CSV的时间格式（FormatTime的逆操作），也接受普通的小数秒格式
+func ParseTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	// 2025-11-14 17:03:36.739.127：毫秒和微秒之间的点去掉后按小数秒解析
+	if len(s) == len("2006-01-02 15:04:05.000.000") && s[19] == '.' && s[23] == '.' {
+		s = s[:23] + s[24:]
+	}
+	return time.ParseInLocation("2006-01-02 15:04:05.999999999", s, time.Local)
+}
+
+// snifferTimeLayout 嗅探器时间格式各字段的位置，数字位为0，分隔符为原字符
+const snifferTimeLayout = "0000-00-00 00:00:00.000.000"
+
+// ParseTimestamp 将Time列解析为Unix纳秒时间戳
+// 嗅探器格式按固定位置直接读取数字（每行都要解析，比time.Parse快得多），其他格式交给ParseTime
+func ParseTimestamp(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	if len(s) == len(snifferTimeLayout) {
+		if t, ok := parseSnifferTime(s); ok {
+			return t.UnixNano(), nil
+		}
+	}
+	t, err := ParseTime(s)
+	if err != nil {
+		return 0, err
+	}
+	return t.UnixNano(), nil
+}
+
+// parseSnifferTime 按snifferTimeLayout解析，日期或时间超出范围时返回false
+func parseSnifferTime(s string) (time.Time, bool) {
+	for i := 0; i < len(s); i++ {
+		if snifferTimeLayout[i] == '0' {
+			if s[i] < '0' || s[i] > '9' {
+				return time.Time{}, false
+			}
+		} else if s[i] != snifferTimeLayout[i] {
+			return time.Time{}, false
+		}
+	}
+	num := func(start, end int) int {
+		n := 0
+		for _, c := range s[start:end] {
+			n = n*10 + int(c-'0')
+		}
+		return n
+	}
+	year, month, day := num(0, 4), num(5, 7), num(8, 10)
+	hour, minute, sec := num(11, 13), num(14, 16), num(17, 19)
+	us := num(20, 23)*1000 + num(24, 27)
+	if month < 1 || month > 12 || day < 1 || hour > 23 || minute > 59 || sec > 59 {
+		return time.Time{}, false
+	}
+	t := time.Date(year, time.Month(month), day, hour, minute, sec, us*1000, time.Local)
+	// time.Date会把2月30日之类的日期顺延到下个月
+	if t.Day() != day {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// ApplyFlags 根据Flags列（FrameFlags的输出）补充Buffer字段无法表示的帧标志
+func ApplyFlags(frame *models.CANFrame, flags string) {
+	for _, flag := range strings.Fields(flags) {
+		switch flag {
+		case "EFF":
+			frame.Extended = true
+		case "RTR":
+			frame.Remote = true
+		case "ERR":
+			frame.ErrorFrame = true
+		case "FD":
+			frame.FD = true
+		}
+	}
+}
+
+// noDateBase 日志中没有记录起始日期时，相对时间戳换算为绝对时间所用的基准
+var noDateBase = time.Date(1970, 1, 1, 0, 0, 0, 0, time.Local)
+
+// fdLengths CAN FD的DLC编码（0-15）对应的数据长度
+var fdLengths = []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 12, 16, 20, 24, 32, 48, 64}
+
+// dlcToLength 将DLC编码转换为字节数，经典CAN的DLC 9-15 按8字节处理
+func dlcToLength(dlc int, fd bool) int {
+	if dlc < 0 || dlc > 15 {
+		return dlc
+	}
+	if !fd && dlc > 8 {
+		return 8
+	}
+	return fdLengths[dlc]
+}
+
+// lengthToDLC 将数据字节数转换为DLC编码（CAN FD的长度向上取整到合法值）
+func lengthToDLC(length int) int {
+	for dlc, n := range fdLengths {
+		if n >= length {
+			return dlc
+		}
+	}
+	return len(fdLengths) - 1
+}
+
+// channelNumber 将通道名称转换为从1开始的通道号：数字直接使用，can0/vcan1 等接口名按序号加1，其余为1
+func channelNumber(channel string) int {
+	if n, err := strconv.Atoi(channel); err == nil && n > 0 {
+		return n
+	}
+	digits := strings.TrimLeftFunc(channel, func(r rune) bool { return r < '0' || r > '9' })
+	if n, err := strconv.Atoi(digits); err == nil && digits != "" {
+		return n + 1
+	}
+	return 1
+}
+
+// writeAsFD 判断帧是否按CAN FD写出：经典CAN最多8个数据字节，
+// 更长的数据（如没有Flags列的嗅探器Buffer）只能作为FD帧写出
+func writeAsFD(frame *models.LogFrame) bool {
+	return frame.FD || (!frame.Remote && len(payload(frame)) > 8)
+}
+
+// payload 返回按DLC截断后的数据（嗅探器Buffer中总是列出8个字节）
+func payload(frame *models.LogFrame) []byte {
+	if frame.DLC >= 0 && frame.DLC < len(frame.Data) {
+		return frame.Data[:frame.DLC]
+	}
+	return frame.Data
+}
+
+// parseSeconds 将 "12.345678" 形式的秒数转换为时间间隔（精确到纳秒）
+func parseSeconds(s string) (time.Duration, error) {
+	whole, fraction := s, ""
+	if idx := strings.Index(s, "."); idx >= 0 {
+		whole, fraction = s[:idx], s[idx+1:]
+	}
+	sec, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("无效的时间戳: %s", s)
+	}
+	if len(fraction) > 9 {
+		fraction = fraction[:9]
+	}
+	var nsec int64
+	if fraction != "" {
+		if nsec, err = strconv.ParseInt(fraction+strings.Repeat("0", 9-len(fraction)), 10, 64); err != nil {
+			return 0, fmt.Errorf("无效的时间戳: %s", s)
+		}
+	}
+	return time.Duration(sec)*time.Second + time.Duration(nsec), nil
+}
+
+// parseHexBytes 解析十六进制（或十进制）数据字节列表
+func parseHexBytes(tokens []string, base int) ([]byte, error) {
+	data := make([]byte, len(tokens))
+	for i, tok := range tokens {
+		b, err := strconv.ParseUint(tok, base, 8)
+		if err != nil {
+			return nil, fmt.Errorf("无效的数据字节: %s", tok)
+		}
+		data[i] = byte(b)
+	}
+	return data, nil
+}
@@ -0,0 +1,151 @@
+package formats
+
+import (
+	"bytes"
+	"csv-parser/models"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTimestamp(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{"2025-11-14 17:03:36.739.127", time.Date(2025, 11, 14, 17, 3, 36, 739127000, time.Local)},
+		{"  2025-11-14 17:03:36.000.001 ", time.Date(2025, 11, 14, 17, 3, 36, 1000, time.Local)},
+		{"2024-02-29 23:59:59.999.999", time.Date(2024, 2, 29, 23, 59, 59, 999999000, time.Local)},
+		// 非嗅探器格式交给ParseTime
+		{"2025-11-14 17:03:36", time.Date(2025, 11, 14, 17, 3, 36, 0, time.Local)},
+		{"2025-11-14 17:03:36.5", time.Date(2025, 11, 14, 17, 3, 36, 500000000, time.Local)},
+		{"2025-11-14 17:03:36.123456789", time.Date(2025, 11, 14, 17, 3, 36, 123456789, time.Local)},
+	}
+	for _, tt := range tests {
+		got, err := ParseTimestamp(tt.in)
+		if err != nil {
+			t.Errorf("ParseTimestamp(%q): %v", tt.in, err)
+			continue
+		}
+		if got != tt.want.UnixNano() {
+			t.Errorf("ParseTimestamp(%q) = %v, want %v", tt.in, time.Unix(0, got), tt.want)
+		}
+	}
+}
+
+func TestParseTimestampInvalid(t *testing.T) {
+	for _, in := range []string{
+		"",
+		"N/A",
+		"2025-02-30 00:00:00.000.000",
+		"2025-13-01 00:00:00.000.000",
+		"2025-11-14 24:00:00.000.000",
+		"2025-11-14 17:03:36.7x9.127",
+		"2025/11/14 17:03:36.739.127",
+		"1763111016.739",
+	} {
+		if got, err := ParseTimestamp(in); err == nil {
+			t.Errorf("ParseTimestamp(%q) = %v, want error", in, time.Unix(0, got))
+		}
+	}
+}
+
+// 快速路径与ParseTime的结果一致，FormatTime的输出可以解析回来
+func TestParseTimestampMatchesParseTime(t *testing.T) {
+	for _, tm := range []time.Time{
+		time.Date(2025, 11, 14, 17, 3, 36, 739127000, time.Local),
+		time.Date(1999, 12, 31, 0, 0, 0, 0, time.Local),
+		time.Date(2030, 6, 1, 8, 9, 10, 1000, time.Local),
+	} {
+		s := FormatTime(tm)
+		fast, err := ParseTimestamp(s)
+		if err != nil {
+			t.Fatalf("ParseTimestamp(%q): %v", s, err)
+		}
+		slow, err := ParseTime(s)
+		if err != nil {
+			t.Fatalf("ParseTime(%q): %v", s, err)
+		}
+		if fast != slow.UnixNano() || fast != tm.UnixNano() {
+			t.Errorf("%q: ParseTimestamp = %d, ParseTime = %d, want %d", s, fast, slow.UnixNano(), tm.UnixNano())
+		}
+	}
+}
+
+// 没有FD标志但超过8字节的帧（如没有Flags列的嗅探器Buffer）按FD帧写出，经典帧保持不变
+func TestWritersLongClassicFrameAsFD(t *testing.T) {
+	start := time.Date(2025, 11, 14, 17, 3, 36, 0, time.Local)
+	long := []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}
+	frames := []*models.LogFrame{
+		{CANFrame: models.CANFrame{ID: 0x208, DLC: 12, Data: long}, Time: start, Channel: "can0", Direction: "Rx"},
+		{CANFrame: models.CANFrame{ID: 0x1CC, DLC: 8, Data: long[:8]}, Time: start.Add(time.Millisecond), Relative: time.Millisecond, Channel: "can0", Direction: "Rx"},
+	}
+
+	tests := []struct {
+		name   string
+		writer func(io.Writer) FrameWriter
+		reader func(io.Reader) FrameReader
+		line   string // 长帧写出的内容
+	}{
+		{
+			name:   "candump",
+			writer: func(w io.Writer) FrameWriter { return NewCandumpWriter(w) },
+			reader: func(r io.Reader) FrameReader { return NewCandumpReader(r) },
+			line:   "208##0010203040506070809",
+		},
+		{
+			name: "asc",
+			writer: func(w io.Writer) FrameWriter {
+				a, err := NewASCWriter(w, start)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return a
+			},
+			reader: func(r io.Reader) FrameReader { return NewASCReader(r) },
+			line:   "CANFD   1 Rx      208 0 0 9 12 01 02",
+		},
+		{
+			name: "trc",
+			writer: func(w io.Writer) FrameWriter {
+				tw, err := NewTRCWriter(w, start)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return tw
+			},
+			reader: func(r io.Reader) FrameReader { return NewTRCReader(r) },
+			line:   "FD  1     0208 Rx -  9 01 02",
+		},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		w := tt.writer(&buf)
+		for _, frame := range frames {
+			if err := w.WriteFrame(frame); err != nil {
+				t.Fatalf("%s: WriteFrame: %v", tt.name, err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(buf.String(), tt.line) {
+			t.Errorf("%s: output does not contain %q:\n%s", tt.name, tt.line, buf.String())
+		}
+
+		got, err := ReadAllFrames(tt.reader(&buf))
+		if err != nil {
+			t.Fatalf("%s: read back: %v", tt.name, err)
+		}
+		if len(got) != 2 {
+			t.Fatalf("%s: read back %d frames, want 2", tt.name, len(got))
+		}
+		if f := got[0]; !f.FD || f.DLC != 12 || !bytes.Equal(f.Data, long) {
+			t.Errorf("%s: long frame = %+v, want FD with 12 bytes", tt.name, f.CANFrame)
+		}
+		if f := got[1]; f.FD || f.DLC != 8 || !bytes.Equal(f.Data, long[:8]) {
+			t.Errorf("%s: classic frame = %+v, want 8 bytes without FD", tt.name, f.CANFrame)
+		}
+	}
+}
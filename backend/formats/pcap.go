@@ -0,0 +1,419 @@
+package formats
+
+import (
+	"bufio"
+	"csv-parser/models"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// pcap/pcapng 中与CAN相关的链路类型
+const (
+	linkTypeLinuxSLL       = 113
+	linkTypeCANSocketCAN   = 227
+	linkTypeLinuxSLL2      = 276
+	ethPCAN                = 0x000C // SLL协议字段: 经典CAN
+	ethPCANFD              = 0x000D // SLL协议字段: CAN FD
+	sllPacketOutgoing      = 4
+	socketCANHeaderSize    = 8
+	socketCANFDMTU         = 72
+	socketCANFDFlagFDF     = 0x04
+	pcapngBlockSHB         = 0x0A0D0D0A
+	pcapngBlockIDB         = 1
+	pcapngBlockPacket      = 2 // 已废弃的Packet Block
+	pcapngBlockSimple      = 3
+	pcapngBlockEPB         = 6
+	pcapngByteOrderMagic   = 0x1A2B3C4D
+	pcapngOptionEnd        = 0
+	pcapngOptionIfName     = 2
+	pcapngOptionIfTsresol  = 9
+	pcapMagicMicroseconds  = 0xA1B2C3D4
+	pcapMagicNanoseconds   = 0xA1B23C4D
+	pcapngDefaultUnitsPerS = 1000000
+)
+
+// pcapInterface pcapng接口描述（IDB）
+type pcapInterface struct {
+	linkType    uint16
+	unitsPerSec uint64
+	name        string
+}
+
+// PcapReader 读取链路类型为 LINKTYPE_CAN_SOCKETCAN 或 Linux cooked capture（SLL/SLL2）封装SocketCAN的
+// pcap和pcapng抓包文件，自动识别两种文件格式和字节序
+type PcapReader struct {
+	r          *bufio.Reader
+	headerRead bool
+	ng         bool
+	order      binary.ByteOrder
+	interfaces []pcapInterface // pcap文件只有一个接口
+	start      time.Time
+	started    bool
+}
+
+// NewPcapReader 创建pcap/pcapng读取器
+func NewPcapReader(r io.Reader) *PcapReader {
+	return &PcapReader{r: bufio.NewReaderSize(r, 256*1024)}
+}
+
+// Next 读取下一帧，非CAN链路类型和非CAN协议的报文会被跳过
+func (p *PcapReader) Next() (*models.LogFrame, error) {
+	if !p.headerRead {
+		if err := p.readFileHeader(); err != nil {
+			return nil, err
+		}
+		p.headerRead = true
+	}
+
+	for {
+		var iface *pcapInterface
+		var ts time.Time
+		var data []byte
+		var err error
+		if p.ng {
+			iface, ts, data, err = p.nextNGPacket()
+		} else {
+			iface, ts, data, err = p.nextPcapPacket()
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		frame := parsePcapCANPacket(iface.linkType, data)
+		if frame == nil {
+			continue
+		}
+		frame.Channel = iface.name
+		if !p.started {
+			p.start = ts
+			p.started = true
+		}
+		frame.Time = ts
+		frame.Relative = ts.Sub(p.start)
+		return frame, nil
+	}
+}
+
+// readFileHeader 按magic区分pcap和pcapng文件
+func (p *PcapReader) readFileHeader() error {
+	magic, err := p.r.Peek(4)
+	if err != nil {
+		return fmt.Errorf("不是pcap/pcapng文件")
+	}
+
+	if binary.BigEndian.Uint32(magic) == pcapngBlockSHB {
+		// pcapng 从SHB开始，字节序在读取SHB时确定
+		p.ng = true
+		return nil
+	}
+
+	header := make([]byte, 24)
+	if _, err := io.ReadFull(p.r, header); err != nil {
+		return fmt.Errorf("不是pcap/pcapng文件")
+	}
+	unitsPerSec := uint64(0)
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		switch order.Uint32(header) {
+		case pcapMagicMicroseconds:
+			p.order, unitsPerSec = order, 1000000
+		case pcapMagicNanoseconds:
+			p.order, unitsPerSec = order, 1000000000
+		}
+		if p.order != nil {
+			break
+		}
+	}
+	if p.order == nil {
+		return fmt.Errorf("不是pcap/pcapng文件")
+	}
+
+	linkType := uint16(p.order.Uint32(header[20:]) & 0xFFFF)
+	if !isCANLinkType(linkType) {
+		return fmt.Errorf("不支持的链路类型: %d（需要SocketCAN或Linux cooked capture）", linkType)
+	}
+	p.interfaces = []pcapInterface{{linkType: linkType, unitsPerSec: unitsPerSec, name: "0"}}
+	return nil
+}
+
+// nextPcapPacket 读取pcap的下一条记录
+func (p *PcapReader) nextPcapPacket() (*pcapInterface, time.Time, []byte, error) {
+	record := make([]byte, 16)
+	if _, err := io.ReadFull(p.r, record); err != nil {
+		return nil, time.Time{}, nil, io.EOF
+	}
+	sec := p.order.Uint32(record)
+	frac := p.order.Uint32(record[4:])
+	capLen := p.order.Uint32(record[8:])
+	if capLen > 256*1024 {
+		return nil, time.Time{}, nil, fmt.Errorf("pcap记录长度无效: %d", capLen)
+	}
+	data := make([]byte, capLen)
+	if _, err := io.ReadFull(p.r, data); err != nil {
+		// 抓包被截断时保留已读出的帧
+		return nil, time.Time{}, nil, io.EOF
+	}
+
+	iface := &p.interfaces[0]
+	nsec := int64(frac)
+	if iface.unitsPerSec == 1000000 {
+		nsec *= 1000
+	}
+	return iface, time.Unix(int64(sec), nsec), data, nil
+}
+
+// nextNGPacket 读取pcapng的下一个报文块，同时处理SHB和IDB
+func (p *PcapReader) nextNGPacket() (*pcapInterface, time.Time, []byte, error) {
+	for {
+		blockType, body, err := p.readNGBlock()
+		if err != nil {
+			return nil, time.Time{}, nil, err
+		}
+
+		switch blockType {
+		case pcapngBlockIDB:
+			if len(body) < 8 {
+				continue
+			}
+			iface := pcapInterface{
+				linkType:    p.order.Uint16(body),
+				unitsPerSec: pcapngDefaultUnitsPerS,
+				name:        strconv.Itoa(len(p.interfaces)),
+			}
+			p.readIDBOptions(&iface, body[8:])
+			p.interfaces = append(p.interfaces, iface)
+
+		case pcapngBlockEPB, pcapngBlockPacket:
+			// EPB: interfaceID(4) tsHigh(4) tsLow(4) capLen(4) origLen(4) data
+			// Packet Block: interfaceID(2) drops(2) tsHigh(4) tsLow(4) capLen(4) origLen(4) data
+			if len(body) < 20 {
+				continue
+			}
+			var ifaceID int
+			if blockType == pcapngBlockEPB {
+				ifaceID = int(p.order.Uint32(body))
+			} else {
+				ifaceID = int(p.order.Uint16(body))
+			}
+			if ifaceID >= len(p.interfaces) {
+				continue
+			}
+			iface := &p.interfaces[ifaceID]
+			ts := uint64(p.order.Uint32(body[4:]))<<32 | uint64(p.order.Uint32(body[8:]))
+			capLen := int(p.order.Uint32(body[12:]))
+			if 20+capLen > len(body) {
+				capLen = len(body) - 20
+			}
+			return iface, iface.timestamp(ts), body[20 : 20+capLen], nil
+
+		case pcapngBlockSimple:
+			// SPB不带时间戳和接口号，固定属于第一个接口
+			if len(body) < 4 || len(p.interfaces) == 0 {
+				continue
+			}
+			capLen := int(p.order.Uint32(body))
+			if 4+capLen > len(body) {
+				capLen = len(body) - 4
+			}
+			return &p.interfaces[0], p.start, body[4 : 4+capLen], nil
+		}
+	}
+}
+
+// readNGBlock 读取一个pcapng块，返回块类型和块体（不含首尾的类型与长度字段）
+func (p *PcapReader) readNGBlock() (uint32, []byte, error) {
+	head := make([]byte, 8)
+	if _, err := io.ReadFull(p.r, head); err != nil {
+		return 0, nil, io.EOF
+	}
+
+	if binary.BigEndian.Uint32(head) == pcapngBlockSHB {
+		// 新的Section，按byte-order magic重新确定字节序，接口列表清空
+		bom := make([]byte, 4)
+		if _, err := io.ReadFull(p.r, bom); err != nil {
+			return 0, nil, io.EOF
+		}
+		switch {
+		case binary.LittleEndian.Uint32(bom) == pcapngByteOrderMagic:
+			p.order = binary.LittleEndian
+		case binary.BigEndian.Uint32(bom) == pcapngByteOrderMagic:
+			p.order = binary.BigEndian
+		default:
+			return 0, nil, fmt.Errorf("pcapng字节序标记无效")
+		}
+		p.interfaces = nil
+		length := p.order.Uint32(head[4:])
+		if length < 12 || length%4 != 0 {
+			return 0, nil, fmt.Errorf("pcapng块长度无效: %d", length)
+		}
+		if _, err := p.r.Discard(int(length) - 12); err != nil {
+			return 0, nil, io.EOF
+		}
+		return pcapngBlockSHB, nil, nil
+	}
+
+	if p.order == nil {
+		return 0, nil, fmt.Errorf("pcapng文件缺少Section Header Block")
+	}
+	blockType := p.order.Uint32(head)
+	length := p.order.Uint32(head[4:])
+	if length < 12 || length%4 != 0 || length > 16*1024*1024 {
+		return 0, nil, fmt.Errorf("pcapng块长度无效: %d", length)
+	}
+	block := make([]byte, length-8)
+	if _, err := io.ReadFull(p.r, block); err != nil {
+		return 0, nil, io.EOF
+	}
+	// 块末尾重复的长度字段不属于块体
+	return blockType, block[:len(block)-4], nil
+}
+
+// readIDBOptions 读取IDB中的 if_name 和 if_tsresol 选项
+func (p *PcapReader) readIDBOptions(iface *pcapInterface, options []byte) {
+	for len(options) >= 4 {
+		code := p.order.Uint16(options)
+		length := int(p.order.Uint16(options[2:]))
+		if code == pcapngOptionEnd || 4+length > len(options) {
+			return
+		}
+		value := options[4 : 4+length]
+
+		switch code {
+		case pcapngOptionIfName:
+			if length > 0 {
+				iface.name = string(value)
+			}
+		case pcapngOptionIfTsresol:
+			if length >= 1 {
+				// 最高位为0时单位为10^-n秒，为1时为2^-n秒
+				exp := uint(value[0] & 0x7F)
+				units := uint64(1)
+				for i := uint(0); i < exp && units < 1<<60; i++ {
+					if value[0]&0x80 != 0 {
+						units *= 2
+					} else {
+						units *= 10
+					}
+				}
+				iface.unitsPerSec = units
+			}
+		}
+		options = options[4+(length+3)/4*4:]
+	}
+}
+
+// timestamp 按接口的时间戳精度换算为时间
+func (iface *pcapInterface) timestamp(ts uint64) time.Time {
+	sec := ts / iface.unitsPerSec
+	rem := ts % iface.unitsPerSec
+	var nsec uint64
+	if iface.unitsPerSec <= 1000000000 {
+		nsec = rem * (1000000000 / iface.unitsPerSec)
+	} else {
+		nsec = rem / (iface.unitsPerSec / 1000000000)
+	}
+	return time.Unix(int64(sec), int64(nsec))
+}
+
+// isCANLinkType 判断链路类型是否可能包含SocketCAN帧
+func isCANLinkType(linkType uint16) bool {
+	switch linkType {
+	case linkTypeCANSocketCAN, linkTypeLinuxSLL, linkTypeLinuxSLL2:
+		return true
+	}
+	return false
+}
+
+// parsePcapCANPacket 去掉链路层头部并解析SocketCAN帧，非CAN报文返回nil
+func parsePcapCANPacket(linkType uint16, data []byte) *models.LogFrame {
+	switch linkType {
+	case linkTypeCANSocketCAN:
+		// libpcap写入的CAN ID为网络字节序
+		return parseSocketCANFrame(data, false, false)
+
+	case linkTypeLinuxSLL:
+		// packetType(2) ARPHRD(2) addrLen(2) addr(8) protocol(2)
+		if len(data) < 16 {
+			return nil
+		}
+		protocol := binary.BigEndian.Uint16(data[14:])
+		if protocol != ethPCAN && protocol != ethPCANFD {
+			return nil
+		}
+		frame := parseSocketCANFrame(data[16:], true, protocol == ethPCANFD)
+		if frame != nil && binary.BigEndian.Uint16(data) == sllPacketOutgoing {
+			frame.Direction = "Tx"
+		}
+		return frame
+
+	case linkTypeLinuxSLL2:
+		// protocol(2) reserved(2) ifindex(4) ARPHRD(2) packetType(1) addrLen(1) addr(8)
+		if len(data) < 20 {
+			return nil
+		}
+		protocol := binary.BigEndian.Uint16(data)
+		if protocol != ethPCAN && protocol != ethPCANFD {
+			return nil
+		}
+		frame := parseSocketCANFrame(data[20:], true, protocol == ethPCANFD)
+		if frame != nil && data[10] == sllPacketOutgoing {
+			frame.Direction = "Tx"
+		}
+		return frame
+	}
+	return nil
+}
+
+// parseSocketCANFrame 解析 struct can_frame / canfd_frame
+// Linux cooked capture中的CAN ID为抓包主机字节序，按小端解析，明显不合法时再按大端解析
+func parseSocketCANFrame(data []byte, hostOrder, fd bool) *models.LogFrame {
+	if len(data) < socketCANHeaderSize {
+		return nil
+	}
+	rawID := binary.BigEndian.Uint32(data)
+	if hostOrder {
+		rawID = binary.LittleEndian.Uint32(data)
+		if !plausibleSocketCANID(rawID) && plausibleSocketCANID(binary.BigEndian.Uint32(data)) {
+			rawID = binary.BigEndian.Uint32(data)
+		}
+	}
+
+	length := int(data[4])
+	fd = fd || data[5]&socketCANFDFlagFDF != 0 || len(data) >= socketCANFDMTU || length > 8
+
+	frame := &models.LogFrame{Direction: "Rx"}
+	frame.FD = fd
+	frame.Extended = rawID&canEFFFlag != 0
+	frame.Remote = rawID&canRTRFlag != 0 && !fd
+	frame.ErrorFrame = rawID&canERRFlag != 0
+	if frame.Extended || frame.ErrorFrame {
+		frame.ID = rawID & canEFFMask
+	} else {
+		frame.ID = rawID & canSFFMask
+	}
+	if frame.ErrorFrame {
+		frame.Extended = false
+	}
+
+	frame.DLC = length
+	if frame.Remote {
+		return frame
+	}
+	payload := data[socketCANHeaderSize:]
+	if length > len(payload) {
+		length = len(payload)
+	}
+	frame.Data = append([]byte(nil), payload[:length]...)
+	frame.DLC = length
+	return frame
+}
+
+// plausibleSocketCANID 标准帧的ID不会超过11位
+func plausibleSocketCANID(rawID uint32) bool {
+	if rawID&(canEFFFlag|canERRFlag) != 0 {
+		return true
+	}
+	return rawID&canEFFMask <= canSFFMask
+}
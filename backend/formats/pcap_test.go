@@ -0,0 +1,248 @@
+package formats
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+	"time"
+)
+
+// pcapRecord pcap文件中的一条记录，frac为微秒或纳秒（取决于magic）
+type pcapRecord struct {
+	sec, frac uint32
+	data      []byte
+}
+
+func pcapFile(order binary.AppendByteOrder, magic uint32, linkType uint32, records []pcapRecord) []byte {
+	buf := order.AppendUint32(nil, magic)
+	buf = order.AppendUint16(buf, 2)
+	buf = order.AppendUint16(buf, 4)
+	buf = order.AppendUint32(buf, 0)     // thiszone
+	buf = order.AppendUint32(buf, 0)     // sigfigs
+	buf = order.AppendUint32(buf, 65535) // snaplen
+	buf = order.AppendUint32(buf, linkType)
+	for _, r := range records {
+		buf = order.AppendUint32(buf, r.sec)
+		buf = order.AppendUint32(buf, r.frac)
+		buf = order.AppendUint32(buf, uint32(len(r.data)))
+		buf = order.AppendUint32(buf, uint32(len(r.data)))
+		buf = append(buf, r.data...)
+	}
+	return buf
+}
+
+// socketCANFrame struct can_frame / canfd_frame，ID按order写入
+func socketCANFrame(order binary.AppendByteOrder, rawID uint32, length byte, flags byte, data []byte, fd bool) []byte {
+	buf := order.AppendUint32(nil, rawID)
+	buf = append(buf, length, flags, 0, 0)
+	size := 8
+	if fd {
+		size = 64
+	}
+	payload := make([]byte, size)
+	copy(payload, data)
+	return append(buf, payload...)
+}
+
+// sllHeader Linux cooked capture v1 头部
+func sllHeader(packetType, protocol uint16) []byte {
+	buf := binary.BigEndian.AppendUint16(nil, packetType)
+	buf = binary.BigEndian.AppendUint16(buf, 280) // ARPHRD_CAN
+	buf = binary.BigEndian.AppendUint16(buf, 0)
+	buf = append(buf, make([]byte, 8)...)
+	return binary.BigEndian.AppendUint16(buf, protocol)
+}
+
+// sll2Header Linux cooked capture v2 头部
+func sll2Header(protocol uint16, packetType byte) []byte {
+	buf := binary.BigEndian.AppendUint16(nil, protocol)
+	buf = append(buf, 0, 0)
+	buf = binary.BigEndian.AppendUint32(buf, 3)
+	buf = binary.BigEndian.AppendUint16(buf, 280)
+	buf = append(buf, packetType, 0)
+	return append(buf, make([]byte, 8)...)
+}
+
+func ngBlock(order binary.AppendByteOrder, blockType uint32, body []byte) []byte {
+	for len(body)%4 != 0 {
+		body = append(body, 0)
+	}
+	length := uint32(12 + len(body))
+	buf := order.AppendUint32(nil, blockType)
+	buf = order.AppendUint32(buf, length)
+	buf = append(buf, body...)
+	return order.AppendUint32(buf, length)
+}
+
+func ngSHB(order binary.AppendByteOrder) []byte {
+	body := order.AppendUint32(nil, pcapngByteOrderMagic)
+	body = order.AppendUint16(body, 1)
+	body = order.AppendUint16(body, 0)
+	body = order.AppendUint64(body, ^uint64(0))
+	return ngBlock(order, pcapngBlockSHB, body)
+}
+
+// ngIDB 接口描述块，name为空时不写if_name，tsresol为0时不写if_tsresol
+func ngIDB(order binary.AppendByteOrder, linkType uint16, name string, tsresol byte) []byte {
+	body := order.AppendUint16(nil, linkType)
+	body = order.AppendUint16(body, 0)
+	body = order.AppendUint32(body, 0)
+	option := func(code uint16, value []byte) {
+		body = order.AppendUint16(body, code)
+		body = order.AppendUint16(body, uint16(len(value)))
+		body = append(body, value...)
+		for len(body)%4 != 0 {
+			body = append(body, 0)
+		}
+	}
+	if name != "" {
+		option(pcapngOptionIfName, []byte(name))
+	}
+	if tsresol != 0 {
+		option(pcapngOptionIfTsresol, []byte{tsresol})
+	}
+	option(pcapngOptionEnd, nil)
+	return ngBlock(order, pcapngBlockIDB, body)
+}
+
+func ngEPB(order binary.AppendByteOrder, iface uint32, ts uint64, data []byte) []byte {
+	body := order.AppendUint32(nil, iface)
+	body = order.AppendUint32(body, uint32(ts>>32))
+	body = order.AppendUint32(body, uint32(ts))
+	body = order.AppendUint32(body, uint32(len(data)))
+	body = order.AppendUint32(body, uint32(len(data)))
+	return ngBlock(order, pcapngBlockEPB, append(body, data...))
+}
+
+func readAllPcap(t *testing.T, data []byte) []frameSummary {
+	t.Helper()
+	frames, err := ReadAllFrames(NewPcapReader(bytes.NewReader(data)))
+	if err != nil {
+		t.Fatalf("ReadAllFrames: %v", err)
+	}
+	var out []frameSummary
+	for _, f := range frames {
+		out = append(out, summarize(f))
+	}
+	return out
+}
+
+func TestPcapReader(t *testing.T) {
+	le, be := binary.LittleEndian, binary.BigEndian
+	payload := []byte{0x10, 0x40, 0xFF, 0x37, 0x48, 0xC1, 0x0A, 0x00}
+
+	tests := []struct {
+		name string
+		file []byte
+		want []frameSummary
+	}{
+		{
+			name: "socketcan little-endian microseconds",
+			file: pcapFile(le, pcapMagicMicroseconds, linkTypeCANSocketCAN, []pcapRecord{
+				{1700000000, 500, socketCANFrame(be, 0x2CF, 8, 0, payload, false)},
+				{1700000000, 1500, socketCANFrame(be, canEFFFlag|0x18FF0102, 2, 0, []byte{0xAA, 0xBB}, false)},
+				{1700000001, 500, socketCANFrame(be, canRTRFlag|0x123, 4, 0, nil, false)},
+				{1700000001, 600, socketCANFrame(be, canERRFlag|0x4, 8, 0, []byte{0, 0, 0x80}, false)},
+			}),
+			want: []frameSummary{
+				{id: 0x2CF, dlc: 8, data: "1040ff3748c10a00", channel: "0", direction: "Rx"},
+				{id: 0x18FF0102, extended: true, dlc: 2, data: "aabb", channel: "0", direction: "Rx", relative: time.Millisecond},
+				{id: 0x123, remote: true, dlc: 4, channel: "0", direction: "Rx", relative: time.Second},
+				{id: 0x4, errorFrame: true, dlc: 8, data: "0000800000000000", channel: "0", direction: "Rx", relative: time.Second + 100*time.Microsecond},
+			},
+		},
+		{
+			name: "sll big-endian nanoseconds",
+			file: pcapFile(be, pcapMagicNanoseconds, linkTypeLinuxSLL, []pcapRecord{
+				{1700000000, 0, append(sllHeader(0, ethPCAN), socketCANFrame(le, 0x123, 3, 0, []byte{1, 2, 3}, false)...)},
+				{1700000000, 250, append(sllHeader(0, 0x0800), make([]byte, 20)...)}, // IPv4，跳过
+				{1700000000, 500, append(sllHeader(sllPacketOutgoing, ethPCANFD), socketCANFrame(le, 0x456, 12, socketCANFDFlagFDF, []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}, true)...)},
+				// ID按大端写入时按小端解析不合法，改按大端识别
+				{1700000000, 750, append(sllHeader(0, ethPCAN), socketCANFrame(be, 0x10F, 1, 0, []byte{0xEE}, false)...)},
+			}),
+			want: []frameSummary{
+				{id: 0x123, dlc: 3, data: "010203", channel: "0", direction: "Rx"},
+				{id: 0x456, fd: true, dlc: 12, data: "0102030405060708090a0b0c", channel: "0", direction: "Tx", relative: 500},
+				{id: 0x10F, dlc: 1, data: "ee", channel: "0", direction: "Rx", relative: 750},
+			},
+		},
+		{
+			name: "pcapng sll2 and socketcan interfaces",
+			file: bytes.Join([][]byte{
+				ngSHB(le),
+				ngIDB(le, linkTypeLinuxSLL2, "can0", 9),
+				ngIDB(le, linkTypeCANSocketCAN, "", 0),
+				ngEPB(le, 0, 1700000000*1e9, append(sll2Header(ethPCAN, sllPacketOutgoing), socketCANFrame(le, 0x1CC, 1, 0, []byte{0x03}, false)...)),
+				ngEPB(le, 1, 1700000000*1e6+2000, socketCANFrame(be, 0x208, 2, 0, []byte{0x01, 0x02}, false)),
+				ngEPB(le, 5, 0, nil), // 接口不存在，跳过
+			}, nil),
+			want: []frameSummary{
+				{id: 0x1CC, dlc: 1, data: "03", channel: "can0", direction: "Tx"},
+				{id: 0x208, dlc: 2, data: "0102", channel: "1", direction: "Rx", relative: 2 * time.Millisecond},
+			},
+		},
+		{
+			name: "pcapng big-endian section",
+			file: bytes.Join([][]byte{
+				ngSHB(be),
+				ngIDB(be, linkTypeCANSocketCAN, "vcan0", 0),
+				ngEPB(be, 0, 1700000000*1e6, socketCANFrame(be, 0x100, 1, 0, []byte{0x42}, false)),
+				ngEPB(be, 0, 1700000000*1e6+10, socketCANFrame(be, 0x101, 0, 0, nil, false)),
+			}, nil),
+			want: []frameSummary{
+				{id: 0x100, dlc: 1, data: "42", channel: "vcan0", direction: "Rx"},
+				{id: 0x101, dlc: 0, data: "", channel: "vcan0", direction: "Rx", relative: 10 * time.Microsecond},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compareFrames(t, readAllPcap(t, tt.file), tt.want)
+		})
+	}
+}
+
+func TestPcapReaderTimestamps(t *testing.T) {
+	file := pcapFile(binary.LittleEndian, pcapMagicMicroseconds, linkTypeCANSocketCAN, []pcapRecord{
+		{1700000000, 123456, socketCANFrame(binary.BigEndian, 0x1, 0, 0, nil, false)},
+	})
+	frames, err := ReadAllFrames(NewPcapReader(bytes.NewReader(file)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Unix(1700000000, 123456000); len(frames) != 1 || !frames[0].Time.Equal(want) {
+		t.Errorf("time = %v, want %v", frames[0].Time, want)
+	}
+}
+
+func TestPcapReaderErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		file []byte
+		want string
+	}{
+		{"unsupported linktype", pcapFile(binary.LittleEndian, pcapMagicMicroseconds, 1, nil), "不支持的链路类型"},
+		{"bad magic", []byte("not a capture file at all"), "不是pcap/pcapng文件"},
+		{"empty", nil, "不是pcap/pcapng文件"},
+		{"pcapng bad byte order", append(ngBlock(binary.LittleEndian, pcapngBlockSHB, []byte{1, 2, 3, 4}), 0), "字节序标记无效"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadAllFrames(NewPcapReader(bytes.NewReader(tt.file)))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+// 抓包在记录中间被截断时保留已读出的帧
+func TestPcapReaderTruncated(t *testing.T) {
+	file := pcapFile(binary.LittleEndian, pcapMagicMicroseconds, linkTypeCANSocketCAN, []pcapRecord{
+		{1700000000, 0, socketCANFrame(binary.BigEndian, 0x1, 1, 0, []byte{0x11}, false)},
+		{1700000000, 1, socketCANFrame(binary.BigEndian, 0x2, 1, 0, []byte{0x22}, false)},
+	})
+	got := readAllPcap(t, file[:len(file)-5])
+	compareFrames(t, got, []frameSummary{{id: 0x1, dlc: 1, data: "11", channel: "0", direction: "Rx"}})
+}
@@ -0,0 +1,216 @@
+package formats
+
+import (
+	"csv-parser/models"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// snifferBufferPattern 嗅探器Buffer字段中的CAN消息格式: string=ID:Length:[HH HH HH ...]
+var snifferBufferPattern = regexp.MustCompile(`^\s*string=([0-9a-fA-F]{1,8}):(\d{1,2}):\[([0-9a-fA-F ]*)\]\s*$`)
+
+// ParseSnifferBuffer 将嗅探器CSV的Buffer字段解析为CAN帧
+// 例如: string=2cf:8:[10 40 ff 37 48 c1 0a 00]
+func ParseSnifferBuffer(buffer string) (*models.CANFrame, error) {
+	m := snifferBufferPattern.FindStringSubmatch(buffer)
+	if m == nil {
+		return nil, fmt.Errorf("不是CAN消息格式: %.60s", buffer)
+	}
+
+	id, err := strconv.ParseUint(m[1], 16, 32)
+	if err != nil || id > 0x1FFFFFFF {
+		return nil, fmt.Errorf("无效的CAN ID: %s", m[1])
+	}
+	dlc, err := strconv.Atoi(m[2])
+	if err != nil {
+		return nil, fmt.Errorf("无效的数据长度: %s", m[2])
+	}
+
+	tokens := strings.Fields(m[3])
+	data := make([]byte, 0, len(tokens))
+	for _, tok := range tokens {
+		b, err := strconv.ParseUint(tok, 16, 8)
+		if err != nil {
+			return nil, fmt.Errorf("无效的数据字节: %s", tok)
+		}
+		data = append(data, byte(b))
+	}
+
+	return &models.CANFrame{
+		ID:       uint32(id),
+		Extended: id > 0x7FF,
+		DLC:      dlc,
+		Data:     data,
+	}, nil
+}
+
+// FormatSnifferBuffer 将CAN帧格式化为嗅探器CSV的Buffer字段
+func FormatSnifferBuffer(frame *models.CANFrame) string {
+	return fmt.Sprintf("string=%s:%d:[%s]", FormatID(frame), frame.DLC, hexBytes(frame.Data, " "))
+}
+
+// FormatID 返回小写十六进制CAN ID（与definitions.json的键一致）
+func FormatID(frame *models.CANFrame) string {
+	return strconv.FormatUint(uint64(frame.ID), 16)
+}
+
+// DisplayID 返回 "0x2CF" / "0x18FF0001" 形式的CAN ID
+func DisplayID(frame *models.CANFrame) string {
+	if frame.Extended {
+		return fmt.Sprintf("0x%08X", frame.ID)
+	}
+	return fmt.Sprintf("0x%03X", frame.ID)
+}
+
+// hexBytes 将字节格式化为小写两位十六进制
+func hexBytes(b []byte, sep string) string {
+	parts := make([]string, len(b))
+	for i, v := range b {
+		parts[i] = fmt.Sprintf("%02x", v)
+	}
+	return strings.Join(parts, sep)
+}
+
+// ParseSnifferStruct 将嗅探器CSV中结构体形式的Buffer字段解析为字段列表
+// 例如: {ulong=1;enum SwitchStatus=EXPOSE_ON;}
+// 每个成员为 "类型 [枚举名] [字段名]=值;"，值可以是嵌套的 {...} 或带双引号的字符串；
+// 没有字段名时以枚举名（没有枚举名时以类型）作为字段名
+func ParseSnifferStruct(buffer string) ([]models.DecodedField, error) {
+	p := structParser{s: strings.TrimSpace(buffer)}
+	fields, err := p.parseStruct()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos != len(p.s) {
+		return nil, fmt.Errorf("结构体后有多余内容: %.60s", p.s[p.pos:])
+	}
+	return fields, nil
+}
+
+// structParser 结构体Buffer字段的递归下降解析器
+type structParser struct {
+	s   string
+	pos int
+}
+
+func (p *structParser) skipSpace() {
+	for p.pos < len(p.s) && (p.s[p.pos] == ' ' || p.s[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+// parseStruct 解析 {成员;成员;...}，末尾的分号可省略
+func (p *structParser) parseStruct() ([]models.DecodedField, error) {
+	p.skipSpace()
+	if p.pos >= len(p.s) || p.s[p.pos] != '{' {
+		return nil, fmt.Errorf("不是结构体格式: %.60s", p.s)
+	}
+	p.pos++
+	fields := []models.DecodedField{}
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.s) {
+			return nil, fmt.Errorf("结构体缺少右括号: %.60s", p.s)
+		}
+		switch p.s[p.pos] {
+		case '}':
+			p.pos++
+			return fields, nil
+		case ';':
+			p.pos++
+			continue
+		}
+		field, err := p.parseMember()
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, field)
+	}
+}
+
+// parseMember 解析 "类型 [枚举名] [字段名]=值"
+func (p *structParser) parseMember() (models.DecodedField, error) {
+	end := strings.IndexAny(p.s[p.pos:], "=;{}")
+	if end < 0 || p.s[p.pos+end] != '=' {
+		return models.DecodedField{}, fmt.Errorf("结构体成员缺少'=': %.60s", p.s[p.pos:])
+	}
+	typ, name := structDecl(strings.Fields(p.s[p.pos : p.pos+end]))
+	if typ == "" {
+		return models.DecodedField{}, fmt.Errorf("结构体成员缺少类型: %.60s", p.s[p.pos:])
+	}
+	p.pos += end + 1
+	p.skipSpace()
+
+	field := models.DecodedField{Name: name, Type: typ}
+	if p.pos < len(p.s) && p.s[p.pos] == '{' {
+		nested, err := p.parseStruct()
+		if err != nil {
+			return models.DecodedField{}, err
+		}
+		parts := make([]string, len(nested))
+		for i, f := range nested {
+			parts[i] = f.Display
+		}
+		field.Fields = nested
+		field.Display = name + "={" + strings.Join(parts, "; ") + "}"
+		return field, nil
+	}
+
+	value, err := p.parseValue()
+	if err != nil {
+		return models.DecodedField{}, err
+	}
+	if v, err := strconv.ParseFloat(value, 64); err == nil && !math.IsNaN(v) && !math.IsInf(v, 0) {
+		field.Value = &v
+	}
+	field.Display = name + "=" + value
+	return field, nil
+}
+
+// parseValue 读取到分号或右括号为止的值，带双引号的值去掉引号（可包含分号和括号）
+func (p *structParser) parseValue() (string, error) {
+	if p.pos < len(p.s) && p.s[p.pos] == '"' {
+		end := strings.IndexByte(p.s[p.pos+1:], '"')
+		if end < 0 {
+			return "", fmt.Errorf("字符串缺少右引号: %.60s", p.s[p.pos:])
+		}
+		value := p.s[p.pos+1 : p.pos+1+end]
+		p.pos += end + 2
+		return value, nil
+	}
+	end := strings.IndexAny(p.s[p.pos:], ";}")
+	if end < 0 {
+		return "", fmt.Errorf("结构体缺少右括号: %.60s", p.s)
+	}
+	value := strings.TrimSpace(p.s[p.pos : p.pos+end])
+	p.pos += end
+	return value, nil
+}
+
+// structDecl 从成员声明中取出类型和字段名：enum/struct/union后的第一个词属于类型
+func structDecl(tokens []string) (typ, name string) {
+	if len(tokens) == 0 {
+		return "", ""
+	}
+	typeLen := 1
+	switch tokens[0] {
+	case "enum", "struct", "union":
+		typeLen = min(2, len(tokens))
+	default:
+		// 多个词时最后一个是字段名，如 "unsigned long count"
+		typeLen = max(1, len(tokens)-1)
+	}
+	typ = strings.Join(tokens[:typeLen], " ")
+	switch {
+	case len(tokens) > typeLen:
+		name = strings.Join(tokens[typeLen:], " ")
+	case typeLen == 2:
+		name = tokens[1] // 枚举名
+	default:
+		name = typ
+	}
+	return typ, name
+}
@@ -0,0 +1,135 @@
+package formats
+
+import (
+	"csv-parser/models"
+	"strings"
+	"testing"
+)
+
+// structField 便于比较的结构体字段摘要
+type structField struct {
+	Name, Type, Display string
+	Value               *float64
+	Fields              []structField
+}
+
+func summarizeStruct(fields []models.DecodedField) []structField {
+	var out []structField
+	for _, f := range fields {
+		out = append(out, structField{Name: f.Name, Type: f.Type, Display: f.Display, Value: f.Value, Fields: summarizeStruct(f.Fields)})
+	}
+	return out
+}
+
+func equalStruct(a, b []structField) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Name != b[i].Name || a[i].Type != b[i].Type || a[i].Display != b[i].Display {
+			return false
+		}
+		if (a[i].Value == nil) != (b[i].Value == nil) || (a[i].Value != nil && *a[i].Value != *b[i].Value) {
+			return false
+		}
+		if !equalStruct(a[i].Fields, b[i].Fields) {
+			return false
+		}
+	}
+	return true
+}
+
+func num(v float64) *float64 {
+	return &v
+}
+
+func TestParseSnifferStruct(t *testing.T) {
+	tests := []struct {
+		name   string
+		buffer string
+		want   []structField
+	}{
+		{
+			name:   "enum without field name",
+			buffer: "{ulong=1;enum SwitchStatus=EXPOSE_ON;}",
+			want: []structField{
+				{Name: "ulong", Type: "ulong", Display: "ulong=1", Value: num(1)},
+				{Name: "SwitchStatus", Type: "enum SwitchStatus", Display: "SwitchStatus=EXPOSE_ON"},
+			},
+		},
+		{
+			name:   "enum with field name",
+			buffer: "{enum SwitchStatus status=EXPOSE_OFF}",
+			want: []structField{
+				{Name: "status", Type: "enum SwitchStatus", Display: "status=EXPOSE_OFF"},
+			},
+		},
+		{
+			name:   "multi-word type",
+			buffer: "{ unsigned long count = 42 ; float kv=-1.5 }",
+			want: []structField{
+				{Name: "count", Type: "unsigned long", Display: "count=42", Value: num(42)},
+				{Name: "kv", Type: "float", Display: "kv=-1.5", Value: num(-1.5)},
+			},
+		},
+		{
+			name:   "nested braces",
+			buffer: "{struct Pos pos={long x=1;long y={short a=2;};};ushort=3;}",
+			want: []structField{
+				{Name: "pos", Type: "struct Pos", Display: "pos={x=1; y={a=2}}", Fields: []structField{
+					{Name: "x", Type: "long", Display: "x=1", Value: num(1)},
+					{Name: "y", Type: "long", Display: "y={a=2}", Fields: []structField{
+						{Name: "a", Type: "short", Display: "a=2", Value: num(2)},
+					}},
+				}},
+				{Name: "ushort", Type: "ushort", Display: "ushort=3", Value: num(3)},
+			},
+		},
+		{
+			name:   "quoted value with separators",
+			buffer: `{string msg="a;b}c={d}";ulong=7}`,
+			want: []structField{
+				{Name: "msg", Type: "string", Display: "msg=a;b}c={d}"},
+				{Name: "ulong", Type: "ulong", Display: "ulong=7", Value: num(7)},
+			},
+		},
+		{
+			name:   "empty",
+			buffer: "{}",
+			want:   nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields, err := ParseSnifferStruct(tt.buffer)
+			if err != nil {
+				t.Fatalf("ParseSnifferStruct(%q): %v", tt.buffer, err)
+			}
+			if got := summarizeStruct(fields); !equalStruct(got, tt.want) {
+				t.Errorf("ParseSnifferStruct(%q) = %+v, want %+v", tt.buffer, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSnifferStructErrors(t *testing.T) {
+	tests := []struct {
+		name, buffer, want string
+	}{
+		{"not a struct", "string=2cf:8:[00]", "不是结构体格式"},
+		{"missing close brace", "{ulong=1;", "缺少右括号"},
+		{"unterminated nested", "{struct P p={long x=1;}", "缺少右括号"},
+		{"missing equals", "{ulong 1;}", "缺少'='"},
+		{"missing type", "{=1}", "缺少类型"},
+		{"unterminated quote", `{string s="abc}`, "缺少右引号"},
+		{"trailing content", "{ulong=1} extra", "多余内容"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseSnifferStruct(tt.buffer)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ParseSnifferStruct(%q) err = %v, want %q", tt.buffer, err, tt.want)
+			}
+		})
+	}
+}
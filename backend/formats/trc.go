@@ -0,0 +1,294 @@
+package formats
+
+import (
+	"bufio"
+	"csv-parser/models"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// trcDefaultColumns 各版本TRC文件的默认列布局（2.x版本可由 $COLUMNS 覆盖）
+// N:序号 O:时间偏移(毫秒) T:类型 B:总线 I:ID d:方向 R:保留 L:DLC l:数据长度 D:数据
+// 1.x版本没有单独的类型列，Rx/Tx/Warng/Error 出现在方向列中
+var trcDefaultColumns = map[string][]string{
+	"1.0": {"N", "O", "I", "L", "D"},
+	"1.1": {"N", "O", "d", "I", "L", "D"},
+	"1.2": {"N", "O", "B", "d", "I", "L", "D"},
+	"1.3": {"N", "O", "B", "d", "I", "R", "L", "D"},
+	"2.0": {"N", "O", "T", "I", "d", "l", "D"},
+	"2.1": {"N", "O", "T", "B", "I", "d", "R", "L", "D"},
+}
+
+// trcEpoch $STARTTIME 的基准日期（天数，与Excel/OLE日期一致）
+var trcEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.Local)
+
+// TRCReader 读取PEAK PCAN-View TRC日志（1.0-2.1版本）
+type TRCReader struct {
+	scanner    *bufio.Scanner
+	lineNumber int
+	version    string
+	columns    []string
+	start      time.Time
+	hasStart   bool
+}
+
+// NewTRCReader 创建TRC日志读取器，未声明 $FILEVERSION 时按1.0处理
+func NewTRCReader(r io.Reader) *TRCReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	return &TRCReader{scanner: scanner, version: "1.0"}
+}
+
+// Next 读取下一帧，跳过注释、状态和警告行
+func (t *TRCReader) Next() (*models.LogFrame, error) {
+	for t.scanner.Scan() {
+		t.lineNumber++
+		line := strings.TrimSpace(t.scanner.Text())
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, ";") {
+			t.parseHeader(line)
+			continue
+		}
+
+		frame, ok := t.parseLine(strings.Fields(line))
+		if !ok {
+			continue
+		}
+		base := noDateBase
+		if t.hasStart {
+			base = t.start
+		}
+		frame.Time = base.Add(frame.Relative)
+		return frame, nil
+	}
+	if err := t.scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取TRC文件失败（第 %d 行）: %v", t.lineNumber, err)
+	}
+	return nil, io.EOF
+}
+
+// parseHeader 解析 ;$FILEVERSION、;$STARTTIME、;$COLUMNS 头部
+func (t *TRCReader) parseHeader(line string) {
+	line = strings.TrimSpace(strings.TrimPrefix(line, ";"))
+	if !strings.HasPrefix(line, "$") {
+		return
+	}
+	key, value, found := strings.Cut(line[1:], "=")
+	if !found {
+		return
+	}
+	value = strings.TrimSpace(value)
+
+	switch strings.ToUpper(strings.TrimSpace(key)) {
+	case "FILEVERSION":
+		t.version = value
+	case "STARTTIME":
+		if days, err := strconv.ParseFloat(value, 64); err == nil {
+			t.start = trcEpoch.Add(time.Duration(days * float64(24*time.Hour)))
+			t.hasStart = true
+		}
+	case "COLUMNS":
+		t.columns = strings.Split(strings.ReplaceAll(value, " ", ""), ",")
+	}
+}
+
+// layout 返回当前使用的列布局
+func (t *TRCReader) layout() []string {
+	if len(t.columns) > 0 && strings.HasPrefix(t.version, "2.") {
+		return t.columns
+	}
+	if cols, ok := trcDefaultColumns[t.version]; ok {
+		return cols
+	}
+	return trcDefaultColumns["1.0"]
+}
+
+// parseLine 按列布局解析一条消息记录，数据列（D）总是最后一列
+func (t *TRCReader) parseLine(fields []string) (*models.LogFrame, bool) {
+	frame := &models.LogFrame{Channel: "1", Direction: "Rx"}
+	length := -1
+	var data []string
+
+	for i, col := range t.layout() {
+		if col == "D" {
+			if i < len(fields) {
+				data = fields[i:]
+			}
+			break
+		}
+		if i >= len(fields) {
+			return nil, false
+		}
+		value := fields[i]
+
+		switch col {
+		case "O":
+			offset, err := parseSeconds(value)
+			if err != nil {
+				return nil, false
+			}
+			frame.Relative = offset / 1000
+		case "T":
+			if !applyTRCType(&frame.CANFrame, value) {
+				return nil, false
+			}
+		case "B":
+			frame.Channel = value
+		case "I":
+			if value == "-" || frame.ErrorFrame {
+				continue
+			}
+			id, err := strconv.ParseUint(value, 16, 32)
+			if err != nil || id > canEFFMask {
+				return nil, false
+			}
+			frame.ID = uint32(id)
+			frame.Extended = len(value) > 4 || id > canSFFMask
+		case "d":
+			switch strings.ToLower(value) {
+			case "rx":
+				frame.Direction = "Rx"
+			case "tx":
+				frame.Direction = "Tx"
+			case "error":
+				frame.ErrorFrame = true
+			default:
+				// Warng 等状态行
+				return nil, false
+			}
+		case "L":
+			dlc, err := strconv.ParseUint(value, 16, 8)
+			if err != nil {
+				return nil, false
+			}
+			if length < 0 {
+				length = dlcToLength(int(dlc), frame.FD)
+			}
+		case "l":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, false
+			}
+			length = n
+		}
+	}
+
+	if frame.ErrorFrame {
+		// 错误帧的数据为错误类型和计数器，不作为CAN数据
+		frame.ID = 0
+		frame.Extended = false
+		return frame, true
+	}
+	if len(data) > 0 && strings.EqualFold(data[0], "RTR") {
+		frame.Remote = true
+	}
+	if length < 0 {
+		length = 0
+	}
+	frame.DLC = length
+	if frame.Remote {
+		return frame, true
+	}
+
+	if length > len(data) {
+		length = len(data)
+	}
+	bytes, err := parseHexBytes(data[:length], 16)
+	if err != nil {
+		return nil, false
+	}
+	frame.Data = bytes
+	return frame, true
+}
+
+// applyTRCType 根据2.x版本的消息类型设置帧标志，返回false表示非CAN帧（状态、错误计数等）
+func applyTRCType(frame *models.CANFrame, msgType string) bool {
+	switch strings.ToUpper(msgType) {
+	case "DT":
+	case "FD", "FB", "FE", "BI":
+		frame.FD = true
+	case "RR":
+		frame.Remote = true
+	case "ER":
+		frame.ErrorFrame = true
+	default:
+		return false
+	}
+	return true
+}
+
+// TRCWriter 写出PCAN-View TRC 2.1版本日志
+type TRCWriter struct {
+	w      *bufio.Writer
+	number int
+}
+
+// NewTRCWriter 创建TRC日志写出器并写出文件头，start为测量开始时间
+func NewTRCWriter(w io.Writer, start time.Time) (*TRCWriter, error) {
+	t := &TRCWriter{w: bufio.NewWriter(w)}
+	days := float64(start.Sub(trcEpoch)) / float64(24*time.Hour)
+	fmt.Fprintf(t.w, ";$FILEVERSION=2.1\n")
+	fmt.Fprintf(t.w, ";$STARTTIME=%.10f\n", days)
+	fmt.Fprintf(t.w, ";$COLUMNS=%s\n", strings.Join(trcDefaultColumns["2.1"], ","))
+	fmt.Fprintf(t.w, ";\n")
+	fmt.Fprintf(t.w, ";   Start time: %s\n", start.Format("2006-01-02 15:04:05.000"))
+	fmt.Fprintf(t.w, ";-------------------------------------------------------------------------------\n")
+	fmt.Fprintf(t.w, ";   Message   Time    Type Bus  ID       Rx/Tx\n")
+	fmt.Fprintf(t.w, ";   Number    Offset  |    |    [hex]    |  Reserved\n")
+	fmt.Fprintf(t.w, ";   |         [ms]    |    |    |        |  |   Data Length Code\n")
+	_, err := fmt.Fprintf(t.w, ";---+-- ------+------ +- --+-- ----+--- +- -+ -- -+ -- -- -- -- -- -- --\n")
+	return t, err
+}
+
+// WriteFrame 写出一帧，时间偏移为相对测量开始的毫秒数
+func (t *TRCWriter) WriteFrame(frame *models.LogFrame) error {
+	t.number++
+	offset := float64(frame.Relative) / float64(time.Millisecond)
+	bus := channelNumber(frame.Channel)
+	direction := frame.Direction
+	if direction == "" {
+		direction = "Rx"
+	}
+
+	if frame.ErrorFrame {
+		_, err := fmt.Fprintf(t.w, "%7d %13.3f ER %2d %8s %s -  0\n", t.number, offset, bus, "-", direction)
+		return err
+	}
+
+	msgType := "DT"
+	switch {
+	case writeAsFD(frame):
+		msgType = "FD"
+	case frame.Remote:
+		msgType = "RR"
+	}
+	id := fmt.Sprintf("%04X", frame.ID)
+	if frame.Extended {
+		id = fmt.Sprintf("%08X", frame.ID)
+	}
+
+	data := payload(frame)
+	dlc := len(data)
+	if msgType == "FD" {
+		dlc = lengthToDLC(len(data))
+		padded := make([]byte, fdLengths[dlc])
+		copy(padded, data)
+		data = padded
+	}
+	if frame.Remote {
+		dlc = frame.DLC
+		data = nil
+	}
+	_, err := fmt.Fprintf(t.w, "%7d %13.3f %s %2d %8s %s - %2X%s\n", t.number, offset, msgType, bus, id, direction, dlc, ascHexBytes(data))
+	return err
+}
+
+// Close 刷新缓冲区
+func (t *TRCWriter) Close() error {
+	return t.w.Flush()
+}
@@ -0,0 +1,185 @@
+package formats
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func readAllTRC(t *testing.T, content string) []frameSummary {
+	t.Helper()
+	frames, err := ReadAllFrames(NewTRCReader(strings.NewReader(content)))
+	if err != nil {
+		t.Fatalf("ReadAllFrames: %v", err)
+	}
+	var out []frameSummary
+	for _, f := range frames {
+		out = append(out, summarize(f))
+	}
+	return out
+}
+
+// ms 将毫秒小数转换为时间间隔
+func ms(v float64) time.Duration {
+	return time.Duration(v * float64(time.Millisecond))
+}
+
+func TestTRCReaderVersions(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []frameSummary
+	}{
+		{
+			name: "1.0 without header",
+			content: `;##########################################################################
+;   PCAN-View
+;##########################################################################
+     1)      1059.9  0300  8  00 11 22 33 44 55 66 77
+     2)      1060.4  18FF0102  2  AA BB
+`,
+			want: []frameSummary{
+				{id: 0x300, dlc: 8, data: "0011223344556677", channel: "1", direction: "Rx", relative: ms(1059.9)},
+				{id: 0x18FF0102, extended: true, dlc: 2, data: "aabb", channel: "1", direction: "Rx", relative: ms(1060.4)},
+			},
+		},
+		{
+			name: "1.1 direction, remote, warning and error rows",
+			content: `;$FILEVERSION=1.1
+     1)      1059.9  Rx         0300  8  00 11 22 33 44 55 66 77
+     2)      1060.4  Tx         0400  4  RTR
+     3)      1061.0  Warng  FFFFFFFF  4  00 00 00 08  BUSHEAVY
+     4)      1062.0  Error      0000  5  01 00 02 00 00
+`,
+			want: []frameSummary{
+				{id: 0x300, dlc: 8, data: "0011223344556677", channel: "1", direction: "Rx", relative: ms(1059.9)},
+				{id: 0x400, remote: true, dlc: 4, channel: "1", direction: "Tx", relative: ms(1060.4)},
+				{errorFrame: true, channel: "1", direction: "Rx", relative: ms(1062)},
+			},
+		},
+		{
+			name: "1.3 bus and reserved columns",
+			content: `;$FILEVERSION=1.3
+     1)      1059.9 1  Rx        0300 -  8  00 11 22 33 44 55 66 77
+     2)      1060.0 2  Tx    18FF0102 -  1  AA
+`,
+			want: []frameSummary{
+				{id: 0x300, dlc: 8, data: "0011223344556677", channel: "1", direction: "Rx", relative: ms(1059.9)},
+				{id: 0x18FF0102, extended: true, dlc: 1, data: "aa", channel: "2", direction: "Tx", relative: ms(1060)},
+			},
+		},
+		{
+			name: "2.0 message types and data length",
+			content: `;$FILEVERSION=2.0
+      1      1059.900 DT     0300 Rx 8  00 11 22 33 44 55 66 77
+      2      1060.000 FD     0400 Tx 12 01 02 03 04 05 06 07 08 09 0A 0B 0C
+      3      1061.000 RR     0500 Rx 2
+      4      1062.000 ST        - Rx 4  00 00 00 08
+      5      1063.000 ER        - Rx 5  04 00 02 00 00
+`,
+			want: []frameSummary{
+				{id: 0x300, dlc: 8, data: "0011223344556677", channel: "1", direction: "Rx", relative: ms(1059.9)},
+				{id: 0x400, fd: true, dlc: 12, data: "0102030405060708090a0b0c", channel: "1", direction: "Tx", relative: ms(1060)},
+				{id: 0x500, remote: true, dlc: 2, channel: "1", direction: "Rx", relative: ms(1061)},
+				{errorFrame: true, channel: "1", direction: "Rx", relative: ms(1063)},
+			},
+		},
+		{
+			name: "2.1 default columns with FD DLC code",
+			content: `;$FILEVERSION=2.1
+      1      1059.900 DT 1      0300 Rx -  8    00 11 22 33 44 55 66 77
+      2      1060.000 FD 2  18FF0102 Tx -  9    01 02 03 04 05 06 07 08 09 0A 0B 0C
+`,
+			want: []frameSummary{
+				{id: 0x300, dlc: 8, data: "0011223344556677", channel: "1", direction: "Rx", relative: ms(1059.9)},
+				{id: 0x18FF0102, extended: true, fd: true, dlc: 12, data: "0102030405060708090a0b0c", channel: "2", direction: "Tx", relative: ms(1060)},
+			},
+		},
+		{
+			name: "2.1 custom columns",
+			content: `;$FILEVERSION=2.1
+;$COLUMNS=N,O,T,B,I,d,l,D
+      1      1059.900 DT 3      0300 Rx 3    01 02 03
+`,
+			want: []frameSummary{
+				{id: 0x300, dlc: 3, data: "010203", channel: "3", direction: "Rx", relative: ms(1059.9)},
+			},
+		},
+		{
+			name: "1.1 ignores columns header",
+			content: `;$FILEVERSION=1.1
+;$COLUMNS=N,O,T,B,I,d,l,D
+     1)      1059.9  Rx         0300  1  01
+`,
+			want: []frameSummary{
+				{id: 0x300, dlc: 1, data: "01", channel: "1", direction: "Rx", relative: ms(1059.9)},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compareFrames(t, readAllTRC(t, tt.content), tt.want)
+		})
+	}
+}
+
+func TestTRCReaderStartTime(t *testing.T) {
+	content := ";$FILEVERSION=1.1\n;$STARTTIME=45975.5\n     1)      1500.0  Rx         0300  1  01\n"
+	frames, err := ReadAllFrames(NewTRCReader(strings.NewReader(content)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 45975.5天即2025-11-14 12:00（trcEpoch为本地时间，按固定时长相加）
+	want := trcEpoch.Add(45975*24*time.Hour + 12*time.Hour + 1500*time.Millisecond)
+	if len(frames) != 1 || !frames[0].Time.Equal(want) {
+		t.Fatalf("frames = %+v, want time %v", frames, want)
+	}
+
+	// 没有 $STARTTIME 时相对时间换算到 noDateBase
+	frames, err = ReadAllFrames(NewTRCReader(strings.NewReader("     1)      1500.0  0300  1  01\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := noDateBase.Add(1500 * time.Millisecond); len(frames) != 1 || !frames[0].Time.Equal(want) {
+		t.Errorf("frames = %+v, want time %v", frames, want)
+	}
+}
+
+func TestTRCWriterRoundTrip(t *testing.T) {
+	const input = `;$FILEVERSION=2.1
+      1         1.000 DT 1      01CC Rx -  2    03 07
+      2         2.500 FD 1  18FF0102 Tx -  9    01 02 03 04 05 06 07 08 09 0A 0B 0C
+      3         3.000 RR 2      0123 Rx -  4
+      4         4.000 ER 1         - Rx -  0
+`
+	start := time.Date(2025, 11, 14, 17, 3, 36, 0, time.Local)
+	frames, err := ReadAllFrames(NewTRCReader(strings.NewReader(input)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	w, err := NewTRCWriter(&buf, start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, frame := range frames {
+		if err := w.WriteFrame(frame); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	compareFrames(t, readAllTRC(t, buf.String()), readAllTRC(t, input))
+
+	// $STARTTIME以天为单位的小数保存，只精确到微秒级
+	written, err := ReadAllFrames(NewTRCReader(&buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := written[0].Time.Sub(start.Add(time.Millisecond)).Abs(); diff > 10*time.Microsecond {
+		t.Errorf("first frame time = %v, want %v", written[0].Time, start.Add(time.Millisecond))
+	}
+}
@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"csv-parser/models"
+	"csv-parser/services"
+	"csv-parser/utils"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CANopenHandler struct {
+	canopenService *services.CANopenService
+	csvService     *services.CSVService
+}
+
+func NewCANopenHandler(canopenService *services.CANopenService, csvService *services.CSVService) *CANopenHandler {
+	return &CANopenHandler{
+		canopenService: canopenService,
+		csvService:     csvService,
+	}
+}
+
+// parseNodeID 解析节点ID参数，空字符串返回0
+func parseNodeID(raw string) (uint8, bool) {
+	if raw == "" {
+		return 0, true
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil || v < 1 || v > 127 {
+		return 0, false
+	}
+	return uint8(v), true
+}
+
+// ImportDictionary 导入EDS/DCF对象字典
+func (h *CANopenHandler) ImportDictionary(c *gin.Context) {
+	utils.Info("开始处理对象字典导入请求")
+
+	nodeID, ok := parseNodeID(c.PostForm("nodeId"))
+	if !ok {
+		c.JSON(http.StatusBadRequest, models.ObjectDictionaryResponse{
+			Success: false,
+			Message: "Invalid nodeId. Must be 1-127",
+		})
+		return
+	}
+
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		utils.Error("获取上传文件失败: %v", err)
+		c.JSON(http.StatusBadRequest, models.ObjectDictionaryResponse{
+			Success: false,
+			Message: "Failed to get uploaded file: " + err.Error(),
+		})
+		return
+	}
+	defer file.Close()
+
+	filename := header.Filename
+	ext := strings.ToLower(filepath.Ext(filename))
+	if ext != ".eds" && ext != ".dcf" {
+		utils.Warn("文件类型不允许: %s", ext)
+		c.JSON(http.StatusBadRequest, models.ObjectDictionaryResponse{
+			Success: false,
+			Message: "Only EDS and DCF files are allowed",
+		})
+		return
+	}
+
+	dict, err := h.canopenService.ImportDictionary(filename, file, nodeID)
+	if err != nil {
+		utils.Error("导入对象字典失败: %v", err)
+		c.JSON(http.StatusBadRequest, models.ObjectDictionaryResponse{
+			Success: false,
+			Message: "Failed to import dictionary: " + err.Error(),
+		})
+		return
+	}
+
+	// 对象字典变化后CANOPEN解析结果需要重新生成
+	h.csvService.DeleteCacheForProtocol("CANOPEN")
+
+	utils.Info("对象字典导入成功: %s, 节点ID: %d, 条目数: %d", filename, dict.NodeID, len(dict.Entries))
+	c.JSON(http.StatusOK, models.ObjectDictionaryResponse{
+		Success:    true,
+		Message:    "Dictionary imported successfully",
+		Dictionary: dict,
+	})
+}
+
+// GetDictionaries 获取已导入的对象字典列表
+func (h *CANopenHandler) GetDictionaries(c *gin.Context) {
+	dictionaries, err := h.canopenService.GetDictionaries()
+	if err != nil {
+		utils.Error("获取对象字典列表失败: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ObjectDictionaryListResponse{
+			Success: false,
+			Message: "Failed to get dictionaries: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.ObjectDictionaryListResponse{
+		Success:      true,
+		Message:      "Dictionaries retrieved successfully",
+		Dictionaries: dictionaries,
+	})
+}
+
+// GetDictionary 获取指定节点的对象字典
+func (h *CANopenHandler) GetDictionary(c *gin.Context) {
+	nodeID, ok := parseNodeID(c.Param("nodeId"))
+	if !ok || nodeID == 0 {
+		c.JSON(http.StatusBadRequest, models.ObjectDictionaryResponse{
+			Success: false,
+			Message: "Invalid nodeId. Must be 1-127",
+		})
+		return
+	}
+
+	dict, err := h.canopenService.GetDictionary(nodeID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ObjectDictionaryResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.ObjectDictionaryResponse{
+		Success:    true,
+		Message:    "Dictionary retrieved successfully",
+		Dictionary: dict,
+	})
+}
+
+// DeleteDictionary 删除指定节点的对象字典
+func (h *CANopenHandler) DeleteDictionary(c *gin.Context) {
+	nodeID, ok := parseNodeID(c.Param("nodeId"))
+	if !ok || nodeID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid nodeId. Must be 1-127",
+		})
+		return
+	}
+
+	if err := h.canopenService.DeleteDictionary(nodeID); err != nil {
+		utils.Error("删除对象字典失败, 节点ID %d: %v", nodeID, err)
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Failed to delete dictionary: " + err.Error(),
+		})
+		return
+	}
+	h.csvService.DeleteCacheForProtocol("CANOPEN")
+
+	utils.Info("对象字典删除成功, 节点ID: %d", nodeID)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Dictionary deleted successfully",
+	})
+}
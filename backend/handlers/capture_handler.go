@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"csv-parser/models"
+	"csv-parser/services"
+	"csv-parser/socketcan"
+	"csv-parser/utils"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CaptureHandler struct {
+	captureService *services.CaptureService
+}
+
+func NewCaptureHandler(captureService *services.CaptureService) *CaptureHandler {
+	return &CaptureHandler{
+		captureService: captureService,
+	}
+}
+
+// StartCapture 开始实时抓包
+// 表单字段: interface（SocketCAN接口，默认使用capture.json中的配置）、protocolType（抓包文件的协议类型，默认CAN）
+func (h *CaptureHandler) StartCapture(c *gin.Context) {
+	iface := c.PostForm("interface")
+	protocolType := c.DefaultPostForm("protocolType", "CAN")
+	if protocolType != "CAN" && protocolType != "CANOPEN" && protocolType != "COMMON" {
+		c.JSON(http.StatusBadRequest, models.CaptureResponse{
+			Success: false,
+			Message: "Invalid protocolType. Must be 'CAN', 'CANOPEN' or 'COMMON'",
+		})
+		return
+	}
+
+	status, err := h.captureService.Start(iface, protocolType)
+	if err != nil {
+		utils.Error("开始抓包失败: %v", err)
+		code := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, services.ErrInvalidInterface):
+			code = http.StatusBadRequest
+		case errors.Is(err, services.ErrCaptureRunning):
+			code = http.StatusConflict
+		case errors.Is(err, socketcan.ErrUnsupported):
+			code = http.StatusNotImplemented
+		}
+		c.JSON(code, models.CaptureResponse{
+			Success: false,
+			Message: "Failed to start capture: " + err.Error(),
+			Status:  h.captureService.Status(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.CaptureResponse{
+		Success: true,
+		Message: "Capture started",
+		Status:  status,
+	})
+}
+
+// StopCapture 停止实时抓包，抓包文件保留在上传目录中
+func (h *CaptureHandler) StopCapture(c *gin.Context) {
+	status, err := h.captureService.Stop()
+	if err != nil {
+		c.JSON(http.StatusConflict, models.CaptureResponse{
+			Success: false,
+			Message: err.Error(),
+			Status:  h.captureService.Status(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.CaptureResponse{
+		Success: true,
+		Message: "Capture stopped",
+		Status:  status,
+	})
+}
+
+// GetCaptureStatus 获取抓包状态和最近收到的帧
+func (h *CaptureHandler) GetCaptureStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, models.CaptureResponse{
+		Success: true,
+		Message: "Capture status retrieved successfully",
+		Status:  h.captureService.Status(),
+	})
+}
+
+// StreamCapture 以Server-Sent Events推送实时抓包解析出的帧
+// 查询参数: ids/exclude（逗号分隔的CAN ID）、names（逗号分隔的关键字，匹配Name或Meaning）
+// 事件: frame（models.CaptureFrame）、status（每秒一次，包含抓包状态和本连接丢弃的帧数）
+func (h *CaptureHandler) StreamCapture(c *gin.Context) {
+	filter := models.LiveFilter{
+		IDs:        splitList(c.Query("ids")),
+		ExcludeIDs: splitList(c.Query("exclude")),
+		Names:      splitList(c.Query("names")),
+	}
+	sub := h.captureService.Subscribe(filter)
+	defer h.captureService.Unsubscribe(sub)
+	utils.Info("实时查看连接: %s, 过滤条件: %+v", c.ClientIP(), filter)
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	// 禁止反向代理缓冲事件流
+	c.Header("X-Accel-Buffering", "no")
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	c.SSEvent("status", h.liveStatus(sub))
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case frame, ok := <-sub.Frames():
+			if !ok {
+				return false
+			}
+			c.SSEvent("frame", frame)
+			return true
+		case <-ticker.C:
+			c.SSEvent("status", h.liveStatus(sub))
+			return true
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+	utils.Info("实时查看断开: %s", c.ClientIP())
+}
+
+// liveStatus 实时查看推送的状态事件（不包含最近帧列表）
+func (h *CaptureHandler) liveStatus(sub *services.LiveSubscription) gin.H {
+	status := h.captureService.Status()
+	if status != nil {
+		status.Recent = nil
+	}
+	return gin.H{
+		"status":  status,
+		"dropped": sub.Dropped(),
+	}
+}
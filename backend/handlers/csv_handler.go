@@ -1,11 +1,17 @@
 package handlers
 
 import (
+	"bufio"
+	"csv-parser/formats"
 	"csv-parser/models"
 	"csv-parser/services"
 	"csv-parser/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
-	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -25,10 +31,15 @@ func NewCSVHandler(csvService *services.CSVService) *CSVHandler {
 func (h *CSVHandler) UploadFile(c *gin.Context) {
 	utils.Info("开始处理文件上传请求")
 
-	// 获取协议类型参数
+	// 获取协议类型参数，未提供时默认使用CAN协议
 	protocolType := c.DefaultPostForm("protocolType", "CAN")
 	if protocolType != "CAN" && protocolType != "CANOPEN" && protocolType != "COMMON" {
-		protocolType = "CAN" // 默认使用CAN协议
+		utils.Warn("无效的协议类型: %s", protocolType)
+		c.JSON(http.StatusBadRequest, models.UploadResponse{
+			Success: false,
+			Message: "Invalid protocolType. Must be 'CAN', 'CANOPEN' or 'COMMON'",
+		})
+		return
 	}
 	utils.Info("协议类型: %s", protocolType)
 
@@ -44,31 +55,26 @@ func (h *CSVHandler) UploadFile(c *gin.Context) {
 	}
 	defer file.Close()
 
-	// 验证文件类型
+	// 文件格式由服务按内容识别，扩展名只作为参考
 	filename := header.Filename
 	utils.Info("正在上传文件: %s", filename)
-	ext := strings.ToLower(filepath.Ext(filename))
-	if ext != ".csv" {
-		utils.Warn("文件类型不允许: %s", ext)
-		c.JSON(http.StatusBadRequest, models.UploadResponse{
-			Success: false,
-			Message: "Only CSV files are allowed",
-		})
-		return
-	}
 
 	// 上传文件（带协议类型）
 	csvFile, err := h.csvService.UploadFile(filename, file, protocolType)
 	if err != nil {
 		utils.Error("上传文件失败: %v", err)
-		c.JSON(http.StatusInternalServerError, models.UploadResponse{
+		status := http.StatusInternalServerError
+		if errors.Is(err, formats.ErrUnknownFormat) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, models.UploadResponse{
 			Success: false,
 			Message: "Failed to upload file: " + err.Error(),
 		})
 		return
 	}
 
-	utils.Info("文件上传成功: %s, 协议类型: %s", filename, protocolType)
+	utils.Info("文件上传成功: %s, 协议类型: %s, 文件格式: %s", filename, protocolType, csvFile.Format)
 	c.JSON(http.StatusOK, models.UploadResponse{
 		Success: true,
 		Message: "File uploaded successfully",
@@ -77,6 +83,8 @@ func (h *CSVHandler) UploadFile(c *gin.Context) {
 }
 
 // ParseFile 解析CSV文件
+// 可选查询参数（在服务端对解析结果求值）: offset、limit、sort（time/id/name）、order（asc/desc）、
+// ids、type、source、target、name（逗号分隔）、from、to（时间范围）、text（任一列包含）
 func (h *CSVHandler) ParseFile(c *gin.Context) {
 	filename := c.Param("filename")
 	protocol := c.DefaultQuery("protocol", "CAN") // 默认使用CAN协议
@@ -101,62 +109,171 @@ func (h *CSVHandler) ParseFile(c *gin.Context) {
 		return
 	}
 
-	// 1. 先检查缓存
-	if cachedData, hasCached := h.csvService.GetCachedResult(filename, protocol); hasCached {
-		utils.Info("从缓存读取解析结果: %s, 协议: %s", filename, protocol)
-		c.JSON(http.StatusOK, models.ParseResponse{
-			Success: true,
-			Message: "File loaded from cache",
-			Data:    cachedData,
-			Cached:  true,
+	query, err := parseQuery(c)
+	if err == nil {
+		_, _, err = services.ValidateQuery(query)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ParseResponse{
+			Success: false,
+			Message: err.Error(),
 		})
 		return
 	}
 
-	// 2. 无缓存，创建以CSV文件名命名的日志文件
-	protocolType := utils.GetProtocolType(protocol)
-	logKey, err := utils.CreateFileLogger(filename, protocolType)
-	if err != nil {
-		utils.Warn("创建日志文件失败: %v，将继续解析但不记录详细日志", err)
-		logKey = ""
-	}
-	defer func() {
-		if logKey != "" {
-			utils.CloseFileLogger(logKey)
+	// 1. 先检查缓存
+	reader, cached := h.csvService.OpenCache(filename, protocol)
+	if cached {
+		utils.Info("从缓存读取解析结果: %s, 协议: %s", filename, protocol)
+	} else {
+		// 2. 无缓存，创建以CSV文件名命名的日志文件
+		logKey := createParseLogger(filename, protocol)
+		defer closeParseLogger(logKey)
+
+		// 3. 打开文件并读取表头，此时出错仍可返回错误状态码
+		stream, err := h.csvService.OpenParse(filename, protocol, logKey)
+		if err != nil {
+			respondParseError(c, filename, logKey, err)
+			return
 		}
-	}()
+		defer stream.Close()
 
-	// 3. 解析文件（带日志记录）
-	data, err := h.csvService.ParseFileWithLog(filename, protocol, logKey)
-	if err != nil {
+		// 4. 先解析到缓存，再从缓存输出或查询；解析出错时尚未发送响应头，可以返回错误状态码
+		total, err := stream.BuildCache()
+		if err != nil {
+			respondParseError(c, filename, logKey, err)
+			return
+		}
 		if logKey != "" {
-			utils.FileLogError(logKey, "解析文件失败: %v", err)
+			utils.FileLogInfo(logKey, "文件解析完成，共 %d 条数据", total)
 		}
-		utils.Error("解析文件失败 %s: %v", filename, err)
+		utils.Info("文件解析成功: %s, 协议: %s, 共 %d 行", filename, protocol, total)
+
+		if reader, cached = h.csvService.OpenCache(filename, protocol); !cached {
+			respondParseError(c, filename, logKey, errors.New("cache file not available"))
+			return
+		}
+		cached = false
+	}
+	defer reader.Close()
+
+	message := "File parsed successfully with " + protocol + " protocol"
+	if cached {
+		message = "File loaded from cache"
+	}
+
+	// 不带查询参数时逐块输出缓存中的全部结果
+	if query.IsEmpty() {
+		writeParseEnvelope(c, func(w io.Writer) error {
+			return services.WriteCacheJSON(w, reader)
+		}, message, cached)
+		return
+	}
+
+	result, err := services.QueryCache(reader, query)
+	if err != nil {
+		utils.Error("查询解析结果失败 %s: %v", filename, err)
 		c.JSON(http.StatusInternalServerError, models.ParseResponse{
 			Success: false,
-			Message: "Failed to parse file: " + err.Error(),
+			Message: "Failed to query parsed data: " + err.Error(),
 		})
 		return
 	}
+	c.JSON(http.StatusOK, models.ParseResponse{
+		Success: true,
+		Message: message,
+		Data:    result,
+		Cached:  cached,
+	})
+}
 
-	// 4. 保存解析结果到缓存
-	if err := h.csvService.SaveCacheResult(filename, protocol, data); err != nil {
-		utils.Warn("保存缓存失败: %v", err)
+// writeParseEnvelope 输出ParseResponse格式的响应，data由writeData写入
+// 响应头在写入data前发送，writeData中途出错（如缓存文件损坏）时只能将success置为false
+func writeParseEnvelope(c *gin.Context, writeData func(w io.Writer) error, message string, cached bool) {
+	c.Header("Content-Type", "application/json; charset=utf-8")
+	c.Status(http.StatusOK)
+
+	w := bufio.NewWriterSize(c.Writer, 64*1024)
+	w.WriteString(`{"data":`)
+	success := true
+	if err := writeData(w); err != nil {
+		success = false
+		message = err.Error()
 	}
+	tail, _ := json.Marshal(struct {
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+		Cached  bool   `json:"cached"`
+	}{success, message, cached})
+	// 去掉开头的 { 拼接到data之后
+	w.WriteByte(',')
+	w.Write(tail[1:])
+	w.Flush()
+}
 
+// createParseLogger 创建以CSV文件名命名的解析日志，失败时返回空字符串（不记录详细日志）
+func createParseLogger(filename, protocol string) string {
+	protocolType := utils.GetProtocolType(protocol)
+	logKey, err := utils.CreateFileLogger(filename, protocolType)
+	if err != nil {
+		utils.Warn("创建日志文件失败: %v，将继续解析但不记录详细日志", err)
+		return ""
+	}
+	return logKey
+}
+
+// closeParseLogger 关闭解析日志
+func closeParseLogger(logKey string) {
 	if logKey != "" {
-		utils.FileLogInfo(logKey, "文件解析完成，返回 %d 条数据", data.Total)
+		utils.CloseFileLogger(logKey)
 	}
-	utils.Info("文件解析成功: %s, 协议: %s", filename, protocol)
-	c.JSON(http.StatusOK, models.ParseResponse{
-		Success: true,
-		Message: "File parsed successfully with " + protocol + " protocol",
-		Data:    data,
-		Cached:  false,
+}
+
+// respondParseError 记录解析失败并返回500
+func respondParseError(c *gin.Context, filename, logKey string, err error) {
+	if logKey != "" {
+		utils.FileLogError(logKey, "解析文件失败: %v", err)
+	}
+	utils.Error("解析文件失败 %s: %v", filename, err)
+	c.JSON(http.StatusInternalServerError, models.ParseResponse{
+		Success: false,
+		Message: "Failed to parse file: " + err.Error(),
 	})
 }
 
+// parseQuery 读取 /api/parse 的分页、排序和过滤参数
+func parseQuery(c *gin.Context) (models.ParseQuery, error) {
+	query := models.ParseQuery{
+		Sort:       strings.ToLower(c.Query("sort")),
+		Order:      strings.ToLower(c.Query("order")),
+		IDs:        splitList(c.Query("ids")),
+		ExcludeIDs: splitList(c.Query("exclude")),
+		Types:      splitList(c.Query("type")),
+		Sources:    splitList(c.Query("source")),
+		Targets:    splitList(c.Query("target")),
+		Names:      splitList(c.Query("name")),
+		From:       c.Query("from"),
+		To:         c.Query("to"),
+		Text:       c.Query("text"),
+		Fields:     splitList(c.Query("fields")),
+	}
+	for _, p := range []struct {
+		name  string
+		value *int
+	}{{"offset", &query.Offset}, {"limit", &query.Limit}} {
+		raw := c.Query(p.name)
+		if raw == "" {
+			continue
+		}
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			return query, fmt.Errorf("Invalid %s: must be a non-negative integer", p.name)
+		}
+		*p.value = n
+	}
+	return query, nil
+}
+
 // GetFiles 获取文件列表
 func (h *CSVHandler) GetFiles(c *gin.Context) {
 	utils.Debug("获取文件列表请求")
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"bytes"
+	"csv-parser/models"
+	"csv-parser/services"
+	"csv-parser/utils"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DBCHandler struct {
+	dbcService *services.DBCService
+	csvService *services.CSVService
+}
+
+func NewDBCHandler(dbcService *services.DBCService, csvService *services.CSVService) *DBCHandler {
+	return &DBCHandler{
+		dbcService: dbcService,
+		csvService: csvService,
+	}
+}
+
+// ImportDBC 导入DBC文件，生成CAN定义和数据解析配置
+// 查询参数 dryRun=true 时只返回生成的配置，不写入文件；
+// overwrite=true 时整体替换已有的配置条目，默认与已有条目合并
+func (h *DBCHandler) ImportDBC(c *gin.Context) {
+	utils.Info("开始处理DBC导入请求")
+
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		utils.Error("获取上传文件失败: %v", err)
+		c.JSON(http.StatusBadRequest, models.DBCImportResponse{
+			Success: false,
+			Message: "Failed to get uploaded file: " + err.Error(),
+		})
+		return
+	}
+	defer file.Close()
+
+	filename := header.Filename
+	if ext := strings.ToLower(filepath.Ext(filename)); ext != ".dbc" {
+		utils.Warn("文件类型不允许: %s", ext)
+		c.JSON(http.StatusBadRequest, models.DBCImportResponse{
+			Success: false,
+			Message: "Only DBC files are allowed",
+		})
+		return
+	}
+
+	dryRun := c.Query("dryRun") == "true"
+	overwrite := c.Query("overwrite") == "true"
+	result, err := h.dbcService.ImportDBC(filename, file, dryRun, overwrite)
+	if err != nil {
+		utils.Error("导入DBC失败: %v", err)
+		c.JSON(http.StatusBadRequest, models.DBCImportResponse{
+			Success: false,
+			Message: "Failed to import DBC: " + err.Error(),
+		})
+		return
+	}
+
+	message := "DBC preview generated"
+	if !dryRun {
+		// CAN配置变化后解析结果需要重新生成
+		h.csvService.DeleteCacheForProtocol("CAN")
+		message = "DBC imported successfully"
+	}
+
+	utils.Info("DBC导入完成: %s, 报文数: %d, 信号数: %d, 新增: %d, 更新: %d, 替换: %d, 跳过信号: %d, 预览: %v",
+		filename, result.MessageCount, result.SignalCount, len(result.Added), len(result.Updated),
+		len(result.Replaced), len(result.Skipped), dryRun)
+	c.JSON(http.StatusOK, models.DBCImportResponse{
+		Success: true,
+		Message: message,
+		Result:  result,
+	})
+}
+
+// ExportDBC 将当前CAN定义和数据解析配置导出为DBC文件
+func (h *DBCHandler) ExportDBC(c *gin.Context) {
+	var buf bytes.Buffer
+	skipped, err := h.dbcService.ExportDBC(&buf)
+	if err != nil {
+		utils.Error("导出DBC失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to export DBC: " + err.Error(),
+		})
+		return
+	}
+	if len(skipped) > 0 {
+		utils.Warn("导出DBC时跳过 %d 个无法表示为信号的字段: %s", len(skipped), strings.Join(skipped, ", "))
+	}
+
+	utils.Info("DBC导出完成, 大小: %d 字节", buf.Len())
+	c.Header("Content-Disposition", `attachment; filename="can_definitions.dbc"`)
+	c.Data(http.StatusOK, "application/octet-stream", buf.Bytes())
+}
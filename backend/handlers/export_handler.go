@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"csv-parser/formats"
+	"csv-parser/models"
+	"csv-parser/services"
+	"csv-parser/utils"
+	"errors"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ExportHandler struct {
+	exportService *services.ExportService
+}
+
+func NewExportHandler(exportService *services.ExportService) *ExportHandler {
+	return &ExportHandler{
+		exportService: exportService,
+	}
+}
+
+// Export 导出解析结果
+// 查询参数: protocol（CAN/CANOPEN/COMMON）、format（csv/ndjson/xlsx，或candump/asc/trc导出CAN帧日志），
+// 过滤、排序和分页参数与 /api/parse 相同；sort=asc/desc 兼容旧的按Time排序写法
+func (h *ExportHandler) Export(c *gin.Context) {
+	filename := c.Param("filename")
+	protocol := c.DefaultQuery("protocol", "CAN")
+	format := strings.ToLower(c.DefaultQuery("format", services.ExportCSV))
+	utils.Info("开始导出文件: %s, 协议: %s, 格式: %s", filename, protocol, format)
+
+	if protocol != "CAN" && protocol != "CANOPEN" && protocol != "COMMON" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid protocol. Must be 'CAN', 'CANOPEN' or 'COMMON'",
+		})
+		return
+	}
+	contentType, ok := services.ExportContentTypes[format]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid format. Must be 'csv', 'ndjson', 'xlsx', 'candump', 'asc' or 'trc'",
+		})
+		return
+	}
+
+	query, err := parseQuery(c)
+	if err == nil {
+		query = legacyTimeSort(query)
+		_, _, err = services.ValidateQuery(query)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if services.IsFrameExport(format) {
+		h.exportFrames(c, filename, format, contentType, query)
+		return
+	}
+
+	reader, err := h.exportService.OpenData(filename, protocol)
+	if err != nil {
+		utils.Error("导出时解析文件失败 %s: %v", filename, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to parse file: " + err.Error(),
+		})
+		return
+	}
+	defer reader.Close()
+
+	selection, err := services.SelectRows(reader, query)
+	if err != nil {
+		respondSelectError(c, filename, err)
+		return
+	}
+
+	exportName := exportFilename(filename, protocol, format)
+	setAttachmentHeaders(c, exportName, contentType)
+
+	// 响应头已发送，之后的错误只能记录日志
+	if err := h.exportService.Export(c.Writer, format, protocol, reader, selection); err != nil {
+		utils.Error("导出失败 %s: %v", filename, err)
+		return
+	}
+	utils.Info("导出完成: %s, 共 %d 行（原始 %d 行）", exportName, selection.Len(), reader.Len())
+}
+
+// exportFrames 将Buffer列中的CAN帧导出为candump、ASC或TRC日志，时间戳为相对第一帧的时间
+// 帧总是按Time升序输出（回放需要），排序和分页参数被忽略
+func (h *ExportHandler) exportFrames(c *gin.Context, filename, format, contentType string, query models.ParseQuery) {
+	reader, err := h.exportService.OpenFrames(filename)
+	if err != nil {
+		utils.Error("导出时读取文件失败 %s: %v", filename, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to read file: " + err.Error(),
+		})
+		return
+	}
+	defer reader.Close()
+
+	frames, err := services.FilterFrames(reader, query)
+	if err != nil {
+		respondSelectError(c, filename, err)
+		return
+	}
+
+	exportName := frameExportFilename(filename, format)
+	setAttachmentHeaders(c, exportName, contentType)
+
+	// 响应头已发送，之后的错误只能记录日志
+	if err := h.exportService.ExportFrames(c.Writer, format, reader, frames); err != nil {
+		utils.Error("导出失败 %s: %v", filename, err)
+		return
+	}
+	utils.Info("导出完成: %s, 共 %d 帧", exportName, frames.Len())
+}
+
+// legacyTimeSort 将旧的 sort=none/asc/desc（按Time排序）转换为 sort=time&order=...
+func legacyTimeSort(q models.ParseQuery) models.ParseQuery {
+	switch q.Sort {
+	case "none":
+		q.Sort = ""
+	case "asc", "desc":
+		q.Sort, q.Order = "time", q.Sort
+	}
+	return q
+}
+
+// respondSelectError 过滤参数不合法时返回400，否则返回500
+func respondSelectError(c *gin.Context, filename string, err error) {
+	status := http.StatusInternalServerError
+	if errors.Is(err, services.ErrInvalidQuery) {
+		status = http.StatusBadRequest
+	}
+	utils.Error("导出时筛选数据失败 %s: %v", filename, err)
+	c.JSON(status, gin.H{
+		"success": false,
+		"message": "Failed to filter rows: " + err.Error(),
+	})
+}
+
+// setAttachmentHeaders 设置下载文件的响应头并发送200状态码
+func setAttachmentHeaders(c *gin.Context, name, contentType string) {
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", `attachment; filename="`+asciiFilename(name)+`"; filename*=UTF-8''`+url.PathEscape(name))
+	c.Status(http.StatusOK)
+}
+
+// frameExportFilename 生成CAN日志导出的文件名，扩展名按日志格式（candump为.log）
+func frameExportFilename(filename, format string) string {
+	return exportBaseName(filename) + formats.WritableFormats[format]
+}
+
+// exportFilename 根据上传文件名生成导出文件名
+func exportFilename(filename, protocol, format string) string {
+	return exportBaseName(filename) + "_" + protocol + "." + format
+}
+
+// exportBaseName 从上传文件名（UUID_PROTOCOL_原始文件名）中取出不带扩展名的原始文件名
+func exportBaseName(filename string) string {
+	name := filename
+	if parts := strings.SplitN(filename, "_", 3); len(parts) == 3 {
+		name = parts[2]
+	}
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
+
+// asciiFilename 供不支持 filename* 的客户端使用的ASCII文件名
+func asciiFilename(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r > 0x7E || r == '"' || r == '\\' {
+			return '_'
+		}
+		return r
+	}, name)
+}
+
+// splitList 拆分逗号分隔的查询参数
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
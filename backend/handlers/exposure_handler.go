@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"csv-parser/models"
+	"csv-parser/services"
+	"csv-parser/utils"
+	"net/http"
+	"path/filepath"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ExposureHandler struct {
+	exposureService *services.ExposureService
+}
+
+func NewExposureHandler(exposureService *services.ExposureService) *ExposureHandler {
+	return &ExposureHandler{
+		exposureService: exposureService,
+	}
+}
+
+// GetExposures 将文件按CAN协议解析后切分为曝光序列
+// 每次曝光包含开始/结束的行号和时间、准备时长、HV ON时长、设置参数与实际参数以及结果
+func (h *ExposureHandler) GetExposures(c *gin.Context) {
+	filename := c.Param("filename")
+	if filepath.Base(filename) != filename {
+		c.JSON(http.StatusBadRequest, models.ExposureResponse{
+			Success: false,
+			Message: "Invalid filename",
+		})
+		return
+	}
+
+	list, err := h.exposureService.Exposures(filename)
+	if err != nil {
+		utils.Error("分析曝光序列失败 %s: %v", filename, err)
+		c.JSON(http.StatusInternalServerError, models.ExposureResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	utils.Info("分析曝光序列: %s, 共 %d 次曝光", filename, list.Total)
+	c.JSON(http.StatusOK, models.ExposureResponse{
+		Success: true,
+		Message: "Exposures extracted successfully",
+		Data:    list,
+	})
+}
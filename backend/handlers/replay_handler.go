@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"csv-parser/models"
+	"csv-parser/services"
+	"csv-parser/socketcan"
+	"csv-parser/utils"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ReplayHandler struct {
+	replayService *services.ReplayService
+}
+
+func NewReplayHandler(replayService *services.ReplayService) *ReplayHandler {
+	return &ReplayHandler{
+		replayService: replayService,
+	}
+}
+
+// StartReplay 将上传文件中的CAN帧回放到SocketCAN接口
+// 表单字段: filename、interface（如 vcan0）、speed（速度倍数，默认1）、loop（true循环回放）、
+// ids/exclude（逗号分隔的消息ID，与预览页和导出的过滤一致）
+func (h *ReplayHandler) StartReplay(c *gin.Context) {
+	filename := c.PostForm("filename")
+	if filename == "" || filepath.Base(filename) != filename {
+		c.JSON(http.StatusBadRequest, models.ReplayResponse{
+			Success: false,
+			Message: "Invalid filename",
+		})
+		return
+	}
+	speed, err := strconv.ParseFloat(c.DefaultPostForm("speed", "1"), 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ReplayResponse{
+			Success: false,
+			Message: "Invalid speed: " + c.PostForm("speed"),
+		})
+		return
+	}
+
+	req := services.ReplayRequest{
+		Filename:   filename,
+		Interface:  c.PostForm("interface"),
+		Speed:      speed,
+		Loop:       c.PostForm("loop") == "true",
+		IDs:        splitList(c.PostForm("ids")),
+		ExcludeIDs: splitList(c.PostForm("exclude")),
+	}
+	status, err := h.replayService.Start(req)
+	if err != nil {
+		utils.Error("开始回放失败 %s: %v", filename, err)
+		code := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, services.ErrInvalidInterface), errors.Is(err, services.ErrInvalidReplay):
+			code = http.StatusBadRequest
+		case errors.Is(err, services.ErrReplayRunning):
+			code = http.StatusConflict
+		case errors.Is(err, socketcan.ErrUnsupported):
+			code = http.StatusNotImplemented
+		}
+		c.JSON(code, models.ReplayResponse{
+			Success: false,
+			Message: "Failed to start replay: " + err.Error(),
+			Status:  h.replayService.Status(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.ReplayResponse{
+		Success: true,
+		Message: "Replay started",
+		Status:  status,
+	})
+}
+
+// StopReplay 停止回放
+func (h *ReplayHandler) StopReplay(c *gin.Context) {
+	status, err := h.replayService.Stop()
+	if err != nil {
+		c.JSON(http.StatusConflict, models.ReplayResponse{
+			Success: false,
+			Message: err.Error(),
+			Status:  h.replayService.Status(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.ReplayResponse{
+		Success: true,
+		Message: "Replay stopped",
+		Status:  status,
+	})
+}
+
+// GetReplayStatus 获取回放进度
+func (h *ReplayHandler) GetReplayStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, models.ReplayResponse{
+		Success: true,
+		Message: "Replay status retrieved successfully",
+		Status:  h.replayService.Status(),
+	})
+}
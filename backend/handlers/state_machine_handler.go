@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"csv-parser/models"
+	"csv-parser/services"
+	"csv-parser/utils"
+	"errors"
+	"net/http"
+	"path/filepath"
+
+	"github.com/gin-gonic/gin"
+)
+
+type StateMachineHandler struct {
+	stateMachineService *services.StateMachineService
+}
+
+func NewStateMachineHandler(stateMachineService *services.StateMachineService) *StateMachineHandler {
+	return &StateMachineHandler{
+		stateMachineService: stateMachineService,
+	}
+}
+
+// CheckStateMachines 按 config/can/state_machines.json 回放文件中的状态报文
+// 查询参数: machines（逗号分隔的状态机名，不区分大小写，为空时检查全部）
+// 违规中的行号与CAN协议的解析结果一致，可用 /api/parse 的offset定位
+func (h *StateMachineHandler) CheckStateMachines(c *gin.Context) {
+	filename := c.Param("filename")
+	if filepath.Base(filename) != filename {
+		c.JSON(http.StatusBadRequest, models.StateCheckResponse{
+			Success: false,
+			Message: "Invalid filename",
+		})
+		return
+	}
+
+	result, err := h.stateMachineService.Check(filename, splitList(c.Query("machines")))
+	if err != nil {
+		code := http.StatusInternalServerError
+		if errors.Is(err, services.ErrInvalidQuery) {
+			code = http.StatusBadRequest
+		} else {
+			utils.Error("检查状态机失败 %s: %v", filename, err)
+		}
+		c.JSON(code, models.StateCheckResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	violations := 0
+	for _, m := range result.Machines {
+		for _, n := range m.Counts {
+			violations += n
+		}
+	}
+	utils.Info("检查状态机: %s, %d 个状态机, 共 %d 条违规", filename, len(result.Machines), violations)
+	c.JSON(http.StatusOK, models.StateCheckResponse{
+		Success: true,
+		Message: "State machines checked successfully",
+		Data:    result,
+	})
+}
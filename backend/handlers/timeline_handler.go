@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"csv-parser/models"
+	"csv-parser/services"
+	"csv-parser/utils"
+	"errors"
+	"net/http"
+	"path/filepath"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TimelineHandler struct {
+	timelineService *services.TimelineService
+}
+
+func NewTimelineHandler(timelineService *services.TimelineService) *TimelineHandler {
+	return &TimelineHandler{
+		timelineService: timelineService,
+	}
+}
+
+// GetRTBTimeline 返回由RTB跳变事件重建的信号线波形（跳变时间、电平和脉宽）
+// 查询参数: lines（逗号分隔的信号线名，如 RTB2_XRAY_ON，不区分大小写）、from/to（时间范围，格式同 /api/parse）
+// 行号和时间戳与CAN协议的解析结果一致，可与 /api/parse 的数据对照
+func (h *TimelineHandler) GetRTBTimeline(c *gin.Context) {
+	filename := c.Param("filename")
+	if filepath.Base(filename) != filename {
+		c.JSON(http.StatusBadRequest, models.RTBTimelineResponse{
+			Success: false,
+			Message: "Invalid filename",
+		})
+		return
+	}
+
+	timeline, err := h.timelineService.RTBTimeline(filename, splitList(c.Query("lines")), c.Query("from"), c.Query("to"))
+	if err != nil {
+		code := http.StatusInternalServerError
+		if errors.Is(err, services.ErrInvalidQuery) {
+			code = http.StatusBadRequest
+		} else {
+			utils.Error("重建RTB时间线失败 %s: %v", filename, err)
+		}
+		c.JSON(code, models.RTBTimelineResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	utils.Info("重建RTB时间线: %s, 共 %d 条信号线", filename, len(timeline.Lines))
+	c.JSON(http.StatusOK, models.RTBTimelineResponse{
+		Success: true,
+		Message: "RTB timeline built successfully",
+		Data:    timeline,
+	})
+}
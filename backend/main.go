@@ -25,6 +25,22 @@ func main() {
 	// 初始化服务
 	csvService := services.NewCSVService("../uploads")
 	csvHandler := handlers.NewCSVHandler(csvService)
+	canopenService := services.NewCANopenService("../backend/config/canopen")
+	canopenHandler := handlers.NewCANopenHandler(canopenService, csvService)
+	dbcService := services.NewDBCService("../backend/config/can")
+	dbcHandler := handlers.NewDBCHandler(dbcService, csvService)
+	exportService := services.NewExportService(csvService)
+	exportHandler := handlers.NewExportHandler(exportService)
+	captureService := services.NewCaptureService("../uploads", "../backend/config/can", csvService)
+	captureHandler := handlers.NewCaptureHandler(captureService)
+	replayService := services.NewReplayService(exportService)
+	replayHandler := handlers.NewReplayHandler(replayService)
+	timelineService := services.NewTimelineService("../backend/config/can", csvService)
+	timelineHandler := handlers.NewTimelineHandler(timelineService)
+	exposureService := services.NewExposureService(csvService, timelineService)
+	exposureHandler := handlers.NewExposureHandler(exposureService)
+	stateMachineService := services.NewStateMachineService("../backend/config/can", csvService)
+	stateMachineHandler := handlers.NewStateMachineHandler(stateMachineService)
 
 	// 创建Gin路由
 	r := gin.Default()
@@ -56,6 +72,39 @@ func main() {
 		api.GET("/files", csvHandler.GetFiles)
 		api.GET("/parse/:filename", csvHandler.ParseFile)
 		api.DELETE("/file/:filename", csvHandler.DeleteFile)
+
+		// CANopen对象字典（EDS/DCF）
+		api.POST("/canopen/dictionaries", canopenHandler.ImportDictionary)
+		api.GET("/canopen/dictionaries", canopenHandler.GetDictionaries)
+		api.GET("/canopen/dictionaries/:nodeId", canopenHandler.GetDictionary)
+		api.DELETE("/canopen/dictionaries/:nodeId", canopenHandler.DeleteDictionary)
+
+		// DBC导入/导出（与CAN定义和数据解析配置互相转换）
+		api.POST("/can/dbc", dbcHandler.ImportDBC)
+		api.GET("/can/dbc", dbcHandler.ExportDBC)
+
+		// 解析结果导出
+		api.GET("/export/:filename", exportHandler.Export)
+
+		// SocketCAN实时抓包
+		api.POST("/capture/start", captureHandler.StartCapture)
+		api.POST("/capture/stop", captureHandler.StopCapture)
+		api.GET("/capture/status", captureHandler.GetCaptureStatus)
+		api.GET("/capture/stream", captureHandler.StreamCapture)
+
+		// 回放到SocketCAN接口
+		api.POST("/replay/start", replayHandler.StartReplay)
+		api.POST("/replay/stop", replayHandler.StopReplay)
+		api.GET("/replay/status", replayHandler.GetReplayStatus)
+
+		// RTB信号线时间线
+		api.GET("/timeline/:filename", timelineHandler.GetRTBTimeline)
+
+		// 曝光序列分析
+		api.GET("/exposures/:filename", exposureHandler.GetExposures)
+
+		// 协议状态机检查
+		api.GET("/state-check/:filename", stateMachineHandler.CheckStateMachines)
 	}
 
 	// 根路径直接提供前端index.html
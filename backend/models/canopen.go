@@ -0,0 +1,69 @@
+package models
+
+// ODEntry 对象字典中的一个条目（索引 + 子索引）
+type ODEntry struct {
+	Index      uint16 `json:"index"`
+	SubIndex   uint8  `json:"subIndex"`
+	Name       string `json:"name"`
+	ObjectType string `json:"objectType,omitempty"` // VAR, ARRAY, RECORD
+	DataType   string `json:"dataType,omitempty"`   // UNSIGNED16, VISIBLE_STRING ...
+	Access     string `json:"access,omitempty"`     // ro, wo, rw, rww, rwr, const
+	Default    string `json:"default,omitempty"`
+	Value      string `json:"value,omitempty"` // DCF中的ParameterValue
+	PDOMapping bool   `json:"pdoMapping"`
+}
+
+// ObjectDictionary 从EDS/DCF文件导入的设备对象字典
+type ObjectDictionary struct {
+	NodeID      uint8      `json:"nodeId"`
+	FileName    string     `json:"fileName"`
+	FileType    string     `json:"fileType"` // EDS 或 DCF
+	VendorName  string     `json:"vendorName,omitempty"`
+	ProductName string     `json:"productName,omitempty"`
+	Entries     []*ODEntry `json:"entries"`
+
+	lookup map[uint32]*ODEntry
+}
+
+// BuildIndex 建立 索引/子索引 -> 条目 的查找表，条目变化后需要重新调用
+func (d *ObjectDictionary) BuildIndex() {
+	d.lookup = make(map[uint32]*ODEntry, len(d.Entries))
+	for _, e := range d.Entries {
+		d.lookup[uint32(e.Index)<<8|uint32(e.SubIndex)] = e
+	}
+}
+
+// Entry 查找对象字典条目
+func (d *ObjectDictionary) Entry(index uint16, subIndex uint8) *ODEntry {
+	if d == nil {
+		return nil
+	}
+	if d.lookup == nil {
+		d.BuildIndex()
+	}
+	return d.lookup[uint32(index)<<8|uint32(subIndex)]
+}
+
+// ObjectDictionaryInfo 对象字典概要信息（列表接口使用）
+type ObjectDictionaryInfo struct {
+	NodeID      uint8  `json:"nodeId"`
+	FileName    string `json:"fileName"`
+	FileType    string `json:"fileType"`
+	VendorName  string `json:"vendorName,omitempty"`
+	ProductName string `json:"productName,omitempty"`
+	EntryCount  int    `json:"entryCount"`
+}
+
+// ObjectDictionaryResponse 对象字典导入/查询响应
+type ObjectDictionaryResponse struct {
+	Success    bool              `json:"success"`
+	Message    string            `json:"message"`
+	Dictionary *ObjectDictionary `json:"dictionary,omitempty"`
+}
+
+// ObjectDictionaryListResponse 对象字典列表响应
+type ObjectDictionaryListResponse struct {
+	Success      bool                    `json:"success"`
+	Message      string                  `json:"message"`
+	Dictionaries []*ObjectDictionaryInfo `json:"dictionaries,omitempty"`
+}
@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+// CaptureStatus 实时抓包的状态
+type CaptureStatus struct {
+	Running      bool           `json:"running"`
+	Interface    string         `json:"interface"`    // SocketCAN接口，如 can0、vcan0
+	ProtocolType string         `json:"protocolType"` // 抓包文件的协议类型: CAN 或 CANOPEN
+	Filename     string         `json:"filename"`     // uploads目录下的抓包文件（candump日志）
+	StartTime    time.Time      `json:"startTime"`
+	StopTime     *time.Time     `json:"stopTime,omitempty"`
+	FrameCount   int64          `json:"frameCount"`
+	Error        string         `json:"error,omitempty"` // 抓包异常结束的原因
+	Recent       []CaptureFrame `json:"recent"`          // 最近收到的帧（按时间先后）
+}
+
+// CaptureFrame 实时抓包收到的一帧，已按definitions.json和data_parser.json解析
+type CaptureFrame struct {
+	Time      string         `json:"time"`     // 嗅探器CSV的时间格式
+	Relative  float64        `json:"relative"` // 相对抓包开始的秒数
+	ID        string         `json:"id"`       // 0x2CF 形式的CAN ID
+	Name      string         `json:"name"`     // 与嗅探器CSV的Name列一致（日志帧为通道名称）
+	Channel   string         `json:"channel"`
+	Direction string         `json:"direction"`
+	Buffer    string         `json:"buffer"` // 嗅探器CSV的Buffer格式
+	Flags     string         `json:"flags,omitempty"`
+	Meaning   string         `json:"meaning,omitempty"`
+	Decoded   []DecodedField `json:"decoded,omitempty"`
+	Highlight *RowHighlight  `json:"highlight,omitempty"` // 按row_highlight.json匹配到的高亮规则
+}
+
+// RowHighlight row_highlight.json中的一条高亮规则
+type RowHighlight struct {
+	Match           string `json:"match"`
+	MatchType       string `json:"matchType"` // contains、equals、startsWith、endsWith
+	BackgroundColor string `json:"backgroundColor,omitempty"`
+	TextColor       string `json:"textColor,omitempty"`
+	Description     string `json:"description,omitempty"`
+}
+
+// LiveFilter 实时查看的服务端过滤条件
+type LiveFilter struct {
+	IDs        []string `json:"ids,omitempty"`        // 只推送这些CAN ID（十六进制，可带0x前缀）
+	ExcludeIDs []string `json:"excludeIds,omitempty"` // 不推送的CAN ID
+	Names      []string `json:"names,omitempty"`      // Name或Meaning包含任一关键字（不区分大小写）
+}
+
+// CaptureResponse 抓包接口的响应
+type CaptureResponse struct {
+	Success bool           `json:"success"`
+	Message string         `json:"message"`
+	Status  *CaptureStatus `json:"status,omitempty"`
+}
@@ -11,14 +11,59 @@ type CSVFile struct {
 	UploadTime   time.Time `json:"uploadTime"`
 	RowCount     int       `json:"rowCount"`
 	ColumnCount  int       `json:"columnCount"`
-	ProtocolType string    `json:"protocolType"` // 协议类型: CAN 或 CANOPEN
+	ProtocolType string    `json:"protocolType"`    // 协议类型: CAN 或 CANOPEN
+	Format       string    `json:"format"`          // 按内容识别的文件格式，如 sniffer_csv、candump、asc
+	Error        string    `json:"error,omitempty"` // 文件无法识别或无效时的原因，此时不能解析
 }
 
 // CSVData 表示解析后的CSV数据
 type CSVData struct {
-	Headers []string   `json:"headers"`
-	Rows    [][]string `json:"rows"`
-	Total   int        `json:"total"`
+	Headers []string         `json:"headers"`
+	Rows    [][]string       `json:"rows"`
+	Decoded [][]DecodedField `json:"decoded,omitempty"` // 与Rows一一对应的解码字段
+	Total   int              `json:"total"`
+	// 以下字段只在分页查询的响应中有意义
+	Filtered int `json:"filtered"`        // 满足过滤条件的行数（分页前）
+	Offset   int `json:"offset"`          // 本页第一行在过滤结果中的位置
+	Limit    int `json:"limit,omitempty"` // 每页行数，0表示不分页
+}
+
+// ParseQuery /api/parse 的分页、排序和过滤参数，在服务端对解析结果（缓存）求值
+type ParseQuery struct {
+	Offset int    `json:"offset"`
+	Limit  int    `json:"limit"` // 0表示返回全部
+	Sort   string `json:"sort"`  // time、id、name，空表示保持文件顺序
+	Order  string `json:"order"` // asc（默认）或 desc
+
+	IDs        []string `json:"ids,omitempty"`        // 消息ID（Buffer中的CAN ID，没有时为Name），与预览页一致
+	ExcludeIDs []string `json:"excludeIds,omitempty"` // 排除的消息ID
+	Types      []string `json:"types,omitempty"`      // Type列，如 publish、receive
+	Sources    []string `json:"sources,omitempty"`    // Source列
+	Targets    []string `json:"targets,omitempty"`    // Target列
+	Names      []string `json:"names,omitempty"`      // Name列
+	From       string   `json:"from,omitempty"`       // 时间范围起点（包含），Time列格式、2006-01-02 15:04:05、日期或RFC3339
+	To         string   `json:"to,omitempty"`         // 时间范围终点（包含）
+	Text       string   `json:"text,omitempty"`       // 任一列包含该文本（不区分大小写）
+	Fields     []string `json:"fields,omitempty"`     // 解码字段条件 "字段名=值" 或 "字段名"（只要求存在），多个条件同时满足
+}
+
+// IsEmpty 没有任何分页、排序和过滤条件时返回true，此时按原样返回全部数据
+func (q ParseQuery) IsEmpty() bool {
+	return q.Offset == 0 && q.Limit == 0 && q.Sort == "" &&
+		len(q.IDs) == 0 && len(q.ExcludeIDs) == 0 && len(q.Types) == 0 && len(q.Sources) == 0 && len(q.Targets) == 0 && len(q.Names) == 0 &&
+		q.From == "" && q.To == "" && q.Text == "" && len(q.Fields) == 0
+}
+
+// DecodedField 表示按data_parser.json解码出的单个字段
+type DecodedField struct {
+	Name    string         `json:"name"`
+	Bytes   string         `json:"bytes,omitempty"` // 字节范围，如 "4-5"
+	Type    string         `json:"type,omitempty"`
+	Raw     string         `json:"raw,omitempty"` // 原始字节（十六进制，空格分隔）
+	Value   *float64       `json:"value,omitempty"`
+	Unit    string         `json:"unit,omitempty"`
+	Display string         `json:"display"`
+	Fields  []DecodedField `json:"fields,omitempty"` // bitfield类型的子字段
 }
 
 // UploadResponse 上传响应
@@ -42,3 +87,28 @@ type FileListResponse struct {
 	Message string     `json:"message"`
 	Files   []*CSVFile `json:"files,omitempty"`
 }
+
+// CANFrame 表示一帧CAN报文
+type CANFrame struct {
+	ID         uint32 `json:"id"`
+	Extended   bool   `json:"extended"`             // 29位扩展帧
+	Remote     bool   `json:"remote,omitempty"`     // 远程帧（RTR），没有数据字节
+	ErrorFrame bool   `json:"errorFrame,omitempty"` // 错误帧
+	FD         bool   `json:"fd,omitempty"`         // CAN FD帧
+	DLC        int    `json:"dlc"`                  // 声明的数据长度（字节数）
+	Data       []byte `json:"data"`                 // 实际数据字节
+}
+
+// DLCMismatch 判断声明的DLC与实际数据字节数是否不一致（远程帧没有数据字节）
+func (f *CANFrame) DLCMismatch() bool {
+	return !f.Remote && f.DLC != len(f.Data)
+}
+
+// LogFrame 表示从日志文件（candump、ASC、TRC等）读取的一帧报文及其记录信息
+type LogFrame struct {
+	CANFrame
+	Time      time.Time     `json:"time"`                // 绝对时间
+	Relative  time.Duration `json:"relative"`            // 相对记录起始的时间
+	Channel   string        `json:"channel,omitempty"`   // 通道/接口，如 can0、1
+	Direction string        `json:"direction,omitempty"` // Rx / Tx，未知时为空
+}
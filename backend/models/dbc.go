@@ -0,0 +1,25 @@
+package models
+
+import "encoding/json"
+
+// DBCImportResult DBC导入结果
+type DBCImportResult struct {
+	FileName     string          `json:"fileName"`
+	MessageCount int             `json:"messageCount"`
+	SignalCount  int             `json:"signalCount"`
+	Added        []string        `json:"added"`     // 新增的CAN ID（小写十六进制）
+	Updated      []string        `json:"updated"`   // 已有配置的CAN ID
+	Replaced     []string        `json:"replaced"`  // overwrite模式下被整体替换的data_parser.json条目
+	Skipped      []string        `json:"skipped"`   // 合并模式下与已有字节规则重叠而未导入的信号（"ID/信号名"）
+	Applied      bool            `json:"applied"`   // false表示仅预览，未写入配置文件
+	Overwrite    bool            `json:"overwrite"` // true表示已有条目被DBC生成的配置整体替换
+	Definitions  json.RawMessage `json:"definitions"`
+	DataParser   json.RawMessage `json:"dataParser"`
+}
+
+// DBCImportResponse DBC导入响应
+type DBCImportResponse struct {
+	Success bool             `json:"success"`
+	Message string           `json:"message"`
+	Result  *DBCImportResult `json:"result,omitempty"`
+}
@@ -0,0 +1,63 @@
+package models
+
+// 曝光结果
+const (
+	ExposureCompleted  = "completed"  // HV ON后回到HV OFF
+	ExposureAborted    = "aborted"    // 准备后没有HV ON就停止或开始了下一次曝光
+	ExposureError      = "error"      // 曝光过程中发生器报告Error
+	ExposureIncomplete = "incomplete" // HV ON后没有看到HV OFF（文件结束或被下一次曝光打断）
+)
+
+// ExposureParams 曝光参数，没有收到的参数为nil
+type ExposureParams struct {
+	KV     *float64 `json:"kv,omitempty"`
+	MA     *float64 `json:"ma,omitempty"`
+	TimeMs *float64 `json:"timeMs,omitempty"`
+	MAs    *float64 `json:"mas,omitempty"`
+	Rows   []int    `json:"rows"` // 参数来源的行号
+}
+
+// ExposurePhase 发生器状态（0x208 Phase）的一次变化
+type ExposurePhase struct {
+	Row   int    `json:"row"`
+	Time  string `json:"time"`
+	Code  string `json:"code"`  // Phase字节，如 "05"
+	Phase string `json:"phase"` // 按data_parser.json显示的名称，如 "HV ON"
+}
+
+// Exposure 从日志中切分出的一次曝光，行号为CAN解析结果中的行号（/api/parse 的offset）
+type Exposure struct {
+	Index     int    `json:"index"`
+	StartRow  int    `json:"startRow"`
+	EndRow    int    `json:"endRow"`
+	Start     int64  `json:"start"` // Unix纳秒
+	End       int64  `json:"end"`
+	StartTime string `json:"startTime"`
+	EndTime   string `json:"endTime"`
+
+	PrepDurationMs   *float64 `json:"prepDurationMs,omitempty"`   // 准备（Prep命令或PrepInProgress）到Ready
+	HVOnDurationMs   *float64 `json:"hvOnDurationMs,omitempty"`   // HV ON到HV OFF
+	XRayOnDurationMs *float64 `json:"xrayOnDurationMs,omitempty"` // RTB X射线信号的上升沿到下降沿
+
+	Phases     []ExposurePhase    `json:"phases"`
+	Commanded  *ExposureParams    `json:"commanded,omitempty"`     // HV ON前最后设置的参数（0x1E0/0x1E1）
+	Reported   *ExposureParams    `json:"reported,omitempty"`      // HV ON后发生器报告的实际参数（0x2BC/0x2C1）
+	Deviations map[string]float64 `json:"deviationsPct,omitempty"` // 实际参数相对设置参数的偏差（百分比）
+
+	Outcome string `json:"outcome"`
+	Error   string `json:"error,omitempty"` // 发生器报告Error时的错误信息
+}
+
+// ExposureList 一个文件中的全部曝光
+type ExposureList struct {
+	Total     int            `json:"total"`
+	Outcomes  map[string]int `json:"outcomes"` // 各结果的曝光数
+	Exposures []Exposure     `json:"exposures"`
+}
+
+// ExposureResponse 曝光分析接口的响应
+type ExposureResponse struct {
+	Success bool          `json:"success"`
+	Message string        `json:"message"`
+	Data    *ExposureList `json:"data,omitempty"`
+}
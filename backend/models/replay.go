@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// ReplayStatus 日志回放的状态
+type ReplayStatus struct {
+	Running     bool       `json:"running"`
+	Filename    string     `json:"filename"`
+	Interface   string     `json:"interface"`
+	Speed       float64    `json:"speed"` // 回放速度倍数，2表示两倍速
+	Loop        bool       `json:"loop"`
+	IDs         []string   `json:"ids,omitempty"`
+	ExcludeIDs  []string   `json:"excludeIds,omitempty"`
+	TotalFrames int        `json:"totalFrames"` // 每轮回放的帧数
+	SentFrames  int64      `json:"sentFrames"`  // 已发送的帧数（累计所有轮次）
+	Loops       int        `json:"loops"`       // 已完成的轮数
+	StartTime   time.Time  `json:"startTime"`
+	StopTime    *time.Time `json:"stopTime,omitempty"`
+	Error       string     `json:"error,omitempty"`
+}
+
+// ReplayResponse 回放接口的响应
+type ReplayResponse struct {
+	Success bool          `json:"success"`
+	Message string        `json:"message"`
+	Status  *ReplayStatus `json:"status,omitempty"`
+}
@@ -0,0 +1,50 @@
+package models
+
+// 状态机检查的违规类型
+const (
+	StateIllegalTransition = "illegal_transition" // 配置中不允许的状态跳变
+	StateMissing           = "missing_state"      // 跳过了中间状态（经过一个中间状态即为合法跳变）
+	StateUnknown           = "unknown_state"      // 配置中没有定义的状态
+	StateUnexpectedInitial = "unexpected_initial" // 文件中的第一个状态不在initial中
+	StateTimeout           = "timeout"            // 状态持续时间超过timeoutMs
+)
+
+// StateViolation 一条状态机违规，行号为CAN解析结果中的行号（/api/parse 的offset）
+type StateViolation struct {
+	Kind       string   `json:"kind"`
+	Row        int      `json:"row"`     // 发现违规的行
+	Time       string   `json:"time"`    // Time列原文
+	FromRow    int      `json:"fromRow"` // 进入上一个状态的行，没有时为-1
+	From       string   `json:"from,omitempty"`
+	FromName   string   `json:"fromName,omitempty"`
+	To         string   `json:"to,omitempty"`
+	ToName     string   `json:"toName,omitempty"`
+	Expected   []string `json:"expected,omitempty"`   // 允许的下一状态，missing_state时为跳过的中间状态
+	DurationMs *float64 `json:"durationMs,omitempty"` // timeout时状态持续的时间
+	Message    string   `json:"message"`
+}
+
+// StateMachineReport 一个状态机的检查结果
+type StateMachineReport struct {
+	Name        string           `json:"name"`
+	Description string           `json:"description,omitempty"`
+	MessageID   string           `json:"messageId"`
+	Field       string           `json:"field"`
+	Rows        int              `json:"rows"`        // 参与检查的行数
+	Transitions int              `json:"transitions"` // 状态变化次数（同一状态的重复报文不计）
+	Counts      map[string]int   `json:"counts"`      // 各类违规的数量
+	Violations  []StateViolation `json:"violations"`
+	Truncated   bool             `json:"truncated,omitempty"` // 违规过多，只返回了前一部分，counts仍为全部数量
+}
+
+// StateCheckResult 一个文件的状态机检查结果
+type StateCheckResult struct {
+	Machines []StateMachineReport `json:"machines"`
+}
+
+// StateCheckResponse 状态机检查接口的响应
+type StateCheckResponse struct {
+	Success bool              `json:"success"`
+	Message string            `json:"message"`
+	Data    *StateCheckResult `json:"data,omitempty"`
+}
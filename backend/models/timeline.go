@@ -0,0 +1,48 @@
+package models
+
+// RTBEdge RTB信号线的一次跳变，对应解析结果中的一行
+type RTBEdge struct {
+	Row       int     `json:"row"`                // CAN解析结果中的行号（/api/parse 的offset）
+	Name      string  `json:"name"`               // 原始Name，如 RTB2_XRAY_ON_HI
+	Time      string  `json:"time"`               // Time列原文
+	Timestamp int64   `json:"timestamp"`          // Unix纳秒，与解析结果的时间戳(ns)列一致
+	Relative  float64 `json:"relative"`           // 相对解析结果第一行的秒数，与相对时间(s)列一致
+	Level     int     `json:"level"`              // 跳变后的电平：1为高，0为低
+	Repeated  bool    `json:"repeated,omitempty"` // 电平与之前相同（重复的跳变记录）
+}
+
+// RTBPulse 信号线的一段高电平
+type RTBPulse struct {
+	Start    int64   `json:"start"` // Unix纳秒
+	End      int64   `json:"end"`
+	StartRow int     `json:"startRow"`
+	EndRow   int     `json:"endRow"`         // 没有下降沿时为-1
+	WidthMs  float64 `json:"widthMs"`        // 脉宽（毫秒）
+	Open     bool    `json:"open,omitempty"` // 到文件结尾仍为高电平，End为最后一行的时间
+}
+
+// RTBLine 由跳变事件重建的一条RTB信号线波形
+// 文件开头的电平按低电平处理，Edges按时间排序，电平在两次跳变之间保持不变
+type RTBLine struct {
+	Name        string     `json:"name"` // 去掉 _HI/_LO 后缀的信号线名，如 RTB2_XRAY_ON
+	Description string     `json:"description,omitempty"`
+	VirtualID   string     `json:"virtualId,omitempty"`
+	Edges       []RTBEdge  `json:"edges"`
+	Pulses      []RTBPulse `json:"pulses"`
+	HighTimeMs  float64    `json:"highTimeMs"` // 整个文件中高电平的总时长（毫秒）
+	Repeated    int        `json:"repeated"`   // 整个文件中重复的跳变记录数
+}
+
+// RTBTimeline 一个文件中全部RTB信号线的时间线
+type RTBTimeline struct {
+	Start int64     `json:"start"` // 解析结果第一行的时间（Unix纳秒），相对时间以此为零点
+	End   int64     `json:"end"`   // 解析结果中最晚的时间
+	Lines []RTBLine `json:"lines"`
+}
+
+// RTBTimelineResponse RTB时间线接口的响应
+type RTBTimelineResponse struct {
+	Success bool         `json:"success"`
+	Message string       `json:"message"`
+	Data    *RTBTimeline `json:"data,omitempty"`
+}
@@ -0,0 +1,122 @@
+// Package rowcache 解析结果的列式缓存文件，支持按行号随机读取、按消息ID和时间范围过滤，无需载入全部数据
+//
+// 文件结构:
+//
+//	magic(8) | 数据块... | 索引(flate压缩) | 索引偏移(8) | 索引长度(8) | magic(8)
+//
+// 每个数据块包含BlockRows行，按列分段存储，每段单独flate压缩，读取某一列时只解压该列:
+//
+//	行宽 | 时间 | 消息ID | 解码字段 | 第0列 | 第1列 | ...
+//
+// 索引包含调用方的输出版本、表头、消息ID字典、每个ID出现的数据块列表，以及每个数据块的行数、时间范围和各段的位置。
+// 输出版本由调用方在解析输出变化时递增，读取时与期望的版本比较，不一致的缓存应重新生成。
+// 时间以Unix纳秒存储，无法解析时为NoTime。
+package rowcache
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+const (
+	magic   = "CSVROWC1"
+	version = 2
+
+	// BlockRows 每个数据块的行数
+	BlockRows = 4096
+
+	// NoTime 行的时间无法解析
+	NoTime = math.MinInt64
+
+	// footerTrailerSize 文件末尾的索引偏移、索引长度和magic
+	footerTrailerSize = 8 + 8 + len(magic)
+)
+
+// 数据块中各段的顺序，列数据从chunkColumns开始
+const (
+	chunkWidths = iota
+	chunkTimes
+	chunkIDs
+	chunkDecoded
+	chunkColumns
+)
+
+// ErrInvalidCache 缓存文件格式错误或版本不一致
+var ErrInvalidCache = errors.New("invalid row cache file")
+
+// chunk 数据块中一段的位置
+type chunk struct {
+	offset int64
+	length int64
+}
+
+// blockInfo 数据块的索引信息
+type blockInfo struct {
+	start   int // 第一行的行号
+	rows    int
+	minTime int64 // 块内可解析时间的范围，没有时均为NoTime
+	maxTime int64
+	chunks  []chunk
+}
+
+// decoder 按写入顺序读取uvarint/varint/字符串，出错后的读取都返回零值
+type decoder struct {
+	buf []byte
+	err error
+}
+
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(d.buf)
+	if n <= 0 {
+		d.err = ErrInvalidCache
+		return 0
+	}
+	d.buf = d.buf[n:]
+	return v
+}
+
+func (d *decoder) varint() int64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(d.buf)
+	if n <= 0 {
+		d.err = ErrInvalidCache
+		return 0
+	}
+	d.buf = d.buf[n:]
+	return v
+}
+
+// count 读取长度，超过剩余字节数时视为格式错误（避免按损坏的长度分配内存）
+func (d *decoder) count() int {
+	n := d.uvarint()
+	if n > uint64(len(d.buf)) {
+		d.err = ErrInvalidCache
+		return 0
+	}
+	return int(n)
+}
+
+func (d *decoder) bytes() []byte {
+	n := d.count()
+	if d.err != nil {
+		return nil
+	}
+	b := d.buf[:n:n]
+	d.buf = d.buf[n:]
+	return b
+}
+
+func (d *decoder) string() string {
+	return string(d.bytes())
+}
+
+func appendString(buf []byte, s string) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(s)))
+	return append(buf, s...)
+}
@@ -0,0 +1,470 @@
+package rowcache
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// Reader 缓存文件读取器，打开时只读取索引，数据块按需读取和解压，每个请求单独打开
+type Reader struct {
+	file       *os.File
+	size       int64
+	schema     uint64
+	headers    []string
+	rows       int
+	hasDecoded bool
+	ids        []string
+	idBlocks   [][]int
+	blocks     []blockInfo
+}
+
+// Open 打开缓存文件并读取索引
+func Open(path string) (*Reader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	r := &Reader{file: file}
+	if err := r.readFooter(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *Reader) readFooter() error {
+	info, err := r.file.Stat()
+	if err != nil {
+		return err
+	}
+	size := info.Size()
+	r.size = size
+	if size < int64(len(magic)+footerTrailerSize) {
+		return ErrInvalidCache
+	}
+	head := make([]byte, len(magic))
+	if _, err := r.file.ReadAt(head, 0); err != nil || string(head) != magic {
+		return ErrInvalidCache
+	}
+	trailer := make([]byte, footerTrailerSize)
+	if _, err := r.file.ReadAt(trailer, size-int64(footerTrailerSize)); err != nil {
+		return err
+	}
+	if string(trailer[16:]) != magic {
+		return ErrInvalidCache
+	}
+	footerChunk := chunk{
+		offset: int64(binary.LittleEndian.Uint64(trailer[0:8])),
+		length: int64(binary.LittleEndian.Uint64(trailer[8:16])),
+	}
+	if !r.validChunk(footerChunk) {
+		return ErrInvalidCache
+	}
+	footer, err := r.readChunk(footerChunk)
+	if err != nil {
+		return err
+	}
+
+	d := &decoder{buf: footer}
+	if v := d.uvarint(); v != version {
+		return fmt.Errorf("%w: version %d", ErrInvalidCache, v)
+	}
+	r.schema = d.uvarint()
+	r.headers = make([]string, d.count())
+	for i := range r.headers {
+		r.headers[i] = d.string()
+	}
+	r.rows = int(d.uvarint())
+	if len(d.buf) > 0 {
+		r.hasDecoded = d.buf[0] == 1
+		d.buf = d.buf[1:]
+	}
+
+	r.ids = make([]string, d.count())
+	r.idBlocks = make([][]int, len(r.ids))
+	for i := range r.ids {
+		r.ids[i] = d.string()
+		blocks := make([]int, d.count())
+		prev := 0
+		for j := range blocks {
+			prev += int(d.uvarint())
+			blocks[j] = prev
+		}
+		r.idBlocks[i] = blocks
+	}
+
+	r.blocks = make([]blockInfo, d.count())
+	start := 0
+	for i := range r.blocks {
+		b := blockInfo{start: start}
+		b.rows = int(d.uvarint())
+		b.minTime = d.varint()
+		b.maxTime = d.varint()
+		b.chunks = make([]chunk, d.count())
+		for j := range b.chunks {
+			b.chunks[j] = chunk{offset: int64(d.uvarint()), length: int64(d.uvarint())}
+			if !r.validChunk(b.chunks[j]) {
+				return ErrInvalidCache
+			}
+		}
+		if len(b.chunks) < chunkColumns {
+			return ErrInvalidCache
+		}
+		r.blocks[i] = b
+		start += b.rows
+	}
+	if d.err != nil {
+		return d.err
+	}
+	if start != r.rows {
+		return ErrInvalidCache
+	}
+	return nil
+}
+
+// validChunk 数据段必须位于文件头magic和末尾的索引位置之间，避免按损坏的长度分配内存
+func (r *Reader) validChunk(c chunk) bool {
+	return c.offset >= int64(len(magic)) && c.length >= 0 && c.offset <= r.size-int64(footerTrailerSize)-c.length
+}
+
+// readChunk 读取并解压一段数据
+func (r *Reader) readChunk(c chunk) ([]byte, error) {
+	if !r.validChunk(c) {
+		return nil, ErrInvalidCache
+	}
+	compressed := make([]byte, c.length)
+	if _, err := r.file.ReadAt(compressed, c.offset); err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(flate.NewReader(bytes.NewReader(compressed)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCache, err)
+	}
+	return data, nil
+}
+
+// Close 关闭缓存文件
+func (r *Reader) Close() error {
+	return r.file.Close()
+}
+
+// Schema 写入时的输出版本
+func (r *Reader) Schema() uint64 {
+	return r.schema
+}
+
+// Headers 表头
+func (r *Reader) Headers() []string {
+	return r.headers
+}
+
+// Len 总行数
+func (r *Reader) Len() int {
+	return r.rows
+}
+
+// HasDecoded 是否有行包含解码字段
+func (r *Reader) HasDecoded() bool {
+	return r.hasDecoded
+}
+
+// IDs 文件中出现的全部消息ID（写入时的形式）
+func (r *Reader) IDs() []string {
+	return r.ids
+}
+
+// Row 扫描时读取到的一行，只有ScanOptions中要求的部分有值
+type Row struct {
+	Index   int      // 行号
+	Cells   []string // 单元格，未要求的列为空字符串
+	Decoded []byte   // 解码字段的JSON，没有时为空
+	Time    int64    // Unix纳秒，无法解析时为NoTime
+	ID      int      // 消息ID在IDs()中的序号
+}
+
+// ScanOptions 扫描的行和列
+type ScanOptions struct {
+	Rows    []int // 要读取的行号（升序），nil表示全部行
+	Columns []int // 要读取的列，nil表示全部列，空切片表示不读取单元格
+	Decoded bool
+	Time    bool
+	ID      bool
+}
+
+// Scan 按行号顺序读取行，只解压需要的数据块和列；fn返回错误时停止扫描并返回该错误
+// fn收到的Row在下一次回调时会被复用，需要保留时应复制
+func (r *Reader) Scan(opts ScanOptions, fn func(row *Row) error) error {
+	rows := opts.Rows
+	for bi := range r.blocks {
+		b := &r.blocks[bi]
+		var blockRows []int
+		if opts.Rows != nil {
+			// 取出落在本块内的行号
+			n := sort.SearchInts(rows, b.start+b.rows)
+			blockRows, rows = rows[:n], rows[n:]
+			if len(blockRows) == 0 {
+				if len(rows) == 0 {
+					return nil
+				}
+				continue
+			}
+		}
+		if err := r.scanBlock(b, opts, blockRows, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scanBlock 读取一个数据块中的行，blockRows为nil时读取整块
+func (r *Reader) scanBlock(b *blockInfo, opts ScanOptions, blockRows []int, fn func(row *Row) error) error {
+	widths, err := r.blockUvarints(b, chunkWidths)
+	if err != nil {
+		return err
+	}
+
+	var times []int64
+	if opts.Time {
+		if times, err = r.blockTimes(b); err != nil {
+			return err
+		}
+	}
+	var ids []uint64
+	if opts.ID {
+		if ids, err = r.blockUvarints(b, chunkIDs); err != nil {
+			return err
+		}
+	}
+	var decoded [][]byte
+	if opts.Decoded {
+		if decoded, err = r.blockStrings(b, chunkDecoded); err != nil {
+			return err
+		}
+	}
+
+	columns := opts.Columns
+	if columns == nil {
+		columns = make([]int, len(b.chunks)-chunkColumns)
+		for i := range columns {
+			columns[i] = i
+		}
+	}
+	values := make([][][]byte, len(b.chunks)-chunkColumns)
+	for _, col := range columns {
+		if col < 0 || col >= len(values) || values[col] != nil {
+			continue
+		}
+		if values[col], err = r.blockStrings(b, chunkColumns+col); err != nil {
+			return err
+		}
+	}
+
+	row := &Row{}
+	emit := func(i int) error {
+		width := int(widths[i])
+		if cap(row.Cells) < width {
+			row.Cells = make([]string, width)
+		}
+		row.Cells = row.Cells[:width]
+		for col := range row.Cells {
+			row.Cells[col] = ""
+			if col < len(values) && values[col] != nil {
+				row.Cells[col] = string(values[col][i])
+			}
+		}
+		row.Index = b.start + i
+		row.Time = NoTime
+		if times != nil {
+			row.Time = times[i]
+		}
+		row.ID = 0
+		if ids != nil {
+			row.ID = int(ids[i])
+		}
+		row.Decoded = nil
+		if decoded != nil {
+			row.Decoded = decoded[i]
+		}
+		return fn(row)
+	}
+
+	if blockRows == nil {
+		for i := 0; i < b.rows; i++ {
+			if err := emit(i); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, index := range blockRows {
+		if err := emit(index - b.start); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// blockUvarints 读取一个数据块中的uvarint段（行宽、消息ID）
+func (r *Reader) blockUvarints(b *blockInfo, part int) ([]uint64, error) {
+	data, err := r.readChunk(b.chunks[part])
+	if err != nil {
+		return nil, err
+	}
+	d := &decoder{buf: data}
+	values := make([]uint64, b.rows)
+	for i := range values {
+		values[i] = d.uvarint()
+	}
+	return values, d.err
+}
+
+// blockTimes 读取一个数据块的时间段，还原块内差值
+func (r *Reader) blockTimes(b *blockInfo) ([]int64, error) {
+	data, err := r.readChunk(b.chunks[chunkTimes])
+	if err != nil {
+		return nil, err
+	}
+	d := &decoder{buf: data}
+	times := make([]int64, b.rows)
+	var prev int64
+	for i := range times {
+		prev = int64(uint64(prev) + uint64(d.varint()))
+		times[i] = prev
+	}
+	return times, d.err
+}
+
+// blockStrings 读取一个数据块的字符串段（解码字段、各列）
+func (r *Reader) blockStrings(b *blockInfo, part int) ([][]byte, error) {
+	data, err := r.readChunk(b.chunks[part])
+	if err != nil {
+		return nil, err
+	}
+	d := &decoder{buf: data}
+	values := make([][]byte, b.rows)
+	for i := range values {
+		values[i] = d.bytes()
+	}
+	return values, d.err
+}
+
+// RowsWithIDs 返回消息ID在ids中的行号（升序），只读取包含这些ID的数据块的ID段
+func (r *Reader) RowsWithIDs(ids map[string]bool) ([]int, error) {
+	wanted := make(map[int]bool)
+	blockSet := make(map[int]bool)
+	for i, id := range r.ids {
+		if ids[id] {
+			wanted[i] = true
+			for _, b := range r.idBlocks[i] {
+				blockSet[b] = true
+			}
+		}
+	}
+
+	rows := []int{}
+	for bi := range r.blocks {
+		if !blockSet[bi] {
+			continue
+		}
+		b := &r.blocks[bi]
+		blockIDs, err := r.blockUvarints(b, chunkIDs)
+		if err != nil {
+			return nil, err
+		}
+		for i, id := range blockIDs {
+			if wanted[int(id)] {
+				rows = append(rows, b.start+i)
+			}
+		}
+	}
+	return rows, nil
+}
+
+// RowsInTimeRange 从rows（升序，nil表示全部行）中筛选时间在[from, to]内的行，
+// from或to为NoTime表示不限；时间范围不相交的数据块直接跳过
+func (r *Reader) RowsInTimeRange(rows []int, from, to int64) ([]int, error) {
+	result := []int{}
+	for bi := range r.blocks {
+		b := &r.blocks[bi]
+		var blockRows []int
+		if rows != nil {
+			n := sort.SearchInts(rows, b.start+b.rows)
+			blockRows, rows = rows[:n], rows[n:]
+			if len(blockRows) == 0 {
+				continue
+			}
+		}
+		if b.minTime == NoTime || (from != NoTime && b.maxTime < from) || (to != NoTime && b.minTime > to) {
+			continue
+		}
+
+		times, err := r.blockTimes(b)
+		if err != nil {
+			return nil, err
+		}
+		match := func(i int) bool {
+			t := times[i]
+			return t != NoTime && (from == NoTime || t >= from) && (to == NoTime || t <= to)
+		}
+		if blockRows == nil {
+			for i := range times {
+				if match(i) {
+					result = append(result, b.start+i)
+				}
+			}
+			continue
+		}
+		for _, index := range blockRows {
+			if match(index - b.start) {
+				result = append(result, index)
+			}
+		}
+	}
+	return result, nil
+}
+
+// ReadRows 按给定顺序读取行（可以无序或重复），返回单元格和解码字段JSON
+func (r *Reader) ReadRows(indexes []int, withDecoded bool) ([][]string, [][]byte, error) {
+	order := make([]int, len(indexes))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return indexes[order[a]] < indexes[order[b]] })
+	sorted := make([]int, 0, len(indexes))
+	for _, i := range order {
+		if n := len(sorted); n == 0 || sorted[n-1] != indexes[i] {
+			sorted = append(sorted, indexes[i])
+		}
+	}
+
+	cells := make(map[int][]string, len(sorted))
+	decoded := make(map[int][]byte)
+	err := r.Scan(ScanOptions{Rows: sorted, Decoded: withDecoded}, func(row *Row) error {
+		cells[row.Index] = append([]string(nil), row.Cells...)
+		if len(row.Decoded) > 0 {
+			decoded[row.Index] = append([]byte(nil), row.Decoded...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rows := make([][]string, len(indexes))
+	var decodedRows [][]byte
+	if withDecoded {
+		decodedRows = make([][]byte, len(indexes))
+	}
+	for i, index := range indexes {
+		rows[i] = cells[index]
+		if withDecoded {
+			decodedRows[i] = decoded[index]
+		}
+	}
+	return rows, decodedRows, nil
+}
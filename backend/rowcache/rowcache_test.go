@@ -0,0 +1,362 @@
+package rowcache
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// testRows 跨越三个数据块的测试行数
+const testRows = 2*BlockRows + 100
+
+// testID 前两个数据块交替使用A、B，C只出现在最后一个数据块
+func testID(i int) string {
+	if i >= 2*BlockRows {
+		return "C"
+	}
+	if i%2 == 0 {
+		return "A"
+	}
+	return "B"
+}
+
+// testTime 每行间隔1ms，行号是7的倍数的行没有时间
+func testTime(i int) int64 {
+	if i%7 == 0 {
+		return NoTime
+	}
+	return int64(i) * 1e6
+}
+
+func testCells(i int) []string {
+	cells := []string{strconv.Itoa(i), testID(i), "payload " + strconv.Itoa(i)}
+	// 行宽可以不一致
+	if i%5 == 0 {
+		cells = cells[:2]
+	}
+	return cells
+}
+
+func testDecoded(i int) []byte {
+	if i%3 != 0 {
+		return nil
+	}
+	return []byte(`[{"name":"n","display":"` + strconv.Itoa(i) + `"}]`)
+}
+
+// writeTestCache 写入testRows行测试数据并返回文件路径
+func writeTestCache(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.cache")
+	w, err := Create(path, 7, []string{"Index", "ID", "Data"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < testRows; i++ {
+		if err := w.Add(testCells(i), testDecoded(i), testID(i), testTime(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if w.Rows() != testRows {
+		t.Fatalf("Rows() = %d, want %d", w.Rows(), testRows)
+	}
+	if err := w.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func openTestCache(t *testing.T, path string) *Reader {
+	t.Helper()
+	r, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { r.Close() })
+	return r
+}
+
+func TestRoundTrip(t *testing.T) {
+	r := openTestCache(t, writeTestCache(t))
+
+	if r.Schema() != 7 {
+		t.Errorf("Schema() = %d, want 7", r.Schema())
+	}
+	if !reflect.DeepEqual(r.Headers(), []string{"Index", "ID", "Data"}) {
+		t.Errorf("Headers() = %q", r.Headers())
+	}
+	if r.Len() != testRows || !r.HasDecoded() {
+		t.Errorf("Len() = %d, HasDecoded() = %v", r.Len(), r.HasDecoded())
+	}
+	if !reflect.DeepEqual(r.IDs(), []string{"A", "B", "C"}) {
+		t.Errorf("IDs() = %q", r.IDs())
+	}
+
+	next := 0
+	err := r.Scan(ScanOptions{Decoded: true, Time: true, ID: true}, func(row *Row) error {
+		i := row.Index
+		if i != next {
+			t.Fatalf("row index = %d, want %d", i, next)
+		}
+		next++
+		if !reflect.DeepEqual(row.Cells, testCells(i)) {
+			t.Errorf("row %d cells = %q, want %q", i, row.Cells, testCells(i))
+		}
+		if string(row.Decoded) != string(testDecoded(i)) {
+			t.Errorf("row %d decoded = %s, want %s", i, row.Decoded, testDecoded(i))
+		}
+		if row.Time != testTime(i) {
+			t.Errorf("row %d time = %d, want %d", i, row.Time, testTime(i))
+		}
+		if r.IDs()[row.ID] != testID(i) {
+			t.Errorf("row %d id = %q, want %q", i, r.IDs()[row.ID], testID(i))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next != testRows {
+		t.Errorf("scanned %d rows, want %d", next, testRows)
+	}
+}
+
+func TestScanSelectedRowsAndColumns(t *testing.T) {
+	r := openTestCache(t, writeTestCache(t))
+
+	rows := []int{0, 1, BlockRows - 1, BlockRows, 2*BlockRows + 99}
+	var got [][]string
+	err := r.Scan(ScanOptions{Rows: rows, Columns: []int{2}}, func(row *Row) error {
+		got = append(got, append([]string(nil), row.Cells...))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(rows) {
+		t.Fatalf("got %d rows, want %d", len(got), len(rows))
+	}
+	for i, index := range rows {
+		want := make([]string, len(testCells(index)))
+		if len(want) > 2 {
+			want[2] = testCells(index)[2]
+		}
+		if !reflect.DeepEqual(got[i], want) {
+			t.Errorf("row %d = %q, want %q", index, got[i], want)
+		}
+	}
+}
+
+func TestReadRows(t *testing.T) {
+	r := openTestCache(t, writeTestCache(t))
+
+	// 无序且重复的行号按给定顺序返回
+	indexes := []int{2*BlockRows + 3, 3, BlockRows + 3, 3}
+	cells, decoded, err := r.ReadRows(indexes, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, index := range indexes {
+		if !reflect.DeepEqual(cells[i], testCells(index)) {
+			t.Errorf("cells[%d] = %q, want %q", i, cells[i], testCells(index))
+		}
+		if string(decoded[i]) != string(testDecoded(index)) {
+			t.Errorf("decoded[%d] = %s, want %s", i, decoded[i], testDecoded(index))
+		}
+	}
+}
+
+func TestRowsWithIDs(t *testing.T) {
+	r := openTestCache(t, writeTestCache(t))
+
+	tests := []struct {
+		name string
+		ids  map[string]bool
+	}{
+		{"single", map[string]bool{"B": true}},
+		{"last block only", map[string]bool{"C": true}},
+		{"multiple", map[string]bool{"A": true, "C": true}},
+		{"unknown", map[string]bool{"X": true}},
+		{"none", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := r.RowsWithIDs(tt.ids)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := []int{}
+			for i := 0; i < testRows; i++ {
+				if tt.ids[testID(i)] {
+					want = append(want, i)
+				}
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got %d rows, want %d", len(got), len(want))
+			}
+		})
+	}
+}
+
+func TestRowsInTimeRange(t *testing.T) {
+	r := openTestCache(t, writeTestCache(t))
+
+	tests := []struct {
+		name     string
+		rows     []int
+		from, to int64
+	}{
+		{"unbounded", nil, NoTime, NoTime},
+		{"from", nil, testTime(2*BlockRows + 50), NoTime},
+		{"to", nil, NoTime, testTime(10)},
+		{"across blocks", nil, testTime(BlockRows - 10), testTime(BlockRows + 10)},
+		{"after last row", nil, testTime(testRows) + 1, NoTime},
+		{"subset", []int{1, 2, BlockRows + 1, 2*BlockRows + 1}, testTime(2), testTime(2*BlockRows + 1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := r.RowsInTimeRange(tt.rows, tt.from, tt.to)
+			if err != nil {
+				t.Fatal(err)
+			}
+			candidates := tt.rows
+			if candidates == nil {
+				for i := 0; i < testRows; i++ {
+					candidates = append(candidates, i)
+				}
+			}
+			want := []int{}
+			for _, i := range candidates {
+				ts := testTime(i)
+				if ts != NoTime && (tt.from == NoTime || ts >= tt.from) && (tt.to == NoTime || ts <= tt.to) {
+					want = append(want, i)
+				}
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+// 写入失败时不留下正式文件
+func TestAbort(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "aborted.cache")
+	w, err := Create(path, 1, []string{"a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Add([]string{"x"}, nil, "A", NoTime); err != nil {
+		t.Fatal(err)
+	}
+	w.Abort()
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 0 {
+		t.Errorf("directory not empty after Abort: %d entries", len(entries))
+	}
+}
+
+func TestOpenInvalidCache(t *testing.T) {
+	valid, err := os.ReadFile(writeTestCache(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	trailerAt := len(valid) - footerTrailerSize
+	footerOffset := binary.LittleEndian.Uint64(valid[trailerAt:])
+
+	tests := []struct {
+		name   string
+		modify func(b []byte) []byte
+	}{
+		{"empty", func(b []byte) []byte { return nil }},
+		{"truncated", func(b []byte) []byte { return b[:len(b)-1] }},
+		{"bad head magic", func(b []byte) []byte { b[0] ^= 0xFF; return b }},
+		{"bad trailer magic", func(b []byte) []byte { b[len(b)-1] ^= 0xFF; return b }},
+		{"footer out of range", func(b []byte) []byte {
+			binary.LittleEndian.PutUint64(b[trailerAt+8:], uint64(len(b)))
+			return b
+		}},
+		{"corrupt footer", func(b []byte) []byte {
+			for i := int(footerOffset); i < trailerAt; i++ {
+				b[i] = 0xFF
+			}
+			return b
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "bad.cache")
+			content := tt.modify(append([]byte(nil), valid...))
+			if err := os.WriteFile(path, content, 0644); err != nil {
+				t.Fatal(err)
+			}
+			r, err := Open(path)
+			if err == nil {
+				r.Close()
+				t.Fatal("Open succeeded")
+			}
+			if !errors.Is(err, ErrInvalidCache) {
+				t.Errorf("err = %v, want ErrInvalidCache", err)
+			}
+		})
+	}
+}
+
+// 索引中数据段的位置损坏时应在打开时报错，而不是按声明的长度分配内存
+func TestOpenInvalidChunk(t *testing.T) {
+	tests := []struct {
+		name  string
+		chunk chunk
+	}{
+		{"length past end", chunk{offset: int64(len(magic)), length: 1 << 40}},
+		{"offset past end", chunk{offset: 1 << 40, length: 1}},
+		{"negative length", chunk{offset: int64(len(magic)), length: -1}},
+		{"negative offset", chunk{offset: -1 << 62, length: 1}},
+		{"overflow", chunk{offset: 1 << 62, length: 1 << 62}},
+		{"inside magic", chunk{offset: 0, length: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "bad.cache")
+			w, err := Create(path, 7, []string{"Index", "ID", "Data"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < 10; i++ {
+				if err := w.Add(testCells(i), testDecoded(i), testID(i), testTime(i)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := w.flushBlock(); err != nil {
+				t.Fatal(err)
+			}
+			w.blocks[0].chunks[chunkColumns] = tt.chunk
+			if err := w.Commit(); err != nil {
+				t.Fatal(err)
+			}
+
+			r, err := Open(path)
+			if err == nil {
+				r.Close()
+				t.Fatal("Open succeeded")
+			}
+			if !errors.Is(err, ErrInvalidCache) {
+				t.Errorf("err = %v, want ErrInvalidCache", err)
+			}
+		})
+	}
+}
+
+// readChunk 读取前同样检查数据段位置
+func TestScanTruncatedChunk(t *testing.T) {
+	r := openTestCache(t, writeTestCache(t))
+	r.blocks[1].chunks[chunkColumns].length = r.size
+	err := r.Scan(ScanOptions{}, func(row *Row) error { return nil })
+	if !errors.Is(err, ErrInvalidCache) {
+		t.Errorf("err = %v, want ErrInvalidCache", err)
+	}
+}
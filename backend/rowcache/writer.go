@@ -0,0 +1,256 @@
+package rowcache
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Writer 逐行写入缓存文件，先写入同目录的临时文件，Commit时改名，内存占用只与块大小和消息ID数量相关
+type Writer struct {
+	path    string
+	file    *os.File
+	buf     *bufio.Writer
+	offset  int64
+	schema  uint64
+	headers []string
+
+	hasDecoded bool
+	rows       int
+	blocks     []blockInfo
+	ids        map[string]int // 消息ID -> 字典序号
+	idList     []string
+	idBlocks   [][]int // 每个消息ID出现的数据块（升序）
+
+	// 当前数据块
+	blockRows int
+	minTime   int64
+	maxTime   int64
+	widths    []byte
+	times     []byte
+	lastTime  int64
+	idCol     []byte
+	decoded   []byte
+	columns   [][]byte
+
+	compressed bytes.Buffer
+	flate      *flate.Writer
+}
+
+// Create 创建缓存文件，path在Commit前不会出现；schema为调用方的输出版本，由Reader.Schema读回
+func Create(path string, schema uint64, headers []string) (*Writer, error) {
+	file, err := os.CreateTemp(filepath.Dir(path), ".rowcache-*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	file.Chmod(0644)
+
+	w := &Writer{
+		path:    path,
+		file:    file,
+		buf:     bufio.NewWriterSize(file, 256*1024),
+		schema:  schema,
+		headers: headers,
+		ids:     make(map[string]int),
+	}
+	w.flate, _ = flate.NewWriter(&w.compressed, flate.BestSpeed)
+	w.resetBlock()
+	if _, err := w.buf.WriteString(magic); err != nil {
+		w.Abort()
+		return nil, err
+	}
+	w.offset = int64(len(magic))
+	return w, nil
+}
+
+func (w *Writer) resetBlock() {
+	w.blockRows = 0
+	w.minTime, w.maxTime = NoTime, NoTime
+	w.widths = w.widths[:0]
+	w.times = w.times[:0]
+	w.lastTime = 0
+	w.idCol = w.idCol[:0]
+	w.decoded = w.decoded[:0]
+	// 每个块的列数单独记录，下一块不再出现的列不写入
+	w.columns = w.columns[:0]
+}
+
+// Add 写入一行，decoded为解码字段的JSON（空表示没有），id为规范化后的消息ID，t为Unix纳秒或NoTime
+func (w *Writer) Add(cells []string, decoded []byte, id string, t int64) error {
+	w.widths = binary.AppendUvarint(w.widths, uint64(len(cells)))
+	// 时间按块内差值存储，NoTime参与差值运算时按补码回绕，解码时同样回绕即可还原
+	w.times = binary.AppendVarint(w.times, int64(uint64(t)-uint64(w.lastTime)))
+	w.lastTime = t
+	if t != NoTime {
+		if w.minTime == NoTime || t < w.minTime {
+			w.minTime = t
+		}
+		if w.maxTime == NoTime || t > w.maxTime {
+			w.maxTime = t
+		}
+	}
+
+	idIndex, ok := w.ids[id]
+	if !ok {
+		idIndex = len(w.idList)
+		w.ids[id] = idIndex
+		w.idList = append(w.idList, id)
+		w.idBlocks = append(w.idBlocks, nil)
+	}
+	w.idCol = binary.AppendUvarint(w.idCol, uint64(idIndex))
+	block := len(w.blocks)
+	if blocks := w.idBlocks[idIndex]; len(blocks) == 0 || blocks[len(blocks)-1] != block {
+		w.idBlocks[idIndex] = append(blocks, block)
+	}
+
+	if len(decoded) > 0 {
+		w.hasDecoded = true
+	}
+	w.decoded = binary.AppendUvarint(w.decoded, uint64(len(decoded)))
+	w.decoded = append(w.decoded, decoded...)
+
+	// 列数可能随行变化，新出现的列为块内之前的行补空值（空字符串编码为一个0字节）
+	for len(w.columns) < len(cells) {
+		w.columns = append(w.columns, make([]byte, w.blockRows, BlockRows*8))
+	}
+	for i := range w.columns {
+		value := ""
+		if i < len(cells) {
+			value = cells[i]
+		}
+		w.columns[i] = appendString(w.columns[i], value)
+	}
+
+	w.blockRows++
+	w.rows++
+	if w.blockRows == BlockRows {
+		return w.flushBlock()
+	}
+	return nil
+}
+
+// flushBlock 压缩并写出当前数据块
+func (w *Writer) flushBlock() error {
+	if w.blockRows == 0 {
+		return nil
+	}
+	info := blockInfo{
+		start:   w.rows - w.blockRows,
+		rows:    w.blockRows,
+		minTime: w.minTime,
+		maxTime: w.maxTime,
+	}
+	parts := append([][]byte{w.widths, w.times, w.idCol, w.decoded}, w.columns...)
+	for _, part := range parts {
+		c, err := w.writeCompressed(part)
+		if err != nil {
+			return err
+		}
+		info.chunks = append(info.chunks, c)
+	}
+	w.blocks = append(w.blocks, info)
+	w.resetBlock()
+	return nil
+}
+
+func (w *Writer) writeCompressed(data []byte) (chunk, error) {
+	w.compressed.Reset()
+	w.flate.Reset(&w.compressed)
+	w.flate.Write(data)
+	if err := w.flate.Close(); err != nil {
+		return chunk{}, err
+	}
+	c := chunk{offset: w.offset, length: int64(w.compressed.Len())}
+	if _, err := w.buf.Write(w.compressed.Bytes()); err != nil {
+		return chunk{}, err
+	}
+	w.offset += c.length
+	return c, nil
+}
+
+// Commit 写出最后一个数据块和索引，改名为正式文件
+func (w *Writer) Commit() error {
+	if err := w.flushBlock(); err != nil {
+		w.Abort()
+		return err
+	}
+
+	var footer []byte
+	footer = binary.AppendUvarint(footer, version)
+	footer = binary.AppendUvarint(footer, w.schema)
+	footer = binary.AppendUvarint(footer, uint64(len(w.headers)))
+	for _, h := range w.headers {
+		footer = appendString(footer, h)
+	}
+	footer = binary.AppendUvarint(footer, uint64(w.rows))
+	if w.hasDecoded {
+		footer = append(footer, 1)
+	} else {
+		footer = append(footer, 0)
+	}
+
+	footer = binary.AppendUvarint(footer, uint64(len(w.idList)))
+	for i, id := range w.idList {
+		footer = appendString(footer, id)
+		footer = binary.AppendUvarint(footer, uint64(len(w.idBlocks[i])))
+		prev := 0
+		for _, b := range w.idBlocks[i] {
+			footer = binary.AppendUvarint(footer, uint64(b-prev))
+			prev = b
+		}
+	}
+
+	footer = binary.AppendUvarint(footer, uint64(len(w.blocks)))
+	for _, b := range w.blocks {
+		footer = binary.AppendUvarint(footer, uint64(b.rows))
+		footer = binary.AppendVarint(footer, b.minTime)
+		footer = binary.AppendVarint(footer, b.maxTime)
+		footer = binary.AppendUvarint(footer, uint64(len(b.chunks)))
+		for _, c := range b.chunks {
+			footer = binary.AppendUvarint(footer, uint64(c.offset))
+			footer = binary.AppendUvarint(footer, uint64(c.length))
+		}
+	}
+
+	footerChunk, err := w.writeCompressed(footer)
+	if err != nil {
+		w.Abort()
+		return err
+	}
+	trailer := binary.LittleEndian.AppendUint64(nil, uint64(footerChunk.offset))
+	trailer = binary.LittleEndian.AppendUint64(trailer, uint64(footerChunk.length))
+	trailer = append(trailer, magic...)
+	if _, err := w.buf.Write(trailer); err != nil {
+		w.Abort()
+		return err
+	}
+
+	if err := w.buf.Flush(); err != nil {
+		w.Abort()
+		return err
+	}
+	if err := w.file.Close(); err != nil {
+		os.Remove(w.file.Name())
+		return err
+	}
+	if err := os.Rename(w.file.Name(), w.path); err != nil {
+		os.Remove(w.file.Name())
+		return fmt.Errorf("rename cache file: %v", err)
+	}
+	return nil
+}
+
+// Abort 放弃写入，删除临时文件
+func (w *Writer) Abort() {
+	w.file.Close()
+	os.Remove(w.file.Name())
+}
+
+// Rows 已写入的行数
+func (w *Writer) Rows() int {
+	return w.rows
+}
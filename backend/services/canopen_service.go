@@ -0,0 +1,129 @@
+package services
+
+import (
+	"bytes"
+	"csv-parser/canopen"
+	"csv-parser/models"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CANopenService 管理CANopen对象字典（EDS/DCF）
+type CANopenService struct {
+	configDir string
+}
+
+func NewCANopenService(configDir string) *CANopenService {
+	return &CANopenService{
+		configDir: configDir,
+	}
+}
+
+// getDictionaryDir 获取对象字典文件目录
+func (s *CANopenService) getDictionaryDir() string {
+	return filepath.Join(s.configDir, canopen.DictionaryDir)
+}
+
+// ImportDictionary 导入EDS/DCF文件，同一节点已有的字典会被替换
+// nodeID为0时使用DCF文件中的NodeID
+func (s *CANopenService) ImportDictionary(filename string, file io.Reader, nodeID uint8) (*models.ObjectDictionary, error) {
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %v", err)
+	}
+
+	dict, err := canopen.ParseEDS(bytes.NewReader(content), filename, nodeID)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := s.getDictionaryDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("创建对象字典目录失败: %v", err)
+	}
+	if err := s.removeDictionaryFiles(dict.NodeID); err != nil {
+		return nil, err
+	}
+
+	// 文件名格式：节点ID_原始文件名
+	baseName := strings.ReplaceAll(filepath.Base(filename), " ", "_")
+	filePath := filepath.Join(dir, fmt.Sprintf("%d_%s", dict.NodeID, baseName))
+	if err := os.WriteFile(filePath, content, 0644); err != nil {
+		return nil, fmt.Errorf("failed to save file: %v", err)
+	}
+
+	return dict, nil
+}
+
+// GetDictionaries 获取已导入的对象字典列表（按节点ID排序）
+func (s *CANopenService) GetDictionaries() ([]*models.ObjectDictionaryInfo, error) {
+	dictionaries, err := canopen.LoadDictionaries(s.getDictionaryDir())
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]*models.ObjectDictionaryInfo, 0, len(dictionaries))
+	for _, dict := range dictionaries {
+		infos = append(infos, &models.ObjectDictionaryInfo{
+			NodeID:      dict.NodeID,
+			FileName:    dict.FileName,
+			FileType:    dict.FileType,
+			VendorName:  dict.VendorName,
+			ProductName: dict.ProductName,
+			EntryCount:  len(dict.Entries),
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].NodeID < infos[j].NodeID
+	})
+
+	return infos, nil
+}
+
+// GetDictionary 获取指定节点的对象字典
+func (s *CANopenService) GetDictionary(nodeID uint8) (*models.ObjectDictionary, error) {
+	dictionaries, err := canopen.LoadDictionaries(s.getDictionaryDir())
+	if err != nil {
+		return nil, err
+	}
+	dict, ok := dictionaries[nodeID]
+	if !ok {
+		return nil, fmt.Errorf("dictionary not found for node %d", nodeID)
+	}
+	return dict, nil
+}
+
+// DeleteDictionary 删除指定节点的对象字典
+func (s *CANopenService) DeleteDictionary(nodeID uint8) error {
+	if _, err := s.GetDictionary(nodeID); err != nil {
+		return err
+	}
+	return s.removeDictionaryFiles(nodeID)
+}
+
+// removeDictionaryFiles 删除指定节点的所有字典文件
+func (s *CANopenService) removeDictionaryFiles(nodeID uint8) error {
+	entries, err := os.ReadDir(s.getDictionaryDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	prefix := strconv.Itoa(int(nodeID)) + "_"
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasPrefix(entry.Name(), prefix) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(s.getDictionaryDir(), entry.Name())); err != nil {
+			return fmt.Errorf("删除对象字典文件失败: %v", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,464 @@
+package services
+
+import (
+	"csv-parser/decoder"
+	"csv-parser/formats"
+	"csv-parser/models"
+	"csv-parser/socketcan"
+	"csv-parser/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// 抓包文件每隔多久刷新到磁盘，停止时总会刷新
+	captureFlushInterval = 500 * time.Millisecond
+	// 每个实时查看连接缓存的帧数，客户端跟不上时丢弃新帧
+	liveSubscriberBuffer = 1024
+)
+
+var (
+	// ErrCaptureRunning 已有抓包在进行中
+	ErrCaptureRunning = errors.New("a capture is already running")
+	// ErrCaptureNotRunning 当前没有进行中的抓包
+	ErrCaptureNotRunning = errors.New("no capture is running")
+	// ErrInvalidInterface 接口名称不合法
+	ErrInvalidInterface = errors.New("invalid interface name")
+
+	// interfaceNamePattern Linux网络接口名称（最长15个字符）
+	interfaceNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.\-]{1,15}$`)
+)
+
+// CaptureConfig 实时抓包配置（config/can/capture.json）
+type CaptureConfig struct {
+	Interface    string `json:"interface"`    // 未指定接口时使用的SocketCAN接口
+	RecentFrames int    `json:"recentFrames"` // 状态接口返回的最近帧数量
+}
+
+// frameSource 抓包的数据来源，由SocketCAN连接实现
+type frameSource interface {
+	ReadFrame() (*models.LogFrame, error)
+	Close() error
+}
+
+// CaptureService 从SocketCAN接口实时抓包
+// 收到的帧按definitions.json和data_parser.json解析，同时以candump日志格式保存到uploads目录，
+// 抓包文件与上传的文件一样可以在文件列表中预览和解析。同一时间只允许一个抓包
+type CaptureService struct {
+	uploadDir  string
+	configDir  string
+	csvService *CSVService
+	open       func(iface string) (frameSource, error)
+
+	mu          sync.Mutex
+	current     *captureSession // 进行中或最近一次结束的抓包
+	subscribers map[*LiveSubscription]bool
+}
+
+// LiveSubscription 实时查看的订阅，按过滤条件接收抓包中解析出的帧
+// 订阅与具体的抓包无关，重新开始抓包后继续接收
+type LiveSubscription struct {
+	frames  chan models.CaptureFrame
+	filter  liveFilter
+	dropped int64
+}
+
+// Frames 返回接收帧的通道，取消订阅后关闭
+func (l *LiveSubscription) Frames() <-chan models.CaptureFrame {
+	return l.frames
+}
+
+// Dropped 返回因客户端处理不及时而丢弃的帧数
+func (l *LiveSubscription) Dropped() int64 {
+	return atomic.LoadInt64(&l.dropped)
+}
+
+// liveFilter 预处理后的实时查看过滤条件
+type liveFilter struct {
+	ids     map[string]bool
+	exclude map[string]bool
+	names   []string
+}
+
+// captureSession 一次抓包的运行状态
+type captureSession struct {
+	source      frameSource
+	file        *os.File
+	writer      *formats.CandumpWriter
+	definitions map[string]string
+	dataParser  decoder.Config
+	highlights  []models.RowHighlight
+	recentLimit int
+	done        chan struct{}
+
+	// 以下字段由CaptureService.mu保护
+	status   models.CaptureStatus
+	stopping bool
+}
+
+func NewCaptureService(uploadDir, configDir string, csvService *CSVService) *CaptureService {
+	return &CaptureService{
+		uploadDir:   uploadDir,
+		configDir:   configDir,
+		csvService:  csvService,
+		subscribers: make(map[*LiveSubscription]bool),
+		open: func(iface string) (frameSource, error) {
+			return socketcan.Open(iface)
+		},
+	}
+}
+
+// loadConfig 读取抓包配置，文件不存在时使用默认值（can0，保留100帧）
+func (s *CaptureService) loadConfig() CaptureConfig {
+	config := CaptureConfig{Interface: "can0", RecentFrames: 100}
+	content, err := os.ReadFile(filepath.Join(s.configDir, "capture.json"))
+	if err != nil {
+		return config
+	}
+	if err := json.Unmarshal(content, &config); err != nil {
+		utils.Warn("解析capture.json失败: %v，使用默认配置", err)
+		return CaptureConfig{Interface: "can0", RecentFrames: 100}
+	}
+	if config.Interface == "" {
+		config.Interface = "can0"
+	}
+	if config.RecentFrames <= 0 {
+		config.RecentFrames = 100
+	}
+	return config
+}
+
+// loadRowHighlights 读取行高亮规则（与预览页使用同一个row_highlight.json）
+func (s *CaptureService) loadRowHighlights() []models.RowHighlight {
+	content, err := os.ReadFile(filepath.Join(s.configDir, "row_highlight.json"))
+	if err != nil {
+		return nil
+	}
+	var config struct {
+		Highlights []models.RowHighlight `json:"highlights"`
+	}
+	if err := json.Unmarshal(content, &config); err != nil {
+		utils.Warn("解析row_highlight.json失败: %v，实时查看不输出高亮", err)
+		return nil
+	}
+	return config.Highlights
+}
+
+// Start 在指定接口上开始抓包，iface为空时使用配置中的接口
+func (s *CaptureService) Start(iface, protocolType string) (*models.CaptureStatus, error) {
+	config := s.loadConfig()
+	if iface == "" {
+		iface = config.Interface
+	}
+	if !interfaceNamePattern.MatchString(iface) {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidInterface, iface)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.current != nil && s.current.status.Running {
+		return nil, ErrCaptureRunning
+	}
+
+	source, err := s.open(iface)
+	if err != nil {
+		return nil, err
+	}
+
+	// 与上传文件相同的命名规则：UUID_PROTOCOL_原始文件名
+	startTime := time.Now()
+	filename := fmt.Sprintf("%s_%s_capture_%s_%s.log", uuid.New().String(), protocolType, iface, startTime.Format("20060102_150405"))
+	file, err := os.Create(filepath.Join(s.uploadDir, filename))
+	if err != nil {
+		source.Close()
+		return nil, fmt.Errorf("failed to create capture file: %v", err)
+	}
+
+	definitions, err := s.csvService.loadCANDefinitions()
+	if err != nil {
+		utils.Warn("加载CAN定义失败: %v，抓包时无法匹配消息含义", err)
+		definitions = make(map[string]string)
+	}
+	dataParser, err := s.csvService.loadDataParserConfig()
+	if err != nil {
+		utils.Warn("加载数据解析配置失败: %v，抓包时不输出解码字段", err)
+		dataParser = decoder.Config{}
+	}
+
+	session := &captureSession{
+		source:      source,
+		file:        file,
+		writer:      formats.NewCandumpWriter(file),
+		definitions: definitions,
+		dataParser:  dataParser,
+		highlights:  s.loadRowHighlights(),
+		recentLimit: config.RecentFrames,
+		done:        make(chan struct{}),
+		status: models.CaptureStatus{
+			Running:      true,
+			Interface:    iface,
+			ProtocolType: protocolType,
+			Filename:     filename,
+			StartTime:    startTime,
+		},
+	}
+	s.current = session
+	go s.run(session)
+
+	utils.Info("开始抓包: 接口=%s, 文件=%s", iface, filename)
+	return s.snapshot(session), nil
+}
+
+// Stop 停止当前抓包并等待抓包文件写完
+func (s *CaptureService) Stop() (*models.CaptureStatus, error) {
+	s.mu.Lock()
+	session := s.current
+	if session == nil || !session.status.Running {
+		s.mu.Unlock()
+		return nil, ErrCaptureNotRunning
+	}
+	session.stopping = true
+	s.mu.Unlock()
+
+	// 关闭套接字使阻塞中的读取返回
+	session.source.Close()
+	<-session.done
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.snapshot(session), nil
+}
+
+// Status 返回进行中或最近一次结束的抓包状态，从未抓包时返回nil
+func (s *CaptureService) Status() *models.CaptureStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.current == nil {
+		return nil
+	}
+	return s.snapshot(s.current)
+}
+
+// snapshot 复制抓包状态，调用方需持有s.mu
+func (s *CaptureService) snapshot(session *captureSession) *models.CaptureStatus {
+	status := session.status
+	status.Recent = append([]models.CaptureFrame{}, session.status.Recent...)
+	return &status
+}
+
+// run 抓包循环：读取帧、写入抓包文件、解析并更新状态，读取出错或停止时结束
+func (s *CaptureService) run(session *captureSession) {
+	defer close(session.done)
+
+	var readErr error
+	lastFlush := time.Now()
+	for {
+		frame, err := session.source.ReadFrame()
+		if err != nil {
+			readErr = err
+			break
+		}
+
+		// StartTime在抓包开始后不再修改，无需加锁
+		frame.Relative = frame.Time.Sub(session.status.StartTime)
+
+		if err := session.writer.WriteFrame(frame); err != nil {
+			readErr = fmt.Errorf("写入抓包文件失败: %v", err)
+			break
+		}
+		if time.Since(lastFlush) >= captureFlushInterval {
+			// CandumpWriter.Close只刷新缓冲区，之后仍可继续写入
+			session.writer.Close()
+			lastFlush = time.Now()
+		}
+
+		captured := session.decode(frame)
+		s.mu.Lock()
+		session.status.FrameCount++
+		session.status.Recent = append(session.status.Recent, captured)
+		if n := len(session.status.Recent); n > session.recentLimit {
+			session.status.Recent = session.status.Recent[n-session.recentLimit:]
+		}
+		s.broadcast(captured)
+		s.mu.Unlock()
+	}
+
+	if err := session.writer.Close(); err != nil {
+		utils.Error("刷新抓包文件失败: %v", err)
+	}
+	session.file.Close()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !session.stopping {
+		// 不是通过Stop结束的（如接口被关闭），记录原因
+		session.source.Close()
+		session.status.Error = readErr.Error()
+		utils.Error("抓包异常结束: 接口=%s, %v", session.status.Interface, readErr)
+	}
+	stopTime := time.Now()
+	session.status.Running = false
+	session.status.StopTime = &stopTime
+
+	filename := session.status.Filename
+	if session.status.FrameCount == 0 {
+		// 没有收到任何帧时不保留空文件（空文件无法识别格式）
+		os.Remove(filepath.Join(s.uploadDir, filename))
+		session.status.Filename = ""
+	} else {
+		// 抓包期间解析过的结果已过期
+		s.csvService.DeleteCacheForFile(filename)
+	}
+	utils.Info("抓包结束: 接口=%s, 共 %d 帧, 文件=%s", session.status.Interface, session.status.FrameCount, session.status.Filename)
+}
+
+// Subscribe 订阅实时帧，使用完后必须调用Unsubscribe
+func (s *CaptureService) Subscribe(filter models.LiveFilter) *LiveSubscription {
+	sub := &LiveSubscription{
+		frames: make(chan models.CaptureFrame, liveSubscriberBuffer),
+		filter: newLiveFilter(filter),
+	}
+	s.mu.Lock()
+	s.subscribers[sub] = true
+	s.mu.Unlock()
+	return sub
+}
+
+// Unsubscribe 取消订阅并关闭帧通道
+func (s *CaptureService) Unsubscribe(sub *LiveSubscription) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.subscribers[sub] {
+		delete(s.subscribers, sub)
+		close(sub.frames)
+	}
+}
+
+// broadcast 将帧推送给过滤条件匹配的订阅者，调用方需持有s.mu
+// 发送不阻塞，订阅者的缓冲区已满时丢弃该帧，避免慢客户端拖慢抓包
+func (s *CaptureService) broadcast(frame models.CaptureFrame) {
+	for sub := range s.subscribers {
+		if !sub.filter.match(&frame) {
+			continue
+		}
+		select {
+		case sub.frames <- frame:
+		default:
+			atomic.AddInt64(&sub.dropped, 1)
+		}
+	}
+}
+
+// newLiveFilter 将过滤条件中的CAN ID规范化，关键字转为小写
+func newLiveFilter(filter models.LiveFilter) liveFilter {
+	f := liveFilter{ids: make(map[string]bool), exclude: make(map[string]bool)}
+	for _, id := range filter.IDs {
+		if id = normalizeCANID(id); id != "" {
+			f.ids[id] = true
+		}
+	}
+	for _, id := range filter.ExcludeIDs {
+		if id = normalizeCANID(id); id != "" {
+			f.exclude[id] = true
+		}
+	}
+	for _, name := range filter.Names {
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+			f.names = append(f.names, name)
+		}
+	}
+	return f
+}
+
+// match 判断帧是否满足过滤条件：ID在包含列表中（为空则不限）且不在排除列表中，Name或Meaning包含任一关键字
+func (f liveFilter) match(frame *models.CaptureFrame) bool {
+	id := normalizeCANID(frame.ID)
+	if len(f.ids) > 0 && !f.ids[id] {
+		return false
+	}
+	if f.exclude[id] {
+		return false
+	}
+	if len(f.names) == 0 {
+		return true
+	}
+	name := strings.ToLower(frame.Name)
+	meaning := strings.ToLower(frame.Meaning)
+	for _, keyword := range f.names {
+		if strings.Contains(name, keyword) || strings.Contains(meaning, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeCANID 将 "0x02CF"、"2cf" 等形式统一为definitions.json的键格式 "2cf"
+func normalizeCANID(id string) string {
+	id = strings.ToLower(strings.TrimSpace(id))
+	if id == "" {
+		return ""
+	}
+	id = strings.TrimPrefix(id, "0x")
+	if id = strings.TrimLeft(id, "0"); id == "" {
+		return "0"
+	}
+	return id
+}
+
+// matchHighlight 按预览页相同的规则匹配行高亮，返回第一条匹配的规则
+func matchHighlight(rules []models.RowHighlight, text string) *models.RowHighlight {
+	for i := range rules {
+		rule := &rules[i]
+		var matched bool
+		switch rule.MatchType {
+		case "equals":
+			matched = text == rule.Match
+		case "startsWith":
+			matched = strings.HasPrefix(text, rule.Match)
+		case "endsWith":
+			matched = strings.HasSuffix(text, rule.Match)
+		default:
+			matched = strings.Contains(text, rule.Match)
+		}
+		if matched {
+			return rule
+		}
+	}
+	return nil
+}
+
+// decode 按definitions.json查找消息含义并按data_parser.json解码数据
+func (session *captureSession) decode(frame *models.LogFrame) models.CaptureFrame {
+	captured := models.CaptureFrame{
+		Time:      formats.FormatTime(frame.Time),
+		Relative:  frame.Relative.Seconds(),
+		ID:        formats.DisplayID(&frame.CANFrame),
+		Name:      frame.Channel,
+		Channel:   frame.Channel,
+		Direction: frame.Direction,
+		Buffer:    formats.FormatSnifferBuffer(&frame.CANFrame),
+		Flags:     formats.FrameFlags(&frame.CANFrame),
+	}
+	// 错误帧的ID是错误类别，不查找定义
+	if !frame.ErrorFrame {
+		canID := formats.FormatID(&frame.CANFrame)
+		captured.Meaning = session.definitions[canID]
+		captured.Decoded = session.dataParser.Decode(canID, frame.Data)
+	}
+
+	// 与预览页一样，用整行文本（时间、Name、ID和含义、数据、解码结果）匹配高亮规则
+	text := strings.Join([]string{captured.Time, captured.Name, captured.ID + " - " + captured.Meaning,
+		captured.Buffer, decoder.FormatFields(captured.Decoded)}, " ")
+	captured.Highlight = matchHighlight(session.highlights, text)
+	return captured
+}
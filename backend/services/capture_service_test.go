@@ -0,0 +1,269 @@
+package services
+
+import (
+	"csv-parser/decoder"
+	"csv-parser/models"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSource 按顺序返回预置的帧，之后阻塞到Close或返回err
+type fakeSource struct {
+	frames chan *models.LogFrame
+	err    error
+	closed chan struct{}
+	once   sync.Once
+}
+
+func newFakeSource(frames []*models.LogFrame, err error) *fakeSource {
+	src := &fakeSource{frames: make(chan *models.LogFrame, len(frames)), err: err, closed: make(chan struct{})}
+	for _, f := range frames {
+		src.frames <- f
+	}
+	return src
+}
+
+func (f *fakeSource) ReadFrame() (*models.LogFrame, error) {
+	select {
+	case frame := <-f.frames:
+		return frame, nil
+	default:
+	}
+	if f.err != nil {
+		return nil, f.err
+	}
+	<-f.closed
+	return nil, errors.New("socket closed")
+}
+
+func (f *fakeSource) Close() error {
+	f.once.Do(func() { close(f.closed) })
+	return nil
+}
+
+func captureFrames(start time.Time) []*models.LogFrame {
+	return []*models.LogFrame{
+		{CANFrame: models.CANFrame{ID: 0x2CF, DLC: 2, Data: []byte{0x03, 0x05}}, Time: start.Add(10 * time.Millisecond), Channel: "vcan0", Direction: "Rx"},
+		{CANFrame: models.CANFrame{ID: 0x18FF0001, Extended: true, DLC: 1, Data: []byte{0xAA}}, Time: start.Add(20 * time.Millisecond), Channel: "vcan0", Direction: "Rx"},
+		{CANFrame: models.CANFrame{ID: 0x1CC, DLC: 1, Data: []byte{0x01}}, Time: start.Add(30 * time.Millisecond), Channel: "vcan0", Direction: "Rx"},
+	}
+}
+
+func newTestCaptureService(t *testing.T, src *fakeSource) (*CaptureService, string) {
+	t.Helper()
+	uploadDir, configDir := t.TempDir(), t.TempDir()
+	if err := os.WriteFile(filepath.Join(configDir, "capture.json"), []byte(`{"interface": "vcan0", "recentFrames": 2}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s := NewCaptureService(uploadDir, configDir, NewCSVService(uploadDir))
+	s.open = func(iface string) (frameSource, error) {
+		if iface != "vcan0" {
+			t.Errorf("open(%q), want vcan0", iface)
+		}
+		return src, nil
+	}
+	return s, uploadDir
+}
+
+// receive 从订阅中读取n帧，超时则失败
+func receive(t *testing.T, sub *LiveSubscription, n int) []models.CaptureFrame {
+	t.Helper()
+	var frames []models.CaptureFrame
+	for len(frames) < n {
+		select {
+		case f := <-sub.Frames():
+			frames = append(frames, f)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("received %d frames, want %d", len(frames), n)
+		}
+	}
+	return frames
+}
+
+func TestCaptureStartStop(t *testing.T) {
+	src := newFakeSource(captureFrames(time.Now()), nil)
+	s, uploadDir := newTestCaptureService(t, src)
+	sub := s.Subscribe(models.LiveFilter{})
+	defer s.Unsubscribe(sub)
+
+	status, err := s.Start("", "CAN")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.Running || status.Interface != "vcan0" || !strings.HasSuffix(status.Filename, ".log") {
+		t.Errorf("start status = %+v", status)
+	}
+	if _, err := s.Start("vcan0", "CAN"); !errors.Is(err, ErrCaptureRunning) {
+		t.Errorf("second Start error = %v, want ErrCaptureRunning", err)
+	}
+
+	frames := receive(t, sub, 3)
+	if frames[0].ID != "0x2CF" || frames[1].ID != "0x18FF0001" || frames[2].ID != "0x1CC" {
+		t.Errorf("frame IDs = %s %s %s", frames[0].ID, frames[1].ID, frames[2].ID)
+	}
+
+	status, err = s.Stop()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Running || status.FrameCount != 3 || status.Error != "" || status.StopTime == nil {
+		t.Errorf("stop status = %+v", status)
+	}
+	// recentFrames=2 时只保留最后两帧
+	if len(status.Recent) != 2 || status.Recent[0].ID != "0x18FF0001" || status.Recent[1].ID != "0x1CC" {
+		t.Errorf("recent = %+v", status.Recent)
+	}
+	if _, err := s.Stop(); !errors.Is(err, ErrCaptureNotRunning) {
+		t.Errorf("second Stop error = %v, want ErrCaptureNotRunning", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(uploadDir, status.Filename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) != 3 || !strings.HasSuffix(lines[0], "vcan0 2CF#0305") || !strings.HasSuffix(lines[1], "vcan0 18FF0001#AA") {
+		t.Errorf("capture file:\n%s", content)
+	}
+}
+
+// 读取出错结束时记录原因，没有收到帧时不保留抓包文件
+func TestCaptureSourceError(t *testing.T) {
+	src := newFakeSource(nil, errors.New("network is down"))
+	s, uploadDir := newTestCaptureService(t, src)
+
+	status, err := s.Start("vcan0", "CAN")
+	if err != nil {
+		t.Fatal(err)
+	}
+	filename := status.Filename
+
+	deadline := time.Now().Add(2 * time.Second)
+	for s.Status().Running && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	status = s.Status()
+	if status.Running || status.Error != "network is down" || status.Filename != "" {
+		t.Errorf("status = %+v", status)
+	}
+	if _, err := os.Stat(filepath.Join(uploadDir, filename)); !os.IsNotExist(err) {
+		t.Errorf("empty capture file kept: %v", err)
+	}
+}
+
+func TestCaptureDecode(t *testing.T) {
+	var parser decoder.Config
+	if err := json.Unmarshal([]byte(`{"2cf": {"bytes": {"0": {"type": "enum", "name": "Phase", "values": {"03": "Prep"}}}}}`), &parser); err != nil {
+		t.Fatal(err)
+	}
+	session := &captureSession{
+		definitions: map[string]string{"2cf": "Generator status", "4": "Should not match"},
+		dataParser:  parser,
+		highlights:  []models.RowHighlight{{Match: "Generator status", MatchType: "contains", BackgroundColor: "#ff0"}},
+	}
+	start := time.Date(2025, 11, 14, 17, 3, 36, 0, time.Local)
+
+	frame := &models.LogFrame{CANFrame: models.CANFrame{ID: 0x2CF, DLC: 2, Data: []byte{0x03, 0x05}},
+		Time: start.Add(739127 * time.Microsecond), Relative: 1500 * time.Millisecond, Channel: "can0", Direction: "Rx"}
+	got := session.decode(frame)
+	if got.Time != "2025-11-14 17:03:36.739.127" || got.Relative != 1.5 || got.ID != "0x2CF" || got.Name != "can0" ||
+		got.Buffer != "string=2cf:2:[03 05]" || got.Flags != "" || got.Meaning != "Generator status" {
+		t.Errorf("decode = %+v", got)
+	}
+	if len(got.Decoded) != 1 || got.Decoded[0].Display != "Prep" {
+		t.Errorf("decoded = %+v", got.Decoded)
+	}
+	if got.Highlight == nil || got.Highlight.BackgroundColor != "#ff0" {
+		t.Errorf("highlight = %+v", got.Highlight)
+	}
+
+	// 错误帧的ID是错误类别，不查找定义也不解码
+	errFrame := &models.LogFrame{CANFrame: models.CANFrame{ID: 0x4, ErrorFrame: true, DLC: 8, Data: make([]byte, 8)}, Time: start}
+	got = session.decode(errFrame)
+	if got.Meaning != "" || got.Decoded != nil || got.Flags != "ERR" || got.Highlight != nil {
+		t.Errorf("error frame decode = %+v", got)
+	}
+
+	extFrame := &models.LogFrame{CANFrame: models.CANFrame{ID: 0x18FF0001, Extended: true, DLC: 1, Data: []byte{0xAA}}, Time: start}
+	if got = session.decode(extFrame); got.ID != "0x18FF0001" || got.Flags != "EFF" || got.Buffer != "string=18ff0001:1:[aa]" {
+		t.Errorf("extended frame decode = %+v", got)
+	}
+}
+
+func TestLiveFilterMatch(t *testing.T) {
+	frames := map[string]models.CaptureFrame{
+		"status":   {ID: "0x2CF", Name: "can0", Meaning: "Generator Status"},
+		"extended": {ID: "0x18FF0001", Name: "can1", Meaning: "Tube temperature"},
+		"zero":     {ID: "0x000", Name: "can0", Meaning: "NMT"},
+	}
+	tests := []struct {
+		name   string
+		filter models.LiveFilter
+		want   []string
+	}{
+		{"empty", models.LiveFilter{}, []string{"extended", "status", "zero"}},
+		{"ids with prefix and padding", models.LiveFilter{IDs: []string{"0x02CF", " 18ff0001 "}}, []string{"extended", "status"}},
+		{"zero ID", models.LiveFilter{IDs: []string{"0"}}, []string{"zero"}},
+		{"exclude", models.LiveFilter{ExcludeIDs: []string{"2CF"}}, []string{"extended", "zero"}},
+		{"exclude wins over include", models.LiveFilter{IDs: []string{"2cf", "0"}, ExcludeIDs: []string{"0x2cf"}}, []string{"zero"}},
+		{"meaning keyword ignores case", models.LiveFilter{Names: []string{"STATUS"}}, []string{"status"}},
+		{"name keyword", models.LiveFilter{Names: []string{"can1"}}, []string{"extended"}},
+		{"any keyword", models.LiveFilter{Names: []string{"nmt", "tube", " "}}, []string{"extended", "zero"}},
+		{"ids and keywords", models.LiveFilter{IDs: []string{"2cf", "0"}, Names: []string{"can0"}}, []string{"status", "zero"}},
+		{"blank entries are ignored", models.LiveFilter{IDs: []string{"", " "}}, []string{"extended", "status", "zero"}},
+	}
+	for _, tt := range tests {
+		f := newLiveFilter(tt.filter)
+		var got []string
+		for _, key := range []string{"extended", "status", "zero"} {
+			frame := frames[key]
+			if f.match(&frame) {
+				got = append(got, key)
+			}
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%s: matched %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// 订阅者缓冲区已满时丢弃帧并计数，不匹配过滤条件的帧不计入
+func TestBroadcastDropsWhenFull(t *testing.T) {
+	s := NewCaptureService(t.TempDir(), t.TempDir(), nil)
+	slow := &LiveSubscription{frames: make(chan models.CaptureFrame, 2), filter: newLiveFilter(models.LiveFilter{IDs: []string{"2cf"}})}
+	fast := s.Subscribe(models.LiveFilter{})
+	s.subscribers[slow] = true
+
+	for i := 0; i < 5; i++ {
+		s.broadcast(models.CaptureFrame{ID: "0x2CF"})
+		s.broadcast(models.CaptureFrame{ID: "0x1CC"})
+	}
+
+	if got := slow.Dropped(); got != 3 {
+		t.Errorf("slow subscriber dropped = %d, want 3", got)
+	}
+	if got := len(slow.frames); got != 2 {
+		t.Errorf("slow subscriber buffered = %d, want 2", got)
+	}
+	if got := fast.Dropped(); got != 0 {
+		t.Errorf("fast subscriber dropped = %d, want 0", got)
+	}
+	if got := len(fast.frames); got != 10 {
+		t.Errorf("fast subscriber buffered = %d, want 10", got)
+	}
+
+	// 取消订阅后关闭通道，不再接收
+	s.Unsubscribe(fast)
+	s.broadcast(models.CaptureFrame{ID: "0x2CF"})
+	if got := len(fast.frames); got != 10 {
+		t.Errorf("unsubscribed buffered = %d, want 10", got)
+	}
+	for range fast.Frames() {
+	}
+}
@@ -2,7 +2,11 @@ package services
 
 import (
 	"bufio"
+	"csv-parser/canopen"
+	"csv-parser/decoder"
+	"csv-parser/formats"
 	"csv-parser/models"
+	"csv-parser/rowcache"
 	"csv-parser/utils"
 	"encoding/csv"
 	"encoding/json"
@@ -13,6 +17,7 @@ import (
 	"regexp"
 	"runtime"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -23,7 +28,7 @@ import (
 // 预编译的正则表达式（避免每次调用都重新编译）
 var (
 	// CAN消息格式: string=ID:Length:[HH HH HH ...]
-	canMsgPattern = regexp.MustCompile(`^string=[0-9a-fA-F]{1,4}:\d{1,2}:\[[0-9a-fA-F ]*\]`)
+	canMsgPattern = regexp.MustCompile(`^string=[0-9a-fA-F]{1,8}:\d{1,2}:\[[0-9a-fA-F ]*\]`)
 	// ushort格式: string=ushort=X
 	ushortPattern = regexp.MustCompile(`^string=ushort=\d+`)
 	// 时间格式
@@ -65,11 +70,32 @@ func (s *CSVService) UploadFile(filename string, file io.Reader, protocolType st
 		return nil, fmt.Errorf("failed to save file: %v", err)
 	}
 
-	// 验证CSV格式
-	rowCount, columnCount, err := s.validateCSV(filePath)
+	// 按文件内容识别格式，扩展名只作为参考
+	format, err := s.detectFileFormat(filePath)
+	if err != nil {
+		os.Remove(filePath)
+		return nil, fmt.Errorf("failed to read file: %v", err)
+	}
+	if format == "" {
+		os.Remove(filePath)
+		return nil, fmt.Errorf("%w, supported formats: %s", formats.ErrUnknownFormat, formats.SupportedFormatNames())
+	}
+	if extFormat := formats.FormatByExtension(filename); extFormat != format {
+		utils.Warn("文件扩展名与内容不一致: %s 识别为 %s", filename, formats.FormatNames[format])
+	}
+
+	rowCount, columnCount, err := s.validateFile(filePath, format)
 	if err != nil {
 		os.Remove(filePath) // 清理无效文件
-		return nil, fmt.Errorf("invalid CSV file: %v", err)
+		return nil, fmt.Errorf("invalid %s file: %v", formats.FormatNames[format], err)
+	}
+
+	// 保存识别结果，文件列表不再重新扫描文件
+	if info, err := os.Stat(filePath); err == nil {
+		meta := &fileMeta{Size: info.Size(), ModTime: info.ModTime(), Format: format, RowCount: rowCount, ColumnCount: columnCount}
+		if err := s.saveFileMeta(newFilename, meta); err != nil {
+			utils.Warn("保存文件元数据失败: %v", err)
+		}
 	}
 
 	// 创建文件记录
@@ -82,6 +108,7 @@ func (s *CSVService) UploadFile(filename string, file io.Reader, protocolType st
 		RowCount:     rowCount,
 		ColumnCount:  columnCount,
 		ProtocolType: protocolType,
+		Format:       format,
 	}
 
 	return csvFile, nil
@@ -92,132 +119,42 @@ func (s *CSVService) ParseFile(filename string, protocol string) (*models.CSVDat
 	return s.ParseFileWithLog(filename, protocol, "")
 }
 
-// ParseFileWithLog 解析CSV文件（带日志记录）
+// ParseFileWithLog 解析CSV文件（带日志记录），结果全部收集到内存中
+// 大文件应使用OpenParse和ParseStream.BuildCache写入缓存后逐块读取
 func (s *CSVService) ParseFileWithLog(filename string, protocol string, logKey string) (*models.CSVData, error) {
-	filePath := filepath.Join(s.uploadDir, filename)
-
-	// 检查文件是否存在
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		if logKey != "" {
-			utils.FileLogError(logKey, "文件不存在: %s", filePath)
-		}
-		return nil, fmt.Errorf("file not found")
+	stream, err := s.OpenParse(filename, protocol, logKey)
+	if err != nil {
+		return nil, err
 	}
+	defer stream.Close()
+	return stream.Collect()
+}
 
-	if logKey != "" {
-		utils.FileLogInfo(logKey, "开始解析CSV文件: %s", filePath)
-	}
+// ReadRecords 读取上传文件的表头和原始数据行
+func (s *CSVService) ReadRecords(filename string) ([]string, [][]string, error) {
+	return s.ReadRecordsWithLog(filename, "")
+}
 
-	// 打开文件
-	file, err := os.Open(filePath)
+// ReadRecordsWithLog 读取上传文件的表头和原始数据行（未做协议处理），日志格式的帧转换为嗅探器CSV行
+func (s *CSVService) ReadRecordsWithLog(filename string, logKey string) ([]string, [][]string, error) {
+	records, err := s.openRecords(filename, logKey)
 	if err != nil {
-		if logKey != "" {
-			utils.FileLogError(logKey, "打开文件失败: %v", err)
-		}
-		return nil, fmt.Errorf("failed to open file: %v", err)
+		return nil, nil, err
 	}
-	defer file.Close()
-
-	// 获取文件大小
-	fileInfo, _ := file.Stat()
-	if logKey != "" {
-		utils.FileLogInfo(logKey, "文件大小: %d 字节", fileInfo.Size())
-	}
-
-	// 使用bufio.Scanner逐行读取，对每行独立解析CSV
-	// 这样格式错误的行不会影响后续有效行的解析
-	scanner := bufio.NewScanner(file)
-	// 设置更大的缓冲区以处理较长的行
-	const maxScanTokenSize = 1024 * 1024 // 1MB
-	buf := make([]byte, maxScanTokenSize)
-	scanner.Buffer(buf, maxScanTokenSize)
+	defer records.Close()
 
-	var headers []string
 	var rows [][]string
-	skippedRows := 0
-	lineNumber := 0
-
-	if logKey != "" {
-		utils.FileLogInfo(logKey, "开始逐行读取CSV数据（增强容错模式）...")
-	}
-
-	// 逐行读取，每行独立解析
-	for scanner.Scan() {
-		lineNumber++
-		line := scanner.Text()
-
-		// 跳过空行
-		if strings.TrimSpace(line) == "" {
-			if logKey != "" {
-				utils.FileLogDebug(logKey, "第 %d 行为空行，已跳过", lineNumber)
-			}
-			skippedRows++
-			continue
+	for {
+		row, err := records.Next()
+		if err == io.EOF {
+			break
 		}
-
-		// 对每一行独立创建CSV reader进行解析
-		lineReader := csv.NewReader(strings.NewReader(line))
-		lineReader.FieldsPerRecord = -1 // 允许不同数量的字段
-		lineReader.LazyQuotes = true    // 更宽松的引号处理
-
-		record, err := lineReader.Read()
 		if err != nil {
-			// 跳过格式错误的行，但不影响后续行
-			if logKey != "" {
-				utils.FileLogWarn(logKey, "第 %d 行解析错误，已跳过: %v (内容: %.100s...)", lineNumber, err, line)
-			}
-			skippedRows++
-			continue
-		}
-
-		// 第一个有效行作为表头
-		if len(headers) == 0 {
-			headers = record
-			if logKey != "" {
-				utils.FileLogInfo(logKey, "识别到表头，共 %d 列: %v", len(headers), headers)
-			}
-		} else {
-			rows = append(rows, record)
-		}
-	}
-
-	// 检查Scanner错误
-	if err := scanner.Err(); err != nil {
-		if logKey != "" {
-			utils.FileLogError(logKey, "读取文件时发生错误: %v", err)
-		}
-	}
-
-	if logKey != "" {
-		utils.FileLogInfo(logKey, "CSV读取完成: 总行数=%d, 有效数据行=%d, 跳过行数=%d", lineNumber, len(rows), skippedRows)
-	}
-
-	if len(headers) == 0 {
-		if logKey != "" {
-			utils.FileLogWarn(logKey, "文件为空或没有有效数据")
+			return nil, nil, err
 		}
-		return &models.CSVData{
-			Headers: []string{},
-			Rows:    [][]string{},
-			Total:   0,
-		}, nil
-	}
-
-	// 根据协议进行特定的数据处理
-	if logKey != "" {
-		utils.FileLogInfo(logKey, "开始使用 %s 协议处理数据...", protocol)
-	}
-	processedData := s.processDataByProtocolWithLog(headers, rows, protocol, logKey)
-
-	if logKey != "" {
-		utils.FileLogInfo(logKey, "数据处理完成: 输出列数=%d, 输出行数=%d", len(processedData.Headers), len(processedData.Rows))
+		rows = append(rows, row)
 	}
-
-	return &models.CSVData{
-		Headers: processedData.Headers,
-		Rows:    processedData.Rows,
-		Total:   len(processedData.Rows),
-	}, nil
+	return records.Headers, rows, nil
 }
 
 // GetFiles 获取已上传的文件列表
@@ -236,16 +173,18 @@ func (s *CSVService) GetFiles() ([]*models.CSVFile, error) {
 		}
 
 		// 获取文件信息
-		filePath := filepath.Join(s.uploadDir, entry.Name())
 		info, err := entry.Info()
 		if err != nil {
 			continue
 		}
 
-		// 验证CSV文件
-		rowCount, columnCount, err := s.validateCSV(filePath)
-		if err != nil {
-			continue // 跳过无效文件
+		// 使用上传时保存的格式和行列数；旧文件或文件被替换时重新识别一次并保存
+		meta, ok := s.loadFileMeta(entry.Name(), info)
+		if !ok {
+			meta = s.inspectFile(filepath.Join(s.uploadDir, entry.Name()), info)
+			if err := s.saveFileMeta(entry.Name(), meta); err != nil {
+				utils.Warn("保存文件元数据失败: %v", err)
+			}
 		}
 
 		// 解析文件名: UUID_PROTOCOL_原始文件名.csv 或 UUID_原始文件名.csv（旧格式）
@@ -272,9 +211,11 @@ func (s *CSVService) GetFiles() ([]*models.CSVFile, error) {
 			OriginalName: originalName,
 			Size:         info.Size(),
 			UploadTime:   info.ModTime(),
-			RowCount:     rowCount,
-			ColumnCount:  columnCount,
+			RowCount:     meta.RowCount,
+			ColumnCount:  meta.ColumnCount,
 			ProtocolType: protocolType,
+			Format:       meta.Format,
+			Error:        meta.Error,
 		}
 
 		files = append(files, file)
@@ -292,12 +233,87 @@ func (s *CSVService) GetFiles() ([]*models.CSVFile, error) {
 func (s *CSVService) DeleteFile(filename string) error {
 	filePath := filepath.Join(s.uploadDir, filename)
 
-	// 删除所有相关的缓存文件
+	// 删除所有相关的缓存文件和元数据
 	s.DeleteCacheForFile(filename)
+	os.Remove(s.getMetaPath(filename))
 
 	return os.Remove(filePath)
 }
 
+// fileMeta 上传文件的元数据，保存在 uploads/meta/<文件名>.json
+// Size和ModTime与文件不一致时视为文件已被替换，需要重新识别
+type fileMeta struct {
+	Size        int64     `json:"size"`
+	ModTime     time.Time `json:"modTime"`
+	Format      string    `json:"format"`
+	RowCount    int       `json:"rowCount"`
+	ColumnCount int       `json:"columnCount"`
+	Error       string    `json:"error,omitempty"` // 无法识别或验证失败的原因
+}
+
+// getMetaPath 获取上传文件的元数据路径
+func (s *CSVService) getMetaPath(filename string) string {
+	return filepath.Join(s.uploadDir, "meta", filename+".json")
+}
+
+// loadFileMeta 读取元数据，不存在或与文件不一致时返回false
+func (s *CSVService) loadFileMeta(filename string, info os.FileInfo) (*fileMeta, bool) {
+	content, err := os.ReadFile(s.getMetaPath(filename))
+	if err != nil {
+		return nil, false
+	}
+	var meta fileMeta
+	if err := json.Unmarshal(content, &meta); err != nil {
+		return nil, false
+	}
+	if meta.Size != info.Size() || !meta.ModTime.Equal(info.ModTime()) {
+		return nil, false
+	}
+	return &meta, true
+}
+
+// saveFileMeta 保存元数据
+func (s *CSVService) saveFileMeta(filename string, meta *fileMeta) error {
+	path := s.getMetaPath(filename)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	content, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, content, 0644)
+}
+
+// inspectFile 按内容识别格式并验证文件，无法识别或无效时在Error中说明原因
+func (s *CSVService) inspectFile(filePath string, info os.FileInfo) *fileMeta {
+	meta := &fileMeta{Size: info.Size(), ModTime: info.ModTime()}
+	format, err := s.detectFileFormat(filePath)
+	if err != nil {
+		meta.Error = fmt.Sprintf("failed to read file: %v", err)
+		return meta
+	}
+	if format == "" {
+		meta.Error = formats.ErrUnknownFormat.Error()
+		return meta
+	}
+	meta.Format = format
+	if meta.RowCount, meta.ColumnCount, err = s.validateFile(filePath, format); err != nil {
+		meta.Error = fmt.Sprintf("invalid %s file: %v", formats.FormatNames[format], err)
+	}
+	return meta
+}
+
+// 缓存文件扩展名，旧版本的JSON缓存（.cache.json）不再读取，删除时一并清理
+const (
+	cacheExt       = ".cache"
+	legacyCacheExt = ".cache.json"
+)
+
+// cacheSchemaVersion 解析输出的版本，写入缓存文件的索引
+// 处理器的输出（列、Decoded字段、DLC校验和计时列等）发生变化时必须加1，版本不一致的缓存视为未命中并重新解析
+const cacheSchemaVersion = 3
+
 // getCacheDir 获取缓存目录路径
 func (s *CSVService) getCacheDir() string {
 	return filepath.Join(s.uploadDir, "cache")
@@ -307,60 +323,103 @@ func (s *CSVService) getCacheDir() string {
 func (s *CSVService) getCachePath(filename, protocol string) string {
 	// 使用文件名（不含扩展名）+ 协议作为缓存文件名
 	baseName := strings.TrimSuffix(filename, filepath.Ext(filename))
-	return filepath.Join(s.getCacheDir(), baseName+"_"+protocol+".cache.json")
+	return filepath.Join(s.getCacheDir(), baseName+"_"+protocol+cacheExt)
 }
 
-// GetCachedResult 检查并读取缓存的解析结果
-func (s *CSVService) GetCachedResult(filename, protocol string) (*models.CSVData, bool) {
+// OpenCache 打开缓存的解析结果（rowcache列式文件），只读取索引，调用方负责关闭
+func (s *CSVService) OpenCache(filename, protocol string) (*rowcache.Reader, bool) {
 	cachePath := s.getCachePath(filename, protocol)
-
-	// 检查缓存文件是否存在
-	if _, err := os.Stat(cachePath); os.IsNotExist(err) {
+	reader, err := rowcache.Open(cachePath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			utils.Warn("读取缓存文件失败: %v", err)
+		}
 		return nil, false
 	}
+	if reader.Schema() != cacheSchemaVersion {
+		// 缓存由旧版本的处理器生成，重新解析时覆盖
+		utils.Info("缓存输出版本不一致（%d，当前 %d），重新解析: %s", reader.Schema(), cacheSchemaVersion, cachePath)
+		reader.Close()
+		return nil, false
+	}
+	utils.Info("成功读取缓存: %s", cachePath)
+	return reader, true
+}
 
-	// 读取缓存文件
-	data, err := os.ReadFile(cachePath)
+// OpenParsedCache 打开解析结果缓存，没有缓存时先解析文件写入缓存（供分析接口使用，不记录解析日志）
+func (s *CSVService) OpenParsedCache(filename, protocol string) (*rowcache.Reader, error) {
+	if reader, ok := s.OpenCache(filename, protocol); ok {
+		return reader, nil
+	}
+	stream, err := s.OpenParse(filename, protocol, "")
 	if err != nil {
-		utils.Warn("读取缓存文件失败: %v", err)
+		return nil, err
+	}
+	defer stream.Close()
+	if _, err := stream.BuildCache(); err != nil {
+		return nil, err
+	}
+	if reader, ok := s.OpenCache(filename, protocol); ok {
+		return reader, nil
+	}
+	return nil, fmt.Errorf("cache file not available")
+}
+
+// GetCachedResult 检查并读取缓存的解析结果（全部载入内存）
+func (s *CSVService) GetCachedResult(filename, protocol string) (*models.CSVData, bool) {
+	reader, ok := s.OpenCache(filename, protocol)
+	if !ok {
 		return nil, false
 	}
+	defer reader.Close()
 
-	// 解析JSON
-	var csvData models.CSVData
-	if err := json.Unmarshal(data, &csvData); err != nil {
+	data := &models.CSVData{
+		Headers: reader.Headers(),
+		Rows:    make([][]string, 0, reader.Len()),
+		Total:   reader.Len(),
+	}
+	if reader.HasDecoded() {
+		data.Decoded = make([][]models.DecodedField, 0, reader.Len())
+	}
+	err := reader.Scan(rowcache.ScanOptions{Decoded: reader.HasDecoded()}, func(row *rowcache.Row) error {
+		data.Rows = append(data.Rows, append([]string(nil), row.Cells...))
+		if data.Decoded != nil {
+			fields, err := unmarshalDecoded(row.Decoded)
+			if err != nil {
+				return err
+			}
+			data.Decoded = append(data.Decoded, fields)
+		}
+		return nil
+	})
+	if err != nil {
 		utils.Warn("解析缓存数据失败: %v", err)
 		return nil, false
 	}
-
-	utils.Info("成功读取缓存: %s", cachePath)
-	return &csvData, true
+	return data, true
 }
 
 // SaveCacheResult 保存解析结果到缓存
 func (s *CSVService) SaveCacheResult(filename, protocol string, data *models.CSVData) error {
-	cacheDir := s.getCacheDir()
-
-	// 确保缓存目录存在
-	if err := os.MkdirAll(cacheDir, 0755); err != nil {
-		return fmt.Errorf("创建缓存目录失败: %v", err)
-	}
-
-	cachePath := s.getCachePath(filename, protocol)
-
-	// 序列化为JSON
-	jsonData, err := json.Marshal(data)
+	sink, err := s.createCacheSink(filename, protocol)
 	if err != nil {
-		return fmt.Errorf("序列化缓存数据失败: %v", err)
+		return err
 	}
-
-	// 写入文件
-	if err := os.WriteFile(cachePath, jsonData, 0644); err != nil {
-		return fmt.Errorf("写入缓存文件失败: %v", err)
+	if err := sink.WriteHeaders(data.Headers); err != nil {
+		sink.abort()
+		return err
 	}
-
-	utils.Info("成功保存缓存: %s", cachePath)
-	return nil
+	for i, row := range data.Rows {
+		var decoded []models.DecodedField
+		if i < len(data.Decoded) {
+			decoded = data.Decoded[i]
+		}
+		if err := sink.WriteRow(parsedRow{Row: row, Decoded: decoded}); err != nil {
+			sink.abort()
+			return err
+		}
+	}
+	return sink.commit()
 }
 
 // DeleteCacheForFile 删除指定文件的所有缓存
@@ -371,13 +430,78 @@ func (s *CSVService) DeleteCacheForFile(filename string) {
 	// 删除所有协议的缓存
 	protocols := []string{"CAN", "CANOPEN", "COMMON"}
 	for _, protocol := range protocols {
-		cachePath := filepath.Join(cacheDir, baseName+"_"+protocol+".cache.json")
-		if err := os.Remove(cachePath); err == nil {
-			utils.Info("已删除缓存文件: %s", cachePath)
+		for _, ext := range []string{cacheExt, legacyCacheExt} {
+			cachePath := filepath.Join(cacheDir, baseName+"_"+protocol+ext)
+			if err := os.Remove(cachePath); err == nil {
+				utils.Info("已删除缓存文件: %s", cachePath)
+			}
 		}
 	}
 }
 
+// DeleteCacheForProtocol 删除指定协议的所有缓存（协议配置变化后调用）
+func (s *CSVService) DeleteCacheForProtocol(protocol string) {
+	for _, ext := range []string{cacheExt, legacyCacheExt} {
+		matches, err := filepath.Glob(filepath.Join(s.getCacheDir(), "*_"+protocol+ext))
+		if err != nil {
+			continue
+		}
+		for _, cachePath := range matches {
+			if err := os.Remove(cachePath); err == nil {
+				utils.Info("已删除缓存文件: %s", cachePath)
+			}
+		}
+	}
+}
+
+// detectFileFormat 读取文件开头识别文件格式，无法识别时返回空字符串
+func (s *CSVService) detectFileFormat(filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	format, _, err := formats.DetectReader(file)
+	return format, err
+}
+
+// validateFile 按格式验证文件，返回行数和列数
+func (s *CSVService) validateFile(filePath, format string) (rowCount, columnCount int, err error) {
+	if format == formats.FormatSnifferCSV {
+		return s.validateCSV(filePath)
+	}
+	return s.validateLog(filePath, format)
+}
+
+// validateLog 验证日志格式文件，至少需要包含一帧报文
+func (s *CSVService) validateLog(filePath, format string) (rowCount, columnCount int, err error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer file.Close()
+
+	reader, err := formats.NewFrameReader(format, file)
+	if err != nil {
+		return 0, 0, err
+	}
+	for {
+		_, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+		rowCount++
+	}
+	if rowCount == 0 {
+		return 0, 0, fmt.Errorf("no CAN frames found")
+	}
+	return rowCount, len(formats.LogHeaders), nil
+}
+
 // validateCSV 验证CSV文件格式，跳过格式错误的行
 func (s *CSVService) validateCSV(filePath string) (rowCount, columnCount int, err error) {
 	file, err := os.Open(filePath)
@@ -392,7 +516,7 @@ func (s *CSVService) validateCSV(filePath string) (rowCount, columnCount int, er
 	buf := make([]byte, maxScanTokenSize)
 	scanner.Buffer(buf, maxScanTokenSize)
 
-	var validRecords [][]string
+	// 只统计行数，不保留数据行，避免大文件占用内存
 	skippedRows := 0
 	lineNumber := 0
 
@@ -419,7 +543,11 @@ func (s *CSVService) validateCSV(filePath string) (rowCount, columnCount int, er
 			skippedRows++
 			continue
 		}
-		validRecords = append(validRecords, record)
+		if rowCount == 0 {
+			// 计算列数（使用第一行的列数）
+			columnCount = len(record)
+		}
+		rowCount++
 	}
 
 	// 检查Scanner错误
@@ -431,41 +559,42 @@ func (s *CSVService) validateCSV(filePath string) (rowCount, columnCount int, er
 		utils.Info("Validated CSV file, skipped %d invalid rows", skippedRows)
 	}
 
-	if len(validRecords) == 0 {
-		return 0, 0, nil
-	}
-
-	// 计算列数（使用第一行的列数）
-	columnCount = len(validRecords[0])
-	rowCount = len(validRecords)
-
 	return rowCount, columnCount, nil
 }
 
-// processDataByProtocol 根据协议处理数据
-func (s *CSVService) processDataByProtocol(headers []string, rows [][]string, protocol string) *models.CSVData {
-	return s.processDataByProtocolWithLog(headers, rows, protocol, "")
-}
-
-// processDataByProtocolWithLog 根据协议处理数据（带日志）
-func (s *CSVService) processDataByProtocolWithLog(headers []string, rows [][]string, protocol string, logKey string) *models.CSVData {
+// newRowProcessor 根据协议创建行处理器
+func (s *CSVService) newRowProcessor(headers []string, protocol string, logKey string) rowProcessor {
 	switch protocol {
 	case "CAN":
-		return s.processCANDataWithLog(headers, rows, logKey)
+		return s.newCANProcessor(headers, logKey)
 	case "CANOPEN":
-		return s.processCANOPENDataWithLog(headers, rows, logKey)
+		return s.newCANOPENProcessor(headers, logKey)
 	default:
 		if logKey != "" {
 			utils.FileLogInfo(logKey, "使用默认处理，直接返回原始数据")
 		}
 		// 默认返回原始数据
-		return &models.CSVData{
-			Headers: headers,
-			Rows:    rows,
-		}
+		return rawProcessor{headers: headers}
 	}
 }
 
+// rawProcessor 不做协议处理，直接返回原始数据
+type rawProcessor struct {
+	headers []string
+}
+
+func (p rawProcessor) Headers() []string { return p.headers }
+
+func (p rawProcessor) Process(rows [][]string) []parsedRow {
+	out := make([]parsedRow, len(rows))
+	for i, row := range rows {
+		out[i].Row = row
+	}
+	return out
+}
+
+func (p rawProcessor) Finish() {}
+
 // ColumnPattern 表示列的正则匹配模式
 type ColumnPattern struct {
 	Name        string `json:"name"`
@@ -506,7 +635,7 @@ func (s *CSVService) loadRowFilterConfig() (*RowFilterConfig, error) {
 				{Name: "Name", Index: 3, Required: false, MatchType: "any"},
 				{Name: "Time", Index: 4, Required: true, MatchType: "regex", Pattern: `^\d{4}-\d{2}-\d{2}\s+\d{2}:\d{2}:\d{2}`},
 				{Name: "Buffer", Index: 5, Required: true, MatchType: "regex", Patterns: []ColumnPattern{
-					{Name: "CAN_MESSAGE", Pattern: `^string=[0-9a-fA-F]{1,4}:\d{1,2}:\[[0-9a-fA-F ]*\]`},
+					{Name: "CAN_MESSAGE", Pattern: `^string=[0-9a-fA-F]{1,8}:\d{1,2}:\[[0-9a-fA-F ]*\]`},
 					{Name: "USHORT_VALUE", Pattern: `^string=ushort=\d+`},
 					{Name: "STRUCT_VALUE", Pattern: `^\{.*\}$`},
 				}},
@@ -532,12 +661,23 @@ func (s *CSVService) isValidRow(row []string, config *RowFilterConfig, headers [
 		return false
 	}
 
+	// 结构体消息（如exposeDeviceStatus）在XRTechMgr以外的模块之间收发，不按Source/Target过滤
+	structRow := false
+	for _, col := range config.Columns {
+		if col.Name == "Buffer" && col.Index >= 0 && col.Index < len(row) {
+			structRow = structPattern.MatchString(row[col.Index])
+		}
+	}
+
 	// 遍历每个配置的列进行验证
 	for _, col := range config.Columns {
 		// 跳过不需要验证的列
 		if !col.Required {
 			continue
 		}
+		if structRow && (col.Name == "Source" || col.Name == "Target") {
+			continue
+		}
 
 		// 检查索引是否有效
 		if col.Index < 0 || col.Index >= len(row) {
@@ -656,6 +796,23 @@ func (s *CSVService) loadCANDefinitions() (map[string]string, error) {
 	return definitions, nil
 }
 
+// loadDataParserConfig 加载CAN数据字节解析配置
+func (s *CSVService) loadDataParserConfig() (decoder.Config, error) {
+	configPath := filepath.Join("..", "backend", "config", "can", "data_parser.json")
+	config, skipped, err := decoder.LoadConfig(configPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// 配置文件不存在时不做字节解析
+			return decoder.Config{}, nil
+		}
+		return nil, err
+	}
+	if len(skipped) > 0 {
+		utils.Warn("data_parser.json中的位字段start/bits无效，已跳过: %s", strings.Join(skipped, ", "))
+	}
+	return config, nil
+}
+
 // isValidCANMessage 检查Buffer字段是否为有效的消息格式
 // 有效格式1: string=ID:Length:[HH HH HH ...] 例如: string=2cf:8:[10 40 ff 37 48 c1 0a 00]
 // 有效格式2: string=ushort=X 例如: string=ushort=0
@@ -677,13 +834,42 @@ func isValidCANMessage(buffer string) bool {
 	return false
 }
 
-// processCANData 处理CAN协议数据（FIXED格式）
-func (s *CSVService) processCANData(headers []string, rows [][]string) *models.CSVData {
-	return s.processCANDataWithLog(headers, rows, "")
+// canProcessor 处理CAN协议数据（FIXED格式），每批数据并行处理，输出保持原始顺序
+type canProcessor struct {
+	service        *CSVService
+	logKey         string
+	headers        []string
+	canHeaders     []string
+	filterConfig   *RowFilterConfig
+	canDefinitions map[string]string
+	dataParser     decoder.Config
+	bufferIdx      int
+	nameIdx        int
+	flagsIdx       int
+	timeIdx        int
+	numWorkers     int
+	timing         *rowTiming
+
+	// 统计信息
+	inputCount       int
+	validCount       int
+	decodedCount     int
+	dlcMismatchCount int
+	matchedMeanings  map[string]int
+}
+
+// canRowResult 单行的处理结果
+type canRowResult struct {
+	parsedRow
+	meaning     string
+	dlcMismatch bool
+	valid       bool
+	time        rowTime
+	rowIdx      int
 }
 
-// processCANDataWithLog 处理CAN协议数据（带日志）
-func (s *CSVService) processCANDataWithLog(headers []string, rows [][]string, logKey string) *models.CSVData {
+// newCANProcessor 加载CAN协议配置并创建处理器
+func (s *CSVService) newCANProcessor(headers []string, logKey string) *canProcessor {
 	if logKey != "" {
 		utils.FileLogInfo(logKey, "===== 开始CAN协议数据处理 =====")
 	}
@@ -709,21 +895,50 @@ func (s *CSVService) processCANDataWithLog(headers []string, rows [][]string, lo
 		utils.FileLogInfo(logKey, "成功加载CAN定义，共 %d 条消息定义", len(canDefinitions))
 	}
 
+	// 加载数据字节解析配置
+	dataParser, err := s.loadDataParserConfig()
+	if err != nil {
+		if logKey != "" {
+			utils.FileLogWarn(logKey, "加载数据解析配置失败: %v，将不输出解码字段", err)
+		}
+		dataParser = decoder.Config{}
+	} else if logKey != "" {
+		utils.FileLogInfo(logKey, "成功加载数据解析配置，共 %d 条解析规则", len(dataParser))
+	}
+
 	// CAN协议FIXED格式处理逻辑
-	// 为CAN协议添加特定的列,包括Meaning
+	// 为CAN协议添加特定的列,包括Meaning、Decoded、DLC校验和由Time列计算的时间列
 	canHeaders := append([]string{"协议类型", "消息ID", "数据长度"}, headers...)
-	canHeaders = append(canHeaders, "Meaning")
+	canHeaders = append(canHeaders, "Meaning", "Decoded", "DLC校验")
+	canHeaders = append(canHeaders, timingHeaders...)
 
 	if logKey != "" {
 		utils.FileLogInfo(logKey, "输出表头: %v", canHeaders)
 	}
 
-	// 找到Buffer列的索引
+	// 找到Buffer列、Name列、Time列和Flags列（日志格式导入）的索引
 	bufferIdx := -1
+	nameIdx := -1
+	timeIdx := -1
+	flagsIdx := -1
 	for i, h := range headers {
-		if strings.ToLower(h) == "buffer" {
-			bufferIdx = i
-			break
+		switch strings.ToLower(h) {
+		case "buffer":
+			if bufferIdx < 0 {
+				bufferIdx = i
+			}
+		case "name":
+			if nameIdx < 0 {
+				nameIdx = i
+			}
+		case "time":
+			if timeIdx < 0 {
+				timeIdx = i
+			}
+		case "flags":
+			if flagsIdx < 0 {
+				flagsIdx = i
+			}
 		}
 	}
 
@@ -733,6 +948,9 @@ func (s *CSVService) processCANDataWithLog(headers []string, rows [][]string, lo
 		} else {
 			utils.FileLogWarn(logKey, "未找到Buffer列，无法解析CAN消息ID")
 		}
+		if timeIdx < 0 {
+			utils.FileLogWarn(logKey, "未找到Time列，时间列将留空")
+		}
 	}
 
 	// 并行处理配置
@@ -740,184 +958,304 @@ func (s *CSVService) processCANDataWithLog(headers []string, rows [][]string, lo
 	if numWorkers < 1 {
 		numWorkers = 1
 	}
-	rowCount := len(rows)
-
-	// 如果数据量较小，使用单线程处理
-	if rowCount < 1000 {
-		numWorkers = 1
-	}
-
 	if logKey != "" {
-		utils.FileLogInfo(logKey, "使用 %d 个并行工作线程处理 %d 行数据", numWorkers, rowCount)
+		utils.FileLogInfo(logKey, "使用 %d 个并行工作线程，每批处理 %d 行数据", numWorkers, parseBatchSize)
 	}
 
-	// 用于存储处理结果的结构
-	type processedRow struct {
-		index   int
-		row     []string
-		meaning string
-		valid   bool
+	return &canProcessor{
+		service:         s,
+		logKey:          logKey,
+		headers:         headers,
+		canHeaders:      canHeaders,
+		filterConfig:    filterConfig,
+		canDefinitions:  canDefinitions,
+		dataParser:      dataParser,
+		bufferIdx:       bufferIdx,
+		nameIdx:         nameIdx,
+		flagsIdx:        flagsIdx,
+		timeIdx:         timeIdx,
+		numWorkers:      numWorkers,
+		timing:          newRowTiming(logKey),
+		matchedMeanings: make(map[string]int),
 	}
+}
 
-	// 创建结果通道
-	results := make(chan processedRow, rowCount)
-	var wg sync.WaitGroup
+func (p *canProcessor) Headers() []string { return p.canHeaders }
 
-	// 计算每个worker处理的行数范围
-	chunkSize := (rowCount + numWorkers - 1) / numWorkers
+// Process 并行处理一批数据，每个worker写入结果切片的固定位置，无需排序
+// 时间列依赖上一行，在并行处理后按顺序填写
+func (p *canProcessor) Process(rows [][]string) []parsedRow {
+	rowCount := len(rows)
+	results := make([]canRowResult, rowCount)
 
-	// 启动并行处理
-	for w := 0; w < numWorkers; w++ {
-		startIdx := w * chunkSize
-		endIdx := startIdx + chunkSize
-		if endIdx > rowCount {
-			endIdx = rowCount
-		}
-		if startIdx >= rowCount {
-			break
-		}
+	// 如果数据量较小，使用单线程处理
+	numWorkers := p.numWorkers
+	if rowCount < 1000 {
+		numWorkers = 1
+	}
+	chunkSize := (rowCount + numWorkers - 1) / numWorkers
 
+	var wg sync.WaitGroup
+	for start := 0; start < rowCount; start += chunkSize {
+		end := min(start+chunkSize, rowCount)
 		wg.Add(1)
 		go func(start, end int) {
 			defer wg.Done()
-			for rowIdx := start; rowIdx < end; rowIdx++ {
-				row := rows[rowIdx]
-
-				// 使用配置驱动的行过滤（注意：日志在并发时可能会乱序，所以禁用）
-				if !s.isValidRow(row, filterConfig, headers, "", rowIdx) {
-					results <- processedRow{index: rowIdx, valid: false}
-					continue
-				}
-
-				// 为每行添加CAN协议特定的信息
-				canRow := []string{
-					"CAN",                                // 协议类型
-					"0x" + fmt.Sprintf("%03X", len(row)), // 消息ID（基于数据长度）
-					fmt.Sprintf("%d", len(row)),          // 数据长度
-				}
-				canRow = append(canRow, row...)
-
-				// 尝试从Buffer字段解析ID并查找Meaning
-				meaning := ""
-				if bufferIdx >= 0 && bufferIdx < len(row) {
-					buffer := row[bufferIdx]
-					// 解析Buffer: 形如string=2cf:8:[10 40 ff 37 48 c1 0a 00]
-					if strings.Contains(buffer, "string=") {
-						// 提取ID部分
-						parts := strings.Split(buffer, ":")
-						if len(parts) >= 1 {
-							idPart := strings.TrimPrefix(parts[0], "string=")
-							idPart = strings.TrimSpace(idPart)
-							// 查找定义
-							if def, exists := canDefinitions[strings.ToLower(idPart)]; exists {
-								meaning = def
-							}
-						}
-					}
-				}
-				canRow = append(canRow, meaning)
-				results <- processedRow{index: rowIdx, row: canRow, meaning: meaning, valid: true}
+			for i := start; i < end; i++ {
+				results[i] = p.processRow(rows[i], p.inputCount+i)
 			}
-		}(startIdx, endIdx)
+		}(start, end)
 	}
+	wg.Wait()
+	p.inputCount += rowCount
 
-	// 等待所有worker完成，然后关闭结果通道
-	go func() {
-		wg.Wait()
-		close(results)
-	}()
+	out := make([]parsedRow, 0, rowCount)
+	for _, r := range results {
+		if !r.valid {
+			continue
+		}
+		p.timing.fill(r.Row[len(r.Row)-len(timingHeaders):], r.time, r.rowIdx+1)
+		out = append(out, r.parsedRow)
+		p.validCount++
+		if len(r.Decoded) > 0 {
+			p.decodedCount++
+		}
+		if r.dlcMismatch {
+			p.dlcMismatchCount++
+		}
+		if r.meaning != "" {
+			p.matchedMeanings[r.meaning]++
+		}
+	}
+	return out
+}
 
-	// 收集结果并按原始顺序排序
-	tempResults := make([]processedRow, 0, rowCount)
-	for r := range results {
-		tempResults = append(tempResults, r)
+// processRow 处理单行，可并发调用
+func (p *canProcessor) processRow(row []string, rowIdx int) canRowResult {
+	// 使用配置驱动的行过滤（注意：日志在并发时可能会乱序，所以禁用）
+	if !p.service.isValidRow(row, p.filterConfig, p.headers, "", rowIdx) {
+		return canRowResult{}
 	}
 
-	// 按原始索引排序，保持顺序
-	sort.Slice(tempResults, func(i, j int) bool {
-		return tempResults[i].index < tempResults[j].index
-	})
+	// 解析Buffer字段中的CAN帧: 形如string=2cf:8:[10 40 ff 37 48 c1 0a 00]
+	var frame *models.CANFrame
+	if p.bufferIdx >= 0 && p.bufferIdx < len(row) {
+		frame, _ = formats.ParseSnifferBuffer(row[p.bufferIdx])
+	}
+	if frame != nil && p.flagsIdx >= 0 && p.flagsIdx < len(row) {
+		formats.ApplyFlags(frame, row[p.flagsIdx])
+	}
 
-	// 构建最终结果
-	var canRows [][]string
-	filteredCount := 0
-	matchedMeanings := make(map[string]int)
+	// 为每行添加CAN协议特定的信息（非CAN帧的行ID和长度留空）
+	canRow := make([]string, 0, len(row)+6+len(timingHeaders))
+	canRow = append(canRow, "CAN", "", "")
+	dlcCheck := ""
+	meaning := ""
+	timeID := cell(row, p.nameIdx)
+	var decoded []models.DecodedField
+	if frame != nil {
+		timeID = formats.FormatID(frame)
+		canRow[1] = formats.DisplayID(frame) // 消息ID
+		canRow[2] = strconv.Itoa(frame.DLC)  // 数据长度
+		if frame.DLCMismatch() {
+			dlcCheck = fmt.Sprintf("声明%d字节, 实际%d字节", frame.DLC, len(frame.Data))
+		}
 
-	for _, r := range tempResults {
-		if r.valid {
-			canRows = append(canRows, r.row)
-			if r.meaning != "" {
-				matchedMeanings[r.meaning]++
+		// 错误帧的ID是错误类别，不查找定义
+		if !frame.ErrorFrame {
+			canID := formats.FormatID(frame)
+			if def, exists := p.canDefinitions[canID]; exists {
+				meaning = def
 			}
-		} else {
-			filteredCount++
+			// 按data_parser.json解码数据字节
+			decoded = p.dataParser.Decode(canID, frame.Data)
 		}
 	}
+	canRow = append(canRow, row...)
 
-	// 记录统计信息
-	if logKey != "" {
-		utils.FileLogInfo(logKey, "CAN数据处理完成:")
-		utils.FileLogInfo(logKey, "  - 输入行数: %d", len(rows))
-		utils.FileLogInfo(logKey, "  - 有效行数: %d", len(canRows))
-		utils.FileLogInfo(logKey, "  - 过滤行数: %d", filteredCount)
-
-		if len(matchedMeanings) > 0 {
-			utils.FileLogInfo(logKey, "  - 消息类型统计:")
-			for meaning, count := range matchedMeanings {
-				utils.FileLogInfo(logKey, "      %s: %d 条", meaning, count)
-			}
+	// 结构体形式的Buffer（如exposeDeviceStatus的{ulong=1;enum SwitchStatus=EXPOSE_ON;}）解析为字段
+	if frame == nil && structPattern.MatchString(cell(row, p.bufferIdx)) {
+		if fields, err := formats.ParseSnifferStruct(cell(row, p.bufferIdx)); err == nil && len(fields) > 0 {
+			decoded = fields
 		}
+	}
+
+	// 非CAN帧尝试按Name匹配（如RTB信号）
+	if decoded == nil && p.nameIdx >= 0 && p.nameIdx < len(row) {
+		decoded = p.dataParser.DecodeByName(row[p.nameIdx])
+	}
+
+	canRow = append(canRow, meaning, decoder.FormatFields(decoded), dlcCheck)
+	canRow = append(canRow, make([]string, len(timingHeaders))...)
+	return canRowResult{
+		parsedRow:   parsedRow{Row: canRow, Decoded: decoded},
+		meaning:     meaning,
+		dlcMismatch: dlcCheck != "",
+		valid:       true,
+		time:        parseRowTime(cell(row, p.timeIdx), timeID),
+		rowIdx:      rowIdx,
+	}
+}
 
-		utils.FileLogInfo(logKey, "===== CAN协议数据处理完成 =====")
+// Finish 记录统计信息
+func (p *canProcessor) Finish() {
+	logKey := p.logKey
+	if logKey == "" {
+		return
+	}
+	utils.FileLogInfo(logKey, "CAN数据处理完成:")
+	utils.FileLogInfo(logKey, "  - 输入行数: %d", p.inputCount)
+	utils.FileLogInfo(logKey, "  - 有效行数: %d", p.validCount)
+	utils.FileLogInfo(logKey, "  - 过滤行数: %d", p.inputCount-p.validCount)
+	utils.FileLogInfo(logKey, "  - 解码行数: %d", p.decodedCount)
+	if p.dlcMismatchCount > 0 {
+		utils.FileLogWarn(logKey, "  - DLC与实际字节数不一致: %d 条", p.dlcMismatchCount)
 	}
+	p.timing.report()
 
-	return &models.CSVData{
-		Headers: canHeaders,
-		Rows:    canRows,
+	if len(p.matchedMeanings) > 0 {
+		utils.FileLogInfo(logKey, "  - 消息类型统计:")
+		for meaning, count := range p.matchedMeanings {
+			utils.FileLogInfo(logKey, "      %s: %d 条", meaning, count)
+		}
 	}
+
+	utils.FileLogInfo(logKey, "===== CAN协议数据处理完成 =====")
 }
 
-// processCANOPENData 处理CANOPEN协议数据（Mobiled格式）
-func (s *CSVService) processCANOPENData(headers []string, rows [][]string) *models.CSVData {
-	return s.processCANOPENDataWithLog(headers, rows, "")
+// loadCANOPENConfig 加载CANOPEN协议配置
+func (s *CSVService) loadCANOPENConfig() (*canopen.Config, error) {
+	return canopen.LoadConfig(filepath.Join("..", "backend", "config", "canopen"))
 }
 
-// processCANOPENDataWithLog 处理CANOPEN协议数据（带日志）
-func (s *CSVService) processCANOPENDataWithLog(headers []string, rows [][]string, logKey string) *models.CSVData {
+// canopenProcessor 处理CANOPEN协议数据（Mobiled格式）
+// SDO分段传输需要跨帧跟踪状态，必须按顺序单线程处理，解码器状态跨批次保留
+type canopenProcessor struct {
+	logKey         string
+	config         *canopen.Config
+	canopenHeaders []string
+	bufferIdx      int
+	flagsIdx       int
+	dec            *canopen.Decoder
+	serviceCounts  map[string]int
+	inputCount     int
+	outputCount    int
+}
+
+// newCANOPENProcessor 加载CANOPEN协议配置并创建处理器
+func (s *CSVService) newCANOPENProcessor(headers []string, logKey string) *canopenProcessor {
 	if logKey != "" {
 		utils.FileLogInfo(logKey, "===== 开始CANOPEN协议数据处理 =====")
 	}
 
+	config, err := s.loadCANOPENConfig()
+	if err != nil {
+		if logKey != "" {
+			utils.FileLogWarn(logKey, "加载CANOPEN配置失败: %v，将使用空配置", err)
+		}
+		config = nil
+	} else if logKey != "" {
+		utils.FileLogInfo(logKey, "成功加载CANOPEN配置: 对象定义 %d 条, From->To规则 %d 条",
+			len(config.Definitions.Definitions), len(config.FromToMapping.Rules))
+	}
+
 	// CANOPEN协议Mobiled格式处理逻辑
-	// 示例：为CANOPEN协议添加特定的列
 	canopenHeaders := append([]string{"协议类型", "节点ID", "对象索引", "子索引"}, headers...)
+	canopenHeaders = append(canopenHeaders, "COB-ID", "服务", "From->To", "Meaning")
 
 	if logKey != "" {
 		utils.FileLogInfo(logKey, "输出表头: %v", canopenHeaders)
 	}
 
-	var canopenRows [][]string
-	for i, row := range rows {
-		// 为每行添加CANOPEN协议特定的信息
-		canopenRow := []string{
-			"CANOPEN",                    // 协议类型
-			fmt.Sprintf("Node_%d", i+1),  // 节点ID
-			fmt.Sprintf("0x%04X", i*100), // 对象索引
-			"0x00",                       // 子索引
+	bufferIdx := -1
+	flagsIdx := -1
+	for i, h := range headers {
+		switch strings.ToLower(h) {
+		case "buffer":
+			if bufferIdx < 0 {
+				bufferIdx = i
+			}
+		case "flags":
+			if flagsIdx < 0 {
+				flagsIdx = i
+			}
 		}
-		canopenRow = append(canopenRow, row...)
-		canopenRows = append(canopenRows, canopenRow)
+	}
+	if bufferIdx < 0 && logKey != "" {
+		utils.FileLogWarn(logKey, "未找到Buffer列，无法解析CANopen报文")
 	}
 
-	if logKey != "" {
-		utils.FileLogInfo(logKey, "CANOPEN数据处理完成:")
-		utils.FileLogInfo(logKey, "  - 输入行数: %d", len(rows))
-		utils.FileLogInfo(logKey, "  - 输出行数: %d", len(canopenRows))
-		utils.FileLogInfo(logKey, "===== CANOPEN协议数据处理完成 =====")
+	return &canopenProcessor{
+		logKey:         logKey,
+		config:         config,
+		canopenHeaders: canopenHeaders,
+		bufferIdx:      bufferIdx,
+		flagsIdx:       flagsIdx,
+		dec:            canopen.NewDecoder(config),
+		serviceCounts:  make(map[string]int),
 	}
+}
+
+func (p *canopenProcessor) Headers() []string { return p.canopenHeaders }
+
+func (p *canopenProcessor) Process(rows [][]string) []parsedRow {
+	out := make([]parsedRow, 0, len(rows))
+	for _, row := range rows {
+		var frame *models.CANFrame
+		if p.bufferIdx >= 0 && p.bufferIdx < len(row) {
+			frame, _ = formats.ParseSnifferBuffer(row[p.bufferIdx])
+		}
+		if frame != nil && p.flagsIdx >= 0 && p.flagsIdx < len(row) {
+			formats.ApplyFlags(frame, row[p.flagsIdx])
+		}
+
+		// 非CAN帧的行保留原始数据，CANopen相关列留空
+		if frame == nil {
+			canopenRow := append([]string{"CANOPEN", "", "", ""}, row...)
+			canopenRow = append(canopenRow, "", "", "", "")
+			out = append(out, parsedRow{Row: canopenRow})
+			continue
+		}
+
+		msg := p.dec.Decode(frame)
+		p.serviceCounts[msg.Service]++
 
-	return &models.CSVData{
-		Headers: canopenHeaders,
-		Rows:    canopenRows,
+		nodeID := ""
+		if msg.NodeID != 0 {
+			nodeID = strconv.Itoa(int(msg.NodeID))
+		}
+		index, subIndex := "", ""
+		if msg.HasObject {
+			index = fmt.Sprintf("0x%04X", msg.Index)
+			subIndex = fmt.Sprintf("0x%02X", msg.SubIndex)
+		}
+		fromTo := ""
+		if p.config != nil {
+			fromTo = p.config.FromTo(msg.Service, msg.NodeID)
+		}
+
+		canopenRow := append([]string{"CANOPEN", nodeID, index, subIndex}, row...)
+		canopenRow = append(canopenRow, formats.DisplayID(frame), msg.Service, fromTo, msg.Summary)
+		out = append(out, parsedRow{Row: canopenRow, Decoded: msg.Fields})
+	}
+	p.inputCount += len(rows)
+	p.outputCount += len(out)
+	return out
+}
+
+func (p *canopenProcessor) Finish() {
+	logKey := p.logKey
+	if logKey == "" {
+		return
+	}
+	utils.FileLogInfo(logKey, "CANOPEN数据处理完成:")
+	utils.FileLogInfo(logKey, "  - 输入行数: %d", p.inputCount)
+	utils.FileLogInfo(logKey, "  - 输出行数: %d", p.outputCount)
+	if len(p.serviceCounts) > 0 {
+		utils.FileLogInfo(logKey, "  - 服务类型统计:")
+		for service, count := range p.serviceCounts {
+			utils.FileLogInfo(logKey, "      %s: %d 条", service, count)
+		}
 	}
+	utils.FileLogInfo(logKey, "===== CANOPEN协议数据处理完成 =====")
 }
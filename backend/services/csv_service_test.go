@@ -0,0 +1,172 @@
+package services
+
+import (
+	"csv-parser/formats"
+	"csv-parser/rowcache"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testCandump = "(1763111016.739000) can0 1CC#03\n(1763111016.741000) can0 208#0102\n"
+
+// 格式按内容识别，扩展名不影响上传
+func TestUploadFileDetectsByContent(t *testing.T) {
+	s := NewCSVService(t.TempDir())
+	file, err := s.UploadFile("capture.txt", strings.NewReader(testCandump), "CAN")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if file.Format != formats.FormatCandump || file.RowCount != 2 {
+		t.Errorf("format = %q, rows = %d", file.Format, file.RowCount)
+	}
+
+	_, err = s.UploadFile("data.csv", strings.NewReader("\x00\x01\x02 not a log"), "CAN")
+	if !errors.Is(err, formats.ErrUnknownFormat) {
+		t.Errorf("err = %v, want ErrUnknownFormat", err)
+	}
+}
+
+// 文件列表使用上传时保存的元数据，无法识别的文件带错误原因列出
+func TestGetFilesUsesMetadata(t *testing.T) {
+	dir := t.TempDir()
+	s := NewCSVService(dir)
+	uploaded, err := s.UploadFile("capture.log", strings.NewReader(testCandump), "CAN")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "legacy_CAN_junk.bin"), []byte("\x00\x01\x02"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := s.GetFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("got %d files, want 2", len(files))
+	}
+	byName := make(map[string]string)
+	for _, f := range files {
+		byName[f.Filename] = f.Error
+	}
+	if byName[uploaded.Filename] != "" {
+		t.Errorf("uploaded file error = %q", byName[uploaded.Filename])
+	}
+	if !strings.Contains(byName["legacy_CAN_junk.bin"], formats.ErrUnknownFormat.Error()) {
+		t.Errorf("junk file error = %q", byName["legacy_CAN_junk.bin"])
+	}
+
+	// 元数据与文件一致时不重新扫描：篡改元数据中的行数后列表应返回篡改的值
+	meta, ok := s.loadFileMeta(uploaded.Filename, statFile(t, filepath.Join(dir, uploaded.Filename)))
+	if !ok {
+		t.Fatal("metadata not saved at upload")
+	}
+	meta.RowCount = 99
+	if err := s.saveFileMeta(uploaded.Filename, meta); err != nil {
+		t.Fatal(err)
+	}
+	if rows := fileRows(t, s, uploaded.Filename); rows != 99 {
+		t.Errorf("rows = %d, want cached 99", rows)
+	}
+
+	// 文件被替换后重新识别
+	path := filepath.Join(dir, uploaded.Filename)
+	if err := os.WriteFile(path, []byte(testCandump+"(1763111016.742000) can0 1CC#04\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(path, time.Now(), time.Now().Add(time.Second))
+	if rows := fileRows(t, s, uploaded.Filename); rows != 3 {
+		t.Errorf("rows = %d, want 3 after replacing the file", rows)
+	}
+}
+
+func statFile(t *testing.T, path string) os.FileInfo {
+	t.Helper()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info
+}
+
+func fileRows(t *testing.T, s *CSVService, filename string) int {
+	t.Helper()
+	files, err := s.GetFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		if f.Filename == filename {
+			return f.RowCount
+		}
+	}
+	t.Fatalf("%s not listed", filename)
+	return 0
+}
+
+// 输出版本不一致的缓存视为未命中
+func TestOpenCacheSchemaMismatch(t *testing.T) {
+	dir := t.TempDir()
+	s := NewCSVService(dir)
+	if err := os.MkdirAll(filepath.Join(dir, "cache"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		schema uint64
+		hit    bool
+	}{{cacheSchemaVersion - 1, false}, {cacheSchemaVersion, true}} {
+		w, err := rowcache.Create(s.getCachePath("test.csv", "CAN"), tt.schema, []string{"Time"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Add([]string{"2025-11-14 17:03:36.739"}, nil, "", rowcache.NoTime); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Commit(); err != nil {
+			t.Fatal(err)
+		}
+		r, ok := s.OpenCache("test.csv", "CAN")
+		if ok {
+			r.Close()
+		}
+		if ok != tt.hit {
+			t.Errorf("schema %d: cache hit = %v, want %v", tt.schema, ok, tt.hit)
+		}
+	}
+}
+
+// 结构体消息不受row_filter.json中Source/Target的限制，其他行仍按配置过滤
+func TestIsValidRowStructBypassesSourceTarget(t *testing.T) {
+	content, err := os.ReadFile(filepath.Join("..", "config", "can", "row_filter.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var config RowFilterConfig
+	if err := json.Unmarshal(content, &config); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewCSVService(t.TempDir())
+	tests := []struct {
+		name string
+		row  []string
+		want bool
+	}{
+		{"can frame", []string{"publish", "XRTechMgr", "N/A", "FROM_JEDI_MSG", "2025-11-24 17:03:48.853", "string=2cf:8:[11 80 f2 aa 47 c8 16 00]"}, true},
+		{"can frame other source", []string{"publish", "XRUserIfActor", "N/A", "FROM_JEDI_MSG", "2025-11-24 17:03:48.853", "string=2cf:8:[11 80 f2 aa 47 c8 16 00]"}, false},
+		{"ushort other target", []string{"publish", "XRTechMgr", "XRAcqSync", "kvValue", "2025-11-24 17:03:48.853", "string=ushort=80"}, false},
+		{"struct", []string{"publish", "XRUserIfActor", "XRAcqSync", "exposeDeviceStatus", "2025-11-24 17:03:48.853", "{ulong=1;enum SwitchStatus=EXPOSE_ON;}"}, true},
+		{"struct bad type", []string{"broadcast", "XRUserIfActor", "XRAcqSync", "exposeDeviceStatus", "2025-11-24 17:03:48.853", "{ulong=1;}"}, false},
+		{"struct bad time", []string{"publish", "XRUserIfActor", "XRAcqSync", "exposeDeviceStatus", "N/A", "{ulong=1;}"}, false},
+	}
+	for _, tt := range tests {
+		if got := s.isValidRow(tt.row, &config, nil, "", 0); got != tt.want {
+			t.Errorf("%s: isValidRow = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,525 @@
+package services
+
+import (
+	"bytes"
+	"csv-parser/dbc"
+	"csv-parser/decoder"
+	"csv-parser/models"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 配置文件的缩进与仓库中手工维护的格式保持一致
+const (
+	definitionsIndent = "  "
+	dataParserIndent  = "    "
+)
+
+// DBCService 在DBC文件与CAN配置（definitions.json、data_parser.json）之间转换
+type DBCService struct {
+	configDir string
+}
+
+func NewDBCService(configDir string) *DBCService {
+	return &DBCService{
+		configDir: configDir,
+	}
+}
+
+// ImportDBC 解析DBC文件并生成CAN定义和数据解析配置
+// 已有配置的报文默认合并：保留已有的定义描述和手工编写的字节规则，只追加字节范围不重叠的信号；
+// overwrite为true时用DBC生成的条目整体替换。其余配置保持不变，dryRun为true时只返回生成结果
+func (s *DBCService) ImportDBC(filename string, file io.Reader, dryRun, overwrite bool) (*models.DBCImportResult, error) {
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %v", err)
+	}
+
+	db, err := dbc.Parse(bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+
+	definitionsPath := filepath.Join(s.configDir, "definitions.json")
+	dataParserPath := filepath.Join(s.configDir, "data_parser.json")
+	definitions, err := readOrderedJSON(definitionsPath)
+	if err != nil {
+		return nil, err
+	}
+	dataParser, err := readOrderedJSON(dataParserPath)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.DBCImportResult{
+		FileName:     filename,
+		MessageCount: len(db.Messages),
+		Added:        []string{},
+		Updated:      []string{},
+		Replaced:     []string{},
+		Skipped:      []string{},
+		Applied:      !dryRun,
+		Overwrite:    overwrite,
+	}
+	generatedDefinitions := make(map[string]CANDefinition)
+	generatedParser := make(map[string]json.RawMessage)
+
+	for _, msg := range db.Messages {
+		key := strconv.FormatUint(uint64(msg.ID), 16)
+		def := dbcDefinition(msg)
+		config := dbcMessageConfig(msg)
+		result.SignalCount += len(msg.Signals)
+
+		if definitions.Has(key) || dataParser.Has(key) {
+			result.Updated = append(result.Updated, key)
+		} else {
+			result.Added = append(result.Added, key)
+		}
+
+		if overwrite || !definitions.Has(key) {
+			generatedDefinitions[key] = def
+			if err := definitions.Set(key, def); err != nil {
+				return nil, err
+			}
+		}
+		// 没有信号的报文只生成ID定义
+		if len(config.Bytes) == 0 {
+			continue
+		}
+		var entry json.RawMessage
+		if entry, err = json.Marshal(config); err != nil {
+			return nil, err
+		}
+		if existing := dataParser.Get(key); existing != nil {
+			if overwrite {
+				result.Replaced = append(result.Replaced, key)
+			} else {
+				var skipped []string
+				if entry, skipped, err = mergeParserEntry(existing, config); err != nil {
+					return nil, fmt.Errorf("合并data_parser.json条目 %s 失败: %v", key, err)
+				}
+				for _, name := range skipped {
+					result.Skipped = append(result.Skipped, key+"/"+name)
+				}
+			}
+		}
+		generatedParser[key] = entry
+		if err := dataParser.Set(key, entry); err != nil {
+			return nil, err
+		}
+	}
+
+	if result.Definitions, err = json.Marshal(generatedDefinitions); err != nil {
+		return nil, err
+	}
+	if result.DataParser, err = json.Marshal(generatedParser); err != nil {
+		return nil, err
+	}
+
+	if dryRun {
+		return result, nil
+	}
+	if err := definitions.WriteFile(definitionsPath, definitionsIndent); err != nil {
+		return nil, fmt.Errorf("写入definitions.json失败: %v", err)
+	}
+	if err := dataParser.WriteFile(dataParserPath, dataParserIndent); err != nil {
+		return nil, fmt.Errorf("写入data_parser.json失败: %v", err)
+	}
+
+	return result, nil
+}
+
+// mergeParserEntry 将DBC生成的字节规则合并到data_parser.json的已有条目
+// 已有条目的字段和字节规则原样保留，与已有规则字节范围重叠的信号不导入，返回这些信号的名称
+func mergeParserEntry(existing json.RawMessage, config *decoder.MessageConfig) (json.RawMessage, []string, error) {
+	entry, err := parseOrderedJSON(existing, "条目")
+	if err != nil {
+		return nil, nil, err
+	}
+	rules := &orderedJSON{values: make(map[string]json.RawMessage)}
+	var current decoder.ByteRules
+	if raw := entry.Get("bytes"); raw != nil {
+		if rules, err = parseOrderedJSON(raw, "bytes"); err != nil {
+			return nil, nil, err
+		}
+		if err := json.Unmarshal(raw, &current); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var skipped []string
+	for _, rule := range config.Bytes {
+		overlaps := rules.Has(rule.Range)
+		for _, r := range current {
+			if rule.StartByte <= r.EndByte && r.StartByte <= rule.EndByte {
+				overlaps = true
+				break
+			}
+		}
+		if overlaps {
+			skipped = append(skipped, rule.Name)
+			continue
+		}
+		if err := rules.Set(rule.Range, rule.FieldConfig); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := entry.Set("bytes", rules.Marshal()); err != nil {
+		return nil, nil, err
+	}
+	return entry.Marshal(), skipped, nil
+}
+
+// dbcDefinition 生成definitions.json条目，描述优先使用报文注释（CM_ BO_）
+func dbcDefinition(msg *dbc.Message) CANDefinition {
+	description := msg.Name
+	if comment := strings.Join(strings.Fields(msg.Comment), " "); comment != "" {
+		description = comment
+	}
+	return CANDefinition{
+		Hex:         strconv.FormatUint(uint64(msg.ID), 16),
+		Dec:         strconv.FormatUint(uint64(msg.ID), 10),
+		Description: description,
+	}
+}
+
+// dbcMessageConfig 将报文的信号转换为signal类型的字节规则
+// 键为信号覆盖的字节范围，同一范围有多个信号时以 "#信号名" 区分
+func dbcMessageConfig(msg *dbc.Message) *decoder.MessageConfig {
+	config := &decoder.MessageConfig{Name: msg.Name}
+	used := make(map[string]bool)
+
+	for i, sig := range msg.Signals {
+		first, last := sig.ByteSpan()
+		key := strconv.Itoa(first)
+		if last != first {
+			key = fmt.Sprintf("%d-%d", first, last)
+		}
+		if used[key] {
+			key += "#" + sig.Name
+		}
+		used[key] = true
+
+		order := i
+		startBit := sig.StartBit
+		factor := sig.Factor
+		min, max := sig.Min, sig.Max
+		byteOrder := "intel"
+		if !sig.LittleEndian {
+			byteOrder = "motorola"
+		}
+
+		field := decoder.FieldConfig{
+			Name:  sig.Name,
+			Type:  "signal",
+			Order: &order,
+			Unit:  sig.Unit,
+			SignalConfig: decoder.SignalConfig{
+				StartBit:    &startBit,
+				Length:      sig.Length,
+				ByteOrder:   byteOrder,
+				Signed:      sig.Signed,
+				ValueType:   sig.ValueType,
+				Factor:      &factor,
+				Offset:      sig.Offset,
+				Min:         &min,
+				Max:         &max,
+				Multiplexer: sig.Multiplexer,
+				Comment:     sig.Comment,
+			},
+		}
+		if sig.MultiplexID != nil {
+			mux := *sig.MultiplexID
+			field.MultiplexValue = &mux
+		}
+		if len(sig.Values) > 0 {
+			field.Values = make(map[string]string, len(sig.Values))
+			for _, v := range sig.Values {
+				field.Values[strconv.FormatInt(v.Value, 10)] = v.Description
+			}
+		}
+
+		config.Bytes = append(config.Bytes, decoder.ByteRule{
+			Range:       key,
+			StartByte:   first,
+			EndByte:     last,
+			FieldConfig: field,
+		})
+	}
+
+	return config
+}
+
+// ExportDBC 将 definitions.json 和 data_parser.json 导出为DBC
+// 返回无法用DBC信号表示而被跳过的字段（如ascii、按Name匹配的配置）
+func (s *DBCService) ExportDBC(w io.Writer) ([]string, error) {
+	definitionsPath := filepath.Join(s.configDir, "definitions.json")
+	dataParserPath := filepath.Join(s.configDir, "data_parser.json")
+
+	definitionKeys, err := readOrderedJSON(definitionsPath)
+	if err != nil {
+		return nil, err
+	}
+	parserKeys, err := readOrderedJSON(dataParserPath)
+	if err != nil {
+		return nil, err
+	}
+
+	definitions := make(map[string]CANDefinition)
+	for _, key := range definitionKeys.keys {
+		var def CANDefinition
+		if err := json.Unmarshal(definitionKeys.values[key], &def); err != nil {
+			return nil, fmt.Errorf("解析definitions.json条目 %s 失败: %v", key, err)
+		}
+		definitions[key] = def
+	}
+	parser := decoder.Config{}
+	var invalid []string
+	if len(parserKeys.keys) > 0 {
+		if parser, invalid, err = decoder.LoadConfig(dataParserPath); err != nil {
+			return nil, err
+		}
+	}
+
+	// 报文顺序：先按definitions.json，再补充只在data_parser.json中出现的CAN ID
+	var ids, skipped []string
+	for _, field := range invalid {
+		skipped = append(skipped, field+" (invalid start/bits)")
+	}
+	seen := make(map[string]bool)
+	for _, keys := range [][]string{definitionKeys.keys, parserKeys.keys} {
+		for _, key := range keys {
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			if _, err := strconv.ParseUint(key, 16, 29); err != nil {
+				// 按Name匹配的配置（如RTB信号）不是CAN报文
+				if _, ok := parser[key]; ok {
+					skipped = append(skipped, key+" (matchBy name)")
+				}
+				continue
+			}
+			ids = append(ids, key)
+		}
+	}
+
+	db := &dbc.Database{}
+	messageNames := make(map[string]bool)
+	for _, key := range ids {
+		id, _ := strconv.ParseUint(key, 16, 32)
+		def := definitions[key]
+		config := parser[key]
+
+		name := def.Description
+		if config != nil && config.Name != "" {
+			name = config.Name
+		}
+		if name == "" {
+			name = "MSG_" + strings.ToUpper(key)
+		}
+		msg := &dbc.Message{
+			ID:       uint32(id),
+			Extended: id > 0x7FF,
+			Name:     uniqueIdentifier(dbc.Identifier(name), "_"+strings.ToUpper(key), messageNames),
+			DLC:      8,
+			Comment:  def.Description,
+		}
+		if config != nil {
+			skipped = append(skipped, exportSignals(msg, key, config)...)
+		}
+		db.Messages = append(db.Messages, msg)
+	}
+
+	if err := dbc.Write(w, db); err != nil {
+		return nil, err
+	}
+	return skipped, nil
+}
+
+// exportSignals 将字节规则转换为DBC信号，返回被跳过的字段
+func exportSignals(msg *dbc.Message, key string, config *decoder.MessageConfig) []string {
+	var skipped []string
+	names := make(map[string]bool)
+
+	add := func(sig *dbc.Signal, originalName, comment string, endByte int) {
+		sig.Name = uniqueIdentifier(dbc.Identifier(originalName), "_"+strconv.Itoa(len(msg.Signals)), names)
+		// 名称被修改时在注释中保留原名
+		if sig.Name != originalName {
+			comment = strings.TrimSpace(originalName + " " + comment)
+		}
+		sig.Comment = comment
+		if endByte+1 > msg.DLC {
+			msg.DLC = endByte + 1
+		}
+		msg.Signals = append(msg.Signals, sig)
+	}
+
+	for _, rule := range config.Bytes {
+		name := rule.Name
+		if name == "" {
+			name = "Byte" + rule.Range
+		}
+		factor := 1.0
+		if rule.Scale != 0 {
+			factor = rule.Scale
+		}
+
+		switch rule.Type {
+		case "enum":
+			sig := integerSignal(rule.StartByte*8, 8, 1)
+			sig.Values = hexValueDescriptions(rule.Values)
+			add(sig, name, rule.Comment, rule.StartByte)
+
+		case "uint8", "hex8", "uint16_le", "hex16_le", "uint24_le", "uint32_le", "hex32_le":
+			bits := map[string]int{
+				"uint8": 8, "hex8": 8, "uint16_le": 16, "hex16_le": 16,
+				"uint24_le": 24, "uint32_le": 32, "hex32_le": 32,
+			}[rule.Type]
+			sig := integerSignal(rule.StartByte*8, bits, factor)
+			sig.Unit = rule.Unit
+			if rule.ZeroText != "" {
+				sig.Values = []dbc.ValueDescription{{Value: 0, Description: rule.ZeroText}}
+			}
+			add(sig, name, rule.Comment, rule.StartByte+bits/8-1)
+
+		case "float32_le":
+			sig := &dbc.Signal{
+				StartBit:     rule.StartByte * 8,
+				Length:       32,
+				LittleEndian: true,
+				Signed:       true,
+				Factor:       1,
+				Unit:         rule.Unit,
+				ValueType:    "float32",
+			}
+			add(sig, name, rule.Comment, rule.StartByte+3)
+
+		case "bitfield":
+			// start/bits无效的位字段已在LoadConfig中跳过
+			for _, bf := range rule.Fields {
+				sig := integerSignal(rule.StartByte*8+bf.Start, bf.Bits, 1)
+				sig.Values = decimalValueDescriptions(bf.Values)
+				bfName := bf.Name
+				if bfName == "" {
+					bfName = fmt.Sprintf("%s_%d", name, bf.Start)
+				}
+				add(sig, bfName, "", rule.StartByte)
+			}
+
+		case "signal":
+			if rule.StartBit == nil || rule.Length == 0 {
+				skipped = append(skipped, fmt.Sprintf("%s.%s (signal without startBit/length)", key, name))
+				continue
+			}
+			sig := &dbc.Signal{
+				StartBit:     *rule.StartBit,
+				Length:       rule.Length,
+				LittleEndian: !strings.EqualFold(rule.ByteOrder, "motorola"),
+				Signed:       rule.Signed,
+				Factor:       1,
+				Offset:       rule.Offset,
+				Unit:         rule.Unit,
+				ValueType:    rule.ValueType,
+				Multiplexer:  rule.Multiplexer,
+				Values:       decimalValueDescriptions(rule.Values),
+			}
+			if rule.Factor != nil {
+				sig.Factor = *rule.Factor
+			}
+			if rule.MultiplexValue != nil {
+				mux := *rule.MultiplexValue
+				sig.MultiplexID = &mux
+			}
+			sig.Min, sig.Max = rawRange(sig)
+			if rule.Min != nil {
+				sig.Min = *rule.Min
+			}
+			if rule.Max != nil {
+				sig.Max = *rule.Max
+			}
+			_, last := sig.ByteSpan()
+			add(sig, name, rule.Comment, last)
+
+		default:
+			// ascii等无法用数值信号表示的字段
+			skipped = append(skipped, fmt.Sprintf("%s.%s (%s)", key, name, rule.Type))
+		}
+	}
+
+	return skipped
+}
+
+// integerSignal 构造小端无符号整数信号
+func integerSignal(startBit, length int, factor float64) *dbc.Signal {
+	sig := &dbc.Signal{
+		StartBit:     startBit,
+		Length:       length,
+		LittleEndian: true,
+		Factor:       factor,
+	}
+	sig.Min, sig.Max = rawRange(sig)
+	return sig
+}
+
+// rawRange 根据位长度、符号和factor/offset计算物理值范围
+func rawRange(sig *dbc.Signal) (float64, float64) {
+	if sig.ValueType != "" {
+		return 0, 0
+	}
+	var lo, hi float64
+	if sig.Signed {
+		lo = -math.Pow(2, float64(sig.Length-1))
+		hi = math.Pow(2, float64(sig.Length-1)) - 1
+	} else {
+		hi = math.Pow(2, float64(sig.Length)) - 1
+	}
+	lo, hi = lo*sig.Factor+sig.Offset, hi*sig.Factor+sig.Offset
+	if lo > hi {
+		lo, hi = hi, lo
+	}
+	return lo, hi
+}
+
+// hexValueDescriptions 转换enum的值表（键为两位十六进制）
+func hexValueDescriptions(values map[string]string) []dbc.ValueDescription {
+	return valueDescriptions(values, func(k string) (int64, error) {
+		v, err := strconv.ParseUint(k, 16, 8)
+		return int64(v), err
+	})
+}
+
+// decimalValueDescriptions 转换bitfield/signal的值表（键为十进制）
+func decimalValueDescriptions(values map[string]string) []dbc.ValueDescription {
+	return valueDescriptions(values, func(k string) (int64, error) {
+		return strconv.ParseInt(k, 10, 64)
+	})
+}
+
+func valueDescriptions(values map[string]string, parse func(string) (int64, error)) []dbc.ValueDescription {
+	var result []dbc.ValueDescription
+	for k, desc := range values {
+		v, err := parse(strings.TrimSpace(k))
+		if err != nil {
+			continue
+		}
+		result = append(result, dbc.ValueDescription{Value: v, Description: desc})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Value < result[j].Value })
+	return result
+}
+
+// uniqueIdentifier 名称重复时追加后缀
+func uniqueIdentifier(name, suffix string, used map[string]bool) string {
+	for used[name] {
+		name += suffix
+	}
+	used[name] = true
+	return name
+}
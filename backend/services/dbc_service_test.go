@@ -0,0 +1,225 @@
+package services
+
+import (
+	"bytes"
+	"csv-parser/dbc"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testDBC = `VERSION ""
+
+BU_: ECU
+
+BO_ 460 Status: 8 ECU
+ SG_ Mode : 0|8@1+ (1,0) [0|255] "" ECU
+ SG_ Speed : 16|16@1+ (0.1,0) [0|6553.5] "km/h" ECU
+
+BO_ 520 Other: 2 ECU
+ SG_ Level : 0|8@1+ (1,0) [0|255] "" ECU
+`
+
+const testDefinitions = `{
+  "1cc": {"hex": "1cc", "dec": "460", "description": "手工描述"}
+}
+`
+
+const testDataParser = `{
+  "1cc": {
+    "name": "Status",
+    "bytes": {
+      "0": {"name": "Mode", "type": "enum", "values": {"03": "RUN"}, "hideIfZero": true}
+    }
+  }
+}
+`
+
+func setupDBCConfig(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "definitions.json"), []byte(testDefinitions), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "data_parser.json"), []byte(testDataParser), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func readParserEntry(t *testing.T, dir, key string) map[string]interface{} {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join(dir, "data_parser.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var parser map[string]map[string]interface{}
+	if err := json.Unmarshal(content, &parser); err != nil {
+		t.Fatal(err)
+	}
+	return parser[key]
+}
+
+// 默认合并：保留手工规则和描述，只追加不重叠的信号
+func TestImportDBCMerge(t *testing.T) {
+	dir := setupDBCConfig(t)
+	result, err := NewDBCService(dir).ImportDBC("test.dbc", strings.NewReader(testDBC), false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result.Added, []string{"208"}) || !reflect.DeepEqual(result.Updated, []string{"1cc"}) {
+		t.Errorf("added = %v, updated = %v", result.Added, result.Updated)
+	}
+	if len(result.Replaced) != 0 || !reflect.DeepEqual(result.Skipped, []string{"1cc/Mode"}) {
+		t.Errorf("replaced = %v, skipped = %v", result.Replaced, result.Skipped)
+	}
+
+	bytes := readParserEntry(t, dir, "1cc")["bytes"].(map[string]interface{})
+	mode := bytes["0"].(map[string]interface{})
+	if mode["type"] != "enum" || mode["hideIfZero"] != true {
+		t.Errorf("hand-written rule changed: %v", mode)
+	}
+	if speed, ok := bytes["2-3"].(map[string]interface{}); !ok || speed["name"] != "Speed" {
+		t.Errorf("Speed signal not merged: %v", bytes)
+	}
+
+	content, _ := os.ReadFile(filepath.Join(dir, "definitions.json"))
+	if !strings.Contains(string(content), "手工描述") {
+		t.Errorf("definition description overwritten:\n%s", content)
+	}
+}
+
+// overwrite=true 时整体替换并报告被替换的ID
+func TestImportDBCOverwrite(t *testing.T) {
+	dir := setupDBCConfig(t)
+	result, err := NewDBCService(dir).ImportDBC("test.dbc", strings.NewReader(testDBC), false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result.Replaced, []string{"1cc"}) || len(result.Skipped) != 0 {
+		t.Errorf("replaced = %v, skipped = %v", result.Replaced, result.Skipped)
+	}
+	mode := readParserEntry(t, dir, "1cc")["bytes"].(map[string]interface{})["0"].(map[string]interface{})
+	if mode["type"] != "signal" {
+		t.Errorf("rule not replaced: %v", mode)
+	}
+}
+
+// dryRun 不修改配置文件
+func TestImportDBCDryRun(t *testing.T) {
+	dir := setupDBCConfig(t)
+	if _, err := NewDBCService(dir).ImportDBC("test.dbc", strings.NewReader(testDBC), true, true); err != nil {
+		t.Fatal(err)
+	}
+	content, _ := os.ReadFile(filepath.Join(dir, "data_parser.json"))
+	if string(content) != testDataParser {
+		t.Errorf("data_parser.json modified by dry run:\n%s", content)
+	}
+}
+
+const testExportDefinitions = `{
+  "1cc": {"hex": "1cc", "dec": "460", "description": "Generator status"},
+  "18ff1234": {"hex": "18ff1234", "dec": "419369524", "description": "Extended frame"}
+}
+`
+
+const testExportDataParser = `{
+  "1cc": {
+    "name": "Status",
+    "bytes": {
+      "0": {"name": "Mode", "type": "enum", "values": {"01": "IDLE", "03": "RUN"}},
+      "2-3": {"name": "Speed", "type": "uint16_le", "scale": 0.1, "unit": "km/h"},
+      "4": {"type": "bitfield", "fields": [
+        {"name": "Door", "start": 0, "bits": 2, "values": {"0": "Closed", "1": "Open"}},
+        {"name": "Lamp", "start": 4, "bits": 4}
+      ]},
+      "5-7": {"name": "Label", "type": "ascii"}
+    }
+  },
+  "18ff1234": {
+    "bytes": {
+      "1#t": {"name": "Temp", "type": "signal", "startBit": 15, "length": 12, "byteOrder": "motorola",
+        "signed": true, "factor": 0.5, "offset": -10, "unit": "C", "values": {"0": "Zero"}},
+      "4-7#f": {"name": "Gain", "type": "signal", "startBit": 32, "length": 32, "valueType": "float32"}
+    }
+  },
+  "RTB_LINE": {"matchBy": "name", "bytes": {"0": {"type": "uint8"}}}
+}
+`
+
+// 导出的DBC重新解析后与配置一致：信号、值表和扩展帧ID
+func TestExportDBCRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "definitions.json"), []byte(testExportDefinitions), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "data_parser.json"), []byte(testExportDataParser), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	skipped, err := NewDBCService(dir).ExportDBC(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"RTB_LINE (matchBy name)", "1cc.Label (ascii)"}; !reflect.DeepEqual(skipped, want) {
+		t.Errorf("skipped = %v, want %v", skipped, want)
+	}
+
+	db, err := dbc.Parse(&buf)
+	if err != nil {
+		t.Fatalf("dbc.Parse: %v\n%s", err, buf.String())
+	}
+	if len(db.Messages) != 2 {
+		t.Fatalf("messages = %d, want 2", len(db.Messages))
+	}
+
+	status, ext := db.Messages[0], db.Messages[1]
+	if status.ID != 0x1CC || status.Extended || status.Name != "Status" || status.DLC != 8 || status.Comment != "Generator status" {
+		t.Errorf("status message = %+v", status)
+	}
+	if ext.ID != 0x18FF1234 || !ext.Extended || ext.Name != "Extended_frame" || ext.DLC != 8 {
+		t.Errorf("extended message = %+v", ext)
+	}
+
+	type signalSummary struct {
+		Name         string
+		StartBit     int
+		Length       int
+		LittleEndian bool
+		Signed       bool
+		Factor       float64
+		Offset       float64
+		Unit         string
+		ValueType    string
+		Values       []dbc.ValueDescription
+	}
+	summarize := func(msg *dbc.Message) []signalSummary {
+		var out []signalSummary
+		for _, s := range msg.Signals {
+			out = append(out, signalSummary{s.Name, s.StartBit, s.Length, s.LittleEndian, s.Signed, s.Factor, s.Offset, s.Unit, s.ValueType, s.Values})
+		}
+		return out
+	}
+
+	// 整数字节键排在字节范围键之前
+	wantStatus := []signalSummary{
+		{"Mode", 0, 8, true, false, 1, 0, "", "", []dbc.ValueDescription{{Value: 1, Description: "IDLE"}, {Value: 3, Description: "RUN"}}},
+		{"Door", 32, 2, true, false, 1, 0, "", "", []dbc.ValueDescription{{Value: 0, Description: "Closed"}, {Value: 1, Description: "Open"}}},
+		{"Lamp", 36, 4, true, false, 1, 0, "", "", nil},
+		{"Speed", 16, 16, true, false, 0.1, 0, "km/h", "", nil},
+	}
+	if got := summarize(status); !reflect.DeepEqual(got, wantStatus) {
+		t.Errorf("status signals:\n got %+v\nwant %+v", got, wantStatus)
+	}
+	wantExt := []signalSummary{
+		{"Temp", 15, 12, false, true, 0.5, -10, "C", "", []dbc.ValueDescription{{Value: 0, Description: "Zero"}}},
+		{"Gain", 32, 32, true, false, 1, 0, "", "float32", nil},
+	}
+	if got := summarize(ext); !reflect.DeepEqual(got, wantExt) {
+		t.Errorf("extended signals:\n got %+v\nwant %+v", got, wantExt)
+	}
+}
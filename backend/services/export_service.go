@@ -0,0 +1,415 @@
+package services
+
+import (
+	"bufio"
+	"csv-parser/formats"
+	"csv-parser/models"
+	"csv-parser/rowcache"
+	"csv-parser/utils"
+	"csv-parser/xlsx"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 导出格式
+const (
+	ExportCSV    = "csv"
+	ExportNDJSON = "ndjson"
+	ExportXLSX   = "xlsx"
+
+	// 以下格式导出Buffer列中的CAN帧，而不是解析后的表格
+	ExportCandump = formats.FormatCandump
+	ExportASC     = formats.FormatASC
+	ExportTRC     = formats.FormatTRC
+)
+
+// ExportContentTypes 各导出格式的Content-Type
+var ExportContentTypes = map[string]string{
+	ExportCSV:     "text/csv; charset=utf-8",
+	ExportNDJSON:  "application/x-ndjson; charset=utf-8",
+	ExportXLSX:    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	ExportCandump: "text/plain; charset=utf-8",
+	ExportASC:     "text/plain; charset=utf-8",
+	ExportTRC:     "text/plain; charset=utf-8",
+}
+
+// IsFrameExport 判断导出格式是否为CAN日志格式
+func IsFrameExport(format string) bool {
+	_, ok := formats.WritableFormats[format]
+	return ok
+}
+
+// bufferIDPattern 从Buffer字段提取CAN ID，与预览页的解析规则一致
+var bufferIDPattern = regexp.MustCompile(`^\s*string=([0-9a-fA-F]+):\d+:\[`)
+
+// ExportService 将解析后的表格导出为CSV、NDJSON或XLSX
+type ExportService struct {
+	csvService *CSVService
+}
+
+func NewExportService(csvService *CSVService) *ExportService {
+	return &ExportService{
+		csvService: csvService,
+	}
+}
+
+// exportChunkSize 导出时每次从缓存读取的行数，内存占用与导出行数无关
+const exportChunkSize = 4096
+
+// OpenData 打开解析结果缓存，无缓存时先解析文件写入缓存，调用方负责关闭
+func (s *ExportService) OpenData(filename, protocol string) (*rowcache.Reader, error) {
+	return s.csvService.OpenParsedCache(filename, protocol)
+}
+
+// Export 将SelectRows选出的行按指定格式写入w，按块从缓存读取
+func (s *ExportService) Export(w io.Writer, format, sheetName string, r *rowcache.Reader, selection *RowSelection) error {
+	switch format {
+	case ExportCSV:
+		return exportCSV(w, r, selection)
+	case ExportNDJSON:
+		return exportNDJSON(w, r, selection)
+	case ExportXLSX:
+		return exportXLSX(w, sheetName, r, selection)
+	}
+	return fmt.Errorf("unsupported export format: %s", format)
+}
+
+// readRowChunks 按选择的顺序分块读取行，decoded为解码字段JSON（没有时为空）
+func readRowChunks(r *rowcache.Reader, selection *RowSelection, withDecoded bool, fn func(row []string, decoded []byte) error) error {
+	indexes := make([]int, 0, exportChunkSize)
+	for start := 0; start < selection.Len(); start += exportChunkSize {
+		indexes = indexes[:0]
+		for i := start; i < min(start+exportChunkSize, selection.Len()); i++ {
+			indexes = append(indexes, selection.At(i))
+		}
+		rows, decoded, err := r.ReadRows(indexes, withDecoded)
+		if err != nil {
+			return err
+		}
+		for i, row := range rows {
+			var d []byte
+			if withDecoded {
+				d = decoded[i]
+			}
+			if err := fn(row, d); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// FrameRows 包含CAN帧的行，按Time升序排列
+type FrameRows struct {
+	entries []sortEntry
+	Start   time.Time // 第一帧的时间，没有帧时为零值
+}
+
+// Len 帧数
+func (f *FrameRows) Len() int {
+	return len(f.entries)
+}
+
+// frameColumns 从行中提取CAN帧需要的列，没有的列为-1
+type frameColumns struct {
+	buffer, flags, channel, direction, msgType int
+}
+
+func newFrameColumns(headers []string) (frameColumns, error) {
+	columns := make(map[string]int)
+	for i, h := range exportHeaders(headers) {
+		columns[h] = i
+	}
+	col := func(name string) int {
+		if idx, ok := columns[name]; ok {
+			return idx
+		}
+		return -1
+	}
+	fc := frameColumns{buffer: col("Buffer"), flags: col("Flags"), channel: col("Channel"), direction: col("Direction"), msgType: col("Type")}
+	if fc.buffer < 0 {
+		return fc, fmt.Errorf("文件中没有Buffer列")
+	}
+	if col("Time") < 0 {
+		return fc, fmt.Errorf("文件中没有Time列")
+	}
+	return fc, nil
+}
+
+// frame 将一行转换为CAN帧，Buffer不是CAN消息时返回false
+func (fc frameColumns) frame(row []string, t time.Time) (*models.LogFrame, bool) {
+	canFrame, err := formats.ParseSnifferBuffer(cell(row, fc.buffer))
+	if err != nil {
+		return nil, false
+	}
+	column := func(idx int) string {
+		return strings.TrimSpace(cell(row, idx))
+	}
+	formats.ApplyFlags(canFrame, column(fc.flags))
+
+	frame := &models.LogFrame{
+		CANFrame:  *canFrame,
+		Time:      t,
+		Channel:   column(fc.channel),
+		Direction: column(fc.direction),
+	}
+	if frame.Direction == "" {
+		frame.Direction = "Rx"
+		if strings.EqualFold(column(fc.msgType), "publish") {
+			frame.Direction = "Tx"
+		}
+	}
+	return frame, true
+}
+
+// OpenFrames 打开上传文件原始数据行（COMMON协议，不做行过滤）的缓存，无缓存时先写入缓存
+// CAN帧的导出和回放与原始文件一致，不受协议的行过滤影响
+func (s *ExportService) OpenFrames(filename string) (*rowcache.Reader, error) {
+	return s.csvService.OpenParsedCache(filename, "COMMON")
+}
+
+// FilterFrames 从缓存中筛选包含CAN帧的行，按与/api/parse相同的条件过滤后按Time升序排列
+// 帧总是按时间升序，q中的排序和分页被忽略；原始数据行没有解码字段，不支持fields条件
+// 时间无法解析的行被跳过；只解压过滤需要的列、Buffer列和时间索引
+func FilterFrames(r *rowcache.Reader, q models.ParseQuery) (*FrameRows, error) {
+	fc, err := newFrameColumns(r.Headers())
+	if err != nil {
+		return nil, err
+	}
+	if len(q.Fields) > 0 {
+		return nil, fmt.Errorf("%w: fields filter is not supported for CAN log exports", ErrInvalidQuery)
+	}
+	q.Sort, q.Order, q.Offset, q.Limit = "", "", 0, 0
+	selection, err := SelectRows(r, q)
+	if err != nil {
+		return nil, err
+	}
+	var rows []int // nil表示全部行
+	if selection.entries != nil || selection.rows != nil {
+		// 未排序时选择结果为升序行号
+		rows = make([]int, selection.Len())
+		for i := range rows {
+			rows[i] = selection.At(i)
+		}
+	}
+
+	var entries []sortEntry
+	skipped := 0
+	err = r.Scan(rowcache.ScanOptions{Rows: rows, Columns: []int{fc.buffer}, Time: true}, func(row *rowcache.Row) error {
+		if _, err := formats.ParseSnifferBuffer(cell(row.Cells, fc.buffer)); err != nil {
+			return nil
+		}
+		if row.Time == rowcache.NoTime {
+			skipped++
+			return nil
+		}
+		entries = append(entries, sortEntry{index: row.Index, num: row.Time})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if skipped > 0 {
+		utils.Warn("提取CAN帧时跳过 %d 行无法解析Time的数据", skipped)
+	}
+
+	sort.SliceStable(entries, func(a, b int) bool { return entries[a].num < entries[b].num })
+	result := &FrameRows{entries: entries}
+	if len(entries) > 0 {
+		result.Start = time.Unix(0, entries[0].num)
+	}
+	return result, nil
+}
+
+// ScanFrames 按Time升序分块读取CAN帧，Relative为相对第一帧的时间
+func ScanFrames(r *rowcache.Reader, frames *FrameRows, fn func(frame *models.LogFrame) error) error {
+	fc, err := newFrameColumns(r.Headers())
+	if err != nil {
+		return err
+	}
+	for start := 0; start < len(frames.entries); start += exportChunkSize {
+		chunk := frames.entries[start:min(start+exportChunkSize, len(frames.entries))]
+		indexes := make([]int, len(chunk))
+		for i, e := range chunk {
+			indexes[i] = e.index
+		}
+		rows, _, err := r.ReadRows(indexes, false)
+		if err != nil {
+			return err
+		}
+		for i, row := range rows {
+			t := time.Unix(0, chunk[i].num)
+			frame, ok := fc.frame(row, t)
+			if !ok {
+				continue
+			}
+			frame.Relative = t.Sub(frames.Start)
+			if err := fn(frame); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// LoadFrames 读取上传文件中的CAN帧，按过滤条件筛选后按Time升序排列（供回放使用，全部载入内存）
+// Relative为相对第一帧的时间；返回的start为第一帧的时间，没有帧时为零值
+func (s *ExportService) LoadFrames(filename string, q models.ParseQuery) ([]*models.LogFrame, time.Time, error) {
+	r, err := s.OpenFrames(filename)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	defer r.Close()
+
+	selected, err := FilterFrames(r, q)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	frames := make([]*models.LogFrame, 0, selected.Len())
+	err = ScanFrames(r, selected, func(frame *models.LogFrame) error {
+		frames = append(frames, frame)
+		return nil
+	})
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return frames, selected.Start, nil
+}
+
+// ExportFrames 将CAN帧按candump、ASC或TRC格式写入w，按块从缓存读取
+func (s *ExportService) ExportFrames(w io.Writer, format string, r *rowcache.Reader, frames *FrameRows) error {
+	start := frames.Start
+	if start.IsZero() {
+		start = time.Now()
+	}
+	fw, err := formats.NewFrameWriter(format, w, start)
+	if err != nil {
+		return err
+	}
+	if err := ScanFrames(r, frames, fw.WriteFrame); err != nil {
+		return err
+	}
+	return fw.Close()
+}
+
+// exportCSV 输出带UTF-8 BOM的CSV，便于Excel直接打开
+func exportCSV(w io.Writer, r *rowcache.Reader, selection *RowSelection) error {
+	if _, err := io.WriteString(w, "\xEF\xBB\xBF"); err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	if err := cw.Write(exportHeaders(r.Headers())); err != nil {
+		return err
+	}
+	err := readRowChunks(r, selection, false, func(row []string, _ []byte) error {
+		return cw.Write(row)
+	})
+	if err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// exportNDJSON 每行输出一个JSON对象，键为表头；有解码字段时附加在 "decoded" 中
+func exportNDJSON(w io.Writer, r *rowcache.Reader, selection *RowSelection) error {
+	headers := exportHeaders(r.Headers())
+	bw := bufio.NewWriterSize(w, 64*1024)
+	err := readRowChunks(r, selection, r.HasDecoded(), func(row []string, decoded []byte) error {
+		bw.WriteString("{")
+		for col, header := range headers {
+			if col > 0 {
+				bw.WriteString(",")
+			}
+			key, _ := json.Marshal(header)
+			value, _ := json.Marshal(cell(row, col))
+			bw.Write(key)
+			bw.WriteString(":")
+			bw.Write(value)
+		}
+		if len(decoded) > 0 {
+			bw.WriteString(`,"decoded":`)
+			bw.Write(decoded)
+		}
+		_, err := bw.WriteString("}\n")
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// exportXLSX 输出单工作表的XLSX，第一行为表头
+func exportXLSX(w io.Writer, sheetName string, r *rowcache.Reader, selection *RowSelection) error {
+	xw, err := xlsx.NewWriter(w, sheetName)
+	if err != nil {
+		return err
+	}
+	if err := xw.WriteRow(exportHeaders(r.Headers())); err != nil {
+		return err
+	}
+	err = readRowChunks(r, selection, false, func(row []string, _ []byte) error {
+		return xw.WriteRow(row)
+	})
+	if err != nil {
+		return err
+	}
+	return xw.Close()
+}
+
+// exportHeaders 返回去掉UTF-8 BOM的表头（嗅探器CSV的第一列表头可能带BOM）
+func exportHeaders(headers []string) []string {
+	trimmed := make([]string, len(headers))
+	for i, h := range headers {
+		trimmed[i] = strings.TrimPrefix(h, "\uFEFF")
+	}
+	return trimmed
+}
+
+// rowMessageID 返回行的消息ID：Buffer中的CAN ID，没有时使用Name
+func rowMessageID(row []string, nameIdx, bufferIdx int) string {
+	if m := bufferIDPattern.FindStringSubmatch(cell(row, bufferIdx)); m != nil {
+		return m[1]
+	}
+	if name := cell(row, nameIdx); name != "" {
+		return name
+	}
+	return "N/A"
+}
+
+// idSet 将消息ID列表转换为小写集合
+func idSet(ids []string) map[string]bool {
+	set := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if id = strings.ToLower(strings.TrimSpace(id)); id != "" {
+			set[id] = true
+		}
+	}
+	return set
+}
+
+// messageIDSet 将消息ID列表按normalizeCANID规范化为集合，"0x01CC"与Buffer中的"1cc"视为同一ID
+func messageIDSet(ids []string) map[string]bool {
+	set := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if id = normalizeCANID(id); id != "" {
+			set[id] = true
+		}
+	}
+	return set
+}
+
+// cell 安全读取单元格
+func cell(row []string, idx int) string {
+	if idx < 0 || idx >= len(row) {
+		return ""
+	}
+	return row[idx]
+}
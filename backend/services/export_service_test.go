@@ -0,0 +1,153 @@
+package services
+
+import (
+	"bytes"
+	"csv-parser/models"
+	"csv-parser/rowcache"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// openTestCache 将data写入临时目录的缓存并打开
+func openTestCache(t *testing.T, data *models.CSVData) *rowcache.Reader {
+	t.Helper()
+	s := NewCSVService(t.TempDir())
+	if err := s.SaveCacheResult("test.csv", "COMMON", data); err != nil {
+		t.Fatal(err)
+	}
+	r, ok := s.OpenCache("test.csv", "COMMON")
+	if !ok {
+		t.Fatal("cache not available")
+	}
+	t.Cleanup(func() { r.Close() })
+	return r
+}
+
+var exportTestData = &models.CSVData{
+	Headers: []string{"\uFEFFTime", "Type", "Name", "Buffer"},
+	Rows: [][]string{
+		{"2025-11-14 17:03:36.002", "receive", "", "string=01CC:8:[03 00 00 00 00 00 00 00]"},
+		{"2025-11-14 17:03:36.001", "publish", "", "string=208:2:[01 02]"},
+		{"2025-11-14 17:03:36.003", "receive", "XRAcqSync", ""},
+		{"bad time", "receive", "", "string=1cc:1:[04]"},
+	},
+}
+
+func TestSelectRowsForExport(t *testing.T) {
+	r := openTestCache(t, exportTestData)
+	tests := []struct {
+		name  string
+		query models.ParseQuery
+		want  []int
+	}{
+		{"all", models.ParseQuery{}, []int{0, 1, 2, 3}},
+		{"include with prefix", models.ParseQuery{IDs: []string{"0x1CC"}}, []int{0, 3}},
+		{"include leading zero", models.ParseQuery{IDs: []string{"01cc", "0208"}}, []int{0, 1, 3}},
+		{"exclude", models.ParseQuery{ExcludeIDs: []string{"0X208"}}, []int{0, 2, 3}},
+		{"name", models.ParseQuery{IDs: []string{"xracqsync"}}, []int{2}},
+		{"type", models.ParseQuery{Types: []string{"Publish"}}, []int{1}},
+		{"text", models.ParseQuery{Text: "acqsync"}, []int{2}},
+		{"time range", models.ParseQuery{From: "2025-11-14 17:03:36.002"}, []int{0, 2}},
+		{"time asc", models.ParseQuery{Sort: "time"}, []int{3, 1, 0, 2}},
+		{"time desc", models.ParseQuery{Sort: "time", Order: "desc", ExcludeIDs: []string{"1cc"}}, []int{2, 1}},
+		{"page", models.ParseQuery{Offset: 1, Limit: 2}, []int{1, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selection, err := SelectRows(r, tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []int{}
+			for i := 0; i < selection.Len(); i++ {
+				got = append(got, selection.At(i))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SelectRows = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// 来自Name列的ID即使是合法的十六进制也按字符串排在CAN ID之后
+func TestSelectRowsSortByID(t *testing.T) {
+	r := openTestCache(t, &models.CSVData{
+		Headers: []string{"Time", "Name", "Buffer"},
+		Rows: [][]string{
+			{"2025-11-14 17:03:36.001", "face", ""},
+			{"2025-11-14 17:03:36.002", "", "string=0BAD:1:[01]"},
+			{"2025-11-14 17:03:36.003", "bad", ""},
+			{"2025-11-14 17:03:36.004", "", "string=208:1:[01]"},
+			{"2025-11-14 17:03:36.005", "XRAcqSync", ""},
+			{"2025-11-14 17:03:36.006", "", "string=1CC:1:[01]"},
+			{"2025-11-14 17:03:36.007", "add", ""},
+		},
+	})
+	tests := []struct {
+		query models.ParseQuery
+		want  []int
+	}{
+		{models.ParseQuery{Sort: "id"}, []int{5, 3, 1, 6, 2, 0, 4}},
+		{models.ParseQuery{Sort: "id", Order: "desc"}, []int{4, 0, 2, 6, 1, 3, 5}},
+		{models.ParseQuery{Sort: "id", Text: "string"}, []int{5, 3, 1}},
+	}
+	for _, tt := range tests {
+		selection, err := SelectRows(r, tt.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := []int{}
+		for i := 0; i < selection.Len(); i++ {
+			got = append(got, selection.At(i))
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%+v: SelectRows = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestExportCSV(t *testing.T) {
+	r := openTestCache(t, exportTestData)
+	selection, err := SelectRows(r, models.ParseQuery{Names: []string{"xracqsync"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := NewExportService(nil).Export(&buf, ExportCSV, "CAN", r, selection); err != nil {
+		t.Fatal(err)
+	}
+	want := "\xEF\xBB\xBFTime,Type,Name,Buffer\n" +
+		"2025-11-14 17:03:36.003,receive,XRAcqSync,\n"
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+// CAN帧按Time升序输出，没有Buffer或时间无法解析的行被跳过
+func TestExportFrames(t *testing.T) {
+	r := openTestCache(t, exportTestData)
+	frames, err := FilterFrames(r, models.ParseQuery{Types: []string{"receive", "publish"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if frames.Len() != 2 {
+		t.Fatalf("got %d frames, want 2", frames.Len())
+	}
+	var buf bytes.Buffer
+	if err := NewExportService(nil).ExportFrames(&buf, ExportCandump, r, frames); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], "208#0102") || !strings.Contains(lines[1], "1CC#0300000000000000") {
+		t.Errorf("unexpected candump output:\n%s", buf.String())
+	}
+
+	if frames, err = FilterFrames(r, models.ParseQuery{Types: []string{"publish"}}); err != nil || frames.Len() != 1 {
+		t.Errorf("type filter: %d frames, err %v", frames.Len(), err)
+	}
+	if _, err := FilterFrames(r, models.ParseQuery{Fields: []string{"x"}}); !errors.Is(err, ErrInvalidQuery) {
+		t.Errorf("fields filter: err = %v, want ErrInvalidQuery", err)
+	}
+}
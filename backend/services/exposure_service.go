@@ -0,0 +1,475 @@
+package services
+
+import (
+	"csv-parser/decoder"
+	"csv-parser/models"
+	"csv-parser/rowcache"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 曝光相关消息的ID（规范化后的消息ID）
+const (
+	exposureCommandID = "1cc" // Command Bitmap，字段Cmd
+	generatorStatusID = "208" // Generator Status，字段Phase
+)
+
+// exposureCommandedIDs 设置曝光参数的消息：Set Record Parameters / Set Limited Parameters
+var exposureCommandedIDs = []string{"1e0", "1e1"}
+
+// exposureReportedIDs 发生器报告实际曝光参数的消息：Actual Exp Parameters / Detailed
+var exposureReportedIDs = []string{"2bc", "2c1"}
+
+// xrayOnLine RTB X射线信号线（去掉 _HI/_LO 后缀）
+const xrayOnLine = "RTB2_XRAY_ON"
+
+// exposureTrailWindow 曝光结束后仍计入该曝光的实际参数报告和X射线下降沿的时间窗口
+const exposureTrailWindow = 2 * time.Second
+
+// 0x1CC Cmd字节
+const (
+	cmdStop  = "00"
+	cmdHold  = "01"
+	cmdPrep  = "03"
+	cmdStart = "07"
+	cmdIdle  = "ff"
+)
+
+// 0x208 Phase字节
+const (
+	phaseInit    = "00"
+	phaseIdle    = "01"
+	phaseStandBy = "02"
+	phasePrep    = "03"
+	phaseReady   = "04" // Ready For Exp / HV OFF
+	phaseHVOn    = "05"
+	phaseError   = "06"
+)
+
+// ExposureService 从发生器日志中切分曝光序列
+type ExposureService struct {
+	csvService      *CSVService
+	timelineService *TimelineService
+}
+
+func NewExposureService(csvService *CSVService, timelineService *TimelineService) *ExposureService {
+	return &ExposureService{
+		csvService:      csvService,
+		timelineService: timelineService,
+	}
+}
+
+// exposureTracker 按行回放曝光相关消息，维护当前曝光
+type exposureTracker struct {
+	timeIdx   int
+	list      *models.ExposureList
+	cur       *exposureState
+	pending   *models.ExposureParams // 不属于当前曝光的参数设置，留给下一次曝光
+	lastPhase string
+}
+
+// exposureState 一次进行中的曝光
+type exposureState struct {
+	exp      models.Exposure
+	prep     int64 // 各事件的时间（Unix纳秒），NoTime表示没有出现
+	ready    int64
+	hvOn     int64
+	hvOff    int64
+	xrayOn   int64
+	xrayOff  int64
+	finished bool  // 已经HV OFF、中止或出错，之后只在exposureTrailWindow内接收报告参数和X射线下降沿
+	endedAt  int64 // finished时的时间
+}
+
+// exposureRow 曝光相关的一行，Cells已从Scan复用的Row中复制
+type exposureRow struct {
+	row    rowcache.Row
+	id     string
+	fields []models.DecodedField
+}
+
+// Exposures 将CAN解析结果切分为曝光：准备命令、PrepInProgress、Start命令或HV ON开始一次新的曝光，
+// 统计准备时长、HV ON时长和X射线时长，并对比设置参数与发生器报告的实际参数
+func (s *ExposureService) Exposures(filename string) (*models.ExposureList, error) {
+	signals, err := s.timelineService.loadRTBSignals()
+	if err != nil {
+		return nil, err
+	}
+
+	reader, err := s.csvService.OpenParsedCache(filename, "CAN")
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	list := &models.ExposureList{Outcomes: make(map[string]int), Exposures: []models.Exposure{}}
+	if !reader.HasDecoded() {
+		return list, nil
+	}
+
+	// xray记录X射线信号线的Name（规范化）对应的电平
+	xray := make(map[string]int)
+	for id, signal := range signals {
+		if strings.EqualFold(signal.line, xrayOnLine) && signal.level >= 0 {
+			xray[id] = signal.level
+		}
+	}
+	if len(signals) == 0 {
+		line := strings.ToLower(xrayOnLine)
+		xray[line+"_hi"], xray[line+"_lo"] = 1, 0
+	}
+
+	ids := idSet(append(append([]string{exposureCommandID, generatorStatusID}, exposureCommandedIDs...), exposureReportedIDs...))
+	for id := range xray {
+		ids[id] = true
+	}
+	rows, err := reader.RowsWithIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	timeIdx := -1
+	for i, h := range reader.Headers() {
+		if strings.TrimPrefix(h, "\uFEFF") == "Time" {
+			timeIdx = i
+		}
+	}
+	readerIDs := reader.IDs()
+	commanded, reported := idSet(exposureCommandedIDs), idSet(exposureReportedIDs)
+
+	var entries []exposureRow
+	err = reader.Scan(rowcache.ScanOptions{Rows: rows, Columns: []int{timeIdx}, Decoded: true, Time: true, ID: true}, func(row *rowcache.Row) error {
+		if row.Time == rowcache.NoTime || row.ID < 0 || row.ID >= len(readerIDs) {
+			return nil
+		}
+		fields, err := unmarshalDecoded(row.Decoded)
+		if err != nil {
+			return nil
+		}
+		entry := exposureRow{row: *row, id: readerIDs[row.ID], fields: fields}
+		entry.row.Cells = append([]string(nil), row.Cells...)
+		entry.row.Decoded = nil
+		entries = append(entries, entry)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 嗅探器记录的时间可能倒退，按时间重新排序后回放
+	sort.SliceStable(entries, func(a, b int) bool { return entries[a].row.Time < entries[b].row.Time })
+
+	t := &exposureTracker{timeIdx: timeIdx, list: list}
+	for i := range entries {
+		row, fields := &entries[i].row, entries[i].fields
+		switch id := entries[i].id; {
+		case id == exposureCommandID:
+			t.command(row, enumRaw(fields, "Cmd"))
+		case id == generatorStatusID:
+			t.phase(row, fields)
+		case commanded[id]:
+			t.commanded(row, fields)
+		case reported[id]:
+			t.reported(row, fields)
+		default:
+			if level, ok := xray[id]; ok {
+				t.xray(row, level)
+			}
+		}
+	}
+	t.finish()
+	return list, nil
+}
+
+// start 结束当前曝光并开始新的曝光
+func (t *exposureTracker) start(row *rowcache.Row) {
+	t.finish()
+	t.cur = &exposureState{
+		exp: models.Exposure{
+			Index:     len(t.list.Exposures),
+			StartRow:  row.Index,
+			Start:     row.Time,
+			StartTime: cell(row.Cells, t.timeIdx),
+			Phases:    []models.ExposurePhase{},
+			Commanded: t.pending,
+		},
+		prep: rowcache.NoTime, ready: rowcache.NoTime,
+		hvOn: rowcache.NoTime, hvOff: rowcache.NoTime,
+		xrayOn: rowcache.NoTime, xrayOff: rowcache.NoTime,
+	}
+	t.pending = nil
+}
+
+// end 标记当前曝光结束
+func (t *exposureTracker) end(row *rowcache.Row) {
+	t.cur.finished = true
+	t.cur.endedAt = row.Time
+}
+
+// trailing 当前曝光已结束，但行仍在结束后的时间窗口内
+func (t *exposureTracker) trailing(row *rowcache.Row) bool {
+	if t.cur == nil || !t.cur.finished {
+		return false
+	}
+	d := row.Time - t.cur.endedAt
+	return d >= 0 && d <= int64(exposureTrailWindow)
+}
+
+// open 当前曝光是否还在进行（没有结束、中止或出错）
+func (t *exposureTracker) open() bool {
+	return t.cur != nil && !t.cur.finished
+}
+
+// touch 将行计入当前曝光的范围，行已按时间排序
+func (t *exposureTracker) touch(row *rowcache.Row) {
+	exp := &t.cur.exp
+	exp.EndRow = row.Index
+	exp.End = row.Time
+	exp.EndTime = cell(row.Cells, t.timeIdx)
+}
+
+func (t *exposureTracker) command(row *rowcache.Row, cmd string) {
+	switch cmd {
+	case cmdPrep, cmdStart:
+		if !t.open() {
+			t.start(row)
+		}
+		if cmd == cmdPrep && t.cur.prep == rowcache.NoTime {
+			t.cur.prep = row.Time
+		}
+		t.touch(row)
+	case cmdStop, cmdHold, cmdIdle:
+		if !t.open() {
+			return
+		}
+		// HV ON之后的停止命令由之后的Phase决定结果，之前的停止命令中止曝光
+		if t.cur.hvOn == rowcache.NoTime {
+			t.end(row)
+		}
+		t.touch(row)
+	}
+}
+
+func (t *exposureTracker) phase(row *rowcache.Row, fields []models.DecodedField) {
+	code := enumRaw(fields, "Phase")
+	if code == "" {
+		return
+	}
+	changed := code != t.lastPhase
+	t.lastPhase = code
+
+	switch code {
+	case phasePrep:
+		if !t.open() {
+			t.start(row)
+		}
+		if t.cur.prep == rowcache.NoTime {
+			t.cur.prep = row.Time
+		}
+	case phaseReady:
+		if !t.open() {
+			return
+		}
+		if t.cur.hvOn != rowcache.NoTime {
+			t.cur.hvOff = row.Time
+			t.end(row)
+		} else if t.cur.ready == rowcache.NoTime {
+			t.cur.ready = row.Time
+		}
+	case phaseHVOn:
+		if !t.open() {
+			t.start(row)
+		}
+		if t.cur.hvOn == rowcache.NoTime {
+			t.cur.hvOn = row.Time
+		}
+	case phaseError:
+		if !t.open() {
+			return
+		}
+		t.cur.exp.Error = decoder.FormatFields(fields)
+		t.end(row)
+	case phaseInit, phaseIdle, phaseStandBy:
+		if !t.open() {
+			return
+		}
+		if t.cur.hvOn != rowcache.NoTime {
+			t.cur.hvOff = row.Time
+		}
+		t.end(row)
+	default:
+		if !t.open() {
+			return
+		}
+	}
+
+	if changed || len(t.cur.exp.Phases) == 0 {
+		t.cur.exp.Phases = append(t.cur.exp.Phases, models.ExposurePhase{
+			Row:   row.Index,
+			Time:  cell(row.Cells, t.timeIdx),
+			Code:  code,
+			Phase: enumDisplay(fields, "Phase"),
+		})
+	}
+	t.touch(row)
+}
+
+// commanded 参数设置在HV ON之前属于当前曝光，否则属于下一次曝光
+func (t *exposureTracker) commanded(row *rowcache.Row, fields []models.DecodedField) {
+	if t.open() && t.cur.hvOn == rowcache.NoTime {
+		t.cur.exp.Commanded = mergeExposureParams(t.cur.exp.Commanded, row.Index, fields)
+		t.touch(row)
+		return
+	}
+	t.pending = mergeExposureParams(t.pending, row.Index, fields)
+}
+
+// reported 实际参数只在HV ON之后到曝光结束后的时间窗口内记录
+func (t *exposureTracker) reported(row *rowcache.Row, fields []models.DecodedField) {
+	if !(t.open() || t.trailing(row)) || t.cur.hvOn == rowcache.NoTime {
+		return
+	}
+	t.cur.exp.Reported = mergeExposureParams(t.cur.exp.Reported, row.Index, fields)
+	t.touch(row)
+}
+
+// xray X射线上升沿只在曝光进行中记录，下降沿可以在曝光结束后的时间窗口内
+func (t *exposureTracker) xray(row *rowcache.Row, level int) {
+	if !(t.open() || t.trailing(row)) {
+		return
+	}
+	if level == 1 {
+		if t.cur.finished || t.cur.xrayOn != rowcache.NoTime {
+			return
+		}
+		t.cur.xrayOn = row.Time
+	} else {
+		if t.cur.xrayOn == rowcache.NoTime || t.cur.xrayOff != rowcache.NoTime {
+			return
+		}
+		t.cur.xrayOff = row.Time
+	}
+	t.touch(row)
+}
+
+// finish 计算当前曝光的时长和结果并加入列表
+func (t *exposureTracker) finish() {
+	if t.cur == nil {
+		return
+	}
+	cur, exp := t.cur, &t.cur.exp
+	t.cur = nil
+
+	exp.PrepDurationMs = durationMs(cur.prep, cur.ready)
+	exp.HVOnDurationMs = durationMs(cur.hvOn, cur.hvOff)
+	exp.XRayOnDurationMs = durationMs(cur.xrayOn, cur.xrayOff)
+	exp.Deviations = exposureDeviations(exp.Commanded, exp.Reported)
+
+	switch {
+	case exp.Error != "":
+		exp.Outcome = models.ExposureError
+	case cur.hvOn == rowcache.NoTime:
+		exp.Outcome = models.ExposureAborted
+	case cur.hvOff == rowcache.NoTime:
+		exp.Outcome = models.ExposureIncomplete
+	default:
+		exp.Outcome = models.ExposureCompleted
+	}
+	t.list.Exposures = append(t.list.Exposures, *exp)
+	t.list.Outcomes[exp.Outcome]++
+	t.list.Total = len(t.list.Exposures)
+}
+
+// durationMs 两个事件之间的毫秒数，任一事件没有出现时为nil
+func durationMs(from, to int64) *float64 {
+	if from == rowcache.NoTime || to == rowcache.NoTime {
+		return nil
+	}
+	ms := nanoToMillis(to - from)
+	return &ms
+}
+
+// mergeExposureParams 按单位从解码字段中取出kV、mA、曝光时间和mAs，覆盖已有的值
+func mergeExposureParams(params *models.ExposureParams, row int, fields []models.DecodedField) *models.ExposureParams {
+	found := false
+	next := models.ExposureParams{}
+	if params != nil {
+		next = *params
+	}
+	for _, f := range fields {
+		if f.Value == nil {
+			continue
+		}
+		v := *f.Value
+		switch f.Unit {
+		case "kV":
+			next.KV = &v
+		case "mA":
+			next.MA = &v
+		case "mAs":
+			next.MAs = &v
+		case "ms":
+			next.TimeMs = &v
+		case "s":
+			v = math.Round(v*1e6) / 1e3
+			next.TimeMs = &v
+		default:
+			continue
+		}
+		found = true
+	}
+	if !found {
+		return params
+	}
+	next.Rows = append(append([]int{}, next.Rows...), row)
+	return &next
+}
+
+// exposureDeviations 实际参数相对设置参数的偏差百分比，设置参数没有mAs时按mA×时间计算
+func exposureDeviations(commanded, reported *models.ExposureParams) map[string]float64 {
+	if commanded == nil || reported == nil {
+		return nil
+	}
+	commandedMAs := commanded.MAs
+	if commandedMAs == nil && commanded.MA != nil && commanded.TimeMs != nil {
+		mas := *commanded.MA * *commanded.TimeMs / 1000
+		commandedMAs = &mas
+	}
+	deviations := make(map[string]float64)
+	add := func(name string, want, got *float64) {
+		if want == nil || got == nil || *want == 0 {
+			return
+		}
+		deviations[name] = math.Round((*got-*want) / *want * 1e4) / 100
+	}
+	add("kv", commanded.KV, reported.KV)
+	add("ma", commanded.MA, reported.MA)
+	add("timeMs", commanded.TimeMs, reported.TimeMs)
+	add("mas", commandedMAs, reported.MAs)
+	if len(deviations) == 0 {
+		return nil
+	}
+	return deviations
+}
+
+// enumRaw 枚举字段的原始字节（小写十六进制），没有该字段时为空
+func enumRaw(fields []models.DecodedField, name string) string {
+	for _, f := range fields {
+		if f.Name == name {
+			return strings.ToLower(strings.TrimSpace(f.Raw))
+		}
+	}
+	return ""
+}
+
+// enumDisplay 枚举字段的显示值
+func enumDisplay(fields []models.DecodedField, name string) string {
+	for _, f := range fields {
+		if f.Name == name {
+			return f.Display
+		}
+	}
+	return ""
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"csv-parser/models"
+	"strings"
+	"testing"
+)
+
+func enumField(name, raw, display string) []models.DecodedField {
+	return []models.DecodedField{{Name: name, Raw: raw, Display: display}}
+}
+
+// 嗅探器记录的时间倒退时按时间回放，时长不会因顺序错乱而丢失
+func TestExposuresOrderedByTime(t *testing.T) {
+	data := &models.CSVData{
+		Headers: []string{"Time", "Type", "Name", "Buffer"},
+		Rows: [][]string{
+			{"2025-11-14 17:03:36.000.000", "receive", "", "string=1cc:1:[03]"},
+			{"2025-11-14 17:03:36.300.000", "publish", "", "string=208:1:[05]"},
+			{"2025-11-14 17:03:36.100.000", "publish", "", "string=208:1:[03]"},
+			{"2025-11-14 17:03:36.200.000", "publish", "", "string=208:1:[04]"},
+			{"2025-11-14 17:03:36.500.000", "publish", "", "string=208:1:[04]"},
+		},
+		Decoded: [][]models.DecodedField{
+			enumField("Cmd", "03", "Prep"),
+			enumField("Phase", "05", "HV ON"),
+			enumField("Phase", "03", "PrepInProgress"),
+			enumField("Phase", "04", "Ready For Exp"),
+			enumField("Phase", "04", "Ready For Exp"),
+		},
+	}
+	csvService := NewCSVService(t.TempDir())
+	if err := csvService.SaveCacheResult("test.csv", "CAN", data); err != nil {
+		t.Fatal(err)
+	}
+	s := NewExposureService(csvService, NewTimelineService(t.TempDir(), csvService))
+
+	list, err := s.Exposures("test.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list.Total != 1 {
+		t.Fatalf("Total = %d, want 1", list.Total)
+	}
+	exp := list.Exposures[0]
+	if exp.Outcome != models.ExposureCompleted {
+		t.Errorf("Outcome = %q, want %q", exp.Outcome, models.ExposureCompleted)
+	}
+	if exp.PrepDurationMs == nil || *exp.PrepDurationMs != 200 {
+		t.Errorf("PrepDurationMs = %v, want 200", exp.PrepDurationMs)
+	}
+	if exp.HVOnDurationMs == nil || *exp.HVOnDurationMs != 200 {
+		t.Errorf("HVOnDurationMs = %v, want 200", exp.HVOnDurationMs)
+	}
+	var codes []string
+	for _, p := range exp.Phases {
+		codes = append(codes, p.Code)
+	}
+	if got := strings.Join(codes, " "); got != "03 04 05 04" {
+		t.Errorf("phases = %s, want 03 04 05 04", got)
+	}
+	if exp.EndRow != 4 || exp.EndTime != "2025-11-14 17:03:36.500.000" {
+		t.Errorf("end = row %d %q, want row 4", exp.EndRow, exp.EndTime)
+	}
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// orderedJSON 保留键顺序的JSON对象
+// 用于在不打乱手工维护的配置文件（definitions.json、data_parser.json）的前提下增改条目
+type orderedJSON struct {
+	keys   []string
+	values map[string]json.RawMessage
+}
+
+// readOrderedJSON 读取JSON对象文件，文件不存在时返回空对象
+func readOrderedJSON(path string) (*orderedJSON, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &orderedJSON{values: make(map[string]json.RawMessage)}, nil
+		}
+		return nil, err
+	}
+	return parseOrderedJSON(content, path)
+}
+
+// parseOrderedJSON 解析JSON对象，name用于错误信息
+func parseOrderedJSON(content []byte, name string) (*orderedJSON, error) {
+	obj := &orderedJSON{values: make(map[string]json.RawMessage)}
+	dec := json.NewDecoder(bytes.NewReader(content))
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, fmt.Errorf("解析%s失败: %v", name, err)
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '{' {
+		return nil, fmt.Errorf("%s 不是JSON对象", name)
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, fmt.Errorf("解析%s失败: %v", name, err)
+		}
+		key := tok.(string)
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, fmt.Errorf("解析%s失败: %v", name, err)
+		}
+		if _, exists := obj.values[key]; !exists {
+			obj.keys = append(obj.keys, key)
+		}
+		obj.values[key] = raw
+	}
+	return obj, nil
+}
+
+// Has 判断键是否存在
+func (o *orderedJSON) Has(key string) bool {
+	_, exists := o.values[key]
+	return exists
+}
+
+// Get 返回键的原始JSON值，不存在时为nil
+func (o *orderedJSON) Get(key string) json.RawMessage {
+	return o.values[key]
+}
+
+// Marshal 按键顺序输出紧凑的JSON对象
+func (o *orderedJSON) Marshal() json.RawMessage {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, _ := json.Marshal(key)
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(o.values[key])
+	}
+	buf.WriteByte('}')
+	return buf.Bytes()
+}
+
+// Set 设置键的值，已存在的键保持原位置，新键追加到末尾
+func (o *orderedJSON) Set(key string, value interface{}) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(value); err != nil {
+		return err
+	}
+	if !o.Has(key) {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = json.RawMessage(bytes.TrimSpace(buf.Bytes()))
+	return nil
+}
+
+// WriteFile 按键顺序写入文件，indent为每级缩进
+func (o *orderedJSON) WriteFile(path, indent string) error {
+	var out bytes.Buffer
+	if err := json.Indent(&out, o.Marshal(), "", indent); err != nil {
+		return err
+	}
+	return os.WriteFile(path, out.Bytes(), 0644)
+}
@@ -0,0 +1,492 @@
+package services
+
+import (
+	"bufio"
+	"csv-parser/formats"
+	"csv-parser/models"
+	"csv-parser/rowcache"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidQuery 分页、排序或过滤参数不合法
+var ErrInvalidQuery = errors.New("invalid query")
+
+// ValidateQuery 检查分页、排序和过滤参数，返回解析后的时间范围（未设置时为零值）
+func ValidateQuery(q models.ParseQuery) (from, to time.Time, err error) {
+	if q.Offset < 0 || q.Limit < 0 {
+		return from, to, fmt.Errorf("%w: offset and limit must not be negative", ErrInvalidQuery)
+	}
+	switch q.Sort {
+	case "", "time", "id", "name":
+	default:
+		return from, to, fmt.Errorf("%w: sort must be 'time', 'id' or 'name'", ErrInvalidQuery)
+	}
+	switch q.Order {
+	case "", "asc", "desc":
+	default:
+		return from, to, fmt.Errorf("%w: order must be 'asc' or 'desc'", ErrInvalidQuery)
+	}
+	if from, err = parseQueryTime(q.From); err != nil {
+		return from, to, fmt.Errorf("%w: from: %v", ErrInvalidQuery, err)
+	}
+	if to, err = parseQueryTime(q.To); err != nil {
+		return from, to, fmt.Errorf("%w: to: %v", ErrInvalidQuery, err)
+	}
+	if _, err = parseFieldFilters(q.Fields); err != nil {
+		return from, to, err
+	}
+	return from, to, nil
+}
+
+// fieldFilter 解码字段条件，没有值时只要求字段存在
+type fieldFilter struct {
+	name     string
+	value    string
+	hasValue bool
+}
+
+// parseFieldFilters 解析 "字段名=值" 形式的条件，字段名不能为空
+func parseFieldFilters(values []string) ([]fieldFilter, error) {
+	var filters []fieldFilter
+	for _, v := range values {
+		name, value, hasValue := strings.Cut(v, "=")
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return nil, fmt.Errorf("%w: fields: missing field name in %q", ErrInvalidQuery, v)
+		}
+		filters = append(filters, fieldFilter{name: name, value: strings.TrimSpace(value), hasValue: hasValue})
+	}
+	return filters, nil
+}
+
+// matchFields 每个条件都有对应的解码字段（含嵌套字段）满足
+func matchFields(fields []models.DecodedField, filters []fieldFilter) bool {
+	for _, f := range filters {
+		if !anyFieldMatches(fields, f) {
+			return false
+		}
+	}
+	return true
+}
+
+func anyFieldMatches(fields []models.DecodedField, f fieldFilter) bool {
+	for _, field := range fields {
+		if strings.EqualFold(field.Name, f.name) && (!f.hasValue || fieldValueEquals(field, f.value)) {
+			return true
+		}
+		if anyFieldMatches(field.Fields, f) {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldValueEquals 比较字段值：数值字段按数值比较，否则比较去掉字段名前缀后的显示文本（不区分大小写）
+func fieldValueEquals(field models.DecodedField, value string) bool {
+	if field.Value != nil {
+		if v, err := strconv.ParseFloat(value, 64); err == nil {
+			return v == *field.Value
+		}
+	}
+	display := field.Display
+	for _, sep := range []string{"=", " "} {
+		if rest, ok := strings.CutPrefix(display, field.Name+sep); ok {
+			display = rest
+			break
+		}
+	}
+	return strings.EqualFold(strings.TrimSpace(display), value)
+}
+
+// columnFilter 一列的等值过滤条件
+type columnFilter struct {
+	col    int
+	values map[string]bool
+}
+
+// sortEntry 过滤后的一行及其排序键
+type sortEntry struct {
+	index int
+	num   int64 // time：Unix纳秒；id、name：取值的排序序号
+}
+
+// RowSelection 在缓存上过滤、排序和分页后的行号
+// entries和rows都为nil时表示全部行（文件顺序），不为每一行分配下标
+type RowSelection struct {
+	entries  []sortEntry
+	rows     []int
+	Filtered int // 过滤后（分页前）的行数
+	Offset   int // 分页起点在过滤结果中的位置
+	end      int
+}
+
+// Len 分页后的行数
+func (s *RowSelection) Len() int {
+	return s.end - s.Offset
+}
+
+// At 返回分页后第i行的行号
+func (s *RowSelection) At(i int) int {
+	i += s.Offset
+	switch {
+	case s.entries != nil:
+		return s.entries[i].index
+	case s.rows != nil:
+		return s.rows[i]
+	}
+	return i
+}
+
+// SelectRows 在缓存的解析结果上按条件过滤、排序并分页
+// 先用消息ID索引和时间索引缩小范围，再只解压过滤和排序需要的列
+func SelectRows(r *rowcache.Reader, q models.ParseQuery) (*RowSelection, error) {
+	from, to, err := ValidateQuery(q)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make(map[string]int)
+	for i, h := range r.Headers() {
+		columns[strings.TrimPrefix(h, "\uFEFF")] = i
+	}
+	col := func(name string) int {
+		if idx, ok := columns[name]; ok {
+			return idx
+		}
+		return -1
+	}
+	nameIdx := col("Name")
+	bufferIdx := col("Buffer")
+
+	// 1. 消息ID索引：只读取包含这些ID的数据块
+	var rows []int // nil表示全部行
+	if len(q.IDs) > 0 || len(q.ExcludeIDs) > 0 {
+		if rows, err = r.RowsWithIDs(queryMessageIDs(r, q)); err != nil {
+			return nil, err
+		}
+	}
+	// 2. 时间索引：跳过时间范围不相交的数据块，时间无法解析的行不在范围内
+	if !from.IsZero() || !to.IsZero() {
+		fromNano, toNano := int64(rowcache.NoTime), int64(rowcache.NoTime)
+		if !from.IsZero() {
+			fromNano = from.UnixNano()
+		}
+		if !to.IsZero() {
+			toNano = to.UnixNano()
+		}
+		if rows, err = r.RowsInTimeRange(rows, fromNano, toNano); err != nil {
+			return nil, err
+		}
+	}
+
+	// 3. 按列过滤并读取排序键，只解压需要的列
+	var filters []columnFilter
+	for _, f := range []struct {
+		name   string
+		values []string
+	}{{"Type", q.Types}, {"Source", q.Sources}, {"Target", q.Targets}, {"Name", q.Names}} {
+		if set := idSet(f.values); len(set) > 0 {
+			filters = append(filters, columnFilter{col: col(f.name), values: set})
+		}
+	}
+	text := strings.ToLower(strings.TrimSpace(q.Text))
+	fieldFilters, err := parseFieldFilters(q.Fields)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []sortEntry
+	filtered := r.Len()
+	if rows != nil {
+		filtered = len(rows)
+	}
+	if len(filters) > 0 || text != "" || len(fieldFilters) > 0 || q.Sort != "" {
+		opts := rowcache.ScanOptions{Rows: rows, Columns: []int{}, Decoded: len(fieldFilters) > 0, Time: q.Sort == "time", ID: q.Sort == "id"}
+		if text != "" {
+			opts.Columns = nil
+		} else {
+			for _, f := range filters {
+				opts.Columns = append(opts.Columns, f.col)
+			}
+			switch q.Sort {
+			case "name":
+				opts.Columns = append(opts.Columns, nameIdx)
+			case "id":
+				// 缓存中的ID不区分来源，需要Buffer列判断是CAN ID还是名称
+				opts.Columns = append(opts.Columns, bufferIdx)
+			}
+		}
+
+		var canRanks, nameRanks []int
+		names := make(map[string]int)
+		var nameList []string
+		if q.Sort == "id" {
+			canRanks, nameRanks = messageIDRanks(r.IDs())
+		}
+		entries = make([]sortEntry, 0, filtered)
+		err := r.Scan(opts, func(row *rowcache.Row) error {
+			for _, f := range filters {
+				if !matchFold(f.values, cell(row.Cells, f.col)) {
+					return nil
+				}
+			}
+			if text != "" && !rowContains(row.Cells, text) {
+				return nil
+			}
+			if len(fieldFilters) > 0 {
+				if len(row.Decoded) == 0 {
+					return nil
+				}
+				fields, err := unmarshalDecoded(row.Decoded)
+				if err != nil {
+					return err
+				}
+				if !matchFields(fields, fieldFilters) {
+					return nil
+				}
+			}
+			entry := sortEntry{index: row.Index}
+			switch q.Sort {
+			case "time":
+				entry.num = row.Time
+			case "id":
+				if canRanks[row.ID] >= 0 && bufferIDPattern.MatchString(cell(row.Cells, bufferIdx)) {
+					entry.num = int64(canRanks[row.ID])
+				} else {
+					entry.num = int64(nameRanks[row.ID])
+				}
+			case "name":
+				// Name的取值很少，先记录字典序号，扫描结束后再换算为排序序号
+				name := strings.ToLower(cell(row.Cells, nameIdx))
+				n, ok := names[name]
+				if !ok {
+					n = len(nameList)
+					names[name] = n
+					nameList = append(nameList, name)
+				}
+				entry.num = int64(n)
+			}
+			entries = append(entries, entry)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		filtered = len(entries)
+		if q.Sort == "name" {
+			ranks := stringRanks(nameList)
+			for i := range entries {
+				entries[i].num = int64(ranks[entries[i].num])
+			}
+		}
+	}
+
+	if q.Sort != "" {
+		desc := q.Order == "desc"
+		sort.SliceStable(entries, func(a, b int) bool {
+			if desc {
+				return entries[a].num > entries[b].num
+			}
+			return entries[a].num < entries[b].num
+		})
+	}
+
+	// 4. 分页
+	start := min(q.Offset, filtered)
+	end := filtered
+	if q.Limit > 0 {
+		end = min(start+q.Limit, filtered)
+	}
+	return &RowSelection{entries: entries, rows: rows, Filtered: filtered, Offset: start, end: end}, nil
+}
+
+// queryMessageIDs 按IDs和ExcludeIDs计算要保留的消息ID（缓存中的规范化ID）
+func queryMessageIDs(r *rowcache.Reader, q models.ParseQuery) map[string]bool {
+	include := messageIDSet(q.IDs)
+	exclude := messageIDSet(q.ExcludeIDs)
+	ids := make(map[string]bool)
+	for _, id := range r.IDs() {
+		if (len(include) == 0 || include[id]) && !exclude[id] {
+			ids[id] = true
+		}
+	}
+	return ids
+}
+
+// QueryCache 在缓存的解析结果上按条件过滤、排序并分页，只读取当前页的行
+// Total为全部行数，Filtered为过滤后（分页前）的行数
+func QueryCache(r *rowcache.Reader, q models.ParseQuery) (*models.CSVData, error) {
+	selection, err := SelectRows(r, q)
+	if err != nil {
+		return nil, err
+	}
+	page := make([]int, selection.Len())
+	for i := range page {
+		page[i] = selection.At(i)
+	}
+
+	pageRows, pageDecoded, err := r.ReadRows(page, r.HasDecoded())
+	if err != nil {
+		return nil, err
+	}
+	result := &models.CSVData{
+		Headers:  r.Headers(),
+		Rows:     pageRows,
+		Total:    r.Len(),
+		Filtered: selection.Filtered,
+		Offset:   selection.Offset,
+		Limit:    q.Limit,
+	}
+	if r.HasDecoded() {
+		result.Decoded = make([][]models.DecodedField, len(page))
+		for i, raw := range pageDecoded {
+			if result.Decoded[i], err = unmarshalDecoded(raw); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return result, nil
+}
+
+// WriteCacheJSON 将缓存中的全部结果以CSVData的JSON格式写入w，逐块读取，内存占用与结果大小无关
+func WriteCacheJSON(w io.Writer, r *rowcache.Reader) error {
+	bw := bufio.NewWriterSize(w, 64*1024)
+	headers, err := json.Marshal(r.Headers())
+	if err != nil {
+		return err
+	}
+	bw.WriteString(`{"headers":`)
+	bw.Write(headers)
+	bw.WriteString(`,"rows":[`)
+	err = r.Scan(rowcache.ScanOptions{}, func(row *rowcache.Row) error {
+		data, err := json.Marshal(row.Cells)
+		if err != nil {
+			return err
+		}
+		if row.Index > 0 {
+			bw.WriteByte(',')
+		}
+		_, err = bw.Write(data)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	bw.WriteByte(']')
+
+	// 解码字段在rows之后，单独扫描一遍解码字段列
+	if r.HasDecoded() {
+		bw.WriteString(`,"decoded":[`)
+		err = r.Scan(rowcache.ScanOptions{Columns: []int{}, Decoded: true}, func(row *rowcache.Row) error {
+			if row.Index > 0 {
+				bw.WriteByte(',')
+			}
+			if len(row.Decoded) == 0 {
+				_, err := bw.WriteString("null")
+				return err
+			}
+			_, err := bw.Write(row.Decoded)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		bw.WriteByte(']')
+	}
+	fmt.Fprintf(bw, `,"total":%d,"filtered":%d,"offset":0}`, r.Len(), r.Len())
+	return bw.Flush()
+}
+
+// messageIDRanks 为消息ID编号，用于按ID排序：Buffer中的CAN ID按数值编号（canRanks，不是十六进制时为-1），
+// 来自Name列的ID按小写字符串编号（nameRanks），排在所有CAN ID之后，即使名称恰好是十六进制（如"bad"）
+func messageIDRanks(ids []string) (canRanks, nameRanks []int) {
+	values := make([]uint64, len(ids))
+	valid := make([]bool, len(ids))
+	for i, id := range ids {
+		v, err := strconv.ParseUint(strings.TrimPrefix(strings.ToLower(id), "0x"), 16, 32)
+		values[i], valid[i] = v, err == nil
+	}
+	canRanks = ranksBy(len(ids), func(a, b int) bool {
+		if valid[a] != valid[b] {
+			return valid[a]
+		}
+		return valid[a] && values[a] < values[b]
+	})
+	numCAN := 0
+	for i := range canRanks {
+		if !valid[i] {
+			canRanks[i] = -1
+		} else {
+			numCAN = max(numCAN, canRanks[i]+1)
+		}
+	}
+
+	lower := make([]string, len(ids))
+	for i, id := range ids {
+		lower[i] = strings.ToLower(id)
+	}
+	nameRanks = stringRanks(lower)
+	for i := range nameRanks {
+		nameRanks[i] += numCAN
+	}
+	return canRanks, nameRanks
+}
+
+// stringRanks 按字符串顺序编号
+func stringRanks(values []string) []int {
+	return ranksBy(len(values), func(a, b int) bool { return values[a] < values[b] })
+}
+
+// ranksBy 返回每个取值排序后的序号，相等的取值序号相同
+func ranksBy(n int, less func(a, b int) bool) []int {
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return less(order[a], order[b]) })
+	ranks := make([]int, n)
+	rank := 0
+	for k, i := range order {
+		if k > 0 && less(order[k-1], i) {
+			rank++
+		}
+		ranks[i] = rank
+	}
+	return ranks
+}
+
+// parseQueryTime 解析时间范围参数，接受Time列格式（秒后的小数部分可选）、日期和RFC3339，空字符串返回零值
+func parseQueryTime(s string) (time.Time, error) {
+	if s = strings.TrimSpace(s); s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := formats.ParseTime(s); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339Nano, s)
+}
+
+// matchFold 集合为空时不过滤，否则要求值（不区分大小写）在集合中
+func matchFold(set map[string]bool, value string) bool {
+	return len(set) == 0 || set[strings.ToLower(strings.TrimSpace(value))]
+}
+
+// rowContains 任一单元格包含text（text已转换为小写）
+func rowContains(row []string, text string) bool {
+	for _, v := range row {
+		if strings.Contains(strings.ToLower(v), text) {
+			return true
+		}
+	}
+	return false
+}
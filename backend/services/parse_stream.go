@@ -0,0 +1,463 @@
+package services
+
+import (
+	"bufio"
+	"csv-parser/formats"
+	"csv-parser/models"
+	"csv-parser/rowcache"
+	"csv-parser/utils"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// parseBatchSize 流水线每批处理的行数，内存占用与批大小相关，与文件大小无关
+const parseBatchSize = 4096
+
+// recordReader 逐行读取原始数据行，读完时返回io.EOF
+type recordReader interface {
+	Next() ([]string, error)
+}
+
+// recordStream 上传文件的表头和原始数据行读取器，日志格式的帧转换为嗅探器CSV行
+type recordStream struct {
+	Headers []string
+	file    *os.File
+	reader  recordReader
+}
+
+// Next 读取下一行原始数据，读完时返回io.EOF
+func (r *recordStream) Next() ([]string, error) {
+	if r.reader == nil {
+		return nil, io.EOF
+	}
+	return r.reader.Next()
+}
+
+// Close 关闭上传文件
+func (r *recordStream) Close() error {
+	return r.file.Close()
+}
+
+// openRecords 打开上传文件并读取表头，数据行由Next逐行读取
+func (s *CSVService) openRecords(filename string, logKey string) (*recordStream, error) {
+	filePath := filepath.Join(s.uploadDir, filename)
+
+	// 检查文件是否存在
+	if _, err := os.Stat(filePath); os.IsNotExist(err) {
+		if logKey != "" {
+			utils.FileLogError(logKey, "文件不存在: %s", filePath)
+		}
+		return nil, fmt.Errorf("file not found")
+	}
+
+	if logKey != "" {
+		utils.FileLogInfo(logKey, "开始解析CSV文件: %s", filePath)
+	}
+
+	// 打开文件
+	file, err := os.Open(filePath)
+	if err != nil {
+		if logKey != "" {
+			utils.FileLogError(logKey, "打开文件失败: %v", err)
+		}
+		return nil, fmt.Errorf("failed to open file: %v", err)
+	}
+
+	// 获取文件大小
+	fileInfo, _ := file.Stat()
+	if logKey != "" {
+		utils.FileLogInfo(logKey, "文件大小: %d 字节", fileInfo.Size())
+	}
+
+	// 按文件格式读取表头和数据行，日志格式的帧会转换为嗅探器CSV行
+	format, reader, err := formats.DetectReader(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to read file: %v", err)
+	}
+	if format == "" {
+		// 内容无法识别时按扩展名处理（兼容旧文件）
+		format = formats.FormatByExtension(filename)
+	}
+	if logKey != "" {
+		utils.FileLogInfo(logKey, "文件格式: %s", formats.FormatNames[format])
+	}
+
+	stream := &recordStream{file: file}
+	if format == formats.FormatSnifferCSV || format == "" {
+		csvReader := newCSVRecordReader(reader, logKey)
+		// 第一个有效行作为表头
+		headers, err := csvReader.Next()
+		if err == nil {
+			stream.Headers = headers
+			stream.reader = csvReader
+			if logKey != "" {
+				utils.FileLogInfo(logKey, "识别到表头，共 %d 列: %v", len(headers), headers)
+			}
+		}
+		return stream, nil
+	}
+
+	if logKey != "" {
+		utils.FileLogInfo(logKey, "按 %s 格式读取日志帧...", format)
+	}
+	frameReader, err := formats.NewFrameReader(format, reader)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	stream.Headers = formats.LogHeaders
+	stream.reader = &logRecordReader{reader: frameReader, format: format, logKey: logKey}
+	return stream, nil
+}
+
+// csvRecordReader 逐行读取嗅探器CSV，每行独立解析，格式错误的行不会影响后续有效行
+type csvRecordReader struct {
+	scanner     *bufio.Scanner
+	logKey      string
+	lineNumber  int
+	rows        int
+	skippedRows int
+}
+
+func newCSVRecordReader(file io.Reader, logKey string) *csvRecordReader {
+	scanner := bufio.NewScanner(file)
+	// 设置更大的缓冲区以处理较长的行
+	const maxScanTokenSize = 1024 * 1024 // 1MB
+	buf := make([]byte, maxScanTokenSize)
+	scanner.Buffer(buf, maxScanTokenSize)
+
+	if logKey != "" {
+		utils.FileLogInfo(logKey, "开始逐行读取CSV数据（增强容错模式）...")
+	}
+	return &csvRecordReader{scanner: scanner, logKey: logKey}
+}
+
+// Next 返回下一个有效行（第一次调用返回表头），读完时返回io.EOF
+func (r *csvRecordReader) Next() ([]string, error) {
+	logKey := r.logKey
+	for r.scanner.Scan() {
+		r.lineNumber++
+		line := r.scanner.Text()
+
+		// 跳过空行
+		if strings.TrimSpace(line) == "" {
+			if logKey != "" {
+				utils.FileLogDebug(logKey, "第 %d 行为空行，已跳过", r.lineNumber)
+			}
+			r.skippedRows++
+			continue
+		}
+
+		// 对每一行独立创建CSV reader进行解析
+		lineReader := csv.NewReader(strings.NewReader(line))
+		lineReader.FieldsPerRecord = -1 // 允许不同数量的字段
+		lineReader.LazyQuotes = true    // 更宽松的引号处理
+
+		record, err := lineReader.Read()
+		if err != nil {
+			// 跳过格式错误的行，但不影响后续行
+			if logKey != "" {
+				utils.FileLogWarn(logKey, "第 %d 行解析错误，已跳过: %v (内容: %.100s...)", r.lineNumber, err, line)
+			}
+			r.skippedRows++
+			continue
+		}
+		r.rows++
+		return record, nil
+	}
+
+	// 检查Scanner错误
+	if err := r.scanner.Err(); err != nil {
+		if logKey != "" {
+			utils.FileLogError(logKey, "读取文件时发生错误: %v", err)
+		}
+	}
+
+	if logKey != "" {
+		// 表头不计入有效数据行
+		utils.FileLogInfo(logKey, "CSV读取完成: 总行数=%d, 有效数据行=%d, 跳过行数=%d", r.lineNumber, max(r.rows-1, 0), r.skippedRows)
+	}
+	return nil, io.EOF
+}
+
+// logRecordReader 读取日志格式（candump等）的帧并转换为嗅探器CSV行
+type logRecordReader struct {
+	reader formats.FrameReader
+	format string
+	logKey string
+	frames int
+}
+
+func (r *logRecordReader) Next() ([]string, error) {
+	frame, err := r.reader.Next()
+	if err == io.EOF {
+		if r.logKey != "" {
+			utils.FileLogInfo(r.logKey, "日志读取完成: 帧数=%d", r.frames)
+		}
+		return nil, io.EOF
+	}
+	if err != nil {
+		if r.logKey != "" {
+			utils.FileLogError(r.logKey, "读取日志帧失败: %v", err)
+		}
+		return nil, fmt.Errorf("failed to read %s log: %v", r.format, err)
+	}
+	r.frames++
+	return formats.LogFrameRow(frame), nil
+}
+
+// parsedRow 协议处理后的一行及其解码字段
+type parsedRow struct {
+	Row     []string
+	Decoded []models.DecodedField
+}
+
+// rowProcessor 按协议处理原始数据行
+type rowProcessor interface {
+	// Headers 输出表头
+	Headers() []string
+	// Process 处理一批原始数据行，按输入顺序返回有效行
+	Process(rows [][]string) []parsedRow
+	// Finish 全部行处理完后输出统计日志
+	Finish()
+}
+
+// rowSink 接收流水线输出的表头和数据行
+type rowSink interface {
+	WriteHeaders(headers []string) error
+	WriteRow(row parsedRow) error
+}
+
+// ParseStream 一次流式解析：读取 → 过滤 → 解码 → 输出，内存占用只与批大小相关
+type ParseStream struct {
+	service   *CSVService
+	filename  string
+	protocol  string
+	logKey    string
+	records   *recordStream
+	processor rowProcessor
+}
+
+// OpenParse 打开上传文件并读取表头，文件不存在或无法读取时返回错误
+// 数据行在Collect或BuildCache时才逐批读取和处理
+func (s *CSVService) OpenParse(filename, protocol, logKey string) (*ParseStream, error) {
+	records, err := s.openRecords(filename, logKey)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &ParseStream{
+		service:  s,
+		filename: filename,
+		protocol: protocol,
+		logKey:   logKey,
+		records:  records,
+	}
+	if len(records.Headers) == 0 {
+		if logKey != "" {
+			utils.FileLogWarn(logKey, "文件为空或没有有效数据")
+		}
+		return p, nil
+	}
+
+	// 根据协议进行特定的数据处理
+	if logKey != "" {
+		utils.FileLogInfo(logKey, "开始使用 %s 协议处理数据...", protocol)
+	}
+	p.processor = s.newRowProcessor(records.Headers, protocol, logKey)
+	return p, nil
+}
+
+// Close 关闭上传文件
+func (p *ParseStream) Close() error {
+	return p.records.Close()
+}
+
+// run 逐批读取原始数据行，处理后写入sink，返回输出的行数
+func (p *ParseStream) run(sink rowSink) (int, error) {
+	if p.processor == nil {
+		return 0, sink.WriteHeaders([]string{})
+	}
+
+	headers := p.processor.Headers()
+	if err := sink.WriteHeaders(headers); err != nil {
+		return 0, err
+	}
+
+	total := 0
+	batch := make([][]string, 0, parseBatchSize)
+	for done := false; !done; {
+		batch = batch[:0]
+		for len(batch) < parseBatchSize {
+			row, err := p.records.Next()
+			if err == io.EOF {
+				done = true
+				break
+			}
+			if err != nil {
+				return total, err
+			}
+			batch = append(batch, row)
+		}
+
+		for _, row := range p.processor.Process(batch) {
+			if err := sink.WriteRow(row); err != nil {
+				return total, err
+			}
+			total++
+		}
+	}
+	p.processor.Finish()
+
+	if p.logKey != "" {
+		utils.FileLogInfo(p.logKey, "数据处理完成: 输出列数=%d, 输出行数=%d", len(headers), total)
+	}
+	return total, nil
+}
+
+// Collect 将全部解析结果收集到内存中
+func (p *ParseStream) Collect() (*models.CSVData, error) {
+	sink := &collectSink{}
+	total, err := p.run(sink)
+	if err != nil {
+		return nil, err
+	}
+	sink.data.Total = total
+	return &sink.data, nil
+}
+
+// BuildCache 解析结果只写入缓存文件，返回行数
+func (p *ParseStream) BuildCache() (int, error) {
+	cache, err := p.service.createCacheSink(p.filename, p.protocol)
+	if err != nil {
+		return 0, err
+	}
+	total, err := p.run(cache)
+	if err != nil {
+		cache.abort()
+		return total, err
+	}
+	return total, cache.commit()
+}
+
+// collectSink 将输出行收集为CSVData
+type collectSink struct {
+	data models.CSVData
+}
+
+func (s *collectSink) WriteHeaders(headers []string) error {
+	s.data.Headers = headers
+	s.data.Rows = [][]string{}
+	return nil
+}
+
+func (s *collectSink) WriteRow(row parsedRow) error {
+	s.data.Rows = append(s.data.Rows, row.Row)
+	if row.Decoded != nil && s.data.Decoded == nil {
+		// 第一次出现解码字段时补齐之前的行
+		s.data.Decoded = make([][]models.DecodedField, len(s.data.Rows)-1)
+	}
+	if s.data.Decoded != nil {
+		s.data.Decoded = append(s.data.Decoded, row.Decoded)
+	}
+	return nil
+}
+
+// cacheSink 将输出行写入rowcache缓存文件，按Buffer/Name列建立消息ID索引，按Time列建立时间索引
+// 方法允许nil接收者（不缓存）
+type cacheSink struct {
+	path      string
+	writer    *rowcache.Writer
+	nameIdx   int
+	timeIdx   int
+	bufferIdx int
+}
+
+// createCacheSink 创建缓存目录，缓存文件在写入表头时创建
+func (s *CSVService) createCacheSink(filename, protocol string) (*cacheSink, error) {
+	if err := os.MkdirAll(s.getCacheDir(), 0755); err != nil {
+		return nil, fmt.Errorf("创建缓存目录失败: %v", err)
+	}
+	return &cacheSink{path: s.getCachePath(filename, protocol)}, nil
+}
+
+func (c *cacheSink) WriteHeaders(headers []string) error {
+	if c == nil {
+		return nil
+	}
+	writer, err := rowcache.Create(c.path, cacheSchemaVersion, headers)
+	if err != nil {
+		return err
+	}
+	c.writer = writer
+	c.nameIdx, c.timeIdx, c.bufferIdx = -1, -1, -1
+	for i, h := range headers {
+		switch strings.TrimPrefix(h, "\uFEFF") {
+		case "Name":
+			c.nameIdx = i
+		case "Time":
+			c.timeIdx = i
+		case "Buffer":
+			c.bufferIdx = i
+		}
+	}
+	return nil
+}
+
+func (c *cacheSink) WriteRow(row parsedRow) error {
+	if c == nil {
+		return nil
+	}
+	var decoded []byte
+	if row.Decoded != nil {
+		var err error
+		if decoded, err = json.Marshal(row.Decoded); err != nil {
+			return err
+		}
+	}
+	id := normalizeCANID(rowMessageID(row.Row, c.nameIdx, c.bufferIdx))
+	t := int64(rowcache.NoTime)
+	if parsed, err := formats.ParseTimestamp(cell(row.Row, c.timeIdx)); err == nil {
+		t = parsed
+	}
+	return c.writer.Add(row.Row, decoded, id, t)
+}
+
+// commit 写入完成，改名为正式的缓存文件
+func (c *cacheSink) commit() error {
+	if c == nil || c.writer == nil {
+		return nil
+	}
+	if err := c.writer.Commit(); err != nil {
+		return fmt.Errorf("写入缓存文件失败: %v", err)
+	}
+	utils.Info("成功保存缓存: %s (%d 行)", c.path, c.writer.Rows())
+	return nil
+}
+
+// abort 放弃缓存，删除临时文件
+func (c *cacheSink) abort() {
+	if c == nil || c.writer == nil {
+		return
+	}
+	c.writer.Abort()
+	c.writer = nil
+}
+
+// unmarshalDecoded 解析缓存中的解码字段JSON，空表示没有
+func unmarshalDecoded(data []byte) ([]models.DecodedField, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	var fields []models.DecodedField
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
@@ -0,0 +1,253 @@
+package services
+
+import (
+	"csv-parser/formats"
+	"csv-parser/models"
+	"csv-parser/socketcan"
+	"csv-parser/utils"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// 回放速度倍数的范围
+const (
+	minReplaySpeed = 0.01
+	maxReplaySpeed = 1000.0
+)
+
+// minReplayLoopGap 循环回放时一轮的最后一帧与下一轮第一帧之间的最小间隔
+const minReplayLoopGap = time.Millisecond
+
+var (
+	// ErrReplayRunning 已有回放在进行中
+	ErrReplayRunning = errors.New("a replay is already running")
+	// ErrReplayNotRunning 当前没有进行中的回放
+	ErrReplayNotRunning = errors.New("no replay is running")
+	// ErrInvalidReplay 回放参数不合法或文件中没有可回放的帧
+	ErrInvalidReplay = errors.New("invalid replay request")
+)
+
+// ReplayRequest 回放参数
+type ReplayRequest struct {
+	Filename   string
+	Interface  string
+	Speed      float64 // 回放速度倍数，<=0 时按1处理
+	Loop       bool    // 回放到结尾后从头开始，直到停止
+	IDs        []string
+	ExcludeIDs []string
+}
+
+// frameSink 回放的发送目标，由SocketCAN连接实现
+type frameSink interface {
+	WriteFrame(frame *models.CANFrame) error
+	Close() error
+}
+
+// ReplayService 将上传文件Buffer列中的CAN帧按原始时间间隔发送到SocketCAN接口
+// 同一时间只允许一个回放
+type ReplayService struct {
+	exportService *ExportService
+	open          func(iface string) (frameSink, error)
+
+	mu      sync.Mutex
+	current *replaySession // 进行中或最近一次结束的回放
+}
+
+// replaySession 一次回放的运行状态
+type replaySession struct {
+	sink     frameSink
+	frames   []*models.LogFrame
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+
+	// 由ReplayService.mu保护
+	status models.ReplayStatus
+}
+
+func NewReplayService(exportService *ExportService) *ReplayService {
+	return &ReplayService{
+		exportService: exportService,
+		open: func(iface string) (frameSink, error) {
+			return socketcan.Open(iface)
+		},
+	}
+}
+
+// Start 开始回放，帧的提取和ID过滤与CAN日志导出一致，按Time升序发送
+func (s *ReplayService) Start(req ReplayRequest) (*models.ReplayStatus, error) {
+	if req.Interface == "" || !interfaceNamePattern.MatchString(req.Interface) {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidInterface, req.Interface)
+	}
+	if math.IsNaN(req.Speed) || math.IsInf(req.Speed, 0) {
+		return nil, fmt.Errorf("%w: invalid speed %g", ErrInvalidReplay, req.Speed)
+	}
+	if req.Speed <= 0 {
+		req.Speed = 1
+	}
+	if req.Speed < minReplaySpeed || req.Speed > maxReplaySpeed {
+		return nil, fmt.Errorf("%w: speed must be between %g and %g", ErrInvalidReplay, minReplaySpeed, maxReplaySpeed)
+	}
+
+	frames, _, err := s.exportService.LoadFrames(req.Filename, models.ParseQuery{IDs: req.IDs, ExcludeIDs: req.ExcludeIDs})
+	if err != nil {
+		return nil, err
+	}
+	if len(frames) == 0 {
+		return nil, fmt.Errorf("%w: no CAN frames to replay in %s", ErrInvalidReplay, req.Filename)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.current != nil && s.current.status.Running {
+		return nil, ErrReplayRunning
+	}
+
+	sink, err := s.open(req.Interface)
+	if err != nil {
+		return nil, err
+	}
+
+	session := &replaySession{
+		sink:   sink,
+		frames: frames,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+		status: models.ReplayStatus{
+			Running:     true,
+			Filename:    req.Filename,
+			Interface:   req.Interface,
+			Speed:       req.Speed,
+			Loop:        req.Loop,
+			IDs:         req.IDs,
+			ExcludeIDs:  req.ExcludeIDs,
+			TotalFrames: len(frames),
+			StartTime:   time.Now(),
+		},
+	}
+	s.current = session
+	go s.run(session)
+
+	utils.Info("开始回放: 文件=%s, 接口=%s, 共 %d 帧, 速度=%gx, 循环=%v", req.Filename, req.Interface, len(frames), req.Speed, req.Loop)
+	status := session.status
+	return &status, nil
+}
+
+// Stop 停止当前回放并等待发送循环结束
+func (s *ReplayService) Stop() (*models.ReplayStatus, error) {
+	s.mu.Lock()
+	session := s.current
+	if session == nil || !session.status.Running {
+		s.mu.Unlock()
+		return nil, ErrReplayNotRunning
+	}
+	s.mu.Unlock()
+
+	// 回放可能在此期间自然结束，此时done已关闭，直接返回最终状态
+	session.stopOnce.Do(func() { close(session.stop) })
+	<-session.done
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := session.status
+	return &status, nil
+}
+
+// Status 返回进行中或最近一次结束的回放状态，从未回放时返回nil
+func (s *ReplayService) Status() *models.ReplayStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.current == nil {
+		return nil
+	}
+	status := s.current.status
+	return &status
+}
+
+// run 发送循环：每帧在 开始时间 + 相对时间/速度 时发送，循环模式下每轮重新计时
+func (s *ReplayService) run(session *replaySession) {
+	defer close(session.done)
+
+	err := s.replay(session)
+
+	session.sink.Close()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stopTime := time.Now()
+	session.status.Running = false
+	session.status.StopTime = &stopTime
+	if err != nil {
+		session.status.Error = err.Error()
+		utils.Error("回放失败: 文件=%s, 接口=%s, %v", session.status.Filename, session.status.Interface, err)
+		return
+	}
+	utils.Info("回放结束: 文件=%s, 已发送 %d 帧, 完成 %d 轮", session.status.Filename, session.status.SentFrames, session.status.Loops)
+}
+
+// replay 按时间发送所有帧，被停止时返回nil，发送失败时返回错误
+func (s *ReplayService) replay(session *replaySession) error {
+	speed := session.status.Speed
+	timer := time.NewTimer(time.Hour)
+	timer.Stop()
+	defer timer.Stop()
+
+	period := replayLoopPeriod(session.frames, speed)
+	start := time.Now()
+	for {
+		for _, frame := range session.frames {
+			wait := time.Until(start.Add(time.Duration(float64(frame.Relative) / speed)))
+			if wait > 0 {
+				timer.Reset(wait)
+				select {
+				case <-timer.C:
+				case <-session.stop:
+					return nil
+				}
+			} else {
+				select {
+				case <-session.stop:
+					return nil
+				default:
+				}
+			}
+
+			if err := session.sink.WriteFrame(&frame.CANFrame); err != nil {
+				return fmt.Errorf("发送帧 %s 失败: %v", formats.DisplayID(&frame.CANFrame), err)
+			}
+			s.mu.Lock()
+			session.status.SentFrames++
+			s.mu.Unlock()
+		}
+
+		s.mu.Lock()
+		session.status.Loops++
+		s.mu.Unlock()
+		// Loop在回放开始后不再修改，无需加锁
+		if !session.status.Loop {
+			return nil
+		}
+		// 下一轮在本轮开始period之后开始；发送落后时立即开始，不补发积压的间隔
+		start = start.Add(period)
+		if now := time.Now(); start.Before(now) {
+			start = now
+		}
+	}
+}
+
+// replayLoopPeriod 循环回放一轮的时长：最后一帧的时间加上最后两帧的间隔，
+// 且最后一帧与下一轮第一帧至少间隔minReplayLoopGap，避免所有帧时间相同（或只有一帧）时空转
+func replayLoopPeriod(frames []*models.LogFrame, speed float64) time.Duration {
+	last := frames[len(frames)-1].Relative
+	var gap time.Duration
+	if n := len(frames); n >= 2 {
+		gap = last - frames[n-2].Relative
+	}
+	period := time.Duration(float64(last+gap) / speed)
+	if minPeriod := time.Duration(float64(last)/speed) + minReplayLoopGap; period < minPeriod {
+		period = minPeriod
+	}
+	return period
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"csv-parser/models"
+	"errors"
+	"math"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestReplayRejectsInvalidSpeed(t *testing.T) {
+	s := NewReplayService(nil)
+	for _, speed := range []float64{math.NaN(), math.Inf(1), math.Inf(-1), 0.001, 5000} {
+		_, err := s.Start(ReplayRequest{Filename: "a.csv", Interface: "vcan0", Speed: speed})
+		if !errors.Is(err, ErrInvalidReplay) {
+			t.Errorf("speed %g: err = %v, want ErrInvalidReplay", speed, err)
+		}
+	}
+}
+
+func TestReplayLoopPeriod(t *testing.T) {
+	frames := func(relatives ...time.Duration) []*models.LogFrame {
+		var out []*models.LogFrame
+		for _, r := range relatives {
+			out = append(out, &models.LogFrame{Relative: r})
+		}
+		return out
+	}
+	tests := []struct {
+		name   string
+		frames []*models.LogFrame
+		speed  float64
+		want   time.Duration
+	}{
+		{"single frame", frames(0), 1, minReplayLoopGap},
+		{"same time", frames(0, 0, 0), 1, minReplayLoopGap},
+		{"last gap", frames(0, 10*time.Millisecond, 30*time.Millisecond), 1, 50 * time.Millisecond},
+		{"speed", frames(0, 10*time.Millisecond, 30*time.Millisecond), 2, 25 * time.Millisecond},
+		{"duplicate last", frames(0, 30*time.Millisecond, 30*time.Millisecond), 1, 30*time.Millisecond + minReplayLoopGap},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := replayLoopPeriod(tt.frames, tt.speed); got != tt.want {
+				t.Errorf("period = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// countingSink 记录发送的帧数
+type countingSink struct {
+	mu    sync.Mutex
+	count int
+}
+
+func (c *countingSink) WriteFrame(*models.CANFrame) error {
+	c.mu.Lock()
+	c.count++
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *countingSink) Close() error { return nil }
+
+// 所有帧时间相同的循环回放不能空转
+func TestReplayLoopPacing(t *testing.T) {
+	sink := &countingSink{}
+	s := &ReplayService{}
+	session := &replaySession{
+		sink:   sink,
+		frames: []*models.LogFrame{{}, {}},
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+		status: models.ReplayStatus{Running: true, Speed: 1, Loop: true},
+	}
+	go s.run(session)
+	time.Sleep(50 * time.Millisecond)
+	close(session.stop)
+	<-session.done
+
+	// 每轮至少1ms，50ms内最多约50轮（100帧）
+	if sink.count > 120 {
+		t.Errorf("sent %d frames in 50ms, loop is not paced", sink.count)
+	}
+	if sink.count == 0 {
+		t.Error("no frames sent")
+	}
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"csv-parser/formats"
+	"csv-parser/utils"
+	"strconv"
+	"time"
+)
+
+// timingHeaders CAN输出中由Time列计算的列，依次为时间戳、相对时间、与上一行的间隔、与上一条同ID消息的间隔和时间校验
+var timingHeaders = []string{"时间戳(ns)", "相对时间(s)", "间隔(ms)", "同ID间隔(ms)", "时间校验"}
+
+// maxBackwardLogs 日志中逐行记录时间倒退的最大行数，超过后只统计数量
+const maxBackwardLogs = 100
+
+// rowTime 单行解析出的时间，在并行处理阶段计算
+type rowTime struct {
+	nano  int64
+	valid bool
+	id    string // 计算同ID间隔用的消息ID
+}
+
+// parseRowTime 解析Time列，id为空时使用"N/A"
+func parseRowTime(value, id string) rowTime {
+	if id == "" {
+		id = "N/A"
+	}
+	nano, err := formats.ParseTimestamp(value)
+	return rowTime{nano: nano, valid: err == nil, id: id}
+}
+
+// rowTiming 按输出顺序计算相对时间和间隔，依赖上一行，必须顺序调用
+type rowTiming struct {
+	logKey   string
+	first    int64
+	last     int64
+	started  bool
+	lastByID map[string]int64
+
+	unparsed  int
+	backwards int
+}
+
+func newRowTiming(logKey string) *rowTiming {
+	return &rowTiming{logKey: logKey, lastByID: make(map[string]int64)}
+}
+
+// fill 将时间列写入cells（长度为len(timingHeaders)），rowNum为原始文件中的行号，用于报告时间倒退
+// 时间无法解析的行各列留空，不影响后续行的间隔
+func (t *rowTiming) fill(cells []string, rt rowTime, rowNum int) {
+	if !rt.valid {
+		t.unparsed++
+		return
+	}
+	if !t.started {
+		t.first, t.last, t.started = rt.nano, rt.nano, true
+	}
+
+	cells[0] = strconv.FormatInt(rt.nano, 10)
+	cells[1] = formatSeconds(rt.nano - t.first)
+	delta := rt.nano - t.last
+	cells[2] = formatMillis(delta)
+	if prev, ok := t.lastByID[rt.id]; ok {
+		cells[3] = formatMillis(rt.nano - prev)
+	}
+
+	if delta < 0 {
+		t.backwards++
+		cells[4] = "时间倒退 " + formatMillis(-delta) + " ms"
+		if t.logKey != "" && t.backwards <= maxBackwardLogs {
+			utils.FileLogWarn(t.logKey, "第 %d 行时间倒退 %s ms（%s 早于上一行 %s）", rowNum, formatMillis(-delta),
+				formats.FormatTime(time.Unix(0, rt.nano)), formats.FormatTime(time.Unix(0, t.last)))
+		}
+	}
+	t.last = rt.nano
+	t.lastByID[rt.id] = rt.nano
+}
+
+// report 记录时间统计
+func (t *rowTiming) report() {
+	if t.logKey == "" {
+		return
+	}
+	if t.unparsed > 0 {
+		utils.FileLogWarn(t.logKey, "  - 时间无法解析: %d 条", t.unparsed)
+	}
+	if t.backwards > 0 {
+		utils.FileLogWarn(t.logKey, "  - 时间倒退: %d 条", t.backwards)
+	}
+}
+
+// formatSeconds 纳秒转换为秒，保留到微秒
+func formatSeconds(nano int64) string {
+	return strconv.FormatFloat(float64(nano)/1e9, 'f', 6, 64)
+}
+
+// formatMillis 纳秒转换为毫秒，保留到微秒
+func formatMillis(nano int64) string {
+	return strconv.FormatFloat(float64(nano)/1e6, 'f', 3, 64)
+}
@@ -0,0 +1,368 @@
+package services
+
+import (
+	"csv-parser/models"
+	"csv-parser/rowcache"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxStateViolations 每个状态机最多返回的违规条数，超过后只计数
+const maxStateViolations = 1000
+
+// stateMachineConfig state_machines.json
+type stateMachineConfig struct {
+	Machines []stateMachine `json:"machines"`
+}
+
+// stateMachine 一个状态机：messageId报文中field字段的原始字节为状态
+type stateMachine struct {
+	Name        string                     `json:"name"`
+	Description string                     `json:"description"`
+	MessageID   string                     `json:"messageId"`
+	Field       string                     `json:"field"`
+	Initial     []string                   `json:"initial"`
+	States      map[string]stateDefinition `json:"states"`
+}
+
+// stateDefinition 一个状态的名称、允许的下一状态和最长持续时间
+type stateDefinition struct {
+	Name      string   `json:"name"`
+	Next      []string `json:"next"`
+	TimeoutMs float64  `json:"timeoutMs"`
+}
+
+// StateMachineService 按state_machines.json回放解析结果，检查状态跳变
+type StateMachineService struct {
+	csvService *CSVService
+	configDir  string
+}
+
+func NewStateMachineService(configDir string, csvService *CSVService) *StateMachineService {
+	return &StateMachineService{
+		csvService: csvService,
+		configDir:  configDir,
+	}
+}
+
+// loadStateMachines 读取并校验state_machines.json，状态统一为小写十六进制
+func (s *StateMachineService) loadStateMachines() ([]stateMachine, error) {
+	content, err := os.ReadFile(filepath.Join(s.configDir, "state_machines.json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var config stateMachineConfig
+	if err := json.Unmarshal(content, &config); err != nil {
+		return nil, fmt.Errorf("解析state_machines.json失败: %v", err)
+	}
+
+	for i := range config.Machines {
+		m := &config.Machines[i]
+		if m.Name == "" || m.MessageID == "" || m.Field == "" || len(m.States) == 0 {
+			return nil, fmt.Errorf("state_machines.json: 第 %d 个状态机缺少name、messageId、field或states", i+1)
+		}
+		states := make(map[string]stateDefinition, len(m.States))
+		for code, def := range m.States {
+			for j := range def.Next {
+				def.Next[j] = strings.ToLower(def.Next[j])
+			}
+			states[strings.ToLower(code)] = def
+		}
+		m.States = states
+		for code, def := range m.States {
+			for _, next := range def.Next {
+				if _, ok := m.States[next]; !ok {
+					return nil, fmt.Errorf("state_machines.json: 状态机 %s 的状态 %s 的next中有未定义的状态 %s", m.Name, code, next)
+				}
+			}
+		}
+		for j := range m.Initial {
+			m.Initial[j] = strings.ToLower(m.Initial[j])
+		}
+	}
+	return config.Machines, nil
+}
+
+// Check 按CAN协议解析文件，用每个状态机回放对应报文，报告非法跳变、跳过的中间状态、未定义状态和超时
+// names为空时检查全部状态机
+func (s *StateMachineService) Check(filename string, names []string) (*models.StateCheckResult, error) {
+	machines, err := s.loadStateMachines()
+	if err != nil {
+		return nil, err
+	}
+	if len(names) > 0 {
+		wanted := idSet(names)
+		selected := machines[:0]
+		for _, m := range machines {
+			if wanted[strings.ToLower(m.Name)] {
+				selected = append(selected, m)
+				delete(wanted, strings.ToLower(m.Name))
+			}
+		}
+		if len(wanted) > 0 {
+			unknown := make([]string, 0, len(wanted))
+			for name := range wanted {
+				unknown = append(unknown, name)
+			}
+			sort.Strings(unknown)
+			return nil, fmt.Errorf("%w: 未定义的状态机: %s", ErrInvalidQuery, strings.Join(unknown, ", "))
+		}
+		machines = selected
+	}
+
+	result := &models.StateCheckResult{Machines: []models.StateMachineReport{}}
+	if len(machines) == 0 {
+		return result, nil
+	}
+
+	reader, err := s.csvService.OpenParsedCache(filename, "CAN")
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	timeIdx := -1
+	for i, h := range reader.Headers() {
+		if strings.TrimPrefix(h, "\uFEFF") == "Time" {
+			timeIdx = i
+		}
+	}
+
+	checkers := make([]*stateChecker, len(machines))
+	byID := make(map[string][]*stateChecker)
+	for i, m := range machines {
+		checkers[i] = newStateChecker(m, timeIdx)
+		id := normalizeCANID(m.MessageID)
+		byID[id] = append(byID[id], checkers[i])
+	}
+
+	if reader.HasDecoded() {
+		ids := make(map[string]bool, len(byID))
+		for id := range byID {
+			ids[id] = true
+		}
+		rows, err := reader.RowsWithIDs(ids)
+		if err != nil {
+			return nil, err
+		}
+		readerIDs := reader.IDs()
+
+		// 嗅探器记录的时间可能倒退，与曝光序列一样按时间排序后回放
+		// 没有时间的行沿用前一行的时间排序，保持在原来的位置
+		var entries []stateRow
+		lastTime := int64(rowcache.NoTime)
+		err = reader.Scan(rowcache.ScanOptions{Rows: rows, Columns: []int{timeIdx}, Decoded: true, Time: true, ID: true}, func(row *rowcache.Row) error {
+			if row.Time != rowcache.NoTime {
+				lastTime = row.Time
+			}
+			if row.ID < 0 || row.ID >= len(readerIDs) {
+				return nil
+			}
+			fields, err := unmarshalDecoded(row.Decoded)
+			if err != nil {
+				return nil
+			}
+			entry := stateRow{row: *row, key: lastTime, id: readerIDs[row.ID], fields: fields}
+			entry.row.Cells = append([]string(nil), row.Cells...)
+			entry.row.Decoded = nil
+			entries = append(entries, entry)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		sort.SliceStable(entries, func(a, b int) bool { return entries[a].key < entries[b].key })
+
+		for i := range entries {
+			for _, c := range byID[entries[i].id] {
+				c.step(&entries[i].row, entries[i].fields)
+			}
+		}
+	}
+
+	for _, c := range checkers {
+		c.finish()
+		result.Machines = append(result.Machines, c.report)
+	}
+	return result, nil
+}
+
+// stateRow 按时间排序前暂存的一行报文，key为排序用的时间
+type stateRow struct {
+	row    rowcache.Row
+	key    int64
+	id     string
+	fields []models.DecodedField
+}
+
+// stateChecker 回放一个状态机
+type stateChecker struct {
+	machine stateMachine
+	timeIdx int
+	report  models.StateMachineReport
+
+	state     string // 当前状态，空表示还没有出现
+	stateName string
+	enterRow  int
+	enterTime int64
+	lastRow   int
+	lastCell  string
+	lastTime  int64
+}
+
+func newStateChecker(m stateMachine, timeIdx int) *stateChecker {
+	return &stateChecker{
+		machine: m,
+		timeIdx: timeIdx,
+		report: models.StateMachineReport{
+			Name:        m.Name,
+			Description: m.Description,
+			MessageID:   m.MessageID,
+			Field:       m.Field,
+			Counts:      make(map[string]int),
+			Violations:  []models.StateViolation{},
+		},
+		enterRow:  -1,
+		enterTime: rowcache.NoTime,
+		lastTime:  rowcache.NoTime,
+	}
+}
+
+// step 处理一行报文，同一状态的重复报文只更新最后出现的位置
+func (c *stateChecker) step(row *rowcache.Row, fields []models.DecodedField) {
+	code := enumRaw(fields, c.machine.Field)
+	if code == "" {
+		return
+	}
+	c.report.Rows++
+	timeCell := cell(row.Cells, c.timeIdx)
+	defer func() {
+		c.lastRow, c.lastCell = row.Index, timeCell
+		if row.Time != rowcache.NoTime {
+			c.lastTime = row.Time
+		}
+	}()
+
+	name := c.stateLabel(code, enumDisplay(fields, c.machine.Field))
+	_, known := c.machine.States[code]
+
+	if c.state == "" {
+		switch {
+		case !known:
+			c.add(models.StateViolation{Kind: models.StateUnknown, Row: row.Index, Time: timeCell, FromRow: -1, To: code, ToName: name,
+				Message: fmt.Sprintf("未定义的状态: %s", name)})
+		case len(c.machine.Initial) > 0 && !containsString(c.machine.Initial, code):
+			c.add(models.StateViolation{Kind: models.StateUnexpectedInitial, Row: row.Index, Time: timeCell, FromRow: -1, To: code, ToName: name,
+				Expected: c.machine.Initial, Message: fmt.Sprintf("第一个状态 %s 不在initial中", name)})
+		}
+		c.enter(code, name, row)
+		return
+	}
+	if code == c.state {
+		return
+	}
+	c.report.Transitions++
+
+	c.checkTimeout(row.Index, timeCell, row.Time, false)
+
+	base := models.StateViolation{Row: row.Index, Time: timeCell, FromRow: c.enterRow, From: c.state, FromName: c.stateName, To: code, ToName: name}
+	if !known {
+		base.Kind = models.StateUnknown
+		base.Message = fmt.Sprintf("未定义的状态: %s → %s", c.stateName, name)
+		c.add(base)
+	} else if from, ok := c.machine.States[c.state]; ok && !containsString(from.Next, code) {
+		// 经过一个中间状态即可到达时视为缺少中间状态
+		var missing []string
+		for _, mid := range from.Next {
+			if containsString(c.machine.States[mid].Next, code) {
+				missing = append(missing, mid)
+			}
+		}
+		if len(missing) > 0 {
+			labels := make([]string, len(missing))
+			for i, mid := range missing {
+				labels[i] = c.stateLabel(mid, "")
+			}
+			base.Kind = models.StateMissing
+			base.Expected = missing
+			base.Message = fmt.Sprintf("缺少中间状态: %s → %s，应经过 %s", c.stateName, name, strings.Join(labels, " 或 "))
+		} else {
+			base.Kind = models.StateIllegalTransition
+			base.Expected = from.Next
+			base.Message = fmt.Sprintf("非法跳变: %s → %s，允许的下一状态: %s", c.stateName, name, strings.Join(from.Next, ", "))
+		}
+		c.add(base)
+	}
+	c.enter(code, name, row)
+}
+
+// enter 进入新状态
+func (c *stateChecker) enter(code, name string, row *rowcache.Row) {
+	c.state, c.stateName = code, name
+	c.enterRow, c.enterTime = row.Index, row.Time
+}
+
+// checkTimeout 检查当前状态持续到t是否超过timeoutMs，时间无法解析或倒退时不检查
+func (c *stateChecker) checkTimeout(rowIdx int, timeCell string, t int64, atEnd bool) {
+	def, ok := c.machine.States[c.state]
+	if !ok || def.TimeoutMs <= 0 || c.enterTime == rowcache.NoTime || t == rowcache.NoTime || t < c.enterTime {
+		return
+	}
+	ms := nanoToMillis(t - c.enterTime)
+	if ms <= def.TimeoutMs {
+		return
+	}
+	message := fmt.Sprintf("状态 %s 持续 %s ms，超过 %s ms", c.stateName, formatMillis(t-c.enterTime), strconv.FormatFloat(def.TimeoutMs, 'f', -1, 64))
+	if atEnd {
+		message += "（到最后一条报文仍未离开）"
+	}
+	c.add(models.StateViolation{Kind: models.StateTimeout, Row: rowIdx, Time: timeCell, FromRow: c.enterRow,
+		From: c.state, FromName: c.stateName, DurationMs: &ms, Message: message})
+}
+
+// finish 检查最后一个状态是否超时
+func (c *stateChecker) finish() {
+	if c.state == "" {
+		return
+	}
+	c.checkTimeout(c.lastRow, c.lastCell, c.lastTime, true)
+}
+
+// add 记录违规，超过maxStateViolations后只计数
+func (c *stateChecker) add(v models.StateViolation) {
+	c.report.Counts[v.Kind]++
+	if len(c.report.Violations) >= maxStateViolations {
+		c.report.Truncated = true
+		return
+	}
+	c.report.Violations = append(c.report.Violations, v)
+}
+
+// stateLabel 状态的显示名称，如 "HV ON(05)"，优先使用配置中的名称
+func (c *stateChecker) stateLabel(code, display string) string {
+	name := c.machine.States[code].Name
+	if name == "" {
+		name = display
+	}
+	if name == "" {
+		return code
+	}
+	return fmt.Sprintf("%s(%s)", name, code)
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"csv-parser/models"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testStateMachines = `{"machines": [{
+	"name": "Generator",
+	"messageId": "208",
+	"field": "Phase",
+	"initial": ["01"],
+	"states": {
+		"01": {"name": "Standby", "next": ["03"]},
+		"03": {"name": "Prep", "next": ["04", "01"], "timeoutMs": 500},
+		"04": {"name": "Ready", "next": ["05", "01"]},
+		"05": {"name": "HV ON", "next": ["04"]}
+	}
+}]}`
+
+// phaseRow 一行 208 报文，Phase 为状态码
+type phaseRow struct {
+	time  string
+	phase string
+}
+
+// checkPhases 把报文写入解析缓存后运行状态机检查
+func checkPhases(t *testing.T, rows []phaseRow) models.StateMachineReport {
+	t.Helper()
+	data := &models.CSVData{Headers: []string{"Time", "Type", "Name", "Buffer"}}
+	for _, r := range rows {
+		data.Rows = append(data.Rows, []string{"2025-11-14 17:03:" + r.time, "publish", "", "string=208:1:[" + r.phase + "]"})
+		data.Decoded = append(data.Decoded, enumField("Phase", r.phase, ""))
+	}
+	csvService := NewCSVService(t.TempDir())
+	if err := csvService.SaveCacheResult("test.csv", "CAN", data); err != nil {
+		t.Fatal(err)
+	}
+	configDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(configDir, "state_machines.json"), []byte(testStateMachines), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := NewStateMachineService(configDir, csvService).Check("test.csv", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Machines) != 1 {
+		t.Fatalf("machines = %d, want 1", len(result.Machines))
+	}
+	return result.Machines[0]
+}
+
+// 嗅探器记录的时间倒退时按时间回放，与曝光序列的顺序一致
+func TestStateCheckOrderedByTime(t *testing.T) {
+	report := checkPhases(t, []phaseRow{
+		{"36.000.000", "01"},
+		{"36.200.000", "04"},
+		{"36.100.000", "03"},
+		{"36.300.000", "01"},
+	})
+	if len(report.Violations) != 0 {
+		t.Errorf("violations = %+v, want none", report.Violations)
+	}
+	if report.Transitions != 3 {
+		t.Errorf("Transitions = %d, want 3", report.Transitions)
+	}
+}
+
+func TestStateCheckViolations(t *testing.T) {
+	tests := []struct {
+		name     string
+		rows     []phaseRow
+		kind     string
+		row      int
+		expected []string
+	}{
+		{"illegal transition", []phaseRow{{"36.000.000", "01"}, {"36.100.000", "05"}},
+			models.StateIllegalTransition, 1, []string{"03"}},
+		{"missing intermediate state", []phaseRow{{"36.000.000", "01"}, {"36.100.000", "01"}, {"36.200.000", "04"}},
+			models.StateMissing, 2, []string{"03"}},
+		{"unknown state", []phaseRow{{"36.000.000", "01"}, {"36.100.000", "07"}},
+			models.StateUnknown, 1, nil},
+		{"unexpected initial state", []phaseRow{{"36.000.000", "03"}, {"36.100.000", "04"}},
+			models.StateUnexpectedInitial, 0, []string{"01"}},
+		{"timeout at end of file", []phaseRow{{"36.000.000", "01"}, {"36.100.000", "03"}, {"36.400.000", "03"}, {"36.900.000", "03"}},
+			models.StateTimeout, 3, nil},
+	}
+	for _, tt := range tests {
+		report := checkPhases(t, tt.rows)
+		if len(report.Violations) != 1 {
+			t.Errorf("%s: violations = %+v, want 1", tt.name, report.Violations)
+			continue
+		}
+		v := report.Violations[0]
+		if v.Kind != tt.kind || v.Row != tt.row {
+			t.Errorf("%s: violation = %s at row %d, want %s at row %d", tt.name, v.Kind, v.Row, tt.kind, tt.row)
+		}
+		if strings.Join(v.Expected, ",") != strings.Join(tt.expected, ",") {
+			t.Errorf("%s: Expected = %v, want %v", tt.name, v.Expected, tt.expected)
+		}
+		if report.Counts[tt.kind] != 1 {
+			t.Errorf("%s: Counts = %v, want %s=1", tt.name, report.Counts, tt.kind)
+		}
+	}
+
+	report := checkPhases(t, []phaseRow{{"36.000.000", "01"}, {"36.100.000", "03"}, {"36.900.000", "03"}})
+	v := report.Violations[0]
+	if v.DurationMs == nil || *v.DurationMs != 800 || v.FromRow != 1 || !strings.Contains(v.Message, "到最后一条报文仍未离开") {
+		t.Errorf("timeout violation = %+v, want 800ms from row 1 at end of file", v)
+	}
+}
+
+// 超过maxStateViolations后只计数，不再返回明细
+func TestStateCheckTruncated(t *testing.T) {
+	// 01 → 05 为非法跳变，05 → 01 缺少中间状态04，每次跳变都违规
+	rows := []phaseRow{{"36.000.000", "01"}}
+	total := maxStateViolations + 5
+	for i := 0; i < total; i++ {
+		phase := "05"
+		if i%2 == 1 {
+			phase = "01"
+		}
+		rows = append(rows, phaseRow{"36.000.000", phase})
+	}
+
+	report := checkPhases(t, rows)
+	if !report.Truncated {
+		t.Error("Truncated = false, want true")
+	}
+	if len(report.Violations) != maxStateViolations {
+		t.Errorf("violations = %d, want %d", len(report.Violations), maxStateViolations)
+	}
+	if got := report.Counts[models.StateIllegalTransition] + report.Counts[models.StateMissing]; got != total {
+		t.Errorf("counted violations = %d, want %d", got, total)
+	}
+	if report.Transitions != total {
+		t.Errorf("Transitions = %d, want %d", report.Transitions, total)
+	}
+}
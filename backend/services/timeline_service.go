@@ -0,0 +1,283 @@
+package services
+
+import (
+	"csv-parser/models"
+	"csv-parser/rowcache"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// rtbCategory name_definitions.json中RTB信号的category
+const rtbCategory = "RTB Signal"
+
+// ushortValuePattern RTB行Buffer中的值: string=ushort=N
+var ushortValuePattern = regexp.MustCompile(`^\s*string=ushort=(\d+)`)
+
+// nameDefinition name_definitions.json中的一条定义
+type nameDefinition struct {
+	Description string `json:"description"`
+	VirtualID   string `json:"virtualId"`
+	Category    string `json:"category"`
+}
+
+// rtbSignal 一个RTB跳变事件的Name对应的信号线和电平
+type rtbSignal struct {
+	name  string // 原始Name
+	line  string
+	level int // -1表示Name没有 _HI/_LO 后缀，按Buffer中的值判断
+	def   nameDefinition
+}
+
+// TimelineService 由解析结果重建信号时间线
+type TimelineService struct {
+	csvService *CSVService
+	configDir  string
+}
+
+func NewTimelineService(configDir string, csvService *CSVService) *TimelineService {
+	return &TimelineService{
+		csvService: csvService,
+		configDir:  configDir,
+	}
+}
+
+// loadRTBSignals 读取name_definitions.json中category为RTB Signal的定义，键为规范化（小写）的Name
+// 配置文件不存在时返回空表，由调用方按Name前缀识别
+func (s *TimelineService) loadRTBSignals() (map[string]rtbSignal, error) {
+	signals := make(map[string]rtbSignal)
+	content, err := os.ReadFile(filepath.Join(s.configDir, "name_definitions.json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return signals, nil
+		}
+		return nil, err
+	}
+	var config struct {
+		Definitions map[string]nameDefinition `json:"definitions"`
+	}
+	if err := json.Unmarshal(content, &config); err != nil {
+		return nil, fmt.Errorf("解析name_definitions.json失败: %v", err)
+	}
+	for name, def := range config.Definitions {
+		if strings.EqualFold(def.Category, rtbCategory) {
+			signals[normalizeCANID(name)] = newRTBSignal(name, def)
+		}
+	}
+	return signals, nil
+}
+
+// newRTBSignal 按Name的 _HI/_LO 后缀确定信号线和跳变后的电平
+func newRTBSignal(name string, def nameDefinition) rtbSignal {
+	upper := strings.ToUpper(name)
+	switch {
+	case strings.HasSuffix(upper, "_HI"):
+		return rtbSignal{name: name, line: name[:len(name)-3], level: 1, def: def}
+	case strings.HasSuffix(upper, "_LO"):
+		return rtbSignal{name: name, line: name[:len(name)-3], level: 0, def: def}
+	}
+	return rtbSignal{name: name, line: name, level: -1, def: def}
+}
+
+// RTBTimeline 由CAN解析结果中的RTB跳变事件重建每条信号线的电平波形
+// lines为空时返回全部信号线；from/to（格式同 /api/parse）只筛选返回的跳变和脉冲，电平仍按整个文件计算
+func (s *TimelineService) RTBTimeline(filename string, lines []string, from, to string) (*models.RTBTimeline, error) {
+	fromTime, err := parseQueryTime(from)
+	if err != nil {
+		return nil, fmt.Errorf("%w: from: %v", ErrInvalidQuery, err)
+	}
+	toTime, err := parseQueryTime(to)
+	if err != nil {
+		return nil, fmt.Errorf("%w: to: %v", ErrInvalidQuery, err)
+	}
+
+	signals, err := s.loadRTBSignals()
+	if err != nil {
+		return nil, err
+	}
+
+	reader, err := s.csvService.OpenParsedCache(filename, "CAN")
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	timeline := &models.RTBTimeline{Start: rowcache.NoTime, End: rowcache.NoTime, Lines: []models.RTBLine{}}
+	err = reader.Scan(rowcache.ScanOptions{Columns: []int{}, Time: true}, func(row *rowcache.Row) error {
+		if row.Time == rowcache.NoTime {
+			return nil
+		}
+		if timeline.Start == rowcache.NoTime {
+			timeline.Start = row.Time
+		}
+		if timeline.End == rowcache.NoTime || row.Time > timeline.End {
+			timeline.End = row.Time
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if timeline.Start == rowcache.NoTime {
+		timeline.Start, timeline.End = 0, 0
+		return timeline, nil
+	}
+
+	// 用消息ID索引找出RTB行（非CAN帧的消息ID为规范化的Name）
+	ids := make(map[string]bool)
+	for _, id := range reader.IDs() {
+		if _, ok := signals[id]; ok {
+			ids[id] = true
+		} else if len(signals) == 0 && strings.HasPrefix(id, "rtb") {
+			ids[id] = true
+		}
+	}
+	if len(ids) == 0 {
+		return timeline, nil
+	}
+	rows, err := reader.RowsWithIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make(map[string]int)
+	for i, h := range reader.Headers() {
+		columns[strings.TrimPrefix(h, "\uFEFF")] = i
+	}
+	nameIdx, timeIdx, bufferIdx := -1, -1, -1
+	if idx, ok := columns["Name"]; ok {
+		nameIdx = idx
+	}
+	if idx, ok := columns["Time"]; ok {
+		timeIdx = idx
+	}
+	if idx, ok := columns["Buffer"]; ok {
+		bufferIdx = idx
+	}
+
+	wanted := idSet(lines)
+	byLine := make(map[string]*models.RTBLine)
+	err = reader.Scan(rowcache.ScanOptions{Rows: rows, Columns: []int{nameIdx, timeIdx, bufferIdx}, Time: true}, func(row *rowcache.Row) error {
+		if row.Time == rowcache.NoTime {
+			return nil
+		}
+		name := cell(row.Cells, nameIdx)
+		signal, ok := signals[normalizeCANID(name)]
+		if !ok {
+			signal = newRTBSignal(name, nameDefinition{})
+		}
+		if len(wanted) > 0 && !wanted[strings.ToLower(signal.line)] {
+			return nil
+		}
+		level := signal.level
+		if level < 0 {
+			level = 0
+			if m := ushortValuePattern.FindStringSubmatch(cell(row.Cells, bufferIdx)); m != nil && strings.Trim(m[1], "0") != "" {
+				level = 1
+			}
+		}
+
+		line, ok := byLine[signal.line]
+		if !ok {
+			line = &models.RTBLine{Name: signal.line, Description: signal.def.Description, VirtualID: signal.def.VirtualID}
+			byLine[signal.line] = line
+		}
+		line.Edges = append(line.Edges, models.RTBEdge{
+			Row:       row.Index,
+			Name:      name,
+			Time:      cell(row.Cells, timeIdx),
+			Timestamp: row.Time,
+			Relative:  float64(row.Time-timeline.Start) / 1e9,
+			Level:     level,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	fromNano, toNano := int64(rowcache.NoTime), int64(rowcache.NoTime)
+	if !fromTime.IsZero() {
+		fromNano = fromTime.UnixNano()
+	}
+	if !toTime.IsZero() {
+		toNano = toTime.UnixNano()
+	}
+	for _, line := range byLine {
+		buildPulses(line, timeline.End)
+		clipLine(line, fromNano, toNano)
+		timeline.Lines = append(timeline.Lines, *line)
+	}
+	sort.Slice(timeline.Lines, func(a, b int) bool { return timeline.Lines[a].Name < timeline.Lines[b].Name })
+	return timeline, nil
+}
+
+// buildPulses 按时间顺序回放跳变，生成高电平脉冲；文件开头按低电平处理，电平未变化的跳变标记为重复
+func buildPulses(line *models.RTBLine, end int64) {
+	// 嗅探器记录的时间可能倒退，按时间重新排序
+	sort.SliceStable(line.Edges, func(a, b int) bool { return line.Edges[a].Timestamp < line.Edges[b].Timestamp })
+
+	line.Pulses = []models.RTBPulse{}
+	level := 0
+	var high int64 // 高电平总时长（纳秒），最后再换算，避免累加舍入误差
+	var pulse *models.RTBPulse
+	for i := range line.Edges {
+		edge := &line.Edges[i]
+		if edge.Level == level {
+			edge.Repeated = true
+			line.Repeated++
+			continue
+		}
+		level = edge.Level
+		if level == 1 {
+			pulse = &models.RTBPulse{Start: edge.Timestamp, StartRow: edge.Row}
+			continue
+		}
+		pulse.End, pulse.EndRow = edge.Timestamp, edge.Row
+		pulse.WidthMs = nanoToMillis(pulse.End - pulse.Start)
+		line.Pulses = append(line.Pulses, *pulse)
+		high += pulse.End - pulse.Start
+		pulse = nil
+	}
+	if pulse != nil {
+		pulse.End, pulse.EndRow, pulse.Open = max(end, pulse.Start), -1, true
+		pulse.WidthMs = nanoToMillis(pulse.End - pulse.Start)
+		line.Pulses = append(line.Pulses, *pulse)
+		high += pulse.End - pulse.Start
+	}
+	line.HighTimeMs = nanoToMillis(high)
+}
+
+// clipLine 只保留时间范围内的跳变和与范围相交的脉冲，from/to为NoTime表示不限制
+func clipLine(line *models.RTBLine, from, to int64) {
+	if from == rowcache.NoTime && to == rowcache.NoTime {
+		return
+	}
+	inRange := func(t int64) bool {
+		return (from == rowcache.NoTime || t >= from) && (to == rowcache.NoTime || t <= to)
+	}
+	edges := line.Edges[:0]
+	for _, e := range line.Edges {
+		if inRange(e.Timestamp) {
+			edges = append(edges, e)
+		}
+	}
+	line.Edges = edges
+	pulses := line.Pulses[:0]
+	for _, p := range line.Pulses {
+		if (from == rowcache.NoTime || p.End >= from) && (to == rowcache.NoTime || p.Start <= to) {
+			pulses = append(pulses, p)
+		}
+	}
+	line.Pulses = pulses
+}
+
+// nanoToMillis 纳秒转换为毫秒，保留到微秒
+func nanoToMillis(nano int64) float64 {
+	return math.Round(float64(nano)/1e3) / 1e3
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"csv-parser/models"
+	"csv-parser/rowcache"
+	"reflect"
+	"testing"
+)
+
+const ms = int64(1e6)
+
+// edges 按(行号, 毫秒, 电平)构造跳变记录
+func edges(specs ...[3]int64) []models.RTBEdge {
+	var out []models.RTBEdge
+	for _, s := range specs {
+		out = append(out, models.RTBEdge{Row: int(s[0]), Timestamp: s[1] * ms, Level: int(s[2])})
+	}
+	return out
+}
+
+func TestBuildPulses(t *testing.T) {
+	tests := []struct {
+		name     string
+		edges    []models.RTBEdge
+		end      int64
+		pulses   []models.RTBPulse
+		highMs   float64
+		repeated int
+		order    []int // 排序后跳变的行号
+	}{
+		{
+			name:  "complete pulses",
+			edges: edges([3]int64{0, 10, 1}, [3]int64{1, 15, 0}, [3]int64{2, 20, 1}, [3]int64{3, 32, 0}),
+			end:   100 * ms,
+			pulses: []models.RTBPulse{
+				{Start: 10 * ms, End: 15 * ms, StartRow: 0, EndRow: 1, WidthMs: 5},
+				{Start: 20 * ms, End: 32 * ms, StartRow: 2, EndRow: 3, WidthMs: 12},
+			},
+			highMs: 17,
+			order:  []int{0, 1, 2, 3},
+		},
+		{
+			name: "repeated edges keep the first transition",
+			// 开头的低电平和重复的高/低电平都不构成跳变
+			edges:    edges([3]int64{0, 5, 0}, [3]int64{1, 10, 1}, [3]int64{2, 12, 1}, [3]int64{3, 20, 0}, [3]int64{4, 25, 0}),
+			end:      100 * ms,
+			pulses:   []models.RTBPulse{{Start: 10 * ms, End: 20 * ms, StartRow: 1, EndRow: 3, WidthMs: 10}},
+			highMs:   10,
+			repeated: 3,
+			order:    []int{0, 1, 2, 3, 4},
+		},
+		{
+			name:   "open pulse at end of file",
+			edges:  edges([3]int64{0, 10, 1}, [3]int64{1, 15, 0}, [3]int64{2, 40, 1}),
+			end:    55 * ms,
+			pulses: []models.RTBPulse{{Start: 10 * ms, End: 15 * ms, StartRow: 0, EndRow: 1, WidthMs: 5}, {Start: 40 * ms, End: 55 * ms, StartRow: 2, EndRow: -1, WidthMs: 15, Open: true}},
+			highMs: 20,
+			order:  []int{0, 1, 2},
+		},
+		{
+			name:   "open pulse never ends before its start",
+			edges:  edges([3]int64{0, 40, 1}),
+			end:    30 * ms,
+			pulses: []models.RTBPulse{{Start: 40 * ms, End: 40 * ms, StartRow: 0, EndRow: -1, Open: true}},
+			order:  []int{0},
+		},
+		{
+			name: "out-of-order timestamps",
+			// 下降沿记录在上升沿之前
+			edges:  edges([3]int64{0, 20, 0}, [3]int64{1, 10, 1}, [3]int64{2, 30, 1}, [3]int64{3, 35, 0}),
+			end:    100 * ms,
+			pulses: []models.RTBPulse{{Start: 10 * ms, End: 20 * ms, StartRow: 1, EndRow: 0, WidthMs: 10}, {Start: 30 * ms, End: 35 * ms, StartRow: 2, EndRow: 3, WidthMs: 5}},
+			highMs: 15,
+			order:  []int{1, 0, 2, 3},
+		},
+	}
+	for _, tt := range tests {
+		line := &models.RTBLine{Edges: tt.edges}
+		buildPulses(line, tt.end)
+		if !reflect.DeepEqual(line.Pulses, tt.pulses) {
+			t.Errorf("%s: pulses =\n %+v\nwant\n %+v", tt.name, line.Pulses, tt.pulses)
+		}
+		if line.HighTimeMs != tt.highMs || line.Repeated != tt.repeated {
+			t.Errorf("%s: HighTimeMs = %v, Repeated = %d, want %v, %d", tt.name, line.HighTimeMs, line.Repeated, tt.highMs, tt.repeated)
+		}
+		var order []int
+		for _, e := range line.Edges {
+			order = append(order, e.Row)
+		}
+		if !reflect.DeepEqual(order, tt.order) {
+			t.Errorf("%s: edge order = %v, want %v", tt.name, order, tt.order)
+		}
+	}
+}
+
+func TestClipLine(t *testing.T) {
+	build := func() *models.RTBLine {
+		line := &models.RTBLine{Edges: edges(
+			[3]int64{0, 10, 1}, [3]int64{1, 20, 0},
+			[3]int64{2, 30, 1}, [3]int64{3, 40, 0},
+			[3]int64{4, 50, 1}, [3]int64{5, 60, 0},
+		)}
+		buildPulses(line, 100*ms)
+		return line
+	}
+
+	tests := []struct {
+		name     string
+		from, to int64
+		edges    []int // 保留的跳变行号
+		pulses   []int // 保留的脉冲起始行号
+	}{
+		{"no limit", rowcache.NoTime, rowcache.NoTime, []int{0, 1, 2, 3, 4, 5}, []int{0, 2, 4}},
+		{"inside one pulse", 32 * ms, 38 * ms, nil, []int{2}},
+		{"overlapping pulse edges", 15 * ms, 35 * ms, []int{1, 2}, []int{0, 2}},
+		{"bounds are inclusive", 20 * ms, 50 * ms, []int{1, 2, 3, 4}, []int{0, 2, 4}},
+		{"from only", 45 * ms, rowcache.NoTime, []int{4, 5}, []int{4}},
+		{"to only", rowcache.NoTime, 25 * ms, []int{0, 1}, []int{0}},
+		{"between pulses", 22 * ms, 28 * ms, nil, nil},
+	}
+	for _, tt := range tests {
+		line := build()
+		highMs := line.HighTimeMs
+		clipLine(line, tt.from, tt.to)
+
+		var gotEdges, gotPulses []int
+		for _, e := range line.Edges {
+			gotEdges = append(gotEdges, e.Row)
+		}
+		for _, p := range line.Pulses {
+			gotPulses = append(gotPulses, p.StartRow)
+		}
+		if !reflect.DeepEqual(gotEdges, tt.edges) || !reflect.DeepEqual(gotPulses, tt.pulses) {
+			t.Errorf("%s: edges = %v, pulses = %v, want %v, %v", tt.name, gotEdges, gotPulses, tt.edges, tt.pulses)
+		}
+		// 统计值覆盖整个文件，不受范围影响
+		if line.HighTimeMs != highMs {
+			t.Errorf("%s: HighTimeMs = %v, want %v", tt.name, line.HighTimeMs, highMs)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+//go:build linux
+
+package socketcan
+
+import (
+	"csv-parser/models"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// Conn SocketCAN原始套接字连接
+type Conn struct {
+	file      *os.File
+	iface     string
+	fdEnabled bool
+}
+
+// Open 打开并绑定指定接口（如 can0、vcan0）的CAN_RAW套接字，尽可能启用CAN FD帧
+func Open(iface string) (*Conn, error) {
+	ifi, err := net.InterfaceByName(iface)
+	if err != nil {
+		return nil, fmt.Errorf("找不到CAN接口 %s: %v", iface, err)
+	}
+
+	fd, err := unix.Socket(unix.AF_CAN, unix.SOCK_RAW|unix.SOCK_CLOEXEC, unix.CAN_RAW)
+	if err != nil {
+		return nil, fmt.Errorf("创建SocketCAN套接字失败: %v", err)
+	}
+	// 接口不支持CAN FD时只收发经典帧
+	fdEnabled := unix.SetsockoptInt(fd, unix.SOL_CAN_RAW, unix.CAN_RAW_FD_FRAMES, 1) == nil
+	if err := unix.Bind(fd, &unix.SockaddrCAN{Ifindex: ifi.Index}); err != nil {
+		unix.Close(fd)
+		return nil, fmt.Errorf("绑定CAN接口 %s 失败: %v", iface, err)
+	}
+	// 非阻塞模式交给运行时的网络轮询器，Close可以中断阻塞中的Read
+	if err := unix.SetNonblock(fd, true); err != nil {
+		unix.Close(fd)
+		return nil, fmt.Errorf("设置非阻塞模式失败: %v", err)
+	}
+
+	return &Conn{
+		file:      os.NewFile(uintptr(fd), iface),
+		iface:     iface,
+		fdEnabled: fdEnabled,
+	}, nil
+}
+
+// Interface 返回绑定的接口名称
+func (c *Conn) Interface() string {
+	return c.iface
+}
+
+// ReadFrame 读取一帧，Time为接收时间，Channel为接口名称
+func (c *Conn) ReadFrame() (*models.LogFrame, error) {
+	buf := make([]byte, canFDMTU)
+	n, err := c.file.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+	frame, err := unmarshalFrame(buf[:n])
+	if err != nil {
+		return nil, err
+	}
+	frame.Time = time.Now()
+	frame.Channel = c.iface
+	return frame, nil
+}
+
+// WriteFrame 发送一帧
+func (c *Conn) WriteFrame(frame *models.CANFrame) error {
+	if frame.FD && !c.fdEnabled {
+		return fmt.Errorf("接口 %s 不支持CAN FD帧", c.iface)
+	}
+	buf, err := marshalFrame(frame)
+	if err != nil {
+		return err
+	}
+	_, err = c.file.Write(buf)
+	return err
+}
+
+// Close 关闭套接字，阻塞中的ReadFrame会返回错误
+func (c *Conn) Close() error {
+	return c.file.Close()
+}
@@ -0,0 +1,33 @@
+//go:build !linux
+
+package socketcan
+
+import "csv-parser/models"
+
+// Conn SocketCAN原始套接字连接（非Linux平台不可用）
+type Conn struct{}
+
+// Open 非Linux平台总是返回ErrUnsupported
+func Open(iface string) (*Conn, error) {
+	return nil, ErrUnsupported
+}
+
+// Interface 返回绑定的接口名称
+func (c *Conn) Interface() string {
+	return ""
+}
+
+// ReadFrame 非Linux平台不可用
+func (c *Conn) ReadFrame() (*models.LogFrame, error) {
+	return nil, ErrUnsupported
+}
+
+// WriteFrame 非Linux平台不可用
+func (c *Conn) WriteFrame(frame *models.CANFrame) error {
+	return ErrUnsupported
+}
+
+// Close 非Linux平台不可用
+func (c *Conn) Close() error {
+	return ErrUnsupported
+}
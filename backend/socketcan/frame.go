@@ -0,0 +1,100 @@
+// Package socketcan 通过Linux SocketCAN原始套接字收发CAN帧
+// 仅Linux可用，其他平台的Open返回ErrUnsupported
+package socketcan
+
+import (
+	"csv-parser/models"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// ErrUnsupported 当前平台不支持SocketCAN
+var ErrUnsupported = errors.New("SocketCAN is only supported on Linux")
+
+// linux/can.h 中的常量
+const (
+	canEFFFlag = 0x80000000
+	canRTRFlag = 0x40000000
+	canERRFlag = 0x20000000
+	canEFFMask = 0x1FFFFFFF
+	canSFFMask = 0x000007FF
+
+	canFDFlagFDF = 0x04 // CANFD_FDF
+
+	headerSize = 8  // can_id(4) + len(1) + flags/pad(1) + res0(1) + res1/len8_dlc(1)
+	canMTU     = 16 // sizeof(struct can_frame)
+	canFDMTU   = 72 // sizeof(struct canfd_frame)
+)
+
+// unmarshalFrame 解析 struct can_frame / canfd_frame（CAN ID为本机字节序）
+func unmarshalFrame(data []byte) (*models.LogFrame, error) {
+	if len(data) != canMTU && len(data) != canFDMTU {
+		return nil, fmt.Errorf("无效的SocketCAN帧长度: %d", len(data))
+	}
+	rawID := binary.NativeEndian.Uint32(data)
+
+	frame := &models.LogFrame{Direction: "Rx"}
+	frame.FD = len(data) == canFDMTU
+	frame.Extended = rawID&canEFFFlag != 0
+	frame.Remote = rawID&canRTRFlag != 0 && !frame.FD
+	frame.ErrorFrame = rawID&canERRFlag != 0
+	if frame.Extended || frame.ErrorFrame {
+		frame.ID = rawID & canEFFMask
+	} else {
+		frame.ID = rawID & canSFFMask
+	}
+	if frame.ErrorFrame {
+		frame.Extended = false
+	}
+
+	length := int(data[4])
+	if length > len(data)-headerSize {
+		length = len(data) - headerSize
+	}
+	frame.DLC = length
+	if !frame.Remote {
+		frame.Data = append([]byte(nil), data[headerSize:headerSize+length]...)
+	}
+	return frame, nil
+}
+
+// marshalFrame 将CAN帧编码为 struct can_frame（FD帧为 struct canfd_frame）
+func marshalFrame(frame *models.CANFrame) ([]byte, error) {
+	maxLen := 8
+	size := canMTU
+	if frame.FD {
+		maxLen = 64
+		size = canFDMTU
+	}
+	if len(frame.Data) > maxLen {
+		return nil, fmt.Errorf("数据长度 %d 超过 %d 字节", len(frame.Data), maxLen)
+	}
+
+	rawID := frame.ID & canSFFMask
+	if frame.Extended || frame.ID > canSFFMask {
+		rawID = frame.ID&canEFFMask | canEFFFlag
+	}
+	if frame.ErrorFrame {
+		rawID = frame.ID&canEFFMask | canERRFlag
+	}
+	if frame.Remote && !frame.FD {
+		rawID |= canRTRFlag
+	}
+
+	buf := make([]byte, size)
+	binary.NativeEndian.PutUint32(buf, rawID)
+	length := len(frame.Data)
+	if frame.Remote {
+		length = frame.DLC
+		if length < 0 || length > 8 {
+			length = 0
+		}
+	}
+	buf[4] = byte(length)
+	if frame.FD {
+		buf[5] = canFDFlagFDF
+	}
+	copy(buf[headerSize:], frame.Data)
+	return buf, nil
+}
@@ -0,0 +1,154 @@
+package socketcan
+
+import (
+	"bytes"
+	"csv-parser/models"
+	"encoding/binary"
+	"testing"
+)
+
+func TestMarshalUnmarshalRoundTrip(t *testing.T) {
+	fd64 := make([]byte, 64)
+	for i := range fd64 {
+		fd64[i] = byte(i)
+	}
+
+	tests := []struct {
+		name  string
+		frame models.CANFrame
+		size  int
+		rawID uint32
+		want  *models.CANFrame // 解析回来的帧，为nil时应与frame相同
+	}{
+		{
+			name:  "standard",
+			frame: models.CANFrame{ID: 0x2CF, DLC: 3, Data: []byte{0x10, 0x40, 0xFF}},
+			size:  canMTU,
+			rawID: 0x2CF,
+		},
+		{
+			name:  "standard empty",
+			frame: models.CANFrame{ID: 0x000, DLC: 0, Data: []byte{}},
+			size:  canMTU,
+			rawID: 0x000,
+		},
+		{
+			name:  "extended",
+			frame: models.CANFrame{ID: 0x18FF0001, Extended: true, DLC: 8, Data: []byte{1, 2, 3, 4, 5, 6, 7, 8}},
+			size:  canMTU,
+			rawID: 0x18FF0001 | canEFFFlag,
+		},
+		{
+			name:  "extended flag on short ID",
+			frame: models.CANFrame{ID: 0x123, Extended: true, DLC: 1, Data: []byte{0xAA}},
+			size:  canMTU,
+			rawID: 0x123 | canEFFFlag,
+		},
+		{
+			name:  "ID above 11 bits is sent as extended",
+			frame: models.CANFrame{ID: 0x800, DLC: 1, Data: []byte{0x01}},
+			size:  canMTU,
+			rawID: 0x800 | canEFFFlag,
+			want:  &models.CANFrame{ID: 0x800, Extended: true, DLC: 1, Data: []byte{0x01}},
+		},
+		{
+			name:  "remote",
+			frame: models.CANFrame{ID: 0x700, Remote: true, DLC: 4},
+			size:  canMTU,
+			rawID: 0x700 | canRTRFlag,
+		},
+		{
+			name:  "extended remote",
+			frame: models.CANFrame{ID: 0x1ABCDE, Extended: true, Remote: true, DLC: 8},
+			size:  canMTU,
+			rawID: 0x1ABCDE | canEFFFlag | canRTRFlag,
+		},
+		{
+			name:  "error",
+			frame: models.CANFrame{ID: 0x04, ErrorFrame: true, DLC: 8, Data: []byte{0, 0x10, 0, 0, 0, 0, 0, 0}},
+			size:  canMTU,
+			rawID: 0x04 | canERRFlag,
+		},
+		{
+			name:  "FD",
+			frame: models.CANFrame{ID: 0x3A0, FD: true, DLC: 12, Data: []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}},
+			size:  canFDMTU,
+			rawID: 0x3A0,
+		},
+		{
+			name:  "extended FD 64 bytes",
+			frame: models.CANFrame{ID: 0x1F000001, Extended: true, FD: true, DLC: 64, Data: fd64},
+			size:  canFDMTU,
+			rawID: 0x1F000001 | canEFFFlag,
+		},
+	}
+	for _, tt := range tests {
+		buf, err := marshalFrame(&tt.frame)
+		if err != nil {
+			t.Errorf("%s: marshalFrame: %v", tt.name, err)
+			continue
+		}
+		if len(buf) != tt.size {
+			t.Errorf("%s: size = %d, want %d", tt.name, len(buf), tt.size)
+		}
+		if rawID := binary.NativeEndian.Uint32(buf); rawID != tt.rawID {
+			t.Errorf("%s: can_id = 0x%08X, want 0x%08X", tt.name, rawID, tt.rawID)
+		}
+		if tt.frame.FD && buf[5]&canFDFlagFDF == 0 {
+			t.Errorf("%s: CANFD_FDF flag not set", tt.name)
+		}
+
+		got, err := unmarshalFrame(buf)
+		if err != nil {
+			t.Errorf("%s: unmarshalFrame: %v", tt.name, err)
+			continue
+		}
+		want := tt.want
+		if want == nil {
+			want = &tt.frame
+		}
+		c := got.CANFrame
+		if c.ID != want.ID || c.Extended != want.Extended || c.Remote != want.Remote || c.ErrorFrame != want.ErrorFrame ||
+			c.FD != want.FD || c.DLC != want.DLC || !bytes.Equal(c.Data, want.Data) {
+			t.Errorf("%s: round trip = %+v, want %+v", tt.name, c, want)
+		}
+		if want.Remote && c.Data != nil {
+			t.Errorf("%s: remote frame has data % X", tt.name, c.Data)
+		}
+		if got.Direction != "Rx" {
+			t.Errorf("%s: Direction = %q, want Rx", tt.name, got.Direction)
+		}
+	}
+}
+
+func TestMarshalFrameTooLong(t *testing.T) {
+	tests := []models.CANFrame{
+		{ID: 0x100, DLC: 9, Data: make([]byte, 9)},
+		{ID: 0x100, FD: true, DLC: 65, Data: make([]byte, 65)},
+	}
+	for _, frame := range tests {
+		if _, err := marshalFrame(&frame); err == nil {
+			t.Errorf("marshalFrame(FD=%v, %d bytes) succeeded, want error", frame.FD, len(frame.Data))
+		}
+	}
+}
+
+func TestUnmarshalFrameInvalid(t *testing.T) {
+	for _, size := range []int{0, 8, 15, 17, 71, 73} {
+		if _, err := unmarshalFrame(make([]byte, size)); err == nil {
+			t.Errorf("unmarshalFrame(%d bytes) succeeded, want error", size)
+		}
+	}
+
+	// len 大于缓冲区时截断到可用的数据字节
+	buf := make([]byte, canMTU)
+	binary.NativeEndian.PutUint32(buf, 0x123)
+	buf[4] = 15
+	frame, err := unmarshalFrame(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if frame.DLC != 8 || len(frame.Data) != 8 {
+		t.Errorf("DLC = %d, data = %d bytes, want 8", frame.DLC, len(frame.Data))
+	}
+}
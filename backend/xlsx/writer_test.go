@@ -0,0 +1,132 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// readSheet 解压XLSX并按行返回sheet1.xml中的单元格（列名 -> 文本）
+func readSheet(t *testing.T, data []byte) []map[string]string {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sheet []byte
+	for _, f := range zr.File {
+		if f.Name != "xl/worksheets/sheet1.xml" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		sheet, err = io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if sheet == nil {
+		t.Fatal("xl/worksheets/sheet1.xml not found")
+	}
+	if !utf8.Valid(sheet) {
+		t.Fatal("sheet1.xml is not valid UTF-8")
+	}
+
+	var doc struct {
+		Rows []struct {
+			R     string `xml:"r,attr"`
+			Cells []struct {
+				R string `xml:"r,attr"`
+				T string `xml:"is>t"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := xml.Unmarshal(sheet, &doc); err != nil {
+		t.Fatalf("sheet1.xml: %v", err)
+	}
+	var rows []map[string]string
+	for _, row := range doc.Rows {
+		cells := make(map[string]string)
+		for _, c := range row.Cells {
+			cells[c.R] = c.T
+		}
+		rows = append(rows, cells)
+	}
+	return rows
+}
+
+func TestWriterSheet(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, "CAN")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 每个汉字3字节，按字节截断会拆开最后一个字符
+	long := strings.Repeat("曝光", maxCellLength)
+	rows := [][]string{
+		{"时间", "ID", "含义"},
+		{"17:03:36", "", "<a & b>"},
+		{"x", "y", long},
+	}
+	for _, row := range rows {
+		if err := w.WriteRow(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	got := readSheet(t, buf.Bytes())
+	if len(got) != 3 {
+		t.Fatalf("rows = %d, want 3", len(got))
+	}
+	if got[0]["A1"] != "时间" || got[0]["C1"] != "含义" {
+		t.Errorf("header = %v", got[0])
+	}
+	if _, ok := got[1]["B2"]; ok || got[1]["C2"] != "<a & b>" {
+		t.Errorf("row 2 = %v", got[1])
+	}
+	cell := got[2]["C3"]
+	if n := utf8.RuneCountInString(cell); n != maxCellLength {
+		t.Errorf("long cell = %d characters, want %d", n, maxCellLength)
+	}
+	if !strings.HasPrefix(long, cell) {
+		t.Error("long cell is not a prefix of the original text")
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{"abc", 5, "abc"},
+		{"abcdef", 3, "abc"},
+		{"曝光开始", 2, "曝光"},
+		{"曝光", 2, "曝光"},
+		{"a曝b光", 3, "a曝b"},
+	}
+	for _, tt := range tests {
+		if got := truncate(tt.s, tt.n); got != tt.want {
+			t.Errorf("truncate(%q, %d) = %q, want %q", tt.s, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestColumnName(t *testing.T) {
+	tests := map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 701: "ZZ", 702: "AAA"}
+	for index, want := range tests {
+		if got := ColumnName(index); got != want {
+			t.Errorf("ColumnName(%d) = %q, want %q", index, got, want)
+		}
+	}
+}
@@ -1,62 +1,5 @@
 // 菜单功能实现
 
-// File 菜单功能
-function exportData(format) {
-    if (!unifiedRows || unifiedRows.length === 0) {
-        showMessage('没有数据可导出', 'warning');
-        return;
-    }
-
-    const urlParams = new URLSearchParams(window.location.search);
-    const filename = urlParams.get('file') || 'export';
-
-    if (format === 'csv') {
-        exportToCSV(filename);
-    } else if (format === 'json') {
-        exportToJSON(filename);
-    }
-}
-
-function exportToCSV(filename) {
-    const headers = ['Time', 'From->To', 'Id', 'Data'];
-    let csvContent = headers.join(',') + '\n';
-
-    unifiedRows.forEach(item => {
-        if (item && item.row) {
-            const row = item.row.map(cell => `"${cell}"`).join(',');
-            csvContent += row + '\n';
-        }
-    });
-
-    downloadFile(csvContent, `${filename}_export.csv`, 'text/csv');
-    showMessage('CSV文件导出成功', 'success');
-}
-
-function exportToJSON(filename) {
-    const jsonData = unifiedRows.map(item => ({
-        time: item.row[0],
-        fromTo: item.row[1],
-        id: item.row[2],
-        data: item.row[3]
-    }));
-
-    const jsonContent = JSON.stringify(jsonData, null, 2);
-    downloadFile(jsonContent, `${filename}_export.json`, 'application/json');
-    showMessage('JSON文件导出成功', 'success');
-}
-
-function downloadFile(content, filename, mimeType) {
-    const blob = new Blob([content], { type: mimeType });
-    const url = URL.createObjectURL(blob);
-    const a = document.createElement('a');
-    a.href = url;
-    a.download = filename;
-    document.body.appendChild(a);
-    a.click();
-    document.body.removeChild(a);
-    URL.revokeObjectURL(url);
-}
-
 // Decode 菜单功能
 function switchProtocol(protocol) {
     const urlParams = new URLSearchParams(window.location.search);
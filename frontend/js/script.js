@@ -2,6 +2,19 @@
 let currentFiles = [];
 let selectedProtocol = null; // 当前选择的协议类型
 
+// 常见的文件扩展名（嗅探器CSV及CAN日志格式），仅用于提示，后端按文件内容识别格式
+const SUPPORTED_EXTENSIONS = ['.csv', '.log', '.asc', '.blf', '.trc', '.pcap', '.pcapng'];
+
+// 后端按内容识别的文件格式对应的显示名称
+const FORMAT_NAMES = {
+    sniffer_csv: '嗅探器CSV',
+    candump: 'candump',
+    asc: 'Vector ASC',
+    blf: 'Vector BLF',
+    trc: 'PCAN-View TRC',
+    pcap: 'pcap/pcapng'
+};
+
 // 获取协议对应的 badge 样式类
 function getProtocolBadgeClass(protocol) {
     switch (protocol) {
@@ -55,7 +68,7 @@ function updateUIForProtocolSelection() {
         uploadArea.classList.remove('disabled');
         selectFileBtn.disabled = false;
         uploadTitle.textContent = '拖拽文件到此处或点击选择';
-        uploadSubtitle.textContent = '支持 .csv 格式文件';
+        uploadSubtitle.textContent = `支持 ${SUPPORTED_EXTENSIONS.join(' / ')} 格式文件`;
         protocolHint.innerHTML = `<i class="bi bi-check-circle-fill text-success me-1"></i>已选择 ${selectedProtocol} 协议`;
 
         // 显示协议 badge
@@ -151,12 +164,6 @@ async function uploadFile(file) {
         return;
     }
 
-    // 验证文件类型
-    if (!file.name.toLowerCase().endsWith('.csv')) {
-        showMessage('请选择CSV文件', 'error');
-        return;
-    }
-
     // 显示上传进度
     showUploadProgress(true);
 
@@ -338,15 +345,17 @@ function renderFilesList() {
                             ${protocolBadge}
                         </h6>
                         <p class="card-text text-muted small mb-0">
+                            <i class="bi bi-file-earmark-code me-1"></i>格式: ${escapeHtml(FORMAT_NAMES[file.format] || file.format || '未知')} | 
                             <i class="bi bi-hdd me-1"></i>大小: ${formatFileSize(file.size)} | 
                             <i class="bi bi-list-ol me-1"></i>行数: ${file.rowCount} | 
                             <i class="bi bi-columns me-1"></i>列数: ${file.columnCount} | 
                             <i class="bi bi-clock me-1"></i>上传时间: ${formatDate(file.uploadTime)}
                         </p>
+                        ${file.error ? `<p class="card-text text-danger small mb-0 mt-1"><i class="bi bi-exclamation-triangle me-1"></i>无法解析: ${escapeHtml(file.error)}</p>` : ''}
                     </div>
                     <div class="col-md-4 text-end">
                         <div class="btn-group" role="group">
-                            <button class="btn btn-primary btn-sm" onclick="parseFile('${file.filename}', '${selectedProtocol}')">
+                            <button class="btn btn-primary btn-sm" onclick="parseFile('${file.filename}', '${selectedProtocol}')" ${file.error ? 'disabled' : ''}>
                                 <i class="bi bi-tools me-1"></i>${selectedProtocol}解析
                             </button>
                             <button class="btn btn-danger btn-sm" onclick="deleteFile('${file.filename}')">
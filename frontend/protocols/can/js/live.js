@@ -0,0 +1,177 @@
+// 实时查看与回放：通过Server-Sent Events接收抓包解析出的帧并追加到预览表格，或将当前文件回放到SocketCAN接口
+// 页面地址: /can/preview.html?live=1[&ids=2cf,1cc][&exclude=208][&names=关键字]，过滤在服务端完成
+const LIVE_MAX_ROWS = 5000; // 最多保留的行数，超出时丢弃最早的行
+const LIVE_RENDER_INTERVAL = 300; // 表格刷新间隔（毫秒）
+
+let liveSource = null; // EventSource连接
+let liveRenderTimer = null; // 待执行的表格刷新
+
+// 进入实时查看模式
+function startLiveView(urlParams) {
+    const params = new URLSearchParams();
+    ['ids', 'exclude', 'names'].forEach(key => {
+        const value = urlParams.get(key);
+        if (value) {
+            params.set(key, value);
+        }
+    });
+
+    // 最新的帧显示在最上面
+    timeSortOrder = 'desc';
+    showPreview({ headers: [], rows: [], total: 0 }, '实时抓包', 'CAN');
+    const subtitle = document.getElementById('pageSubtitle');
+    if (subtitle) {
+        subtitle.textContent = params.toString() ? `过滤: ${decodeURIComponent(params.toString())}` : '';
+    }
+
+    liveSource = new EventSource(`/api/capture/stream?${params.toString()}`);
+    liveSource.addEventListener('frame', event => appendLiveFrame(JSON.parse(event.data)));
+    liveSource.addEventListener('status', event => updateLiveStatus(JSON.parse(event.data)));
+    liveSource.onerror = () => {
+        const previewInfo = document.getElementById('previewInfo');
+        if (previewInfo) {
+            previewInfo.textContent = '连接中断，正在重连...';
+        }
+    };
+}
+
+// 将一帧追加到统一视图数据，行格式与文件预览一致：Time, From->To, Id, Data, Description
+function appendLiveFrame(frame) {
+    let id = frame.name || 'N/A';
+    let dataField = frame.buffer || '';
+    const m = dataField.match(/^\s*string=([0-9a-fA-F]+):\d+:\[(.*?)\]\s*$/);
+    if (m) {
+        id = m[1];
+        dataField = m[2].trim().split(/\s+/).filter(b => b).map(b => b.toUpperCase()).join(' ');
+    }
+
+    const idColumnDisplay = frame.meaning ? `${frame.id} - ${frame.meaning}` : frame.id;
+    const descriptionField = (frame.decoded || []).map(field => field.display).join(' - ');
+    unifiedRows.push({
+        id: id,
+        lineNumber: unifiedRows.length + 1,
+        row: [frame.time, transformFromTo(frame.name, 'N/A', 'N/A'), idColumnDisplay, dataField, descriptionField],
+        highlight: frame.highlight || null // 服务端按row_highlight.json匹配的结果
+    });
+    if (unifiedRows.length > LIVE_MAX_ROWS) {
+        unifiedRows.splice(0, unifiedRows.length - LIVE_MAX_ROWS);
+    }
+    scheduleLiveRender();
+}
+
+// 合并短时间内的多次刷新
+function scheduleLiveRender() {
+    if (liveRenderTimer) {
+        return;
+    }
+    liveRenderTimer = setTimeout(() => {
+        liveRenderTimer = null;
+        renderTable(unifiedRows.length);
+    }, LIVE_RENDER_INTERVAL);
+}
+
+// 更新抓包状态徽章
+function updateLiveStatus(data) {
+    const previewInfo = document.getElementById('previewInfo');
+    if (!previewInfo) {
+        return;
+    }
+    const status = data.status;
+    if (!status || !status.running) {
+        previewInfo.textContent = status && status.error ? `抓包已停止: ${status.error}` : '未在抓包';
+        return;
+    }
+    const dropped = data.dropped > 0 ? ` | 丢弃:${data.dropped}` : '';
+    previewInfo.textContent = `实时 | ${status.interface} | 帧:${status.frameCount} | 显示:${unifiedRows.length}${dropped}`;
+}
+
+// 开始抓包
+async function startCapture() {
+    const iface = prompt('SocketCAN接口（留空使用配置中的接口）', '');
+    if (iface === null) {
+        return;
+    }
+    const formData = new FormData();
+    formData.append('interface', iface.trim());
+    formData.append('protocolType', 'CAN');
+    try {
+        const response = await fetch('/api/capture/start', { method: 'POST', body: formData });
+        const result = await response.json();
+        if (result.success) {
+            showMessage(`开始抓包: ${result.status.interface}`, 'success');
+        } else {
+            showMessage(result.message, 'error');
+        }
+    } catch (error) {
+        showMessage('开始抓包失败: ' + error.message, 'error');
+    }
+}
+
+// 停止抓包，抓包文件保存在上传目录中
+async function stopCapture() {
+    try {
+        const response = await fetch('/api/capture/stop', { method: 'POST' });
+        const result = await response.json();
+        if (result.success) {
+            const file = result.status.filename ? `，已保存为 ${result.status.filename}` : '';
+            showMessage(`抓包已停止，共 ${result.status.frameCount} 帧${file}`, 'success');
+        } else {
+            showMessage(result.message, 'error');
+        }
+    } catch (error) {
+        showMessage('停止抓包失败: ' + error.message, 'error');
+    }
+}
+
+// 将当前文件中可见消息的CAN帧回放到SocketCAN接口
+async function startReplay() {
+    const urlParams = new URLSearchParams(window.location.search);
+    const filename = urlParams.get('file');
+    if (!filename) {
+        showMessage('实时查看模式下没有可回放的文件', 'error');
+        return;
+    }
+    const iface = prompt('回放到SocketCAN接口', 'vcan0');
+    if (!iface) {
+        return;
+    }
+    const speed = prompt('回放速度倍数（1为原始速度）', '1');
+    if (speed === null) {
+        return;
+    }
+
+    const formData = new FormData();
+    formData.append('filename', filename);
+    formData.append('interface', iface.trim());
+    formData.append('speed', speed.trim() || '1');
+    formData.append('loop', confirm('是否循环回放？') ? 'true' : 'false');
+    // 与导出一致，只回放预览中可见的消息
+    const visibleIds = [...new Set(unifiedRows.map(item => item.id))].filter(id => !hiddenMessageIds.has(id));
+    formData.append('ids', visibleIds.join(','));
+    try {
+        const response = await fetch('/api/replay/start', { method: 'POST', body: formData });
+        const result = await response.json();
+        if (result.success) {
+            showMessage(`开始回放 ${result.status.totalFrames} 帧到 ${result.status.interface}`, 'success');
+        } else {
+            showMessage(result.message, 'error');
+        }
+    } catch (error) {
+        showMessage('开始回放失败: ' + error.message, 'error');
+    }
+}
+
+// 停止回放
+async function stopReplay() {
+    try {
+        const response = await fetch('/api/replay/stop', { method: 'POST' });
+        const result = await response.json();
+        if (result.success) {
+            showMessage(`回放已停止，已发送 ${result.status.sentFrames} 帧`, 'success');
+        } else {
+            showMessage(result.message, 'error');
+        }
+    } catch (error) {
+        showMessage('停止回放失败: ' + error.message, 'error');
+    }
+}
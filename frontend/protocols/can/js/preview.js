@@ -104,6 +104,77 @@ function parseNameData(name) {
     return config.displayText || null;
 }
 
+// 按DBC位编号取出信号原始值（intel: startBit为最低位；motorola: startBit为最高位，锯齿顺序）
+function extractSignalRaw(bytes, fieldConfig) {
+    const startBit = fieldConfig.startBit;
+    const length = fieldConfig.length;
+    if (startBit === undefined || !length || length > 64) return null;
+
+    const motorola = (fieldConfig.byteOrder || '').toLowerCase() === 'motorola';
+    const bitAt = (pos) => {
+        const byteIndex = Math.floor(pos / 8);
+        if (byteIndex >= bytes.length) return null;
+        return BigInt((parseInt(bytes[byteIndex], 16) >> (pos % 8)) & 1);
+    };
+
+    let raw = 0n;
+    if (!motorola) {
+        for (let i = length - 1; i >= 0; i--) {
+            const bit = bitAt(startBit + i);
+            if (bit === null) return null;
+            raw = (raw << 1n) | bit;
+        }
+        return raw;
+    }
+
+    let pos = startBit;
+    for (let i = 0; i < length; i++) {
+        const bit = bitAt(pos);
+        if (bit === null) return null;
+        raw = (raw << 1n) | bit;
+        pos = pos % 8 === 0 ? pos + 15 : pos - 1;
+    }
+    return raw;
+}
+
+// 信号原始值在values表中的键（有符号信号按补码转换）
+function signalRawKey(raw, fieldConfig) {
+    return fieldConfig.signed ? BigInt.asIntN(fieldConfig.length, raw).toString() : raw.toString();
+}
+
+// 信号物理值：原始值 * factor + offset
+function signalPhysicalValue(raw, fieldConfig) {
+    let value;
+    if (fieldConfig.valueType === 'float32' && fieldConfig.length === 32) {
+        const view = new DataView(new ArrayBuffer(4));
+        view.setUint32(0, Number(raw));
+        value = view.getFloat32(0);
+    } else if (fieldConfig.valueType === 'float64' && fieldConfig.length === 64) {
+        const view = new DataView(new ArrayBuffer(8));
+        view.setBigUint64(0, raw);
+        value = view.getFloat64(0);
+    } else if (fieldConfig.signed) {
+        value = Number(BigInt.asIntN(fieldConfig.length, raw));
+    } else {
+        value = Number(raw);
+    }
+    if (fieldConfig.factor !== undefined) value *= fieldConfig.factor;
+    return value + (fieldConfig.offset || 0);
+}
+
+// 未配置precision时，按factor和offset的小数位数确定显示精度
+function signalPrecision(fieldConfig) {
+    if (fieldConfig.precision !== undefined) return fieldConfig.precision;
+    if (fieldConfig.valueType) return 2;
+    let precision = 0;
+    for (const v of [fieldConfig.offset || 0, fieldConfig.factor !== undefined ? fieldConfig.factor : 1]) {
+        const text = v.toString();
+        const idx = text.indexOf('.');
+        if (idx >= 0) precision = Math.max(precision, text.length - idx - 1);
+    }
+    return Math.min(precision, 9);
+}
+
 // 解析CAN数据字节（按Z0-Z7 LSB-MSB顺序）
 function parseCanData(canId, dataBytes) {
     if (!canId || !dataBytes) return null;
@@ -124,17 +195,33 @@ function parseCanData(canId, dataBytes) {
         const orderB = config.bytes[b].order !== undefined ? config.bytes[b].order : 999;
         return orderA - orderB;
     });
+
+    // 多路复用：先取出选择信号的值，mN信号只在值匹配时解析
+    let muxValue = null;
+    for (const byteRange of byteRanges) {
+        const fieldConfig = config.bytes[byteRange];
+        if (fieldConfig.type === 'signal' && fieldConfig.multiplexer) {
+            const raw = extractSignalRaw(bytes, fieldConfig);
+            if (raw !== null) muxValue = Number(raw);
+            break;
+        }
+    }
+
     for (const byteRange of byteRanges) {
         const fieldConfig = config.bytes[byteRange];
         const fieldName = fieldConfig.name || byteRange;
 
+        if (fieldConfig.multiplexValue !== undefined && muxValue !== fieldConfig.multiplexValue) continue;
+
+        // 同一字节范围的多条规则以 "#后缀" 区分，例如 "0#Mode"
+        const rangeKey = byteRange.split('#')[0];
         let startByte, endByte;
-        if (byteRange.includes('-')) {
-            const [start, end] = byteRange.split('-').map(Number);
+        if (rangeKey.includes('-')) {
+            const [start, end] = rangeKey.split('-').map(Number);
             startByte = start;
             endByte = end;
         } else {
-            startByte = endByte = parseInt(byteRange);
+            startByte = endByte = parseInt(rangeKey);
         }
 
         // 检查字节范围是否有效
@@ -180,12 +267,12 @@ function parseCanData(canId, dataBytes) {
                 break;
 
             case 'uint32_le':
-                // 小端序32位无符号整数
+                // 小端序32位无符号整数（<< 24 会得到有符号数，用 >>> 0 转回无符号）
                 if (relevantBytes.length >= 4) {
-                    value = parseInt(relevantBytes[0], 16) +
+                    value = (parseInt(relevantBytes[0], 16) +
                         (parseInt(relevantBytes[1], 16) << 8) +
                         (parseInt(relevantBytes[2], 16) << 16) +
-                        (parseInt(relevantBytes[3], 16) << 24);
+                        (parseInt(relevantBytes[3], 16) << 24)) >>> 0;
                     if (fieldConfig.scale) value = (value * fieldConfig.scale).toFixed(2);
                     let valueStr = fieldConfig.unit ? `${value}${fieldConfig.unit}` : `${value}`;
                     // 如果有字段名且不为空，添加前缀
@@ -244,10 +331,10 @@ function parseCanData(canId, dataBytes) {
 
             case 'hex32_le':
                 if (relevantBytes.length >= 4) {
-                    value = parseInt(relevantBytes[0], 16) +
+                    value = (parseInt(relevantBytes[0], 16) +
                         (parseInt(relevantBytes[1], 16) << 8) +
                         (parseInt(relevantBytes[2], 16) << 16) +
-                        (parseInt(relevantBytes[3], 16) << 24);
+                        (parseInt(relevantBytes[3], 16) << 24)) >>> 0;
                     displayValue = `0x${relevantBytes[3].toUpperCase()}${relevantBytes[2].toUpperCase()}${relevantBytes[1].toUpperCase()}${relevantBytes[0].toUpperCase()}`;
                     if (fieldName && fieldName !== byteRange) {
                         displayValue = `${fieldName}: ${displayValue}`;
@@ -286,6 +373,25 @@ function parseCanData(canId, dataBytes) {
                 }
                 break;
 
+            case 'signal':
+                // 按位定义的信号（DBC导入）
+                {
+                    const raw = extractSignalRaw(bytes, fieldConfig);
+                    if (raw !== null) {
+                        value = signalPhysicalValue(raw, fieldConfig);
+                        const rawKey = signalRawKey(raw, fieldConfig);
+                        let signalStr;
+                        if (fieldConfig.values && fieldConfig.values[rawKey]) {
+                            signalStr = fieldConfig.values[rawKey];
+                        } else {
+                            const text = value.toFixed(signalPrecision(fieldConfig));
+                            signalStr = fieldConfig.unit ? `${text}${fieldConfig.unit}` : text;
+                        }
+                        displayValue = fieldName && fieldName !== byteRange ? `${fieldName} ${signalStr}` : signalStr;
+                    }
+                }
+                break;
+
             case 'ascii':
                 // 将字节转换为ASCII字符串
                 if (relevantBytes.length > 0) {
@@ -460,6 +566,12 @@ document.addEventListener('DOMContentLoaded', async function () {
     const filename = urlParams.get('file');
     const protocol = urlParams.get('protocol') || 'CAN';
 
+    // 实时查看模式（live.js）
+    if (urlParams.get('live')) {
+        startLiveView(urlParams);
+        return;
+    }
+
     if (!filename) {
         showMessage('缺少文件参数', 'error');
         setTimeout(() => {
@@ -656,16 +768,19 @@ function showPreview(data, filename, protocol = 'CAN') {
         // 解析 Buffer: 形如 string=2cf:8:[10 40 ff 37 48 c1 0a 00]
         let parsedId = '';
         let parsedData = '';
+        let rawData = '';
         if (buffer) {
             const m = buffer.match(/^\s*string=([0-9a-fA-F]+):\d+:\[(.*?)\]\s*$/);
             if (m) {
                 parsedId = m[1];
-                // 规范化数据字节为大写两位分隔
-                parsedData = m[2]
+                // 规范化数据字节为两位：显示用大写，解析用小写（与后端解码结果一致）
+                const dataBytes = m[2]
                     .trim()
                     .split(/\s+/)
-                    .map(b => b.toUpperCase())
-                    .join(' ');
+                    .filter(b => b)
+                    .map(b => b.padStart(2, '0'));
+                parsedData = dataBytes.map(b => b.toUpperCase()).join(' ');
+                rawData = dataBytes.map(b => b.toLowerCase()).join(' ');
             }
         }
 
@@ -694,7 +809,7 @@ function showPreview(data, filename, protocol = 'CAN') {
             let descriptionField = '';
             if (parsedData && parsedId) {
                 // 尝试解析CAN数据
-                const parsedMeaning = parseCanData(parsedId, parsedData);
+                const parsedMeaning = parseCanData(parsedId, rawData);
                 if (parsedMeaning) {
                     descriptionField = parsedMeaning;
                 }
@@ -848,8 +963,8 @@ function renderTable(totalRows) {
                 return '';
             }
 
-            // 获取行高亮样式
-            const highlightStyle = getRowHighlightStyle(item.row);
+            // 获取行高亮样式（实时查看的行已由服务端匹配）
+            const highlightStyle = item.highlight || getRowHighlightStyle(item.row);
             const rowStyle = highlightStyle ?
                 `background-color: ${highlightStyle.backgroundColor || 'inherit'}; color: ${highlightStyle.textColor || 'inherit'};` : '';
 
@@ -1030,6 +1145,35 @@ function updateTimeSortIcon() {
     sortBtn.title = sortText;
 }
 
+// 导出解析结果（csv/ndjson/xlsx）或CAN帧日志（candump/asc/trc），与当前预览使用相同的消息过滤
+// CAN帧日志总是按时间升序输出，因此只有解析结果使用当前的时间排序
+const FRAME_EXPORT_FORMATS = ['candump', 'asc', 'trc'];
+
+function exportData(format) {
+    const urlParams = new URLSearchParams(window.location.search);
+    const filename = urlParams.get('file');
+    const protocol = urlParams.get('protocol') || 'CAN';
+    if (!filename) {
+        showMessage('缺少文件参数', 'error');
+        return;
+    }
+
+    // 预览只显示定义过的消息，因此传递当前可见的消息ID而不是隐藏列表
+    const visibleIds = [...new Set(unifiedRows.map(item => item.id))].filter(id => !hiddenMessageIds.has(id));
+    if (visibleIds.length === 0) {
+        showMessage('没有可导出的数据', 'error');
+        return;
+    }
+
+    const params = new URLSearchParams({ protocol: protocol, format: format });
+    if (timeSortOrder !== 'none' && !FRAME_EXPORT_FORMATS.includes(format)) {
+        params.set('sort', 'time');
+        params.set('order', timeSortOrder);
+    }
+    params.set('ids', visibleIds.join(','));
+    window.location.href = `/api/export/${encodeURIComponent(filename)}?${params.toString()}`;
+}
+
 // 切换消息过滤（支持逗号分隔的多个ID）
 function toggleMessageFilter(messageIdStr) {
     // 解析逗号分隔的ID列表